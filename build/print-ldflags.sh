@@ -16,5 +16,7 @@
 
 version=$($(dirname "${BASH_SOURCE}")/print-version.sh)
 goversion=$(go env GOVERSION)
+gitcommit=$(git rev-parse HEAD 2>/dev/null)
+builddate=$(date -u +%Y-%m-%dT%H:%M:%SZ)
 
-echo "-ldflags \"-X sigs.k8s.io/kubectl-check-ownerreferences/pkg.Version=${version} -X sigs.k8s.io/kubectl-check-ownerreferences/pkg.GoVersion=${goversion}\""
+echo "-ldflags \"-X sigs.k8s.io/kubectl-check-ownerreferences/pkg.Version=${version} -X sigs.k8s.io/kubectl-check-ownerreferences/pkg.GoVersion=${goversion} -X sigs.k8s.io/kubectl-check-ownerreferences/pkg.GitCommit=${gitcommit} -X sigs.k8s.io/kubectl-check-ownerreferences/pkg.BuildDate=${builddate}\""
@@ -0,0 +1,78 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+func newCheckRefCommand(clientOpts *clientOptions) *cobra.Command {
+	opts := &pkg.CheckRefOptions{}
+	uid := ""
+	cmd := &cobra.Command{
+		Use:   "check-ref --api-version X --kind Y --name Z --uid U [--namespace N] [--child-namespace M]",
+		Short: "Check a single ownerReference against the cluster, without a scan",
+		Long: `Resolves an ownerReference and looks up its owner as the garbage collector would,
+and prints whether each check passes, to test a reference before writing it.
+
+The owner is looked up in --namespace (the kubeconfig namespace by default), and the
+reference is checked as if written to a child in --child-namespace, which defaults to
+the same namespace. Use --child-namespace="" for a cluster-scoped child.
+Exits with an error if any check fails.
+
+  kubectl-check-ownerreferences check-ref --api-version apps/v1 --kind ReplicaSet --name web-5d4f --uid 6f1c... -n team-a`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := clientOpts.complete(cmd.Flags()); err != nil {
+				return err
+			}
+			config, err := clientOpts.restConfig()
+			if err != nil {
+				return err
+			}
+			discoveryClient, metadataClient, err := clientOpts.clients(config)
+			if err != nil {
+				return err
+			}
+			opts.Validator = pkg.NewValidator(discoveryClient, metadataClient)
+			if opts.OwnerNamespace, _, err = clientOpts.configFlags.ToRawKubeConfigLoader().Namespace(); err != nil {
+				return err
+			}
+			if !cmd.Flags().Changed("child-namespace") {
+				opts.ChildNamespace = opts.OwnerNamespace
+			}
+			opts.OwnerReference.UID = types.UID(uid)
+			opts.Stdout = cmd.OutOrStdout()
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			return opts.Run(cmd.Context())
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&opts.OwnerReference.APIVersion, "api-version", opts.OwnerReference.APIVersion, "apiVersion of the ownerReference.")
+	flags.StringVar(&opts.OwnerReference.Kind, "kind", opts.OwnerReference.Kind, "kind of the ownerReference.")
+	flags.StringVar(&opts.OwnerReference.Name, "name", opts.OwnerReference.Name, "name of the ownerReference.")
+	flags.StringVar(&uid, "uid", uid, "uid of the ownerReference.")
+	flags.StringVar(&opts.ChildNamespace, "child-namespace", opts.ChildNamespace, "Namespace of the child the ownerReference would be written to, empty for a cluster-scoped child. Defaults to --namespace.")
+	flags.StringVarP(&opts.Output, "output", "o", opts.Output, "Output format. May be '' or 'json'.")
+	return cmd
+}
@@ -0,0 +1,266 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/metadata"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+// clientOptions holds the flags controlling how clients talk to the cluster
+type clientOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+
+	burst           int
+	qps             int
+	discoveryBurst  int
+	discoveryQPS    int
+	groupRateLimits []string
+
+	disableCompression bool
+
+	asServiceAccount string
+	readOnly         bool
+
+	recordFixtures string
+	replayFixtures string
+
+	// set by complete()
+	listLimit      rateLimit
+	discoveryLimit rateLimit
+	groupLimits    map[string]rateLimit
+	recorder       *pkg.FixtureRecorder
+	fixtures       *pkg.Fixtures
+}
+
+func newClientOptions() *clientOptions {
+	return &clientOptions{
+		configFlags:     genericclioptions.NewConfigFlags(false),
+		burst:           100,
+		qps:             25,
+		discoveryBurst:  100,
+		discoveryQPS:    25,
+		groupRateLimits: []string{},
+	}
+}
+
+func (o *clientOptions) addFlags(flags *pflag.FlagSet) {
+	o.configFlags.AddFlags(flags)
+
+	flags.IntVar(&o.burst, "burst", o.burst, "API requests allowed per second (burst).")
+	flags.IntVar(&o.qps, "qps", o.qps, "API requests allowed per second (steady state). Set to -1 to disable rate limiter.")
+	flags.IntVar(&o.discoveryBurst, "discovery-burst", o.discoveryBurst, "Discovery requests allowed per second (burst). Defaults to --burst.")
+	flags.IntVar(&o.discoveryQPS, "discovery-qps", o.discoveryQPS, "Discovery requests allowed per second (steady state). Defaults to --qps. Set to -1 to disable rate limiter.")
+	flags.StringSliceVar(&o.groupRateLimits, "group-rate-limit", o.groupRateLimits, "List requests allowed per second for a specific API group, as group=qps[:burst]. Use 'core' for the core group. May be repeated.")
+
+	flags.StringVar(&o.asServiceAccount, "as-service-account", o.asServiceAccount, "Service account to impersonate, as namespace/name, to see what a scan as that service account covers. Sets the service account user and groups.")
+	flags.BoolVar(&o.readOnly, "read-only", o.readOnly, "Refuse every API request that could modify the cluster, and refuse to start with options that write to it.")
+	flags.BoolVar(&o.disableCompression, "disable-compression", o.disableCompression, "If true, opt out of gzip response compression for all requests to the server. Compression helps over slow links, but costs server CPU.")
+
+	flags.StringVar(&o.recordFixtures, "record-fixtures", o.recordFixtures, "File to record sanitized discovery and list responses to at exit, for reproducing a run with --replay-fixtures. Annotation values and managed fields are removed. Compressed if the name ends in .gz.")
+	flags.StringVar(&o.replayFixtures, "replay-fixtures", o.replayFixtures, "File of responses recorded with --record-fixtures to serve instead of contacting a cluster.")
+}
+
+// complete validates and defaults the client flags
+func (o *clientOptions) complete(flags *pflag.FlagSet) error {
+	o.listLimit = rateLimit{qps: o.qps, burst: o.burst}
+	if err := o.listLimit.validate(); err != nil {
+		return err
+	}
+	o.discoveryLimit = o.listLimit
+	if flags.Changed("discovery-qps") {
+		o.discoveryLimit.qps = o.discoveryQPS
+	}
+	if flags.Changed("discovery-burst") {
+		o.discoveryLimit.burst = o.discoveryBurst
+	}
+	if err := o.discoveryLimit.validate(); err != nil {
+		return fmt.Errorf("discovery: %v", err)
+	}
+	groupLimits, err := parseGroupRateLimits(o.groupRateLimits, o.burst)
+	if err != nil {
+		return err
+	}
+	o.groupLimits = groupLimits
+
+	if o.asServiceAccount != "" {
+		if _, _, err := parseServiceAccount(o.asServiceAccount); err != nil {
+			return fmt.Errorf("invalid --as-service-account: %v", err)
+		}
+		if o.configFlags.Impersonate != nil && *o.configFlags.Impersonate != "" {
+			return fmt.Errorf("--as-service-account and --as cannot be used together")
+		}
+	}
+	if o.recordFixtures != "" && o.replayFixtures != "" {
+		return fmt.Errorf("--record-fixtures and --replay-fixtures cannot be used together")
+	}
+	if o.recordFixtures != "" && o.recorder == nil {
+		o.recorder = pkg.NewFixtureRecorder()
+		path := o.recordFixtures
+		atExit = append(atExit, func() {
+			if err := o.recorder.Write(path); err != nil {
+				klog.Errorf("error writing fixtures: %v", err)
+			}
+		})
+	}
+	if o.replayFixtures != "" && o.fixtures == nil {
+		fixtures, err := pkg.LoadFixtures(o.replayFixtures)
+		if err != nil {
+			return err
+		}
+		o.fixtures = fixtures
+	}
+	return nil
+}
+
+// restConfig returns the REST config for the selected cluster, falling back to in-cluster config
+func (o *clientOptions) restConfig() (*rest.Config, error) {
+	if o.fixtures != nil {
+		config := &rest.Config{Host: "http://fixtures.invalid", Transport: o.fixtures}
+		o.tune(config)
+		if o.readOnly {
+			config.Wrap(pkg.ReadOnly)
+		}
+		return config, nil
+	}
+	config, err := o.configFlags.ToRESTConfig()
+	if err != nil && (strings.Contains(err.Error(), "incomplete configuration") || strings.Contains(err.Error(), "no configuration")) {
+		// try falling back to in-cluster config
+		klog.Warningf("attempting to use in-cluster config, error loading client config: %v", err)
+		config, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return nil, err
+	}
+	o.configureClient(config)
+	return config, nil
+}
+
+// configureClient tunes config for scanning and applies the read-only guard, service account impersonation,
+// and fixture recorder, so every cluster the command talks to, including Cluster API workload clusters, is treated alike
+func (o *clientOptions) configureClient(config *rest.Config) {
+	o.tune(config)
+	if o.readOnly {
+		config.Wrap(pkg.ReadOnly)
+	}
+	if o.asServiceAccount != "" {
+		namespace, name, _ := parseServiceAccount(o.asServiceAccount)
+		config.Impersonate = serviceAccountImpersonation(namespace, name)
+	}
+	if o.recorder != nil {
+		config.Wrap(o.recorder.Wrap)
+	}
+}
+
+// tune adjusts config for scanning
+func (o *clientOptions) tune(config *rest.Config) {
+	// silence deprecation warnings, we're iterating over all types
+	config.WarningHandler = rest.NoWarnings{}
+	// prefer protobuf for efficiency
+	config.AcceptContentTypes = "application/vnd.kubernetes.protobuf,application/json"
+	config.DisableCompression = o.disableCompression
+}
+
+// checkReadOnly returns an error in read-only mode if any of writeFlags, the set flags that write to the cluster, are set
+func (o *clientOptions) checkReadOnly(writeFlags []string) error {
+	if o.readOnly && len(writeFlags) > 0 {
+		return fmt.Errorf("--read-only cannot be used with %s, which write to the cluster", strings.Join(writeFlags, ", "))
+	}
+	return nil
+}
+
+// restConfigForContext returns the REST config for the named kubeconfig context
+func (o *clientOptions) restConfigForContext(name string) (*rest.Config, error) {
+	previous := o.configFlags.Context
+	defer func() { o.configFlags.Context = previous }()
+	o.configFlags.Context = &name
+	return o.restConfig()
+}
+
+// contextNames returns the names of all contexts in the kubeconfig, sorted
+func (o *clientOptions) contextNames() ([]string, error) {
+	rawConfig, err := o.configFlags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(rawConfig.Contexts))
+	for name := range rawConfig.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// clusterInfo identifies the cluster config talks to
+func (o *clientOptions) clusterInfo(config *rest.Config) *pkg.ClusterInfo {
+	info := &pkg.ClusterInfo{Server: config.Host}
+	if o.configFlags.Context != nil && *o.configFlags.Context != "" {
+		info.Context = *o.configFlags.Context
+	} else if rawConfig, err := o.configFlags.ToRawKubeConfigLoader().RawConfig(); err == nil {
+		info.Context = rawConfig.CurrentContext
+	}
+	return info
+}
+
+// clients returns discovery and metadata clients built from config with the configured rate limits.
+// The metadata client falls back to getting full objects from API servers that reject metadata-only requests.
+func (o *clientOptions) clients(config *rest.Config) (discovery.DiscoveryInterface, metadata.Interface, error) {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(o.discoveryLimit.apply(config))
+	if err != nil {
+		return nil, nil, err
+	}
+	metadataClient, err := newMetadataClient(o.listLimit.apply(config), o.groupLimits)
+	if err != nil {
+		return nil, nil, err
+	}
+	// some older aggregated API servers only serve full objects
+	dynamicClient, groupDynamicClients, err := newDynamicClients(o.listLimit.apply(config), o.groupLimits)
+	if err != nil {
+		return nil, nil, err
+	}
+	return discoveryClient, pkg.NewMetadataFallbackClient(metadataClient, dynamicClient, groupDynamicClients, os.Stderr), nil
+}
+
+// parseServiceAccount parses a service account reference in namespace/name form
+func parseServiceAccount(value string) (string, string, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("expected namespace/name, got %q", value)
+	}
+	return parts[0], parts[1], nil
+}
+
+// serviceAccountImpersonation returns the user and groups the service account is authenticated as
+func serviceAccountImpersonation(namespace, name string) rest.ImpersonationConfig {
+	return rest.ImpersonationConfig{
+		UserName: "system:serviceaccount:" + namespace + ":" + name,
+		Groups:   []string{"system:serviceaccounts", "system:serviceaccounts:" + namespace, "system:authenticated"},
+	}
+}
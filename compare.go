@@ -0,0 +1,81 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+func newCompareCommand(clientOpts *clientOptions) *cobra.Command {
+	contexts := []string{}
+	rules := &ruleOptions{}
+	opts := &pkg.CompareOptions{}
+	cmd := &cobra.Command{
+		Use:   "compare --context A --context B",
+		Short: "Compare findings in two clusters",
+		Long: `Scans the clusters of two kubeconfig contexts and prints the findings present in only one of them,
+for example to validate a cluster rebuild, migration, or blue/green cluster swap.
+Objects are matched by resource, namespace, and name, and owners by group, kind, and name,
+since UIDs differ between clusters.
+
+  kubectl-check-ownerreferences compare --context blue --context green`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(contexts) != 2 {
+				return fmt.Errorf("exactly two --context flags are required, got %d", len(contexts))
+			}
+			if err := clientOpts.complete(cmd.Flags()); err != nil {
+				return err
+			}
+			clusters := []*pkg.ClusterScan{&opts.A, &opts.B}
+			for i, context := range contexts {
+				config, err := clientOpts.restConfigForContext(context)
+				if err != nil {
+					return fmt.Errorf("context %s: %v", context, err)
+				}
+				discoveryClient, metadataClient, err := clientOpts.clients(config)
+				if err != nil {
+					return fmt.Errorf("context %s: %v", context, err)
+				}
+				verifyOpts := &pkg.VerifyGCOptions{DiscoveryClient: discoveryClient, MetadataClient: metadataClient}
+				if err := rules.configure(verifyOpts); err != nil {
+					return err
+				}
+				*clusters[i] = pkg.ClusterScan{Name: context, Verify: verifyOpts}
+			}
+			opts.Stderr = os.Stderr
+			opts.Stdout = os.Stdout
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			return opts.Run(cmd.Context())
+		},
+	}
+	// shadows the persistent --context flag selecting a single cluster
+	cmd.Flags().StringArrayVar(&contexts, "context", contexts, "Kubeconfig context of a cluster to compare. Must be specified twice.")
+	cmd.RegisterFlagCompletionFunc("context", contextCompletion(clientOpts))
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", opts.Output, "Output format. May be '' or 'json'.")
+	cmd.Flags().BoolVar(&opts.ShowCommon, "show-common", opts.ShowCommon, "Include findings present in both clusters in the table output.")
+	cmd.Flags().BoolVar(&opts.FailOnDifferences, "fail-on-differences", opts.FailOnDifferences, "Exit with an error if any findings are only present in one of the clusters.")
+	rules.addFlags(cmd.Flags())
+	return cmd
+}
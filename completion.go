@@ -0,0 +1,212 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+func newCompletionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion bash|zsh|fish|powershell",
+		Short: "Output shell completion code for the specified shell",
+		Long: `Output shell completion code for the specified shell (bash, zsh, fish, or powershell).
+
+To load completions in the current bash shell:
+
+  source <(kubectl-check-ownerreferences completion bash)
+
+To load completions in the current zsh shell:
+
+  source <(kubectl-check-ownerreferences completion zsh)
+  compdef _kubectl-check-ownerreferences kubectl-check-ownerreferences`,
+		Args:      cobra.ExactValidArgs(1),
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := cmd.Root()
+			out := cmd.OutOrStdout()
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletion(out)
+			case "zsh":
+				return root.GenZshCompletion(out)
+			case "fish":
+				return root.GenFishCompletion(out, true)
+			case "powershell":
+				return root.GenPowerShellCompletion(out)
+			default:
+				return fmt.Errorf("unsupported shell %q", args[0])
+			}
+		},
+	}
+}
+
+// registerCompletions registers dynamic completion functions for the client flags,
+// and for the flags of cmd and its subcommands that take namespaces and resources
+func registerCompletions(cmd *cobra.Command, clientOpts *clientOptions) {
+	namespaceCompletion := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return filterCompletions(completeNamespaces(cmd, clientOpts), toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+	cmd.RegisterFlagCompletionFunc("namespace", namespaceCompletion)
+	for _, name := range []string{"capi-namespace", "service-account-namespace", "leader-elect-lease-namespace"} {
+		registerFlagCompletion(cmd, name, namespaceCompletion)
+	}
+	registerFlagCompletion(cmd, "namespaces", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return filterListCompletions(completeNamespaces(cmd, clientOpts), toComplete), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	})
+	registerFlagCompletion(cmd, "start-from", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return filterCompletions(completeResources(cmd, clientOpts), toComplete), cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.RegisterFlagCompletionFunc("context", contextCompletion(clientOpts))
+	cmd.RegisterFlagCompletionFunc("cluster", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		config, err := clientOpts.configFlags.ToRawKubeConfigLoader().RawConfig()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		names := []string{}
+		for name := range config.Clusters {
+			names = append(names, name)
+		}
+		return filterCompletions(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// registerFlagCompletion registers completion for the named flag of cmd and each of its subcommands that defines it
+func registerFlagCompletion(cmd *cobra.Command, name string, f func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)) {
+	if cmd.Flags().Lookup(name) != nil {
+		cmd.RegisterFlagCompletionFunc(name, f)
+	}
+	for _, subcommand := range cmd.Commands() {
+		registerFlagCompletion(subcommand, name, f)
+	}
+}
+
+// contextCompletion completes kubeconfig context names
+func contextCompletion(clientOpts *clientOptions) func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		names, err := clientOpts.contextNames()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return filterCompletions(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeNamespaces lists namespace names from the cluster, returning nil on any error
+func completeNamespaces(cmd *cobra.Command, clientOpts *clientOptions) []string {
+	if err := clientOpts.complete(cmd.Flags()); err != nil {
+		return nil
+	}
+	config, err := clientOpts.restConfig()
+	if err != nil {
+		return nil
+	}
+	_, metadataClient, err := clientOpts.clients(config)
+	if err != nil {
+		return nil
+	}
+	list, err := metadataClient.Resource(schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil
+	}
+	names := []string{}
+	for _, item := range list.Items {
+		names = append(names, item.Name)
+	}
+	return names
+}
+
+// completeResources returns the resources scans list with the verbs of --require-verbs, if set,
+// as group/resource or resource for the core group, returning nil on any error
+func completeResources(cmd *cobra.Command, clientOpts *clientOptions) []string {
+	if err := clientOpts.complete(cmd.Flags()); err != nil {
+		return nil
+	}
+	config, err := clientOpts.restConfig()
+	if err != nil {
+		return nil
+	}
+	discoveryClient, _, err := clientOpts.clients(config)
+	if err != nil {
+		return nil
+	}
+	verbs := pkg.DefaultRequiredVerbs
+	if flag := cmd.Flags().Lookup("require-verbs"); flag != nil && flag.Changed {
+		if verbs, err = cmd.Flags().GetStringSlice("require-verbs"); err != nil {
+			return nil
+		}
+	}
+	gvrs, err := pkg.ScannedResources(discoveryClient, verbs)
+	if err != nil {
+		return nil
+	}
+	resources := []string{}
+	for _, gvr := range gvrs {
+		if gvr.Group == "" {
+			resources = append(resources, gvr.Resource)
+		} else {
+			resources = append(resources, gvr.Group+"/"+gvr.Resource)
+		}
+	}
+	return resources
+}
+
+// filterListCompletions completes the last item of a comma-separated list with the candidates that start with it,
+// excluding candidates already in the list
+func filterListCompletions(candidates []string, toComplete string) []string {
+	i := strings.LastIndex(toComplete, ",")
+	listed := strings.Split(toComplete[:i+1], ",")
+	completions := []string{}
+	for _, candidate := range filterCompletions(candidates, toComplete[i+1:]) {
+		if !contains(listed, candidate) {
+			completions = append(completions, toComplete[:i+1]+candidate)
+		}
+	}
+	return completions
+}
+
+// filterCompletions returns the sorted candidates that start with prefix
+func filterCompletions(candidates []string, prefix string) []string {
+	filtered := []string{}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			filtered = append(filtered, candidate)
+		}
+	}
+	sort.Strings(filtered)
+	return filtered
+}
+
+// contains returns true if values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+func newCoverageCommand(clientOpts *clientOptions) *cobra.Command {
+	personas := []string{}
+	opts := &pkg.CoverageOptions{}
+	cmd := &cobra.Command{
+		Use:   "coverage",
+		Short: "Report which resources scans can list, for each persona",
+		Long: `Reviews access to list each resource scans list with SelfSubjectAccessReviews,
+and prints whether each persona can list it in all namespaces, as scans do.
+
+By default, access is reviewed for the current user, or the user impersonated with
+--as or --as-service-account. Use --personas to review access for several service
+accounts at once by impersonating each, and --namespaces to also show which of those
+namespaces a tenant-scoped persona can list resources in.
+
+  kubectl-check-ownerreferences coverage --personas=monitoring/checker,team-a/deployer --namespaces=team-a`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := clientOpts.complete(cmd.Flags()); err != nil {
+				return err
+			}
+			config, err := clientOpts.restConfig()
+			if err != nil {
+				return err
+			}
+			if opts.DiscoveryClient, _, err = clientOpts.clients(config); err != nil {
+				return err
+			}
+			config = clientOpts.listLimit.apply(config)
+
+			if len(personas) == 0 {
+				name := "current"
+				if clientOpts.asServiceAccount != "" {
+					name = clientOpts.asServiceAccount
+				} else if config.Impersonate.UserName != "" {
+					name = config.Impersonate.UserName
+				}
+				client, err := authorizationv1client.NewForConfig(config)
+				if err != nil {
+					return err
+				}
+				opts.Personas = append(opts.Personas, pkg.CoveragePersona{Name: name, Client: client})
+			}
+			for _, persona := range personas {
+				namespace, name, err := parseServiceAccount(persona)
+				if err != nil {
+					return fmt.Errorf("invalid --personas: %v", err)
+				}
+				personaConfig := rest.CopyConfig(config)
+				personaConfig.Impersonate = serviceAccountImpersonation(namespace, name)
+				client, err := authorizationv1client.NewForConfig(personaConfig)
+				if err != nil {
+					return err
+				}
+				opts.Personas = append(opts.Personas, pkg.CoveragePersona{Name: persona, Client: client})
+			}
+			opts.Stderr = os.Stderr
+			opts.Stdout = cmd.OutOrStdout()
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			return opts.Run(cmd.Context())
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringSliceVar(&personas, "personas", personas, "Service accounts to review access for by impersonating each, as namespace/name. Requires permission to impersonate them.")
+	flags.StringSliceVar(&opts.Namespaces, "namespaces", opts.Namespaces, "Namespaces to review access in for resources a persona cannot list in all namespaces.")
+	flags.StringVarP(&opts.Output, "output", "o", opts.Output, "Output format. May be '' or 'json'.")
+	return cmd
+}
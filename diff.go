@@ -0,0 +1,76 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+func newDiffCommand() *cobra.Command {
+	opts := &pkg.DiffOptions{}
+	cmd := &cobra.Command{
+		Use:   "diff OLD NEW",
+		Short: "Compare findings from two scans",
+		Long: `Reads findings written by "scan -o json", or reports from /results or --export,
+and prints the findings introduced and resolved between the OLD and NEW scans.
+Findings are matched by object and code, independent of the order they were reported in.
+Either file may be '-' to read from stdin.
+
+  kubectl-check-ownerreferences scan -o json > before.json
+  ...
+  kubectl-check-ownerreferences scan -o json > after.json
+  kubectl-check-ownerreferences diff before.json after.json`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			oldInput, err := openInput(args[0])
+			if err != nil {
+				return err
+			}
+			defer oldInput.Close()
+			newInput, err := openInput(args[1])
+			if err != nil {
+				return err
+			}
+			defer newInput.Close()
+			opts.Old = oldInput
+			opts.New = newInput
+			opts.Stderr = cmd.ErrOrStderr()
+			opts.Stdout = cmd.OutOrStdout()
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			return opts.Run()
+		},
+	}
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", opts.Output, "Output format. May be '' or 'json'.")
+	cmd.Flags().BoolVar(&opts.ShowUnchanged, "show-unchanged", opts.ShowUnchanged, "Include findings present in both scans in the table output.")
+	cmd.Flags().BoolVar(&opts.FailOnIntroduced, "fail-on-introduced", opts.FailOnIntroduced, "Exit with an error if any findings were introduced.")
+	return cmd
+}
+
+// openInput opens filename for reading, or stdin if filename is '-'
+func openInput(filename string) (io.ReadCloser, error) {
+	if filename == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(filename)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// envPrefix prefixes the names of the environment variables that set flags
+const envPrefix = "CHECK_OWNERREFERENCES_"
+
+// envVarName returns the name of the environment variable that sets the named flag, e.g.
+// CHECK_OWNERREFERENCES_PUBLISH_CONFIGMAP for --publish-configmap
+func envVarName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(flag))
+}
+
+// applyEnv sets each flag not set on the command line from its environment variable, if set,
+// so flags take precedence over the environment. Slice and map flags are parsed as on the command line,
+// e.g. CHECK_OWNERREFERENCES_CONTEXTS=a,b.
+func applyEnv(flags *pflag.FlagSet) error {
+	var errs []string
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed || flag.Name == "help" || flag.Name == "version" {
+			return
+		}
+		name := envVarName(flag.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if err := flags.Set(flag.Name, value); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid value %q for %s: %v", value, name, err))
+		}
+	})
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
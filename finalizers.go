@@ -0,0 +1,65 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+func newFinalizersCommand(clientOpts *clientOptions) *cobra.Command {
+	opts := &pkg.FinalizersOptions{}
+	cmd := &cobra.Command{
+		Use:   "finalizers",
+		Short: "List the finalizers present cluster-wide, with object counts",
+		Long: `Lists the resources scans list, and prints each finalizer present on their objects
+with the number of objects it is on, how many of those are being deleted, and the
+namespaces with the most of them.
+
+Finalizers qualified by a domain no served API group matches are marked as missing
+their controller: the controller that would remove them, or its CRDs, were likely
+uninstalled, and objects being deleted with them will not go away.
+
+  kubectl-check-ownerreferences finalizers -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := clientOpts.complete(cmd.Flags()); err != nil {
+				return err
+			}
+			config, err := clientOpts.restConfig()
+			if err != nil {
+				return err
+			}
+			if opts.DiscoveryClient, opts.MetadataClient, err = clientOpts.clients(config); err != nil {
+				return err
+			}
+			opts.Stderr = os.Stderr
+			opts.Stdout = cmd.OutOrStdout()
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			return opts.Run(cmd.Context())
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.Output, "output", "o", opts.Output, "Output format. May be '' or 'json'.")
+	flags.StringSliceVar(&opts.RequiredVerbs, "require-verbs", pkg.DefaultRequiredVerbs, "Verbs a resource must support to be listed, matching the --require-verbs of scans.")
+	return cmd
+}
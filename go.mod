@@ -3,10 +3,21 @@ module sigs.k8s.io/kubectl-check-ownerreferences
 go 1.16
 
 require (
+	github.com/google/cel-go v0.9.0
 	github.com/google/go-cmp v0.5.5
+	github.com/open-policy-agent/opa v0.33.1
+	github.com/spf13/cobra v1.2.1
 	github.com/spf13/pflag v1.0.5
+	go.etcd.io/bbolt v1.3.6
+	golang.org/x/oauth2 v0.0.0-20210514164344-f6687ab2804c
+	golang.org/x/term v0.0.0-20210220032956-6a3ed077a48d
+	google.golang.org/grpc v1.40.0
+	google.golang.org/protobuf v1.27.1
+	k8s.io/api v0.22.1
 	k8s.io/apimachinery v0.22.1
 	k8s.io/cli-runtime v0.22.1
 	k8s.io/client-go v0.22.1
 	k8s.io/klog/v2 v2.9.0
+	modernc.org/sqlite v1.14.6
+	sigs.k8s.io/yaml v1.2.0
 )
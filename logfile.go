@@ -0,0 +1,114 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// rotatingFile appends to a file, renaming it to path.1 (replacing any previous backup) when it exceeds maxSize bytes
+type rotatingFile struct {
+	path    string
+	maxSize int64
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+func openRotatingFile(path string, maxSize int64) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, maxSize: maxSize}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.file.Close(); err != nil {
+			return 0, err
+		}
+		if err := os.Rename(f.path, f.path+".1"); err != nil {
+			return 0, err
+		}
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.file.Close()
+}
+
+// teeStderr copies everything written to os.Stderr from now on, including logs, to a rotating file at path as well as
+// the original stderr. The returned function restores os.Stderr and closes the file once everything written is copied.
+func teeStderr(path string, maxSizeMB int) (func(), error) {
+	if maxSizeMB < 0 {
+		return nil, fmt.Errorf("invalid --log-file-max-size, must be >= 0")
+	}
+	file, err := openRotatingFile(path, int64(maxSizeMB)*1024*1024)
+	if err != nil {
+		return nil, fmt.Errorf("error opening log file: %v", err)
+	}
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	stderr := os.Stderr
+	os.Stderr = writer
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		io.Copy(io.MultiWriter(stderr, file), reader)
+		// keep draining to stderr if writing the file fails, so writers do not block
+		io.Copy(stderr, reader)
+	}()
+	return func() {
+		os.Stderr = stderr
+		writer.Close()
+		<-done
+		reader.Close()
+		file.Close()
+	}, nil
+}
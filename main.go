@@ -17,94 +17,138 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
-	"fmt"
 	"os"
-	"strings"
+	"os/signal"
+	"syscall"
 
-	"github.com/spf13/pflag"
+	"github.com/spf13/cobra"
 	"k8s.io/klog/v2"
 
 	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
-
-	"k8s.io/cli-runtime/pkg/genericclioptions"
-	"k8s.io/client-go/discovery"
-	"k8s.io/client-go/metadata"
-	_ "k8s.io/client-go/plugin/pkg/client/auth"
-	"k8s.io/client-go/rest"
 )
 
+// atExit holds functions to run before the process exits
+var atExit []func()
+
+func exit(code int) {
+	for i := len(atExit) - 1; i >= 0; i-- {
+		atExit[i]()
+	}
+	os.Exit(code)
+}
+
 func checkErr(err error) {
 	if err != nil {
 		klog.Error(err.Error())
-		os.Exit(1)
+		exitErr := &pkg.ExitError{}
+		if errors.As(err, &exitErr) {
+			exit(exitErr.Code)
+		}
+		exit(1)
 	}
 }
 
-func main() {
-	version := false
-	flag.BoolVar(&version, "version", version, "display version information")
+func newRootCommand() *cobra.Command {
+	clientOpts := newClientOptions()
+	scanOpts := newScanOptions()
+
+	profileOutput := ""
+	pprofAddress := ""
+	logFile := ""
+	logFileMaxSize := 0
+
+	cmd := &cobra.Command{
+		Use:   "kubectl-check-ownerreferences",
+		Short: "Identify objects with problematic ownerReferences",
+		Long: `kubectl-check-ownerreferences identifies objects with potentially
+problematic items in metadata.ownerReferences.
+
+It reads cluster metadata and, only with publishing options, writes reports
+and Events to the cluster. Set --read-only to guarantee nothing is written.
+
+Invoking it without a subcommand is equivalent to "kubectl-check-ownerreferences scan".
+
+Each flag not set on the command line may be set with an environment variable
+named CHECK_OWNERREFERENCES_ followed by the flag name in upper case with
+dashes replaced by underscores, e.g. CHECK_OWNERREFERENCES_OUTPUT=json.`,
+		Version:       pkg.Version,
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := applyEnv(cmd.Flags()); err != nil {
+				return err
+			}
+			if logFile != "" {
+				stopLogging, err := teeStderr(logFile, logFileMaxSize)
+				if err != nil {
+					return err
+				}
+				atExit = append(atExit, stopLogging)
+				stderrTerminal = false
+			}
+			stopProfiling, err := startProfiling(profileOutput, pprofAddress)
+			if err != nil {
+				return err
+			}
+			atExit = append(atExit, stopProfiling)
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runScan(cmd, clientOpts, scanOpts)
+		},
+	}
+	cmd.SetVersionTemplate(versionString())
+
+	// bare invocation accepts the same flags as scan
+	scanOpts.addFlags(cmd.Flags())
 
-	output := ""
-	burst := 100
-	qps := 25
-	pflag.StringVarP(&output, "output", "o", output, "Output format. May be '' or 'json'.")
-	pflag.IntVar(&burst, "burst", burst, "API requests allowed per second (burst).")
-	pflag.IntVar(&qps, "qps", qps, "API requests allowed per second (steady state). Set to -1 to disable rate limiter.")
+	flags := cmd.PersistentFlags()
+	clientOpts.addFlags(flags)
+	flags.StringVar(&profileOutput, "profile-output", profileOutput, "Directory to write CPU and heap profiles (cpu.pprof, heap.pprof) to at exit.")
+	flags.StringVar(&logFile, "log-file", logFile, "File to also write progress, warnings, and logs written to stderr to, so they can be kept separately from findings.")
+	flags.IntVar(&logFileMaxSize, "log-file-max-size", logFileMaxSize, "Size in megabytes at which --log-file is renamed to <file>.1, replacing any previous backup, and a new file started. Unlimited if 0.")
+	flags.StringVar(&pprofAddress, "pprof-address", pprofAddress, "Localhost address to serve pprof handlers on while running (e.g. localhost:6060).")
 
 	// set up logging
 	klog.InitFlags(nil)
 	flag.Set("logtostderr", "true")
-	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
-
-	// set up config flags
-	configFlags := genericclioptions.NewConfigFlags(false)
-	configFlags.AddFlags(pflag.CommandLine)
-
-	// parse flags
-	pflag.Parse()
-
-	if version {
-		fmt.Printf("kubectl-check-ownerreferences version %s (built with %v)\n", pkg.Version, pkg.GoVersion)
-		os.Exit(0)
-	}
-
-	if burst <= 0 {
-		klog.Fatalf("invalid burst rate, must be > 0")
-	}
-	if qps < -1 {
-		klog.Fatalf("invalid qps, must be >= 0")
-	}
+	flags.AddGoFlagSet(flag.CommandLine)
+
+	cmd.AddCommand(
+		newScanCommand(clientOpts, scanOpts),
+		newServeCommand(clientOpts),
+		newVersionCommand(),
+		newOutputSchemaCommand(),
+		newCompletionCommand(),
+		newCRDCommand(),
+		newPolicyCommand(),
+		newDiffCommand(),
+		newTrendsCommand(),
+		newCompareCommand(clientOpts),
+		newDiscoverySnapshotCommand(clientOpts),
+		newRBACCommand(clientOpts),
+		newCoverageCommand(clientOpts),
+		newFinalizersCommand(clientOpts),
+		newWhyTerminatingCommand(clientOpts),
+		newCheckRefCommand(clientOpts),
+		newVerifyReportCommand(),
+	)
+	registerCompletions(cmd, clientOpts)
+	return cmd
+}
 
-	// set up REST config
-	config, err := configFlags.ToRESTConfig()
-	if err != nil && (strings.Contains(err.Error(), "incomplete configuration") || strings.Contains(err.Error(), "no configuration")) {
-		// try falling back to in-cluster config
-		klog.Warningf("attempting to use in-cluster config, error loading client config: %v", err)
-		config, err = rest.InClusterConfig()
-	}
-	checkErr(err)
-	// raise burst/qps
-	config.Burst = burst
-	config.QPS = float32(qps)
-	// silence deprecation warnings, we're iterating over all types
-	config.WarningHandler = rest.NoWarnings{}
-	// prefer protobuf for efficiency
-	config.AcceptContentTypes = "application/vnd.kubernetes.protobuf,application/json"
-
-	// set up clients
-	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
-	checkErr(err)
-	metadataClient, err := metadata.NewForConfig(config)
-	checkErr(err)
-
-	opts := &pkg.VerifyGCOptions{
-		DiscoveryClient: discoveryClient,
-		MetadataClient:  metadataClient,
-		Output:          output,
-		Stderr:          os.Stderr,
-		Stdout:          os.Stdout,
-	}
-	checkErr(opts.Validate())
-	checkErr(opts.Run())
+func main() {
+	// cancel on the first SIGINT or SIGTERM so partial results can be written,
+	// and restore default handling so a second signal exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	checkErr(newRootCommand().ExecuteContext(ctx))
+	exit(0)
 }
@@ -0,0 +1,51 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+func newOutputSchemaCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "output-schema finding|report|summary",
+		Short: "Print the JSON Schema of the finding, report, or summary output format",
+		Long: fmt.Sprintf(`Prints the JSON Schema (draft-07) of an output format, for validating output
+and generating code for consumers:
+
+  finding  each finding written by "scan -o json"
+  report   the reports served at /results and exported to storage by "serve"
+  summary  the summary written by "scan --summary-file"
+
+The schema $id ends with the format version, currently %s, which changes when
+fields are removed or change shape. New optional fields may be added within a version.`, pkg.OutputSchemaVersion),
+		Args:      cobra.ExactValidArgs(1),
+		ValidArgs: pkg.OutputSchemas,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := pkg.OutputSchema(args[0])
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		},
+	}
+}
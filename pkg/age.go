@@ -0,0 +1,55 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// creationTimestampOf returns the creation timestamp of object, or nil if it is not set, such as for objects read from manifests
+func creationTimestampOf(object *metav1.PartialObjectMetadata) *metav1.Time {
+	if object.CreationTimestamp.IsZero() {
+		return nil
+	}
+	timestamp := object.CreationTimestamp
+	return &timestamp
+}
+
+// findingAge returns how long before now the object of finding was created, as a human-readable duration,
+// or <unknown> if its creation timestamp is not known
+func findingAge(finding Finding, now time.Time) string {
+	if finding.CreationTimestamp == nil {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(now.Sub(finding.CreationTimestamp.Time))
+}
+
+// olderThan returns the findings of objects created at least min before now, and the number of findings of newer objects.
+// Findings of objects with unknown creation timestamps are kept.
+func olderThan(findings []Finding, min time.Duration, now time.Time) ([]Finding, int) {
+	kept := make([]Finding, 0, len(findings))
+	for _, finding := range findings {
+		if finding.CreationTimestamp != nil && now.Sub(finding.CreationTimestamp.Time) < min {
+			continue
+		}
+		kept = append(kept, finding)
+	}
+	return kept, len(findings) - len(kept)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOlderThan(t *testing.T) {
+	now := time.Now()
+	created := func(age time.Duration) *metav1.Time {
+		return &metav1.Time{Time: now.Add(-age)}
+	}
+	findings := []Finding{
+		{Name: "legacy", CreationTimestamp: created(90 * 24 * time.Hour), Level: LevelError},
+		{Name: "new", CreationTimestamp: created(time.Hour), Level: LevelError},
+		{Name: "unknown", Level: LevelError},
+	}
+
+	kept, newer := olderThan(findings, 24*time.Hour, now)
+	if newer != 1 || len(kept) != 2 || kept[0].Name != "legacy" || kept[1].Name != "unknown" {
+		t.Errorf("expected the legacy and unknown findings and 1 newer, got %#v, %d", kept, newer)
+	}
+
+	out := &bytes.Buffer{}
+	if err := (&Report{Findings: findings}).Print(out, ""); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if !strings.Contains(lines[0], "LEVEL   AGE") {
+		t.Errorf("expected an age column, got %q", lines[0])
+	}
+	for i, age := range []string{"90d", "60m", "<unknown>"} {
+		if !strings.Contains(lines[i+1], " "+age+" ") {
+			t.Errorf("expected age %s, got %q", age, lines[i+1])
+		}
+	}
+}
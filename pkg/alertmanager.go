@@ -0,0 +1,174 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// alertName is the alertname label of alerts sent for findings
+const alertName = "InvalidOwnerReference"
+
+// AlertmanagerOptions contains options controlling how Error-level findings are sent to Alertmanager as alerts
+type AlertmanagerOptions struct {
+	// URL is the base URL of the Alertmanager, e.g. http://alertmanager:9093
+	URL string
+	// Labels are added to every alert
+	Labels map[string]string
+	// Annotations are added to every alert
+	Annotations map[string]string
+	// Client is used to send alerts. If nil, a client with a 30 second timeout is used.
+	Client *http.Client
+
+	// firing holds the alerts sent for the previous scan, keyed by fingerprint
+	firing map[string]alert
+}
+
+// Validate ensures the specified options are valid
+func (o *AlertmanagerOptions) Validate() error {
+	if u, err := url.Parse(o.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid alertmanager url, must be an http or https url: %v", o.URL)
+	}
+	return nil
+}
+
+// alert is an alert in the Alertmanager v2 API
+type alert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      time.Time         `json:"endsAt"`
+}
+
+func (a alert) fingerprint() string {
+	keys := make([]string, 0, len(a.Labels))
+	for key := range a.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	b := &strings.Builder{}
+	for _, key := range keys {
+		fmt.Fprintf(b, "%s=%q,", key, a.Labels[key])
+	}
+	return b.String()
+}
+
+// publish sends an alert for each Error-level finding in result, and resolves alerts sent for the previous scan
+// that are no longer present. Alerts expire if not re-sent within twice the time until the next scan completes,
+// estimated as the interval between scans, which starts once a scan completes, plus the duration of this scan.
+func (o *AlertmanagerOptions) publish(ctx context.Context, result *Report, interval time.Duration) error {
+	now := time.Now()
+	endsAt := now.Add(2 * (interval + result.Duration))
+
+	firing := map[string]alert{}
+	for _, finding := range result.Findings {
+		if finding.Level != LevelError {
+			continue
+		}
+		a := alert{
+			Labels: map[string]string{
+				"alertname": alertName,
+				"resource":  schema.GroupResource{Group: finding.Resource.Group, Resource: finding.Resource.Resource}.String(),
+				"namespace": finding.Namespace,
+				"name":      finding.Name,
+				"owner_uid": string(finding.OwnerReference.UID),
+				"code":      finding.Code,
+			},
+			Annotations: map[string]string{
+				"summary": fmt.Sprintf("%s %s has an invalid ownerReference to %s %s", finding.Kind.Kind, namespacedName(finding.Namespace, finding.Name), finding.OwnerReference.Kind, finding.OwnerReference.Name),
+				"message": finding.Message,
+			},
+			StartsAt: now,
+			EndsAt:   endsAt,
+		}
+		for key, value := range o.Labels {
+			a.Labels[key] = value
+		}
+		for key, value := range o.Annotations {
+			a.Annotations[key] = value
+		}
+		fingerprint := a.fingerprint()
+		if previous, ok := o.firing[fingerprint]; ok {
+			a.StartsAt = previous.StartsAt
+		}
+		firing[fingerprint] = a
+	}
+
+	alerts := []alert{}
+	for _, a := range firing {
+		alerts = append(alerts, a)
+	}
+	for fingerprint, a := range o.firing {
+		if _, stillFiring := firing[fingerprint]; !stillFiring {
+			a.EndsAt = now
+			alerts = append(alerts, a)
+		}
+	}
+	if len(alerts) > 0 {
+		if err := o.send(ctx, alerts); err != nil {
+			return err
+		}
+	}
+	o.firing = firing
+	return nil
+}
+
+func (o *AlertmanagerOptions) send(ctx context.Context, alerts []alert) error {
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(o.URL, "/")+"/api/v2/alerts", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := o.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending alerts: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("error sending alerts: %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// namespacedName formats a namespace and name as namespace/name, or name if namespace is empty
+func namespacedName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
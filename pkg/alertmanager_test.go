@@ -0,0 +1,101 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAlertmanagerPublish(t *testing.T) {
+	var received [][]alert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/alerts" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		alerts := []alert{}
+		if err := json.NewDecoder(r.Body).Decode(&alerts); err != nil {
+			t.Error(err)
+		}
+		received = append(received, alerts)
+	}))
+	defer server.Close()
+
+	opts := &AlertmanagerOptions{URL: server.URL, Labels: map[string]string{"severity": "ticket"}}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	pods := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	pod1 := Finding{Resource: pods, Namespace: "ns1", Name: "pod1", Level: LevelError, Code: CodeOwnerNotFound}
+	pod2 := Finding{Resource: pods, Namespace: "ns1", Name: "pod2", Level: LevelError, Code: CodeOwnerNotFound}
+	warning := Finding{Resource: pods, Namespace: "ns1", Name: "pod3", Level: LevelWarning, Code: CodeOwnerListFailed}
+
+	// first scan fires both errors
+	if err := opts.publish(context.Background(), &Report{Findings: []Finding{pod1, pod2, warning}}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 1 || len(received[0]) != 2 {
+		t.Fatalf("expected 2 alerts, got %v", received)
+	}
+	for _, a := range received[0] {
+		if a.Labels["alertname"] != alertName || a.Labels["severity"] != "ticket" || a.Labels["code"] != CodeOwnerNotFound {
+			t.Errorf("unexpected labels: %v", a.Labels)
+		}
+		if !a.EndsAt.After(time.Now()) {
+			t.Errorf("expected firing alert, got endsAt %v", a.EndsAt)
+		}
+	}
+
+	// second scan keeps pod1 firing and resolves pod2
+	if err := opts.publish(context.Background(), &Report{Findings: []Finding{pod1}}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 2 || len(received[1]) != 2 {
+		t.Fatalf("expected 2 alerts, got %v", received)
+	}
+	for _, a := range received[1] {
+		resolved := !a.EndsAt.After(time.Now())
+		if a.Labels["name"] == "pod1" && resolved {
+			t.Errorf("expected pod1 to still be firing")
+		}
+		if a.Labels["name"] == "pod2" && !resolved {
+			t.Errorf("expected pod2 to be resolved")
+		}
+	}
+
+	// third scan re-sends pod1 to keep it firing
+	if err := opts.publish(context.Background(), &Report{Findings: []Finding{pod1}}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 3 || len(received[2]) != 1 {
+		t.Fatalf("expected 1 alert, got %v", received)
+	}
+
+	// alerts of a scan slower than the interval stay firing until the next scan completes
+	if err := opts.publish(context.Background(), &Report{Findings: []Finding{pod1}, Duration: 3 * time.Hour}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if nextScan := time.Now().Add(time.Hour + 3*time.Hour); len(received) != 4 || !received[3][0].EndsAt.After(nextScan) {
+		t.Errorf("expected alert to fire past the next scan at %v, got %v", nextScan, received)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/restmapper"
+)
+
+// resourceAliases are resources that serve the objects of another resource in a different group or kind,
+// from the same storage
+var resourceAliases = map[schema.GroupResource]schema.GroupResource{
+	{Group: "events.k8s.io", Resource: "events"}:           {Resource: "events"},
+	{Group: "extensions", Resource: "daemonsets"}:          {Group: "apps", Resource: "daemonsets"},
+	{Group: "extensions", Resource: "deployments"}:         {Group: "apps", Resource: "deployments"},
+	{Group: "extensions", Resource: "replicasets"}:         {Group: "apps", Resource: "replicasets"},
+	{Group: "extensions", Resource: "ingresses"}:           {Group: "networking.k8s.io", Resource: "ingresses"},
+	{Group: "extensions", Resource: "networkpolicies"}:     {Group: "networking.k8s.io", Resource: "networkpolicies"},
+	{Group: "extensions", Resource: "podsecuritypolicies"}: {Group: "policy", Resource: "podsecuritypolicies"},
+	{Group: "project.openshift.io", Resource: "projects"}:  {Resource: "namespaces"},
+	// OpenShift serves RBAC objects in its own group too, and a namespaced view of each cluster quota
+	{Group: "authorization.openshift.io", Resource: "roles"}:                {Group: "rbac.authorization.k8s.io", Resource: "roles"},
+	{Group: "authorization.openshift.io", Resource: "rolebindings"}:         {Group: "rbac.authorization.k8s.io", Resource: "rolebindings"},
+	{Group: "authorization.openshift.io", Resource: "clusterroles"}:         {Group: "rbac.authorization.k8s.io", Resource: "clusterroles"},
+	{Group: "authorization.openshift.io", Resource: "clusterrolebindings"}:  {Group: "rbac.authorization.k8s.io", Resource: "clusterrolebindings"},
+	{Group: "quota.openshift.io", Resource: "appliedclusterresourcequotas"}: {Group: "quota.openshift.io", Resource: "clusterresourcequotas"},
+}
+
+// virtualResources are resources without storage of their own, whose objects are computed from the objects of other resources,
+// such as OpenShift image stream tags, which are views of the tags of image streams
+var virtualResources = map[schema.GroupResource]bool{
+	{Group: "image.openshift.io", Resource: "imagestreamtags"}:   true,
+	{Group: "image.openshift.io", Resource: "imagetags"}:         true,
+	{Group: "image.openshift.io", Resource: "imagestreamimages"}: true,
+}
+
+// errVirtualResource is recorded as the list error of virtual resources, since their objects cannot be found as owners
+var errVirtualResource = errors.New("virtual resource is not listed")
+
+// resourceAlias is a resource that is not listed, since it serves the same objects as Target
+type resourceAlias struct {
+	Alias  schema.GroupVersionResource
+	Target schema.GroupVersionResource
+	// Kind is the kind of the objects served by Alias
+	Kind string
+}
+
+// dedupeAliases returns gvrs without the resources that serve the same objects as other resources in gvrs, and the aliases removed:
+// known aliases in other groups, such as events.k8s.io events of core events,
+// and resources serving the same group, version, and kind as an earlier resource in gvrs, according to groupResources.
+// Known virtual resources are removed too, and returned separately.
+func dedupeAliases(gvrs []schema.GroupVersionResource, groupResources []*restmapper.APIGroupResources) ([]schema.GroupVersionResource, []resourceAlias, []schema.GroupVersionResource) {
+	kinds := map[schema.GroupVersionResource]string{}
+	for _, group := range groupResources {
+		for version, resources := range group.VersionedResources {
+			for _, resource := range resources {
+				kinds[schema.GroupVersionResource{Group: group.Group.Name, Version: version, Resource: resource.Name}] = resource.Kind
+			}
+		}
+	}
+	listed := map[schema.GroupResource]schema.GroupVersionResource{}
+	for _, gvr := range gvrs {
+		listed[gvr.GroupResource()] = gvr
+	}
+
+	deduped := []schema.GroupVersionResource{}
+	aliases := []resourceAlias{}
+	virtual := []schema.GroupVersionResource{}
+	byKind := map[schema.GroupVersionKind]schema.GroupVersionResource{}
+	for _, gvr := range gvrs {
+		if virtualResources[gvr.GroupResource()] {
+			virtual = append(virtual, gvr)
+			continue
+		}
+		if targetResource, ok := resourceAliases[gvr.GroupResource()]; ok {
+			if target, ok := listed[targetResource]; ok {
+				aliases = append(aliases, resourceAlias{Alias: gvr, Target: target, Kind: kinds[gvr]})
+				continue
+			}
+		}
+		if kind := kinds[gvr]; kind != "" {
+			gvk := gvr.GroupVersion().WithKind(kind)
+			if target, ok := byKind[gvk]; ok {
+				aliases = append(aliases, resourceAlias{Alias: gvr, Target: target, Kind: kind})
+				continue
+			}
+			byKind[gvk] = gvr
+		}
+		deduped = append(deduped, gvr)
+	}
+	return deduped, aliases, virtual
+}
+
+// indexAliases adds the objects of the target of each alias in aliases of another group or kind to objects again,
+// as objects of the alias, so ownerReferences to the alias find them. The owners of an alias whose target
+// could not be listed cannot be found either, so the list error of the target is recorded for the alias in listErrors.
+func indexAliases(objects *ObjectIndex, aliases []resourceAlias, listErrors map[schema.GroupResource]error) {
+	for _, alias := range aliases {
+		if err := listErrors[alias.Target.GroupResource()]; err != nil {
+			listErrors[alias.Alias.GroupResource()] = err
+			continue
+		}
+		apiVersion := alias.Alias.GroupVersion().String()
+		for _, object := range objects.ByResource(alias.Target) {
+			if object.APIVersion == apiVersion && object.Kind == alias.Kind {
+				continue
+			}
+			aliased := *object
+			aliased.APIVersion, aliased.Kind = apiVersion, alias.Kind
+			objects.add(alias.Alias, &aliased)
+		}
+	}
+}
+
+// aliasList formats aliases for messages
+func aliasList(aliases []resourceAlias) string {
+	names := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		names = append(names, fmt.Sprintf("%s (as %s)", gvrList([]schema.GroupVersionResource{alias.Alias}), gvrList([]schema.GroupVersionResource{alias.Target})))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestRunAliases(t *testing.T) {
+	gcVerbs := []string{"get", "list", "delete"}
+	discoveryClient := &fake.FakeDiscovery{Fake: &coretesting.Fake{}}
+	discoveryClient.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: gcVerbs},
+			{Name: "namespaces", Kind: "Namespace", Verbs: gcVerbs},
+		}},
+		{GroupVersion: "project.openshift.io/v1", APIResources: []metav1.APIResource{{Name: "projects", Kind: "Project", Verbs: gcVerbs}}},
+		{GroupVersion: "example.com/v1", APIResources: []metav1.APIResource{
+			{Name: "widgets", Namespaced: true, Kind: "Widget", Verbs: gcVerbs},
+			{Name: "widgetviews", Namespaced: true, Kind: "Widget", Verbs: gcVerbs},
+		}},
+	}
+	metadataClient := metadatafake.NewSimpleMetadataClient(runtime.NewScheme())
+	create := func(gvr schema.GroupVersionResource, kind, namespace, name string, ownerRefs ...metav1.OwnerReference) {
+		t.Helper()
+		client := metadataClient.Resource(gvr).Namespace(namespace)
+		if _, err := client.(metadatafake.MetadataClient).CreateFake(&metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: gvr.GroupVersion().String(), Kind: kind},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, UID: types.UID("uid-" + name), OwnerReferences: ownerRefs},
+		}, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	create(schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}, "Namespace", "", "ns1")
+	create(schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}, "Widget", "ns1", "widget1")
+	// owned by the project of the namespace, and by a widget, which are not listed through their aliases
+	create(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, "ConfigMap", "ns1", "cm1",
+		metav1.OwnerReference{APIVersion: "project.openshift.io/v1", Kind: "Project", Name: "ns1", UID: "uid-ns1"},
+		metav1.OwnerReference{APIVersion: "example.com/v1", Kind: "Widget", Name: "widget1", UID: "uid-widget1"},
+	)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	opts := &VerifyGCOptions{
+		DiscoveryClient: discoveryClient,
+		MetadataClient:  metadataClient,
+		Output:          "json",
+		Stderr:          stderr,
+		Stdout:          stdout,
+	}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opts.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	findings, err := readFindings(stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("expected no findings, got %#v", findings)
+	}
+	if !strings.Contains(stderr.String(), "not listing 2 resources serving the objects of other resources: widgetviews.v1.example.com (as widgets.v1.example.com), projects.v1.project.openshift.io (as namespaces.v1)\n") {
+		t.Errorf("expected aliases on stderr, got %q", stderr.String())
+	}
+	listed := []string{}
+	for _, action := range metadataClient.Actions() {
+		if action.GetVerb() == "list" {
+			listed = append(listed, action.GetResource().Resource)
+		}
+	}
+	if diff := cmp.Diff([]string{"configmaps", "namespaces", "widgets"}, listed); diff != "" {
+		t.Errorf("unexpected resources listed (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunVirtualResources(t *testing.T) {
+	gcVerbs := []string{"get", "list", "delete"}
+	discoveryClient := &fake.FakeDiscovery{Fake: &coretesting.Fake{}}
+	discoveryClient.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: gcVerbs}}},
+		{GroupVersion: "image.openshift.io/v1", APIResources: []metav1.APIResource{
+			{Name: "imagestreams", Namespaced: true, Kind: "ImageStream", Verbs: gcVerbs},
+			{Name: "imagestreamtags", Namespaced: true, Kind: "ImageStreamTag", Verbs: gcVerbs},
+		}},
+	}
+	metadataClient := metadatafake.NewSimpleMetadataClient(runtime.NewScheme())
+	client := metadataClient.Resource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}).Namespace("ns1")
+	if _, err := client.(metadatafake.MetadataClient).CreateFake(&metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "cm1", Namespace: "ns1", UID: "uid-cm1", OwnerReferences: []metav1.OwnerReference{
+			{APIVersion: "image.openshift.io/v1", Kind: "ImageStreamTag", Name: "stream:latest", UID: "uid-tag"},
+		}},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	opts := &VerifyGCOptions{
+		DiscoveryClient: discoveryClient,
+		MetadataClient:  metadataClient,
+		Output:          "json",
+		Stderr:          stderr,
+		Stdout:          stdout,
+	}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opts.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	findings, err := readFindings(stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 || findings[0].Level != LevelWarning || findings[0].Code != CodeOwnerListFailed {
+		t.Errorf("expected a single owner list failure warning, got %#v", findings)
+	}
+	if !strings.Contains(stderr.String(), "not listing 1 virtual resource computed from other resources: imagestreamtags.v1.image.openshift.io\n") {
+		t.Errorf("expected virtual resources on stderr, got %q", stderr.String())
+	}
+	for _, action := range metadataClient.Actions() {
+		if action.GetVerb() == "list" && action.GetResource().Resource == "imagestreamtags" {
+			t.Errorf("expected image stream tags not to be listed")
+		}
+	}
+}
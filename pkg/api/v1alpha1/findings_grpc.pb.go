@@ -0,0 +1,199 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// FindingsClient is the client API for Findings service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type FindingsClient interface {
+	// Watch streams the events of the scans the server runs on its schedule, starting with the next scan.
+	// The stream is ended with RESOURCE_EXHAUSTED if the client does not keep up.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Findings_WatchClient, error)
+	// Scan runs a scan limited to the requested scope and streams its events, ending after the scan completes.
+	// On-demand scans are run one at a time.
+	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (Findings_ScanClient, error)
+}
+
+type findingsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFindingsClient(cc grpc.ClientConnInterface) FindingsClient {
+	return &findingsClient{cc}
+}
+
+func (c *findingsClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Findings_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Findings_ServiceDesc.Streams[0], "/ownerreferences.v1alpha1.Findings/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &findingsWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Findings_WatchClient interface {
+	Recv() (*ScanEvent, error)
+	grpc.ClientStream
+}
+
+type findingsWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *findingsWatchClient) Recv() (*ScanEvent, error) {
+	m := new(ScanEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *findingsClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (Findings_ScanClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Findings_ServiceDesc.Streams[1], "/ownerreferences.v1alpha1.Findings/Scan", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &findingsScanClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Findings_ScanClient interface {
+	Recv() (*ScanEvent, error)
+	grpc.ClientStream
+}
+
+type findingsScanClient struct {
+	grpc.ClientStream
+}
+
+func (x *findingsScanClient) Recv() (*ScanEvent, error) {
+	m := new(ScanEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FindingsServer is the server API for Findings service.
+// All implementations must embed UnimplementedFindingsServer
+// for forward compatibility
+type FindingsServer interface {
+	// Watch streams the events of the scans the server runs on its schedule, starting with the next scan.
+	// The stream is ended with RESOURCE_EXHAUSTED if the client does not keep up.
+	Watch(*WatchRequest, Findings_WatchServer) error
+	// Scan runs a scan limited to the requested scope and streams its events, ending after the scan completes.
+	// On-demand scans are run one at a time.
+	Scan(*ScanRequest, Findings_ScanServer) error
+	mustEmbedUnimplementedFindingsServer()
+}
+
+// UnimplementedFindingsServer must be embedded to have forward compatible implementations.
+type UnimplementedFindingsServer struct {
+}
+
+func (UnimplementedFindingsServer) Watch(*WatchRequest, Findings_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedFindingsServer) Scan(*ScanRequest, Findings_ScanServer) error {
+	return status.Errorf(codes.Unimplemented, "method Scan not implemented")
+}
+func (UnimplementedFindingsServer) mustEmbedUnimplementedFindingsServer() {}
+
+// UnsafeFindingsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FindingsServer will
+// result in compilation errors.
+type UnsafeFindingsServer interface {
+	mustEmbedUnimplementedFindingsServer()
+}
+
+func RegisterFindingsServer(s grpc.ServiceRegistrar, srv FindingsServer) {
+	s.RegisterService(&Findings_ServiceDesc, srv)
+}
+
+func _Findings_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FindingsServer).Watch(m, &findingsWatchServer{stream})
+}
+
+type Findings_WatchServer interface {
+	Send(*ScanEvent) error
+	grpc.ServerStream
+}
+
+type findingsWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *findingsWatchServer) Send(m *ScanEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Findings_Scan_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ScanRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FindingsServer).Scan(m, &findingsScanServer{stream})
+}
+
+type Findings_ScanServer interface {
+	Send(*ScanEvent) error
+	grpc.ServerStream
+}
+
+type findingsScanServer struct {
+	grpc.ServerStream
+}
+
+func (x *findingsScanServer) Send(m *ScanEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Findings_ServiceDesc is the grpc.ServiceDesc for Findings service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Findings_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ownerreferences.v1alpha1.Findings",
+	HandlerType: (*FindingsServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Findings_Watch_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Scan",
+			Handler:       _Findings_Scan_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "findings.proto",
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// AuditOptions contains options controlling how findings are correlated with apiserver audit logs
+type AuditOptions struct {
+	// Paths are audit log files, containing audit.k8s.io Events written by the log backend,
+	// or EventLists written by the webhook backend. Files ending in .gz are decompressed.
+	Paths []string
+}
+
+// Validate ensures the specified options are valid
+func (o *AuditOptions) Validate() error {
+	if len(o.Paths) == 0 {
+		return fmt.Errorf("at least one audit log path is required")
+	}
+	return nil
+}
+
+// AuditEntry describes the audited request that last wrote a child object
+type AuditEntry struct {
+	// User is the username of the requester
+	User string `json:"user"`
+	// UserAgent is the user agent of the requester
+	UserAgent string `json:"userAgent,omitempty"`
+	// Verb is the request verb, e.g. create, update, or patch
+	Verb string `json:"verb"`
+	// Time is when the request completed
+	Time metav1.Time `json:"time"`
+	// AuditID identifies the request in the audit log
+	AuditID types.UID `json:"auditID,omitempty"`
+	// OwnerReferencesWritten is true if the request body set ownerReferences.
+	// If false, the request is the last write to the object, and the audit policy did not log request bodies
+	// or no logged request set ownerReferences.
+	OwnerReferencesWritten bool `json:"ownerReferencesWritten"`
+}
+
+// auditEvent holds the fields of audit.k8s.io/v1 Event and EventList objects used to correlate findings
+type auditEvent struct {
+	Kind      string    `json:"kind"`
+	AuditID   types.UID `json:"auditID"`
+	Stage     string    `json:"stage"`
+	Verb      string    `json:"verb"`
+	UserAgent string    `json:"userAgent"`
+	User      struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	ObjectRef *struct {
+		Resource    string `json:"resource"`
+		Namespace   string `json:"namespace"`
+		Name        string `json:"name"`
+		APIGroup    string `json:"apiGroup"`
+		Subresource string `json:"subresource"`
+	} `json:"objectRef"`
+	ResponseStatus *struct {
+		Code int `json:"code"`
+	} `json:"responseStatus"`
+	RequestObject  json.RawMessage  `json:"requestObject"`
+	ResponseObject json.RawMessage  `json:"responseObject"`
+	StageTimestamp metav1.MicroTime `json:"stageTimestamp"`
+
+	Items []auditEvent `json:"items"`
+}
+
+// auditObjectKey identifies an object in audit events
+type auditObjectKey struct {
+	group     string
+	resource  string
+	namespace string
+	name      string
+}
+
+// correlate sets the Audit field of findings to the request that last wrote the ownerReferences of each child object
+func (o *AuditOptions) correlate(findings []Finding) error {
+	if len(findings) == 0 {
+		return nil
+	}
+	wanted := map[auditObjectKey]*AuditEntry{}
+	for _, finding := range findings {
+		wanted[auditObjectKey{finding.Resource.Group, finding.Resource.Resource, finding.Namespace, finding.Name}] = nil
+	}
+	for _, path := range o.Paths {
+		if err := readAuditLog(path, func(event *auditEvent) {
+			key, entry := auditEntryFor(event)
+			if entry == nil {
+				return
+			}
+			current, ok := wanted[key]
+			if !ok {
+				return
+			}
+			if current == nil || entry.OwnerReferencesWritten && !current.OwnerReferencesWritten ||
+				entry.OwnerReferencesWritten == current.OwnerReferencesWritten && current.Time.Before(&entry.Time) {
+				wanted[key] = entry
+			}
+		}); err != nil {
+			return fmt.Errorf("error reading audit log %s: %v", path, err)
+		}
+	}
+	for i, finding := range findings {
+		findings[i].Audit = wanted[auditObjectKey{finding.Resource.Group, finding.Resource.Resource, finding.Namespace, finding.Name}]
+	}
+	return nil
+}
+
+// auditEntryFor returns the object written by event and a description of the request,
+// or nil if event is not a successful write
+func auditEntryFor(event *auditEvent) (auditObjectKey, *AuditEntry) {
+	if event.Stage != "ResponseComplete" || event.ObjectRef == nil || event.ObjectRef.Subresource != "" {
+		return auditObjectKey{}, nil
+	}
+	if event.Verb != "create" && event.Verb != "update" && event.Verb != "patch" {
+		return auditObjectKey{}, nil
+	}
+	if event.ResponseStatus != nil && event.ResponseStatus.Code >= 300 {
+		return auditObjectKey{}, nil
+	}
+	name := event.ObjectRef.Name
+	if name == "" {
+		// creates using generateName only record the name in the response
+		var object struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		}
+		if json.Unmarshal(event.ResponseObject, &object) == nil {
+			name = object.Metadata.Name
+		}
+	}
+	if name == "" {
+		return auditObjectKey{}, nil
+	}
+	key := auditObjectKey{event.ObjectRef.APIGroup, event.ObjectRef.Resource, event.ObjectRef.Namespace, name}
+	return key, &AuditEntry{
+		User:      event.User.Username,
+		UserAgent: event.UserAgent,
+		Verb:      event.Verb,
+		Time:      metav1.NewTime(event.StageTimestamp.Time),
+		AuditID:   event.AuditID,
+		// matches objects, merge and strategic merge patches, and JSON patch paths setting ownerReferences
+		OwnerReferencesWritten: bytes.Contains(event.RequestObject, []byte("ownerReferences")),
+	}
+}
+
+// readAuditLog calls f for each audit event in the file at path
+func readAuditLog(path string, f func(*auditEvent)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	decoder := json.NewDecoder(r)
+	for {
+		event := &auditEvent{}
+		if err := decoder.Decode(event); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if event.Kind == "EventList" {
+			for i := range event.Items {
+				f(&event.Items[i])
+			}
+			continue
+		}
+		f(event)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAuditCorrelation(t *testing.T) {
+	dir := t.TempDir()
+	// written by the log backend, one event per line
+	logFile := filepath.Join(dir, "audit.log")
+	if err := ioutil.WriteFile(logFile, []byte(`
+{"kind":"Event","auditID":"1","stage":"ResponseComplete","verb":"create","user":{"username":"alice"},"userAgent":"kubectl/v1.22.1","objectRef":{"resource":"pods","namespace":"ns1","name":"pod1","apiVersion":"v1"},"responseStatus":{"code":201},"requestObject":{"metadata":{"name":"pod1","ownerReferences":[{"uid":"missinguid-c"}]}},"stageTimestamp":"2021-09-01T00:00:00.000000Z"}
+{"kind":"Event","auditID":"2","stage":"ResponseStarted","verb":"update","user":{"username":"eve"},"objectRef":{"resource":"pods","namespace":"ns1","name":"pod1","apiVersion":"v1"},"stageTimestamp":"2021-09-02T00:00:00.000000Z"}
+{"kind":"Event","auditID":"3","stage":"ResponseComplete","verb":"update","user":{"username":"bob"},"objectRef":{"resource":"pods","namespace":"ns1","name":"pod1","apiVersion":"v1"},"responseStatus":{"code":200},"stageTimestamp":"2021-09-03T00:00:00.000000Z"}
+{"kind":"Event","auditID":"4","stage":"ResponseComplete","verb":"patch","user":{"username":"kubelet"},"objectRef":{"resource":"pods","namespace":"ns1","name":"pod1","apiVersion":"v1","subresource":"status"},"responseStatus":{"code":200},"requestObject":{"metadata":{"ownerReferences":[]}},"stageTimestamp":"2021-09-04T00:00:00.000000Z"}
+{"kind":"Event","auditID":"5","stage":"ResponseComplete","verb":"create","user":{"username":"system:serviceaccount:kube-system:replicaset-controller"},"objectRef":{"resource":"pods","namespace":"ns1","apiVersion":"v1"},"responseStatus":{"code":201},"responseObject":{"metadata":{"name":"pod2"}},"stageTimestamp":"2021-09-01T00:00:00.000000Z"}
+{"kind":"Event","auditID":"6","stage":"ResponseComplete","verb":"update","user":{"username":"mallory"},"objectRef":{"resource":"pods","namespace":"ns1","name":"pod2","apiVersion":"v1"},"responseStatus":{"code":409},"requestObject":{"metadata":{"ownerReferences":[]}},"stageTimestamp":"2021-09-02T00:00:00.000000Z"}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// exported from the webhook backend as event lists, and compressed by log rotation
+	webhookFile := filepath.Join(dir, "webhook.json.gz")
+	compressed := &bytes.Buffer{}
+	gz := gzip.NewWriter(compressed)
+	gz.Write([]byte(`{"kind":"EventList","apiVersion":"audit.k8s.io/v1","items":[
+{"auditID":"7","stage":"ResponseComplete","verb":"patch","user":{"username":"carol"},"objectRef":{"resource":"pods","namespace":"ns1","name":"pod3","apiVersion":"v1"},"responseStatus":{"code":200},"requestObject":[{"op":"add","path":"/metadata/ownerReferences/-","value":{}}],"stageTimestamp":"2021-09-05T00:00:00.000000Z"}
+]}`))
+	gz.Close()
+	if err := ioutil.WriteFile(webhookFile, compressed.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := newFakeCluster(t, "c", "pod1", "pod2", "pod3", "pod4").Verify
+	opts.Audit = &AuditOptions{Paths: []string{logFile, webhookFile}}
+	report, err := opts.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(day int) metav1.Time {
+		return metav1.NewTime(time.Date(2021, 9, day, 0, 0, 0, 0, time.UTC))
+	}
+	expect := map[string]*AuditEntry{
+		// the create setting ownerReferences is preferred over a later update that did not log a request body
+		"pod1": {User: "alice", UserAgent: "kubectl/v1.22.1", Verb: "create", Time: at(1), AuditID: "1", OwnerReferencesWritten: true},
+		// the name of objects created with generateName is read from the response, and failed requests are ignored
+		"pod2": {User: "system:serviceaccount:kube-system:replicaset-controller", Verb: "create", Time: at(1), AuditID: "5"},
+		"pod3": {User: "carol", Verb: "patch", Time: at(5), AuditID: "7", OwnerReferencesWritten: true},
+		"pod4": nil,
+	}
+	got := map[string]*AuditEntry{}
+	for _, finding := range report.Findings {
+		got[finding.Name] = finding.Audit
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("unexpected audit entries (-want +got):\n%s", diff)
+	}
+
+	out := &bytes.Buffer{}
+	if err := report.Print(out, ""); err != nil {
+		t.Fatal(err)
+	}
+	expectTable := `
+GROUP RESOURCE NAMESPACE NAME OWNER_UID LEVEL WRITTEN_BY MESSAGE
+pods ns1 pod1 missinguid-c Error alice no object found for uid
+pods ns1 pod2 missinguid-c Error system:serviceaccount:kube-system:replicaset-controller no object found for uid
+pods ns1 pod3 missinguid-c Error carol no object found for uid
+pods ns1 pod4 missinguid-c Error <unknown> no object found for uid
+`
+	if diff := cmp.Diff(normalize(expectTable), normalize(out.String())); diff != "" {
+		t.Errorf("unexpected table (-want +got):\n%s", diff)
+	}
+}
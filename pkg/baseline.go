@@ -0,0 +1,94 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Baseline holds previously acknowledged findings, which are excluded from or demoted in later scans
+type Baseline struct {
+	// Findings are the acknowledged findings, matched by object, owner uid, and code
+	Findings []Finding
+	// Demote reports findings matching the baseline as warnings instead of excluding them
+	Demote bool
+}
+
+// LoadBaseline reads a baseline written by --write-baseline or -o json, or a report document, from path
+func LoadBaseline(path string) ([]Finding, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	findings, err := readFindings(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading baseline %s: %v", path, err)
+	}
+	return findings, nil
+}
+
+// apply returns findings with those matching the baseline excluded or demoted, and the number that matched
+func (b *Baseline) apply(findings []Finding) ([]Finding, int) {
+	acknowledged := map[findingKey]bool{}
+	for _, finding := range b.Findings {
+		acknowledged[keyOf(finding)] = true
+	}
+	var kept []Finding
+	matched := 0
+	for _, finding := range findings {
+		if !acknowledged[keyOf(finding)] {
+			kept = append(kept, finding)
+			continue
+		}
+		matched++
+		if b.Demote {
+			finding.Level = LevelWarning
+			kept = append(kept, finding)
+		}
+	}
+	return kept, matched
+}
+
+// writeBaseline writes the findings of report, including those matching a baseline, to path as a stream of JSON findings.
+// The file is replaced atomically, so a baseline can be read and rewritten by the same scan.
+func writeBaseline(path string, report *Report) error {
+	findings := report.Findings
+	if report.unbaselinedFindings != nil {
+		findings = report.unbaselinedFindings
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := (&Report{Findings: findings}).Print(f, "json"); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
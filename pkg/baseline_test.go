@@ -0,0 +1,107 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBaseline(t *testing.T) {
+	// acknowledge the finding for pod1, with a different level and message
+	acknowledged := Finding{
+		Resource:       metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+		Namespace:      "ns1",
+		Name:           "pod1",
+		OwnerReference: metav1.OwnerReference{UID: "missinguid-c"},
+		Level:          LevelWarning,
+		Code:           CodeOwnerNotFound,
+		Message:        "old message",
+	}
+
+	testcases := []struct {
+		name          string
+		demote        bool
+		expectLevels  map[string]string
+		expectSummary string
+	}{
+		{
+			name:          "exclude",
+			expectLevels:  map[string]string{"pod2": LevelError},
+			expectSummary: "1 error, 0 warnings\n1 finding excluded by the baseline\n",
+		},
+		{
+			name:          "demote",
+			demote:        true,
+			expectLevels:  map[string]string{"pod1": LevelWarning, "pod2": LevelError},
+			expectSummary: "1 error, 1 warning\n1 finding demoted to warnings by the baseline\n",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			baselineFile := filepath.Join(t.TempDir(), "baseline.json")
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			opts := newFakeCluster(t, "c", "pod1", "pod2").Verify
+			opts.Output = "json"
+			opts.Stdout = stdout
+			opts.Stderr = stderr
+			opts.Baseline = &Baseline{Findings: []Finding{acknowledged}, Demote: tc.demote}
+			opts.WriteBaseline = baselineFile
+			if err := opts.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			if err := opts.Run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			findings, err := readFindings(stdout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			levels := map[string]string{}
+			for _, finding := range findings {
+				levels[finding.Name] = finding.Level
+			}
+			if diff := cmp.Diff(tc.expectLevels, levels); diff != "" {
+				t.Errorf("unexpected findings (-want +got):\n%s", diff)
+			}
+			if !strings.HasSuffix(stderr.String(), tc.expectSummary) {
+				t.Errorf("expected summary %q, got %q", tc.expectSummary, stderr.String())
+			}
+
+			// the written baseline includes findings matching the existing baseline, at their original level
+			written, err := LoadBaseline(baselineFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			writtenLevels := map[string]string{}
+			for _, finding := range written {
+				writtenLevels[finding.Name] = finding.Level
+			}
+			if diff := cmp.Diff(map[string]string{"pod1": LevelError, "pod2": LevelError}, writtenLevels); diff != "" {
+				t.Errorf("unexpected written baseline (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
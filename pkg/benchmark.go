@@ -0,0 +1,98 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// APICallCounter counts requests made through wrapped transports
+type APICallCounter struct {
+	count int64
+}
+
+// Wrap returns a RoundTripper that counts requests made through rt.
+// It is suitable for use as a rest.Config#WrapTransport function.
+func (c *APICallCounter) Wrap(rt http.RoundTripper) http.RoundTripper {
+	return &countingRoundTripper{counter: c, delegate: rt}
+}
+
+// Count returns the number of requests made so far
+func (c *APICallCounter) Count() int64 {
+	return atomic.LoadInt64(&c.count)
+}
+
+type countingRoundTripper struct {
+	counter  *APICallCounter
+	delegate http.RoundTripper
+}
+
+func (c *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt64(&c.counter.count, 1)
+	return c.delegate.RoundTrip(req)
+}
+
+type benchmarkResult struct {
+	Resources        int     `json:"resources"`
+	Objects          int     `json:"objects"`
+	Pages            int     `json:"pages"`
+	APICalls         int64   `json:"apiCalls,omitempty"`
+	DiscoverySeconds float64 `json:"discoverySeconds"`
+	ListSeconds      float64 `json:"listSeconds"`
+	ObjectsPerSecond float64 `json:"objectsPerSecond"`
+	PagesPerSecond   float64 `json:"pagesPerSecond"`
+	ScanTime         string  `json:"scanTime"`
+}
+
+func (v *VerifyGCOptions) printBenchmark(stats ScanStats) error {
+	result := benchmarkResult{
+		Resources:        stats.Resources,
+		Objects:          stats.Objects,
+		Pages:            stats.Pages,
+		DiscoverySeconds: stats.DiscoveryDuration.Seconds(),
+		ListSeconds:      stats.ListDuration.Seconds(),
+	}
+	if v.APICallCounter != nil {
+		result.APICalls = v.APICallCounter.Count()
+	}
+	if seconds := stats.ListDuration.Seconds(); seconds > 0 {
+		result.ObjectsPerSecond = float64(stats.Objects) / seconds
+		result.PagesPerSecond = float64(stats.Pages) / seconds
+	}
+	// every resource is listed, so this is measured rather than extrapolated, and validation happens in memory once
+	// listing completes, so a full scan takes about as long as discovery and listing
+	result.ScanTime = (stats.DiscoveryDuration + stats.ListDuration).Round(time.Second).String()
+
+	if v.Output == "json" {
+		return json.NewEncoder(v.Stdout).Encode(result)
+	}
+
+	fmt.Fprintf(v.Stdout, "discovery:           %v\n", stats.DiscoveryDuration.Round(time.Millisecond))
+	fmt.Fprintf(v.Stdout, "listing:             %v\n", stats.ListDuration.Round(time.Millisecond))
+	fmt.Fprintf(v.Stdout, "resources:           %d\n", result.Resources)
+	fmt.Fprintf(v.Stdout, "objects:             %d (%.1f/sec)\n", result.Objects, result.ObjectsPerSecond)
+	fmt.Fprintf(v.Stdout, "pages:               %d (%.1f/sec)\n", result.Pages, result.PagesPerSecond)
+	if v.APICallCounter != nil {
+		fmt.Fprintf(v.Stdout, "api calls:           %d\n", result.APICalls)
+	}
+	fmt.Fprintf(v.Stdout, "scan time:           %s\n", result.ScanTime)
+	return nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestBenchmark(t *testing.T) {
+	discoveryClient := &fake.FakeDiscovery{Fake: &coretesting.Fake{}}
+	discoveryClient.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "nodes", Namespaced: false, Kind: "Node", Verbs: []string{"get", "list", "delete"}},
+			{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: []string{"get", "list", "delete"}},
+		},
+	}}
+	metadataClient := metadatafake.NewSimpleMetadataClient(runtime.NewScheme())
+	for _, pod := range []*metav1.PartialObjectMetadata{
+		{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns1", UID: types.UID("poduid1"), OwnerReferences: []metav1.OwnerReference{{APIVersion: "v1", Kind: "Node", Name: "missing", UID: "missing"}}},
+		},
+		{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: "pod2", Namespace: "ns1", UID: types.UID("poduid2")},
+		},
+	} {
+		podClient := metadataClient.Resource(schema.GroupVersionResource{Version: "v1", Resource: "pods"}).Namespace(pod.Namespace)
+		if _, err := podClient.(metadatafake.MetadataClient).CreateFake(pod, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := bytes.NewBuffer(nil)
+	opts := &VerifyGCOptions{
+		DiscoveryClient: discoveryClient,
+		MetadataClient:  metadataClient,
+		Output:          "json",
+		Stdout:          out,
+		Stderr:          bytes.NewBuffer(nil),
+		Benchmark:       true,
+	}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := opts.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	result := benchmarkResult{}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("unexpected output %q: %v", out.String(), err)
+	}
+	if result.Resources != 2 || result.Objects != 2 || result.Pages != 2 || result.ScanTime == "" {
+		t.Errorf("expected 2 resources, 2 objects, 2 pages, and the scan time, got %#v", result)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// maxOwnerCandidates is the number of candidates included in a finding
+const maxOwnerCandidates = 3
+
+// OwnerCandidate is an object of the referenced kind and name that may be the intended owner of an ownerReference
+// whose owner was not found, such as the owner recreated with a new uid, or an object with the name in another namespace
+type OwnerCandidate struct {
+	Namespace string    `json:"namespace,omitempty"`
+	Name      string    `json:"name"`
+	UID       types.UID `json:"uid"`
+}
+
+// String returns the candidate as it is described in messages
+func (c OwnerCandidate) String() string {
+	return fmt.Sprintf("%s (uid %s)", namespacedName(c.Namespace, c.Name), c.UID)
+}
+
+// ownerCandidates returns the closest objects of the resource of mapping with the name of the ownerReference of ctx,
+// the owner being looked up in: those in the namespace the owner was expected in first, then by namespace
+func ownerCandidates(ctx *RuleContext) []OwnerCandidate {
+	if ctx.OwnerMapping == nil || ctx.Objects == nil {
+		return nil
+	}
+	namespace := ""
+	if ctx.OwnerMapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		namespace = ctx.Child.Namespace
+	}
+	var candidates []OwnerCandidate
+	seen := sets.NewString()
+	for _, object := range ctx.Objects.ByName(ctx.OwnerMapping.Resource.GroupResource(), ctx.OwnerReference.Name) {
+		// objects of aliased resources are indexed once for each resource
+		if object.UID == ctx.OwnerReference.UID || seen.Has(string(object.UID)) {
+			continue
+		}
+		seen.Insert(string(object.UID))
+		candidates = append(candidates, OwnerCandidate{Namespace: object.Namespace, Name: object.Name, UID: object.UID})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if (candidates[i].Namespace == namespace) != (candidates[j].Namespace == namespace) {
+			return candidates[i].Namespace == namespace
+		}
+		if candidates[i].Namespace != candidates[j].Namespace {
+			return candidates[i].Namespace < candidates[j].Namespace
+		}
+		return candidates[i].UID < candidates[j].UID
+	})
+	if len(candidates) > maxOwnerCandidates {
+		candidates = candidates[:maxOwnerCandidates]
+	}
+	return candidates
+}
+
+// describeCandidates returns a note listing candidates, appended to the message of a finding
+func describeCandidates(candidates []OwnerCandidate) string {
+	descriptions := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		descriptions = append(descriptions, candidate.String())
+	}
+	return fmt.Sprintf("; did you mean %s?", strings.Join(descriptions, ", "))
+}
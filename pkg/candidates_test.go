@@ -0,0 +1,78 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestOwnerCandidates(t *testing.T) {
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	objects := newObjectIndex()
+	add := func(gvr schema.GroupVersionResource, namespace, name string, uid types.UID) *metav1.PartialObjectMetadata {
+		object := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, UID: uid}}
+		objects.add(gvr, object)
+		return object
+	}
+	add(deployments, "ns3", "web", "uid3")
+	add(deployments, "ns2", "web", "uid2")
+	uid4 := add(deployments, "ns4", "web", "uid4")
+	add(deployments, "ns1", "api", "apiuid")
+	add(schema.GroupVersionResource{Version: "v1", Resource: "services"}, "ns1", "web", "serviceuid")
+
+	ctx := &RuleContext{
+		Child:          &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "web-1"}},
+		OwnerReference: metav1.OwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", UID: "olduid"},
+		OwnerMapping:   &meta.RESTMapping{Resource: deployments, Scope: meta.RESTScopeNamespace},
+		Objects:        objects,
+	}
+	// other namespaces are sorted by name, and only the closest are included
+	expected := []OwnerCandidate{{Namespace: "ns2", Name: "web", UID: "uid2"}, {Namespace: "ns3", Name: "web", UID: "uid3"}, {Namespace: "ns4", Name: "web", UID: "uid4"}}
+	if diff := cmp.Diff(expected, ownerCandidates(ctx)); diff != "" {
+		t.Errorf("unexpected candidates (-want +got):\n%s", diff)
+	}
+
+	// the owner recreated in the expected namespace is the closest candidate, however it is listed
+	recreated := add(schema.GroupVersionResource{Group: "apps", Version: "v1beta2", Resource: "deployments"}, "ns1", "web", "newuid")
+	candidates := ownerCandidates(ctx)
+	expected = []OwnerCandidate{{Namespace: "ns1", Name: "web", UID: "newuid"}, {Namespace: "ns2", Name: "web", UID: "uid2"}, {Namespace: "ns3", Name: "web", UID: "uid3"}}
+	if diff := cmp.Diff(expected, candidates); diff != "" {
+		t.Errorf("unexpected candidates (-want +got):\n%s", diff)
+	}
+	if message := describeCandidates(candidates[:2]); message != "; did you mean ns1/web (uid newuid), ns2/web (uid uid2)?" {
+		t.Errorf("unexpected message %q", message)
+	}
+
+	// objects removed, as by informer events when watching, are no longer candidates
+	objects.remove(schema.GroupVersionResource{Group: "apps", Version: "v1beta2", Resource: "deployments"}, recreated)
+	objects.remove(deployments, uid4)
+	expected = []OwnerCandidate{{Namespace: "ns2", Name: "web", UID: "uid2"}, {Namespace: "ns3", Name: "web", UID: "uid3"}}
+	if diff := cmp.Diff(expected, ownerCandidates(ctx)); diff != "" {
+		t.Errorf("unexpected candidates after removal (-want +got):\n%s", diff)
+	}
+
+	ctx.OwnerReference.Name = "db"
+	if candidates := ownerCandidates(ctx); candidates != nil {
+		t.Errorf("expected no candidates, got %v", candidates)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// capiGroup is the API group of Cluster API Cluster objects
+	capiGroup = "cluster.x-k8s.io"
+	// capiKubeconfigKey is the key of the kubeconfig in a workload cluster kubeconfig Secret
+	capiKubeconfigKey = "value"
+)
+
+// CAPIOptions contains options controlling how Cluster API workload clusters are found from a management cluster
+type CAPIOptions struct {
+	// DiscoveryClient is used to find the served version of Cluster objects
+	DiscoveryClient discovery.DiscoveryInterface
+	// MetadataClient is used to list Cluster objects
+	MetadataClient metadata.Interface
+	// Secrets is used to read the <cluster>-kubeconfig Secret of each workload cluster
+	Secrets corev1client.SecretsGetter
+	// Namespace limits the Cluster objects found to a namespace, or all namespaces if empty
+	Namespace string
+	// Selector limits the Cluster objects found to those matching a label selector
+	Selector string
+	// ConfigureClient optionally adjusts the REST config read for each workload cluster,
+	// such as to guard it as the management cluster's is
+	ConfigureClient func(*rest.Config)
+}
+
+// Validate ensures the specified options are valid
+func (o *CAPIOptions) Validate() error {
+	if o.DiscoveryClient == nil {
+		return fmt.Errorf("discovery client is required")
+	}
+	if o.MetadataClient == nil {
+		return fmt.Errorf("metadata client is required")
+	}
+	if o.Secrets == nil {
+		return fmt.Errorf("secrets client is required")
+	}
+	if _, err := labels.Parse(o.Selector); err != nil {
+		return fmt.Errorf("invalid selector: %v", err)
+	}
+	return nil
+}
+
+// WorkloadCluster is a workload cluster found from a Cluster API Cluster object
+type WorkloadCluster struct {
+	// Name is namespace/name of the Cluster object
+	Name string
+	// Config is the REST config read from the cluster's kubeconfig Secret, or nil if it could not be read
+	Config *rest.Config
+	// Err is set if the kubeconfig could not be read
+	Err error
+}
+
+// WorkloadClusters lists Cluster objects and reads their kubeconfig Secrets, sorted by name.
+// Clusters being deleted are skipped. An error reading a kubeconfig is returned in the cluster's Err.
+func (o *CAPIOptions) WorkloadClusters(ctx context.Context) ([]WorkloadCluster, error) {
+	gvr, err := o.clusterResource()
+	if err != nil {
+		return nil, err
+	}
+	list, err := o.MetadataClient.Resource(gvr).Namespace(o.Namespace).List(ctx, metav1.ListOptions{LabelSelector: o.Selector})
+	if err != nil {
+		return nil, fmt.Errorf("error listing %s: %v", gvr.GroupResource(), err)
+	}
+	clusters := []WorkloadCluster{}
+	for _, item := range list.Items {
+		if item.DeletionTimestamp != nil {
+			continue
+		}
+		cluster := WorkloadCluster{Name: item.Namespace + "/" + item.Name}
+		cluster.Config, cluster.Err = o.kubeconfig(ctx, item.Namespace, item.Name)
+		if cluster.Config != nil && o.ConfigureClient != nil {
+			o.ConfigureClient(cluster.Config)
+		}
+		clusters = append(clusters, cluster)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+	return clusters, nil
+}
+
+// clusterResource returns the preferred version of the clusters resource served by the management cluster
+func (o *CAPIOptions) clusterResource() (schema.GroupVersionResource, error) {
+	groups, err := o.DiscoveryClient.ServerGroups()
+	if err != nil {
+		return schema.GroupVersionResource{}, err
+	}
+	for _, group := range groups.Groups {
+		if group.Name == capiGroup {
+			return schema.GroupVersionResource{Group: capiGroup, Version: group.PreferredVersion.Version, Resource: "clusters"}, nil
+		}
+	}
+	return schema.GroupVersionResource{}, fmt.Errorf("the %s API group is not served, is this a Cluster API management cluster?", capiGroup)
+}
+
+// kubeconfig reads the REST config for the named cluster from its kubeconfig Secret
+func (o *CAPIOptions) kubeconfig(ctx context.Context, namespace, name string) (*rest.Config, error) {
+	secret, err := o.Secrets.Secrets(namespace).Get(ctx, name+"-kubeconfig", metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error reading kubeconfig: %v", err)
+	}
+	data, ok := secret.Data[capiKubeconfigKey]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig secret %s/%s has no %q key", namespace, secret.Name, capiKubeconfigKey)
+	}
+	config, err := clientcmd.RESTConfigFromKubeConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig in secret %s/%s: %v", namespace, secret.Name, err)
+	}
+	return config, nil
+}
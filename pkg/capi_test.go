@@ -0,0 +1,142 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	"k8s.io/client-go/rest"
+	coretesting "k8s.io/client-go/testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: workload
+  cluster:
+    server: https://workload.example.com:6443
+contexts:
+- name: workload
+  context:
+    cluster: workload
+    user: admin
+users:
+- name: admin
+  user:
+    token: secret-token
+current-context: workload
+`
+
+func TestCAPIWorkloadClusters(t *testing.T) {
+	discoveryClient := &discoveryfake.FakeDiscovery{Fake: &coretesting.Fake{}}
+	discoveryClient.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "cluster.x-k8s.io/v1beta1",
+		APIResources: []metav1.APIResource{{Name: "clusters", Namespaced: true, Kind: "Cluster", Verbs: []string{"get", "list", "delete"}}},
+	}}
+	metadataClient := metadatafake.NewSimpleMetadataClient(runtime.NewScheme())
+	gvr := schema.GroupVersionResource{Group: "cluster.x-k8s.io", Version: "v1beta1", Resource: "clusters"}
+	now := metav1.Now()
+	for _, cluster := range []metav1.ObjectMeta{
+		{Namespace: "team-b", Name: "prod"},
+		{Namespace: "team-a", Name: "prod"},
+		{Namespace: "team-a", Name: "no-secret"},
+		{Namespace: "team-a", Name: "deleting", DeletionTimestamp: &now},
+	} {
+		object := &metav1.PartialObjectMetadata{TypeMeta: metav1.TypeMeta{APIVersion: "cluster.x-k8s.io/v1beta1", Kind: "Cluster"}, ObjectMeta: cluster}
+		if _, err := metadataClient.Resource(gvr).Namespace(cluster.Namespace).(metadatafake.MetadataClient).CreateFake(object, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	coreClient := fake.NewSimpleClientset(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "prod-kubeconfig"}, Data: map[string][]byte{"value": []byte(testKubeconfig)}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "prod-kubeconfig"}, Data: map[string][]byte{"other": []byte(testKubeconfig)}},
+	)
+
+	opts := &CAPIOptions{DiscoveryClient: discoveryClient, MetadataClient: metadataClient, Secrets: coreClient.CoreV1()}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	clusters, err := opts.WorkloadClusters(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		Name   string
+		Server string
+		Err    string
+	}
+	got := []result{}
+	for _, cluster := range clusters {
+		r := result{Name: cluster.Name}
+		if cluster.Config != nil {
+			r.Server = cluster.Config.Host
+		}
+		if cluster.Err != nil {
+			r.Err = cluster.Err.Error()
+		}
+		got = append(got, r)
+	}
+	expect := []result{
+		{Name: "team-a/no-secret", Err: `error reading kubeconfig: secrets "no-secret-kubeconfig" not found`},
+		{Name: "team-a/prod", Server: "https://workload.example.com:6443"},
+		{Name: "team-b/prod", Err: `kubeconfig secret team-b/prod-kubeconfig has no "value" key`},
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("unexpected clusters (-want +got):\n%s", diff)
+	}
+
+	// workload cluster configs are guarded as configured, so writes are refused without being sent
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { requests++ }))
+	defer server.Close()
+	opts.ConfigureClient = func(config *rest.Config) { config.Wrap(ReadOnly) }
+	if clusters, err = opts.WorkloadClusters(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	config := rest.CopyConfig(clusters[1].Config)
+	config.Host = server.URL
+	workloadClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := workloadClient.CoreV1().Pods("ns1").Delete(context.Background(), "pod1", metav1.DeleteOptions{}); err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("expected the write to the workload cluster to be refused, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("expected no requests to be sent, got %d", requests)
+	}
+
+	// not a management cluster
+	discoveryClient.Resources = nil
+	if _, err := opts.WorkloadClusters(context.Background()); err == nil {
+		t.Error("expected error when the cluster.x-k8s.io group is not served")
+	}
+}
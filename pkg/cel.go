@@ -0,0 +1,156 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CELRule is a custom check written as a CEL expression.
+//
+// The expression must evaluate to true if the ownerReference is valid, and has these variables:
+//
+//	resource:       the child resource, with group, version, and resource fields
+//	child:          the child object metadata, with apiVersion, kind, namespace, name, uid, labels, and annotations fields
+//	ownerReference: the ownerReference, with apiVersion, kind, name, uid, controller, and blockOwnerDeletion fields
+//	ownerFound:     true if an object was found with the ownerReference uid
+//	owner:          the owner object metadata, with the same fields as child, or an empty map if ownerFound is false
+type CELRule struct {
+	// Code identifies findings reported by the rule
+	Code string `json:"code"`
+	// Level of findings reported by the rule, either Error or Warning. Defaults to Error.
+	Level string `json:"level,omitempty"`
+	// Expression is a CEL expression that evaluates to true if the ownerReference is valid
+	Expression string `json:"expression"`
+	// Message describes findings reported by the rule
+	Message string `json:"message"`
+}
+
+// celRule is a compiled CELRule
+type celRule struct {
+	CELRule
+	program cel.Program
+}
+
+var celEnvOptions = []cel.EnvOption{
+	cel.Declarations(
+		decls.NewVar("resource", decls.NewMapType(decls.String, decls.String)),
+		decls.NewVar("child", decls.NewMapType(decls.String, decls.Dyn)),
+		decls.NewVar("ownerReference", decls.NewMapType(decls.String, decls.Dyn)),
+		decls.NewVar("ownerFound", decls.Bool),
+		decls.NewVar("owner", decls.NewMapType(decls.String, decls.Dyn)),
+	),
+}
+
+// compile checks and compiles the expression of r
+func (r CELRule) compile() (*celRule, error) {
+	if r.Code == "" {
+		return nil, fmt.Errorf("code is required")
+	}
+	if r.Level != "" && r.Level != LevelError && r.Level != LevelWarning {
+		return nil, fmt.Errorf("%s: invalid level %q, only %q and %q are supported", r.Code, r.Level, LevelError, LevelWarning)
+	}
+	if r.Message == "" {
+		return nil, fmt.Errorf("%s: message is required", r.Code)
+	}
+	env, err := cel.NewEnv(celEnvOptions...)
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(r.Expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("%s: invalid expression: %v", r.Code, issues.Err())
+	}
+	if resultType := cel.FormatType(ast.ResultType()); resultType != "bool" && resultType != "dyn" {
+		return nil, fmt.Errorf("%s: expression must evaluate to a bool, got %s", r.Code, resultType)
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", r.Code, err)
+	}
+	if r.Level == "" {
+		r.Level = LevelError
+	}
+	return &celRule{CELRule: r, program: program}, nil
+}
+
+// Check evaluates the expression, returning a problem if it is false
+func (r *celRule) Check(ctx *RuleContext) []Problem {
+	out, _, err := r.program.Eval(ruleInput(ctx))
+	if err != nil {
+		return problem(LevelWarning, r.Code, fmt.Sprintf("error evaluating rule: %v", err))
+	}
+	if valid, ok := out.(types.Bool); !ok {
+		return problem(LevelWarning, r.Code, fmt.Sprintf("error evaluating rule: expected bool, got %v", out.Type()))
+	} else if !valid {
+		return problem(r.Level, r.Code, r.Message)
+	}
+	return nil
+}
+
+// ruleInput returns the variables describing ctx to custom rule expressions and policies
+func ruleInput(ctx *RuleContext) map[string]interface{} {
+	owner := map[string]interface{}{}
+	if len(ctx.Owners) > 0 {
+		owner = ruleObject(ctx.Owners[0])
+	}
+	return map[string]interface{}{
+		"resource": map[string]interface{}{
+			"group":    ctx.Resource.Group,
+			"version":  ctx.Resource.Version,
+			"resource": ctx.Resource.Resource,
+		},
+		"child": ruleObject(ctx.Child),
+		"ownerReference": map[string]interface{}{
+			"apiVersion":         ctx.OwnerReference.APIVersion,
+			"kind":               ctx.OwnerReference.Kind,
+			"name":               ctx.OwnerReference.Name,
+			"uid":                string(ctx.OwnerReference.UID),
+			"controller":         ctx.OwnerReference.Controller != nil && *ctx.OwnerReference.Controller,
+			"blockOwnerDeletion": ctx.OwnerReference.BlockOwnerDeletion != nil && *ctx.OwnerReference.BlockOwnerDeletion,
+		},
+		"ownerFound": len(ctx.Owners) > 0,
+		"owner":      owner,
+	}
+}
+
+// ruleObject returns the fields of object exposed to custom rule expressions and policies
+func ruleObject(object *metav1.PartialObjectMetadata) map[string]interface{} {
+	labels := object.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	annotations := object.Annotations
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	return map[string]interface{}{
+		"apiVersion":  object.APIVersion,
+		"kind":        object.Kind,
+		"namespace":   object.Namespace,
+		"name":        object.Name,
+		"uid":         string(object.UID),
+		"labels":      labels,
+		"annotations": annotations,
+	}
+}
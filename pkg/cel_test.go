@@ -0,0 +1,111 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCELRuleCompile(t *testing.T) {
+	testcases := []struct {
+		name      string
+		rule      CELRule
+		expectErr bool
+	}{
+		{name: "valid", rule: CELRule{Code: "Test", Expression: "ownerReference.controller", Message: "m"}},
+		{name: "missing code", rule: CELRule{Expression: "true", Message: "m"}, expectErr: true},
+		{name: "missing message", rule: CELRule{Code: "Test", Expression: "true"}, expectErr: true},
+		{name: "invalid level", rule: CELRule{Code: "Test", Level: "Info", Expression: "true", Message: "m"}, expectErr: true},
+		{name: "syntax error", rule: CELRule{Code: "Test", Expression: "child.name ==", Message: "m"}, expectErr: true},
+		{name: "undeclared variable", rule: CELRule{Code: "Test", Expression: "parent.name == ''", Message: "m"}, expectErr: true},
+		{name: "non-bool result", rule: CELRule{Code: "Test", Expression: "resource.group", Message: "m"}, expectErr: true},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.rule.compile()
+			if tc.expectErr && err == nil {
+				t.Error("expected error")
+			} else if !tc.expectErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCELRuleCheck(t *testing.T) {
+	isController := true
+	child := &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "pod1", Labels: map[string]string{"team": "a"}},
+	}
+	owner := &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "ReplicaSet"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "rs1", Labels: map[string]string{"team": "b"}},
+	}
+	ctx := &RuleContext{
+		Resource:       schema.GroupVersionResource{Version: "v1", Resource: "pods"},
+		Child:          child,
+		OwnerReference: metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs1", UID: "rsuid1", Controller: &isController},
+		Owners:         []*metav1.PartialObjectMetadata{owner},
+	}
+
+	testcases := []struct {
+		name       string
+		expression string
+		ctx        *RuleContext
+		expect     []Problem
+	}{
+		{
+			name:       "valid",
+			expression: "ownerReference.controller && resource.resource == 'pods'",
+			ctx:        ctx,
+		},
+		{
+			name:       "invalid",
+			expression: "!ownerFound || owner.labels['team'] == child.labels['team']",
+			ctx:        ctx,
+			expect:     []Problem{{Level: LevelWarning, Code: "Test", Message: "teams must match"}},
+		},
+		{
+			name:       "owner not found",
+			expression: "!ownerFound || owner.labels['team'] == child.labels['team']",
+			ctx:        &RuleContext{Resource: ctx.Resource, Child: child, OwnerReference: ctx.OwnerReference},
+		},
+		{
+			name:       "evaluation error",
+			expression: "child.labels['missing'] == 'x'",
+			ctx:        ctx,
+			expect:     []Problem{{Level: LevelWarning, Code: "Test", Message: "error evaluating rule: no such key: missing"}},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := CELRule{Code: "Test", Level: LevelWarning, Expression: tc.expression, Message: "teams must match"}.compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.expect, rule.Check(tc.ctx)); diff != "" {
+				t.Errorf("unexpected problems (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
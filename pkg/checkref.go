@@ -0,0 +1,175 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// Results of the checks of a single ownerReference
+const (
+	CheckPass = "Pass"
+	CheckFail = "Fail"
+	CheckWarn = "Warn"
+	CheckSkip = "Skip"
+)
+
+// checkRefRules are the default rules with the names printed for them, in the order they are evaluated
+var checkRefRules = []struct {
+	name string
+	rule Rule
+}{
+	{"apiVersion is valid", RuleFunc(checkAPIVersion)},
+	{"apiVersion/kind resolves", RuleFunc(checkOwnerResolvable)},
+	{"owner scope allowed for child", RuleFunc(checkOwnerScope)},
+	{"owner exists with uid", RuleFunc(checkOwnerExists)},
+	{"owner in child namespace", RuleFunc(checkOwnerNamespace)},
+	{"name matches owner", RuleFunc(checkOwnerName)},
+	{"group/kind matches owner", RuleFunc(checkOwnerGroupKind)},
+	{"apiVersion not deprecated", RuleFunc(checkOwnerAPIVersionDeprecated)},
+}
+
+// CheckRefOptions contains options controlling how a single ownerReference is checked against the cluster,
+// to test a reference before writing it
+type CheckRefOptions struct {
+	// Validator resolves the ownerReference and gets the owner as the garbage collector would
+	Validator *Validator
+	Output    string
+	Stdout    io.Writer
+
+	// OwnerReference is the reference to check. Its uid is required.
+	OwnerReference metav1.OwnerReference
+	// ChildNamespace is the namespace of the child the reference would be written to, empty for a cluster-scoped child
+	ChildNamespace string
+	// OwnerNamespace is the namespace the owner is expected in, if it is namespaced. Defaults to ChildNamespace.
+	OwnerNamespace string
+}
+
+// Validate ensures the specified options are valid
+func (o *CheckRefOptions) Validate() error {
+	if o.Validator == nil || o.Validator.RESTMapper == nil || o.Validator.Owners == nil {
+		return fmt.Errorf("validator with a REST mapper and owner getter is required")
+	}
+	if o.Stdout == nil {
+		return fmt.Errorf("stdout is required")
+	}
+	if o.Output != "" && o.Output != "json" {
+		return fmt.Errorf("invalid output format, only '' and 'json' are supported: %v", o.Output)
+	}
+	ref := o.OwnerReference
+	if ref.APIVersion == "" || ref.Kind == "" || ref.Name == "" || ref.UID == "" {
+		return fmt.Errorf("apiVersion, kind, name, and uid are required")
+	}
+	return nil
+}
+
+// CheckResult is the result of one check of an ownerReference
+type CheckResult struct {
+	Check  string `json:"check"`
+	Result string `json:"result"`
+	// Code and Message describe the problem found, if the check did not pass
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Run checks the ownerReference, writes the result of each check to Stdout, either as a table if Output is ”,
+// or as a JSON array if Output is 'json', and returns an error if any check failed.
+// Checks after the first that does not pass are skipped, as the garbage collector would not get past it.
+func (o *CheckRefOptions) Run(ctx context.Context) error {
+	child := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: o.ChildNamespace, OwnerReferences: []metav1.OwnerReference{o.OwnerReference}}}
+	ruleCtx := &RuleContext{Child: child, OwnerReference: o.OwnerReference, Objects: newObjectIndex()}
+	resolveOwner(o.Validator.RESTMapper, ruleCtx)
+
+	// the owner is found by name, and only counts if its uid matches
+	var note string
+	if ruleCtx.OwnerMapping != nil {
+		namespace := ""
+		if ruleCtx.OwnerMapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			namespace = o.ChildNamespace
+			if o.OwnerNamespace != "" {
+				namespace = o.OwnerNamespace
+			}
+		}
+		owner, err := o.Validator.Owners.Get(ctx, ruleCtx.OwnerMapping.Resource, namespace, o.OwnerReference.Name)
+		switch {
+		case apierrors.IsNotFound(err):
+		case err != nil:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			ruleCtx.OwnerListError = err
+		case owner.UID != o.OwnerReference.UID:
+			note = fmt.Sprintf(" (%s is uid %s)", namespacedName(namespace, owner.Name), owner.UID)
+		default:
+			owner = owner.DeepCopy()
+			owner.APIVersion, owner.Kind = ruleCtx.OwnerMapping.GroupVersionKind.GroupVersion().String(), ruleCtx.OwnerMapping.GroupVersionKind.Kind
+			ruleCtx.Owners = []*metav1.PartialObjectMetadata{owner}
+			ruleCtx.Objects.add(ruleCtx.OwnerMapping.Resource, owner)
+		}
+	}
+
+	results := make([]CheckResult, 0, len(checkRefRules))
+	var failure *Problem
+	stopped := false
+	for _, checkRefRule := range checkRefRules {
+		result := CheckResult{Check: checkRefRule.name, Result: CheckPass}
+		if stopped {
+			result.Result = CheckSkip
+		} else if problems := checkRefRule.rule.Check(ruleCtx); len(problems) > 0 {
+			stopped = true
+			found := problems[0]
+			if found.Code == CodeOwnerNotFound {
+				found.Message += note
+			}
+			result.Result, result.Code, result.Message = CheckWarn, found.Code, found.Message
+			if found.Level == LevelError {
+				result.Result = CheckFail
+				failure = &found
+			}
+		}
+		results = append(results, result)
+	}
+
+	if o.Output == "json" {
+		encoder := json.NewEncoder(o.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		tabwriter := printers.GetNewTabWriter(o.Stdout)
+		fmt.Fprintln(tabwriter, "CHECK\tRESULT\tMESSAGE")
+		for _, result := range results {
+			fmt.Fprintf(tabwriter, "%s\t%s\t%s\n", result.Check, result.Result, result.Message)
+		}
+		if err := tabwriter.Flush(); err != nil {
+			return err
+		}
+	}
+	if failure != nil {
+		return fmt.Errorf("invalid ownerReference: %s: %s", failure.Code, failure.Message)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckRef(t *testing.T) {
+	cluster := newFakeCluster(t, "c", "owner")
+	validator := NewValidator(cluster.Verify.DiscoveryClient, cluster.Verify.MetadataClient)
+	owner := metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: "owner", UID: "uid-c-owner"}
+
+	results := func(ref metav1.OwnerReference, childNamespace, ownerNamespace string) ([]string, error) {
+		t.Helper()
+		stdout := &bytes.Buffer{}
+		opts := &CheckRefOptions{Validator: validator, Output: "json", Stdout: stdout, OwnerReference: ref, ChildNamespace: childNamespace, OwnerNamespace: ownerNamespace}
+		if err := opts.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		runErr := opts.Run(context.Background())
+		checkResults := []CheckResult{}
+		if err := json.Unmarshal(stdout.Bytes(), &checkResults); err != nil {
+			t.Fatal(err)
+		}
+		summary := []string{}
+		for _, result := range checkResults {
+			summary = append(summary, result.Result+" "+result.Code)
+		}
+		return summary, runErr
+	}
+	pass, skip := CheckPass+" ", CheckSkip+" "
+
+	summary, err := results(owner, "ns1", "")
+	if err != nil {
+		t.Errorf("unexpected error for a valid ownerReference: %v", err)
+	}
+	if diff := cmp.Diff([]string{pass, pass, pass, pass, pass, pass, pass, pass}, summary); diff != "" {
+		t.Errorf("unexpected results (-want +got):\n%s", diff)
+	}
+
+	// the owner is in another namespace than the child
+	summary, err = results(owner, "ns2", "ns1")
+	if err == nil || !strings.Contains(err.Error(), CodeNamespaceMismatch) {
+		t.Errorf("expected namespace mismatch error, got %v", err)
+	}
+	if diff := cmp.Diff([]string{pass, pass, pass, pass, CheckFail + " " + CodeNamespaceMismatch, skip, skip, skip}, summary); diff != "" {
+		t.Errorf("unexpected results (-want +got):\n%s", diff)
+	}
+
+	// an object with the name but another uid is not the owner
+	recreated := owner
+	recreated.UID = "olduid"
+	if _, err = results(recreated, "ns1", ""); err == nil || !strings.Contains(err.Error(), "(ns1/owner is uid uid-c-owner)") {
+		t.Errorf("expected owner not found error noting the uid of the object with the name, got %v", err)
+	}
+
+	// a cluster-scoped child cannot have a namespaced owner
+	summary, _ = results(owner, "", "ns1")
+	if diff := cmp.Diff([]string{pass, pass, CheckFail + " " + CodeNamespacedOwnerOfClusterScopedChild, skip, skip, skip, skip, skip}, summary); diff != "" {
+		t.Errorf("unexpected results (-want +got):\n%s", diff)
+	}
+
+	unknown := metav1.OwnerReference{APIVersion: "example.com/v1", Kind: "Widget", Name: "w", UID: "wuid"}
+	summary, _ = results(unknown, "ns1", "")
+	if diff := cmp.Diff([]string{pass, CheckFail + " " + CodeUnresolvableOwner, skip, skip, skip, skip, skip, skip}, summary); diff != "" {
+		t.Errorf("unexpected results (-want +got):\n%s", diff)
+	}
+
+	opts := &CheckRefOptions{Validator: validator, Stdout: &bytes.Buffer{}, OwnerReference: metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: "owner"}}
+	if err := opts.Validate(); err == nil {
+		t.Errorf("expected error for an ownerReference without a uid")
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Confidence levels of the consistency of a scan
+const (
+	ConfidenceHigh   = "High"
+	ConfidenceMedium = "Medium"
+	ConfidenceLow    = "Low"
+)
+
+// Consistency estimates how much the cluster changed while a scan was listing.
+// Resources are listed one after another, so owners deleted or children created between listing them
+// can be reported as invalid ownerReferences that are only artifacts of the race.
+type Consistency struct {
+	// ResourceVersionSpread is the difference between the highest and lowest resourceVersion of the lists of the scan,
+	// approximating the number of writes to the cluster while listing. Zero if resourceVersions are not numbers.
+	ResourceVersionSpread uint64 `json:"resourceVersionSpread,omitempty"`
+	// Created is the number of listed objects created after the scan started
+	Created int `json:"created"`
+	// Deleting is the number of listed objects that were being deleted
+	Deleting int `json:"deleting"`
+	// Confidence is ConfidenceHigh, ConfidenceMedium, or ConfidenceLow, from the changes relative to the objects listed
+	Confidence string `json:"confidence"`
+}
+
+// churnTracker observes the lists and objects of a scan to estimate its Consistency
+type churnTracker struct {
+	start        time.Time
+	minRV, maxRV uint64
+	created      int
+	deleting     int
+}
+
+// observeList records the resourceVersion of list, if it is a number
+func (c *churnTracker) observeList(list *metav1.PartialObjectMetadataList) {
+	rv, err := strconv.ParseUint(list.ResourceVersion, 10, 64)
+	if err != nil {
+		return
+	}
+	if c.minRV == 0 || rv < c.minRV {
+		c.minRV = rv
+	}
+	if rv > c.maxRV {
+		c.maxRV = rv
+	}
+}
+
+// observe records whether object was created after the scan started or is being deleted
+func (c *churnTracker) observe(object *metav1.PartialObjectMetadata) {
+	if object.CreationTimestamp.After(c.start) {
+		c.created++
+	}
+	if object.DeletionTimestamp != nil {
+		c.deleting++
+	}
+}
+
+// consistency returns the consistency of a scan that listed objects.
+// Confidence is Low if the changes while listing are at least 5% of the objects listed, and Medium if at least 0.5%.
+func (c *churnTracker) consistency(objects int) *Consistency {
+	consistency := &Consistency{ResourceVersionSpread: c.maxRV - c.minRV, Created: c.created, Deleting: c.deleting, Confidence: ConfidenceHigh}
+	changes := consistency.ResourceVersionSpread
+	if observed := uint64(c.created + c.deleting); observed > changes {
+		changes = observed
+	}
+	switch {
+	case objects == 0 || changes == 0:
+	case changes*20 >= uint64(objects):
+		consistency.Confidence = ConfidenceLow
+	case changes*200 >= uint64(objects):
+		consistency.Confidence = ConfidenceMedium
+	}
+	return consistency
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConsistency(t *testing.T) {
+	start := time.Now()
+	before := metav1.NewTime(start.Add(-time.Hour))
+	after := metav1.NewTime(start.Add(time.Minute))
+	list := func(rv string) *metav1.PartialObjectMetadataList {
+		return &metav1.PartialObjectMetadataList{ListMeta: metav1.ListMeta{ResourceVersion: rv}}
+	}
+	object := func(created metav1.Time, deleted *metav1.Time) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created, DeletionTimestamp: deleted}}
+	}
+	for _, tc := range []struct {
+		name     string
+		lists    []*metav1.PartialObjectMetadataList
+		objects  []*metav1.PartialObjectMetadata
+		listed   int
+		expected *Consistency
+	}{
+		{
+			name:     "quiet",
+			lists:    []*metav1.PartialObjectMetadataList{list("100"), list("100")},
+			objects:  []*metav1.PartialObjectMetadata{object(before, nil)},
+			listed:   1000,
+			expected: &Consistency{Confidence: ConfidenceHigh},
+		},
+		{
+			name:     "some writes",
+			lists:    []*metav1.PartialObjectMetadataList{list("100"), list("110")},
+			listed:   1000,
+			expected: &Consistency{ResourceVersionSpread: 10, Confidence: ConfidenceMedium},
+		},
+		{
+			name:     "busy",
+			lists:    []*metav1.PartialObjectMetadataList{list("100"), list("200"), list("150")},
+			listed:   1000,
+			expected: &Consistency{ResourceVersionSpread: 100, Confidence: ConfidenceLow},
+		},
+		{
+			name:     "opaque resourceVersions",
+			lists:    []*metav1.PartialObjectMetadataList{list("a"), list("b")},
+			objects:  []*metav1.PartialObjectMetadata{object(after, nil), object(before, &after)},
+			listed:   20,
+			expected: &Consistency{Created: 1, Deleting: 1, Confidence: ConfidenceLow},
+		},
+	} {
+		churn := &churnTracker{start: start}
+		for _, list := range tc.lists {
+			churn.observeList(list)
+		}
+		for _, object := range tc.objects {
+			churn.observe(object)
+		}
+		if diff := cmp.Diff(tc.expected, churn.consistency(tc.listed)); diff != "" {
+			t.Errorf("%s: unexpected consistency (-want +got):\n%s", tc.name, diff)
+		}
+	}
+}
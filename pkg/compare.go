@@ -0,0 +1,126 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// CompareOptions contains options controlling how findings in two clusters are compared
+type CompareOptions struct {
+	// A and B are the clusters to compare
+	A ClusterScan
+	B ClusterScan
+	// Output is the format the comparison is written to Stdout in, either '' or 'json'
+	Output string
+	// ShowCommon includes findings present in both clusters in the table output
+	ShowCommon bool
+	// FailOnDifferences returns an error from Run if any findings are only present in one of the clusters
+	FailOnDifferences bool
+	Stderr            io.Writer
+	Stdout            io.Writer
+}
+
+// Validate ensures the specified options are valid
+func (o *CompareOptions) Validate() error {
+	if o.Output != "" && o.Output != "json" {
+		return fmt.Errorf("invalid output format, only '' and 'json' are supported: %v", o.Output)
+	}
+	return o.multiCluster().Validate()
+}
+
+func (o *CompareOptions) multiCluster() *MultiClusterOptions {
+	return &MultiClusterOptions{Clusters: []ClusterScan{o.A, o.B}, Parallelism: 2, Output: o.Output, Stderr: o.Stderr, Stdout: o.Stdout}
+}
+
+// Run scans both clusters, writes the findings present in only one of them to Stdout, and a summary to Stderr
+func (o *CompareOptions) Run(ctx context.Context) error {
+	results := o.multiCluster().Scan(ctx)
+	for _, result := range results {
+		if result.Err != nil {
+			return fmt.Errorf("error scanning %s: %v", result.Name, result.Err)
+		}
+	}
+	if ctx.Err() != nil {
+		// comparing partial results would report differences that do not exist
+		return fmt.Errorf("scan interrupted: %v", ctx.Err())
+	}
+
+	comparison := CompareFindings(o.A.Name, results[0].Report.Findings, o.B.Name, results[1].Report.Findings)
+	if err := comparison.Print(o.Stdout, o.Output, o.ShowCommon); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Stderr, "%d only in %s, %d only in %s, %d in both\n", len(comparison.OnlyInA), o.A.Name, len(comparison.OnlyInB), o.B.Name, len(comparison.Common))
+	if o.FailOnDifferences && (len(comparison.OnlyInA) > 0 || len(comparison.OnlyInB) > 0) {
+		return fmt.Errorf("%s differ between %s and %s", pluralize(len(comparison.OnlyInA)+len(comparison.OnlyInB), "finding", "findings"), o.A.Name, o.B.Name)
+	}
+	return nil
+}
+
+// Comparison holds the findings that differ between two clusters
+type Comparison struct {
+	// A and B name the compared clusters
+	A string `json:"a"`
+	B string `json:"b"`
+	// OnlyInA are findings only present in cluster A
+	OnlyInA []Finding `json:"onlyInA"`
+	// OnlyInB are findings only present in cluster B
+	OnlyInB []Finding `json:"onlyInB"`
+	// Common are findings present in both clusters, as reported in cluster B
+	Common []Finding `json:"common"`
+}
+
+// comparisonKey identifies a finding independent of the cluster it was found in.
+// UIDs differ between clusters, so owners are identified by group, kind, and name.
+func comparisonKey(finding Finding) findingKey {
+	key := keyOf(finding)
+	key.cluster = ""
+	ownerGroup := finding.OwnerReference.APIVersion
+	if gv, err := schema.ParseGroupVersion(finding.OwnerReference.APIVersion); err == nil {
+		ownerGroup = gv.Group
+	}
+	key.owner = schema.GroupKind{Group: ownerGroup, Kind: finding.OwnerReference.Kind}.String() + "/" + finding.OwnerReference.Name
+	return key
+}
+
+// CompareFindings compares the findings of clusters a and b, matching objects by resource, namespace, and name
+func CompareFindings(a string, aFindings []Finding, b string, bFindings []Finding) *Comparison {
+	onlyA, onlyB, common := matchFindings(aFindings, bFindings, comparisonKey)
+	return &Comparison{A: a, B: b, OnlyInA: onlyA, OnlyInB: onlyB, Common: common}
+}
+
+// Print writes c to w, either as a table if output is ”, or as a JSON object if output is 'json'.
+// Common findings are only included in the table if showCommon is true.
+func (c *Comparison) Print(w io.Writer, output string, showCommon bool) error {
+	switch output {
+	case "":
+		rows := []diffRows{{"only in " + c.A, c.OnlyInA}, {"only in " + c.B, c.OnlyInB}}
+		if showCommon {
+			rows = append(rows, diffRows{"both", c.Common})
+		}
+		return printDiffRows(w, rows)
+	case "json":
+		return json.NewEncoder(w).Encode(c)
+	default:
+		return fmt.Errorf("invalid output format, only '' and 'json' are supported: %v", output)
+	}
+}
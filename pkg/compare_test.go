@@ -0,0 +1,82 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompareFindings(t *testing.T) {
+	a := diffFinding("a", CodeOwnerNotFound, "no object found for uid")
+	// the same problem in another cluster has different uids
+	aOtherCluster := a
+	aOtherCluster.UID = "otheruid"
+	aOtherCluster.OwnerReference.UID = "otherowneruid"
+	aOtherCluster.OwnerReference.APIVersion = "apps/v1beta2"
+	// a different owner is a different problem
+	aOtherOwner := a
+	aOtherOwner.OwnerReference.Name = "other"
+	b := diffFinding("b", CodeOwnerNotFound, "no object found for uid")
+
+	comparison := CompareFindings("blue", []Finding{a, b}, "green", []Finding{aOtherCluster, aOtherOwner})
+	expect := &Comparison{
+		A:       "blue",
+		B:       "green",
+		OnlyInA: []Finding{b},
+		OnlyInB: []Finding{aOtherOwner},
+		Common:  []Finding{aOtherCluster},
+	}
+	if diff := cmp.Diff(expect, comparison); diff != "" {
+		t.Errorf("unexpected comparison (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompareRun(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	opts := &CompareOptions{
+		A:                 newFakeCluster(t, "blue", "pod1", "pod2"),
+		B:                 newFakeCluster(t, "green", "pod2", "pod3"),
+		FailOnDifferences: true,
+		Stderr:            stderr,
+		Stdout:            stdout,
+	}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	err := opts.Run(context.Background())
+	if err == nil || err.Error() != "2 findings differ between blue and green" {
+		t.Errorf("expected error for differences, got %v", err)
+	}
+
+	expectStdout := `
+STATUS GROUP RESOURCE NAMESPACE NAME OWNER_UID LEVEL MESSAGE
+only in blue pods ns1 pod1 missinguid-blue Error no object found for uid
+only in green pods ns1 pod3 missinguid-green Error no object found for uid
+`
+	if diff := cmp.Diff(normalize(expectStdout), normalize(stdout.String())); diff != "" {
+		t.Errorf("unexpected stdout (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(stderr.String(), "1 only in blue, 1 only in green, 1 in both") {
+		t.Errorf("unexpected summary: %s", stderr.String())
+	}
+}
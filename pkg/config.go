@@ -0,0 +1,167 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// RuleConfig configures how the findings reported by rules are filtered and leveled
+type RuleConfig struct {
+	// Checks configures the findings reported with each code
+	Checks map[string]CheckConfig `json:"checks,omitempty"`
+	// Rules are custom checks written as CEL expressions, evaluated after the built-in rules
+	Rules []CELRule `json:"rules,omitempty"`
+	// RegoPolicies are custom checks written as Rego policies, evaluated after the CEL rules
+	RegoPolicies []RegoPolicy `json:"regoPolicies,omitempty"`
+
+	// customRules are Rules and RegoPolicies once compiled
+	customRules []Rule
+}
+
+// CheckConfig configures the findings reported with a code
+type CheckConfig struct {
+	// Enabled reports findings with the code if unset or true
+	Enabled *bool `json:"enabled,omitempty"`
+	// Level overrides the level of findings with the code, either Error or Warning
+	Level string `json:"level,omitempty"`
+	// Kinds limits findings to child objects of these kinds, as Kind (any group) or Kind.group
+	Kinds []string `json:"kinds,omitempty"`
+	// ExcludeKinds drops findings for child objects of these kinds, as Kind (any group) or Kind.group
+	ExcludeKinds []string `json:"excludeKinds,omitempty"`
+	// ExcludeNamespaces drops findings for child objects in these namespaces
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty"`
+	// Threshold drops all findings with the code unless at least this many are found
+	Threshold int `json:"threshold,omitempty"`
+}
+
+// LoadRuleConfig reads a YAML or JSON rule config from path
+func LoadRuleConfig(path string) (*RuleConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &RuleConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("error reading rule config %s: %v", path, err)
+	}
+	for i, policy := range config.RegoPolicies {
+		if policy.File != "" && !filepath.IsAbs(policy.File) {
+			config.RegoPolicies[i].File = filepath.Join(filepath.Dir(path), policy.File)
+		}
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rule config %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// Validate ensures the config is valid
+func (c *RuleConfig) Validate() error {
+	for code, check := range c.Checks {
+		if check.Level != "" && check.Level != LevelError && check.Level != LevelWarning {
+			return fmt.Errorf("%s: invalid level %q, only %q and %q are supported", code, check.Level, LevelError, LevelWarning)
+		}
+		if check.Threshold < 0 {
+			return fmt.Errorf("%s: invalid threshold, must be >= 0", code)
+		}
+	}
+	_, err := c.CustomRules()
+	return err
+}
+
+// CustomRules returns the custom rules and Rego policies in the config, compiling them the first time it is called
+func (c *RuleConfig) CustomRules() ([]Rule, error) {
+	if c.customRules != nil {
+		return c.customRules, nil
+	}
+	rules := []Rule{}
+	for _, r := range c.Rules {
+		compiled, err := r.compile()
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule: %v", err)
+		}
+		rules = append(rules, compiled)
+	}
+	if len(c.RegoPolicies) > 0 {
+		compiled, err := compileRego(c.RegoPolicies)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, compiled)
+	}
+	c.customRules = rules
+	return rules, nil
+}
+
+// apply returns problem adjusted by the config for its code, or false if it should not be reported
+func (c *RuleConfig) apply(ctx *RuleContext, problem Problem) (Problem, bool) {
+	check, ok := c.Checks[problem.Code]
+	if !ok {
+		return problem, true
+	}
+	if check.Enabled != nil && !*check.Enabled {
+		return problem, false
+	}
+	childKind := schema.GroupKind{Group: ctx.Resource.Group, Kind: ctx.Child.Kind}
+	if len(check.Kinds) > 0 && !matchesKind(check.Kinds, childKind) {
+		return problem, false
+	}
+	if matchesKind(check.ExcludeKinds, childKind) {
+		return problem, false
+	}
+	for _, namespace := range check.ExcludeNamespaces {
+		if ctx.Child.Namespace == namespace {
+			return problem, false
+		}
+	}
+	if check.Level != "" {
+		problem.Level = check.Level
+	}
+	return problem, true
+}
+
+// applyThresholds drops findings with codes that were found fewer times than their configured threshold
+func (c *RuleConfig) applyThresholds(findings []Finding) []Finding {
+	counts := map[string]int{}
+	for _, finding := range findings {
+		counts[finding.Code]++
+	}
+	var kept []Finding
+	for _, finding := range findings {
+		if counts[finding.Code] < c.Checks[finding.Code].Threshold {
+			continue
+		}
+		kept = append(kept, finding)
+	}
+	return kept
+}
+
+// matchesKind returns true if kind matches any of kinds, given as Kind (any group) or Kind.group
+func matchesKind(kinds []string, kind schema.GroupKind) bool {
+	for _, k := range kinds {
+		if k == kind.Kind || k == kind.String() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestLoadRuleConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    string
+		expectErr bool
+	}{
+		{
+			name: "valid",
+			config: `
+checks:
+  NameMismatch:
+    enabled: false
+  OwnerListFailed:
+    level: Error
+    excludeNamespaces: [kube-system]
+  OwnerNotFound:
+    kinds: [Pod, ReplicaSet.apps]
+    threshold: 5
+rules:
+- code: ControllerRequired
+  level: Warning
+  expression: ownerReference.controller
+  message: ownerReferences must set controller
+`,
+		},
+		{name: "invalid rule", config: "rules:\n- code: Test\n  expression: 'child.name =='\n  message: m\n", expectErr: true},
+		{name: "unknown field", config: "checks:\n  NameMismatch:\n    enable: false\n", expectErr: true},
+		{name: "invalid level", config: "checks:\n  NameMismatch:\n    level: Info\n", expectErr: true},
+		{name: "invalid threshold", config: "checks:\n  NameMismatch:\n    threshold: -1\n", expectErr: true},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := ioutil.WriteFile(path, []byte(tc.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadRuleConfig(path)
+			if tc.expectErr && err == nil {
+				t.Error("expected error")
+			} else if !tc.expectErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRuleConfigApply(t *testing.T) {
+	disabled := false
+	config := &RuleConfig{Checks: map[string]CheckConfig{
+		CodeNameMismatch:    {Enabled: &disabled},
+		CodeOwnerListFailed: {Level: LevelError, ExcludeNamespaces: []string{"kube-system"}},
+		CodeOwnerNotFound:   {Kinds: []string{"Pod", "ReplicaSet.apps"}, ExcludeKinds: []string{"Pod.example.com"}},
+	}}
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	examplePods := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "pods"}
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	child := func(kind, namespace string) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{TypeMeta: metav1.TypeMeta{Kind: kind}, ObjectMeta: metav1.ObjectMeta{Name: "child", Namespace: namespace}}
+	}
+
+	testcases := []struct {
+		name        string
+		ctx         *RuleContext
+		problem     Problem
+		expectOk    bool
+		expectLevel string
+	}{
+		{
+			name:        "unconfigured",
+			ctx:         &RuleContext{Resource: pods, Child: child("Pod", "ns1")},
+			problem:     Problem{Level: LevelError, Code: CodeNamespaceMismatch},
+			expectOk:    true,
+			expectLevel: LevelError,
+		},
+		{
+			name:    "disabled",
+			ctx:     &RuleContext{Resource: pods, Child: child("Pod", "ns1")},
+			problem: Problem{Level: LevelError, Code: CodeNameMismatch},
+		},
+		{
+			name:        "level override",
+			ctx:         &RuleContext{Resource: pods, Child: child("Pod", "ns1")},
+			problem:     Problem{Level: LevelWarning, Code: CodeOwnerListFailed},
+			expectOk:    true,
+			expectLevel: LevelError,
+		},
+		{
+			name:    "excluded namespace",
+			ctx:     &RuleContext{Resource: pods, Child: child("Pod", "kube-system")},
+			problem: Problem{Level: LevelWarning, Code: CodeOwnerListFailed},
+		},
+		{
+			name:        "included kind",
+			ctx:         &RuleContext{Resource: pods, Child: child("Pod", "ns1")},
+			problem:     Problem{Level: LevelError, Code: CodeOwnerNotFound},
+			expectOk:    true,
+			expectLevel: LevelError,
+		},
+		{
+			name:    "kind not included",
+			ctx:     &RuleContext{Resource: deployments, Child: child("Deployment", "ns1")},
+			problem: Problem{Level: LevelError, Code: CodeOwnerNotFound},
+		},
+		{
+			name:    "excluded kind",
+			ctx:     &RuleContext{Resource: examplePods, Child: child("Pod", "ns1")},
+			problem: Problem{Level: LevelError, Code: CodeOwnerNotFound},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			problem, ok := config.apply(tc.ctx, tc.problem)
+			if ok != tc.expectOk {
+				t.Fatalf("expected ok=%v, got %v", tc.expectOk, ok)
+			}
+			if ok && problem.Level != tc.expectLevel {
+				t.Errorf("expected level %s, got %s", tc.expectLevel, problem.Level)
+			}
+		})
+	}
+}
+
+func TestRuleConfigApplyThresholds(t *testing.T) {
+	config := &RuleConfig{Checks: map[string]CheckConfig{
+		CodeOwnerNotFound:     {Threshold: 2},
+		CodeNameMismatch:      {Threshold: 3},
+		CodeGroupKindMismatch: {},
+	}}
+	findings := []Finding{
+		{Name: "a", Code: CodeOwnerNotFound},
+		{Name: "b", Code: CodeNameMismatch},
+		{Name: "c", Code: CodeOwnerNotFound},
+		{Name: "d", Code: CodeNameMismatch},
+		{Name: "e", Code: CodeGroupKindMismatch},
+	}
+	expect := []Finding{
+		{Name: "a", Code: CodeOwnerNotFound},
+		{Name: "c", Code: CodeOwnerNotFound},
+		{Name: "e", Code: CodeGroupKindMismatch},
+	}
+	if diff := cmp.Diff(expect, config.applyThresholds(findings)); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// maxConfigMapFindingsBytes bounds the size of the findings stored in a ConfigMap to stay under the 1MiB ConfigMap size limit
+const maxConfigMapFindingsBytes = 768 * 1024
+
+// ConfigMapOptions contains options controlling how a summary of results is published to a ConfigMap
+type ConfigMapOptions struct {
+	// Client is used to write the ConfigMap
+	Client corev1client.ConfigMapsGetter
+	// Namespace is the namespace of the ConfigMap
+	Namespace string
+	// Name is the name of the ConfigMap
+	Name string
+}
+
+// Validate ensures the specified options are valid
+func (o *ConfigMapOptions) Validate() error {
+	if o.Client == nil {
+		return fmt.Errorf("configmap client is required")
+	}
+	if o.Namespace == "" || o.Name == "" {
+		return fmt.Errorf("configmap namespace and name are required")
+	}
+	return nil
+}
+
+// configMapSummary is the summary written to the summary.json key of the ConfigMap
+type configMapSummary struct {
+	ScanID            string       `json:"scanID,omitempty"`
+	Cluster           *ClusterInfo `json:"cluster,omitempty"`
+	CompletionTime    metav1.Time  `json:"completionTime"`
+	DurationSeconds   float64      `json:"durationSeconds"`
+	Errors            int          `json:"errors"`
+	Warnings          int          `json:"warnings"`
+	Findings          int          `json:"findings"`
+	FindingsTruncated bool         `json:"findingsTruncated"`
+	Complete          bool         `json:"complete"`
+}
+
+// publish writes a summary of result and as many findings as fit to the ConfigMap
+func (o *ConfigMapOptions) publish(ctx context.Context, result *Report) error {
+	// keep as many findings as fit in the size limit
+	findings := result.Findings
+	findingsJSON, err := json.Marshal(findings)
+	if err != nil {
+		return err
+	}
+	for len(findingsJSON) > maxConfigMapFindingsBytes {
+		// shrink proportionally to the overage, removing at least one finding each time
+		keep := len(findings) * maxConfigMapFindingsBytes / len(findingsJSON)
+		if keep >= len(findings) {
+			keep = len(findings) - 1
+		}
+		findings = findings[:keep]
+		if findingsJSON, err = json.Marshal(findings); err != nil {
+			return err
+		}
+	}
+	if findings == nil {
+		findingsJSON = []byte("[]")
+	}
+
+	summaryJSON, err := json.Marshal(configMapSummary{
+		ScanID:            result.Scan.ID,
+		Cluster:           result.Scan.Cluster,
+		CompletionTime:    metav1.Now(),
+		DurationSeconds:   result.Duration.Seconds(),
+		Errors:            result.Errors,
+		Warnings:          result.Warnings,
+		Findings:          len(result.Findings),
+		FindingsTruncated: len(findings) < len(result.Findings),
+		Complete:          result.Complete(),
+	})
+	if err != nil {
+		return err
+	}
+
+	data := map[string]string{
+		"summary.json":  string(summaryJSON),
+		"findings.json": string(findingsJSON),
+	}
+	client := o.Client.ConfigMaps(o.Namespace)
+	existing, err := client.Get(ctx, o.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.Create(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: o.Namespace, Name: o.Name}, Data: data}, metav1.CreateOptions{})
+	} else if err == nil {
+		existing.Data = data
+		_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("error writing configmap %s/%s: %v", o.Namespace, o.Name, err)
+	}
+	return nil
+}
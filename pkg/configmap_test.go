@@ -0,0 +1,77 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConfigMapPublish(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	opts := &ConfigMapOptions{Client: client.CoreV1(), Namespace: "monitoring", Name: "ownerreferences"}
+	pods := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+	readConfigMap := func() (configMapSummary, []Finding) {
+		t.Helper()
+		configMap, err := client.CoreV1().ConfigMaps("monitoring").Get(context.Background(), "ownerreferences", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		summary := configMapSummary{}
+		if err := json.Unmarshal([]byte(configMap.Data["summary.json"]), &summary); err != nil {
+			t.Fatal(err)
+		}
+		findings := []Finding{}
+		if err := json.Unmarshal([]byte(configMap.Data["findings.json"]), &findings); err != nil {
+			t.Fatal(err)
+		}
+		return summary, findings
+	}
+
+	// create
+	if err := opts.publish(context.Background(), &Report{
+		Findings: []Finding{{Resource: pods, Namespace: "ns1", Name: "pod1", Level: LevelError, Code: CodeOwnerNotFound}},
+		Errors:   1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	summary, findings := readConfigMap()
+	if summary.Errors != 1 || summary.Findings != 1 || summary.FindingsTruncated || len(findings) != 1 {
+		t.Errorf("unexpected summary %#v, findings %#v", summary, findings)
+	}
+
+	// update with more findings than fit
+	result := &Report{}
+	for i := 0; i < 5000; i++ {
+		result.Findings = append(result.Findings, Finding{Resource: pods, Namespace: "ns1", Name: fmt.Sprintf("pod%d", i), Level: LevelError, Code: CodeOwnerNotFound, Message: strings.Repeat("x", 200)})
+		result.Errors++
+	}
+	if err := opts.publish(context.Background(), result); err != nil {
+		t.Fatal(err)
+	}
+	summary, findings = readConfigMap()
+	if summary.Errors != 5000 || summary.Findings != 5000 || !summary.FindingsTruncated || len(findings) == 0 || len(findings) >= 5000 {
+		t.Errorf("unexpected summary %#v, %d findings", summary, len(findings))
+	}
+}
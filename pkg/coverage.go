@@ -0,0 +1,200 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/discovery"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+)
+
+// CoveragePersona is an identity whose access to the resources scans list is reviewed
+type CoveragePersona struct {
+	// Name identifies the persona in output, such as the impersonated service account
+	Name string
+	// Client creates SelfSubjectAccessReviews as the persona, such as a client impersonating it
+	Client authorizationv1client.SelfSubjectAccessReviewsGetter
+}
+
+// CoverageOptions contains options controlling how the resources that scans by each persona would list are reported
+type CoverageOptions struct {
+	DiscoveryClient discovery.DiscoveryInterface
+	Output          string
+	Stderr          io.Writer
+	Stdout          io.Writer
+
+	// Personas are the identities to review access for
+	Personas []CoveragePersona
+	// Namespaces are optionally reviewed for resources a persona cannot list in all namespaces,
+	// to show what a tenant-scoped persona can see
+	Namespaces []string
+}
+
+// Validate ensures the specified options are valid
+func (o *CoverageOptions) Validate() error {
+	if o.DiscoveryClient == nil {
+		return fmt.Errorf("discovery client is required")
+	}
+	if o.Stderr == nil {
+		return fmt.Errorf("stderr is required")
+	}
+	if o.Stdout == nil {
+		return fmt.Errorf("stdout is required")
+	}
+	if o.Output != "" && o.Output != "json" {
+		return fmt.Errorf("invalid output format, only '' and 'json' are supported: %v", o.Output)
+	}
+	if len(o.Personas) == 0 {
+		return fmt.Errorf("at least one persona is required")
+	}
+	names := map[string]bool{}
+	for _, persona := range o.Personas {
+		if persona.Name == "" || persona.Client == nil {
+			return fmt.Errorf("persona name and client are required")
+		}
+		if names[persona.Name] {
+			return fmt.Errorf("duplicate persona %s", persona.Name)
+		}
+		names[persona.Name] = true
+	}
+	return nil
+}
+
+// PersonaCoverage describes the resources a persona can list
+type PersonaCoverage struct {
+	Persona string `json:"persona"`
+	// Coverage is the percentage of resources the persona can list in all namespaces
+	Coverage  float64            `json:"coverage"`
+	Resources []ResourceCoverage `json:"resources"`
+}
+
+// ResourceCoverage describes whether a persona can list a resource
+type ResourceCoverage struct {
+	Resource metav1.GroupVersionResource `json:"resource"`
+	// AllNamespaces is true if the resource can be listed in all namespaces, as scans list it
+	AllNamespaces bool `json:"allNamespaces"`
+	// Namespaces are the reviewed namespaces the resource can be listed in, if it cannot be listed in all namespaces
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Reason is why the resource cannot be listed in all namespaces
+	Reason string `json:"reason,omitempty"`
+	// VersionFallback is true if no preferred version of the resource was discovered, and a served version is listed instead
+	VersionFallback bool `json:"versionFallback,omitempty"`
+}
+
+// Run reviews access to list each resource scans list as each persona, and writes the coverage of each persona to Stdout,
+// either as a table with a column per persona if Output is ”, or as a JSON array if Output is 'json'
+func (o *CoverageOptions) Run(ctx context.Context) error {
+	gvrs, fallbacks, err := discoverGCResources(o.DiscoveryClient, nil, o.Stderr)
+	if err != nil {
+		return err
+	}
+	fallback := map[schema.GroupVersionResource]bool{}
+	for _, gvr := range fallbacks {
+		fallback[gvr] = true
+	}
+
+	coverages := make([]PersonaCoverage, 0, len(o.Personas))
+	for _, persona := range o.Personas {
+		personaCoverage := PersonaCoverage{Persona: persona.Name, Resources: []ResourceCoverage{}}
+		denied := 0
+		for _, gvr := range gvrs {
+			resource := ResourceCoverage{Resource: metav1.GroupVersionResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource}, VersionFallback: fallback[gvr]}
+			resource.AllNamespaces, resource.Reason, err = reviewList(ctx, persona.Client, gvr, "")
+			if err != nil {
+				return fmt.Errorf("%s: %v", persona.Name, err)
+			}
+			if !resource.AllNamespaces {
+				denied++
+				for _, namespace := range o.Namespaces {
+					allowed, _, err := reviewList(ctx, persona.Client, gvr, namespace)
+					if err != nil {
+						return fmt.Errorf("%s: %v", persona.Name, err)
+					}
+					if allowed {
+						resource.Namespaces = append(resource.Namespaces, namespace)
+					}
+				}
+			}
+			personaCoverage.Resources = append(personaCoverage.Resources, resource)
+		}
+		personaCoverage.Coverage = coverage(len(gvrs), denied)
+		coverages = append(coverages, personaCoverage)
+	}
+
+	if o.Output == "json" {
+		encoder := json.NewEncoder(o.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(coverages)
+	}
+	tabwriter := printers.GetNewTabWriter(o.Stdout)
+	header := []string{"GROUP", "RESOURCE"}
+	for _, personaCoverage := range coverages {
+		header = append(header, strings.ToUpper(personaCoverage.Persona))
+	}
+	fmt.Fprintln(tabwriter, strings.Join(header, "\t"))
+	for i, gvr := range gvrs {
+		row := []string{gvr.Group, gvr.Resource}
+		if fallback[gvr] {
+			row[1] += "*"
+		}
+		for _, personaCoverage := range coverages {
+			row = append(row, coverageCell(personaCoverage.Resources[i]))
+		}
+		fmt.Fprintln(tabwriter, strings.Join(row, "\t"))
+	}
+	footer := []string{"", "COVERAGE"}
+	for _, personaCoverage := range coverages {
+		footer = append(footer, fmt.Sprintf("%.1f%%", personaCoverage.Coverage))
+	}
+	fmt.Fprintln(tabwriter, strings.Join(footer, "\t"))
+	if err := tabwriter.Flush(); err != nil {
+		return err
+	}
+	if len(fallbacks) > 0 {
+		fmt.Fprintf(o.Stdout, "* no preferred version was discovered, so a served version is listed: %s\n", gvrList(fallbacks))
+	}
+	return nil
+}
+
+// coverageCell describes whether a resource can be listed in the table: in all namespaces, in some of the reviewed namespaces, or not at all
+func coverageCell(resource ResourceCoverage) string {
+	switch {
+	case resource.AllNamespaces:
+		return "all"
+	case len(resource.Namespaces) > 0:
+		return strings.Join(resource.Namespaces, ",")
+	default:
+		return "-"
+	}
+}
+
+// gvrList returns gvrs as a comma-separated list of resource.version.group
+func gvrList(gvrs []schema.GroupVersionResource) string {
+	names := make([]string, 0, len(gvrs))
+	for _, gvr := range gvrs {
+		names = append(names, strings.TrimSuffix(gvr.Resource+"."+gvr.Version+"."+gvr.Group, "."))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+// fakeAccessReviews returns a client allowing list if allowed returns true for the resource and namespace
+func fakeAccessReviews(allowed func(resource, namespace string) bool) *kubefake.Clientset {
+	client := kubefake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action coretesting.Action) (bool, runtime.Object, error) {
+		review := action.(coretesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		review.Status.Allowed = attributes.Verb == "list" && allowed(attributes.Resource, attributes.Namespace)
+		return true, review, nil
+	})
+	return client
+}
+
+func TestCoverageRun(t *testing.T) {
+	verbs := []string{"get", "list", "delete"}
+	discoveryClient := &fake.FakeDiscovery{Fake: &coretesting.Fake{}}
+	discoveryClient.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: verbs},
+			{Name: "secrets", Namespaced: true, Kind: "Secret", Verbs: verbs},
+		}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{
+			{Name: "deployments", Namespaced: true, Kind: "Deployment", Verbs: verbs},
+		}},
+	}
+
+	admin := fakeAccessReviews(func(resource, namespace string) bool { return true })
+	tenant := fakeAccessReviews(func(resource, namespace string) bool {
+		return resource != "secrets" && namespace == "team-a"
+	})
+	stdout := &bytes.Buffer{}
+	opts := &CoverageOptions{
+		DiscoveryClient: discoveryClient,
+		Stderr:          ioutil.Discard,
+		Stdout:          stdout,
+		Personas: []CoveragePersona{
+			{Name: "ops/checker", Client: admin.AuthorizationV1()},
+			{Name: "team-a/deployer", Client: tenant.AuthorizationV1()},
+		},
+		Namespaces: []string{"team-a", "team-b"},
+	}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := opts.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expect := `
+GROUP RESOURCE OPS/CHECKER TEAM-A/DEPLOYER
+ pods all team-a
+ secrets all -
+apps deployments all team-a
+ COVERAGE 100.0% 0.0%
+`
+	if diff := cmp.Diff(normalize(expect), normalize(stdout.String())); diff != "" {
+		t.Errorf("unexpected coverage (-want +got):\n%s", diff)
+	}
+
+	opts.Personas[0].Name = opts.Personas[1].Name
+	if err := opts.Validate(); err == nil {
+		t.Error("expected duplicate personas to be invalid")
+	}
+}
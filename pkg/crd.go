@@ -0,0 +1,378 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// ReportGroupVersionResource is the resource OwnerReferenceReport objects are written to
+var ReportGroupVersionResource = schema.GroupVersionResource{Group: "ownerreferences.x-k8s.io", Version: "v1alpha1", Resource: "ownerreferencereports"}
+
+const (
+	// reportSetLabel identifies all the reports written under the same report name
+	reportSetLabel = "ownerreferences.x-k8s.io/report"
+	// reportNamespaceLabel identifies the namespace a per-namespace report covers
+	reportNamespaceLabel = "ownerreferences.x-k8s.io/namespace"
+
+	// maxReportFindings bounds the findings stored in a single report object to stay well under the etcd object size limit
+	maxReportFindings = 1000
+)
+
+// CRDReportOptions contains options controlling how results are written to OwnerReferenceReport custom resources
+type CRDReportOptions struct {
+	// Client is used to write OwnerReferenceReport objects
+	Client dynamic.Interface
+	// Name is the name of the report. Per-namespace reports are named <name>.<namespace>.
+	Name string
+	// PerNamespace writes a report per namespace instead of a single report for the whole cluster.
+	// Findings for cluster-scoped objects are written to the report named <name>.
+	PerNamespace bool
+}
+
+// Validate ensures the specified options are valid
+func (o *CRDReportOptions) Validate() error {
+	if o.Client == nil {
+		return fmt.Errorf("dynamic client is required")
+	}
+	if o.Name == "" {
+		return fmt.Errorf("report name is required")
+	}
+	return nil
+}
+
+type ownerReferenceReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   ownerReferenceReportSpec   `json:"spec"`
+	Status ownerReferenceReportStatus `json:"status"`
+}
+
+type ownerReferenceReportSpec struct {
+	// Namespace is the namespace covered by this report, or empty if the report covers the whole cluster
+	Namespace string `json:"namespace,omitempty"`
+}
+
+type ownerReferenceReportStatus struct {
+	ScanID            string       `json:"scanID,omitempty"`
+	Cluster           *ClusterInfo `json:"cluster,omitempty"`
+	CompletionTime    metav1.Time  `json:"completionTime"`
+	Duration          string       `json:"duration"`
+	Errors            int          `json:"errors"`
+	Warnings          int          `json:"warnings"`
+	FindingsTruncated bool         `json:"findingsTruncated,omitempty"`
+	Findings          []Finding    `json:"findings"`
+}
+
+// publish writes result to OwnerReferenceReport objects, returning the names of the reports written
+func (o *CRDReportOptions) publish(ctx context.Context, result *Report) ([]string, error) {
+	reports := map[string]*ownerReferenceReport{}
+	getReport := func(namespace string) *ownerReferenceReport {
+		name := o.Name
+		if o.PerNamespace && namespace != "" {
+			name = o.Name + "." + namespace
+		} else {
+			namespace = ""
+		}
+		if report, ok := reports[name]; ok {
+			return report
+		}
+		report := &ownerReferenceReport{
+			TypeMeta: metav1.TypeMeta{APIVersion: ReportGroupVersionResource.GroupVersion().String(), Kind: "OwnerReferenceReport"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{reportSetLabel: o.Name},
+			},
+			Spec: ownerReferenceReportSpec{Namespace: namespace},
+			Status: ownerReferenceReportStatus{
+				ScanID:         result.Scan.ID,
+				Cluster:        result.Scan.Cluster,
+				CompletionTime: metav1.Now(),
+				Duration:       result.Duration.Round(time.Second).String(),
+				Findings:       []Finding{},
+			},
+		}
+		if namespace != "" {
+			report.Labels[reportNamespaceLabel] = namespace
+		}
+		reports[name] = report
+		return report
+	}
+
+	// always write the top-level report, even if there are no findings
+	getReport("")
+	for _, finding := range result.Findings {
+		report := getReport(finding.Namespace)
+		if finding.Level == LevelError {
+			report.Status.Errors++
+		} else {
+			report.Status.Warnings++
+		}
+		if len(report.Status.Findings) < maxReportFindings {
+			report.Status.Findings = append(report.Status.Findings, finding)
+		} else {
+			report.Status.FindingsTruncated = true
+		}
+	}
+
+	names := []string{}
+	for name := range reports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	client := o.Client.Resource(ReportGroupVersionResource)
+	for _, name := range names {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(reports[name])
+		if err != nil {
+			return nil, err
+		}
+		obj := &unstructured.Unstructured{Object: content}
+		existing, err := client.Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			_, err = client.Create(ctx, obj, metav1.CreateOptions{})
+		} else if err == nil {
+			obj.SetResourceVersion(existing.GetResourceVersion())
+			_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error writing OwnerReferenceReport %s: %v", name, err)
+		}
+	}
+
+	// clean up per-namespace reports for namespaces that no longer have findings
+	existing, err := client.List(ctx, metav1.ListOptions{LabelSelector: reportSetLabel + "=" + o.Name})
+	if err != nil {
+		return nil, fmt.Errorf("error listing OwnerReferenceReports: %v", err)
+	}
+	for _, item := range existing.Items {
+		if _, written := reports[item.GetName()]; written {
+			continue
+		}
+		if err := client.Delete(ctx, item.GetName(), metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("error deleting stale OwnerReferenceReport %s: %v", item.GetName(), err)
+		}
+	}
+
+	return names, nil
+}
+
+// ReportCRD is the CustomResourceDefinition manifest for OwnerReferenceReport objects
+const ReportCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: ownerreferencereports.ownerreferences.x-k8s.io
+spec:
+  group: ownerreferences.x-k8s.io
+  names:
+    kind: OwnerReferenceReport
+    listKind: OwnerReferenceReportList
+    plural: ownerreferencereports
+    singular: ownerreferencereport
+  scope: Cluster
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+    additionalPrinterColumns:
+    - name: Namespace
+      type: string
+      jsonPath: .spec.namespace
+    - name: Errors
+      type: integer
+      jsonPath: .status.errors
+    - name: Warnings
+      type: integer
+      jsonPath: .status.warnings
+    - name: Completed
+      type: date
+      jsonPath: .status.completionTime
+    schema:
+      openAPIV3Schema:
+        type: object
+        description: OwnerReferenceReport holds the results of checking ownerReferences in a cluster or namespace.
+        properties:
+          apiVersion:
+            type: string
+          kind:
+            type: string
+          metadata:
+            type: object
+          spec:
+            type: object
+            properties:
+              namespace:
+                type: string
+                description: namespace covered by this report, or empty if the report covers the whole cluster
+          status:
+            type: object
+            properties:
+              scanID:
+                type: string
+                description: unique to each scan
+              cluster:
+                type: object
+                description: the scanned cluster
+                properties:
+                  server:
+                    type: string
+                  context:
+                    type: string
+                  uid:
+                    type: string
+                    description: UID of the kube-system namespace
+              completionTime:
+                type: string
+                format: date-time
+              duration:
+                type: string
+              errors:
+                type: integer
+              warnings:
+                type: integer
+              findingsTruncated:
+                type: boolean
+                description: true if more findings were found than could be stored in the report
+              findings:
+                type: array
+                items:
+                  type: object
+                  properties:
+                    cluster:
+                      type: string
+                      description: the cluster the finding is from, when scanning multiple clusters
+                    resource:
+                      type: object
+                      properties:
+                        group:
+                          type: string
+                        version:
+                          type: string
+                        resource:
+                          type: string
+                    kind:
+                      type: object
+                      properties:
+                        group:
+                          type: string
+                        version:
+                          type: string
+                        kind:
+                          type: string
+                    namespace:
+                      type: string
+                    name:
+                      type: string
+                    uid:
+                      type: string
+                    ownerReference:
+                      type: object
+                      properties:
+                        apiVersion:
+                          type: string
+                        kind:
+                          type: string
+                        name:
+                          type: string
+                        uid:
+                          type: string
+                        controller:
+                          type: boolean
+                        blockOwnerDeletion:
+                          type: boolean
+                    level:
+                      type: string
+                    code:
+                      type: string
+                    message:
+                      type: string
+                    candidates:
+                      type: array
+                      description: objects of the referenced kind and name that may be the intended owner, if the owner was not found
+                      items:
+                        type: object
+                        properties:
+                          namespace:
+                            type: string
+                          name:
+                            type: string
+                          uid:
+                            type: string
+                    managedBy:
+                      type: object
+                      description: the tool managing the object, if detected from its labels and annotations
+                      properties:
+                        tool:
+                          type: string
+                        instance:
+                          type: string
+                    creationTimestamp:
+                      type: string
+                      format: date-time
+                      description: when the object was created, if known
+                    namespaceLabels:
+                      type: object
+                      description: the values of the selected labels and annotations of the namespace of the object
+                      additionalProperties:
+                        type: string
+                    audit:
+                      type: object
+                      description: the request that last wrote the object, if audit logs were correlated and a request was found
+                      properties:
+                        user:
+                          type: string
+                        userAgent:
+                          type: string
+                        verb:
+                          type: string
+                        time:
+                          type: string
+                          format: date-time
+                        auditID:
+                          type: string
+                        ownerReferencesWritten:
+                          type: boolean
+                    delta:
+                      type: string
+                      description: whether the finding is new, persisting, or resolved, if findings were compared to a previous run
+                    scan:
+                      type: object
+                      description: the scan and cluster the finding is from
+                      properties:
+                        id:
+                          type: string
+                        cluster:
+                          type: object
+                          properties:
+                            server:
+                              type: string
+                            context:
+                              type: string
+                            uid:
+                              type: string
+`
@@ -0,0 +1,149 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func TestCRDReportPublish(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		ReportGroupVersionResource: "OwnerReferenceReportList",
+	})
+	opts := &CRDReportOptions{Client: client, Name: "cluster", PerNamespace: true}
+	pods := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	nodes := metav1.GroupVersionResource{Version: "v1", Resource: "nodes"}
+
+	listReports := func() map[string]*unstructured.Unstructured {
+		t.Helper()
+		list, err := client.Resource(ReportGroupVersionResource).List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		reports := map[string]*unstructured.Unstructured{}
+		for i := range list.Items {
+			reports[list.Items[i].GetName()] = &list.Items[i]
+		}
+		return reports
+	}
+
+	names, err := opts.publish(context.Background(), &Report{Findings: []Finding{
+		{Resource: nodes, Name: "node1", Level: LevelError, Code: CodeOwnerNotFound},
+		{Resource: pods, Namespace: "ns1", Name: "pod1", Level: LevelError, Code: CodeOwnerNotFound},
+		{Resource: pods, Namespace: "ns2", Name: "pod2", Level: LevelWarning, Code: CodeOwnerListFailed},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := []string{"cluster", "cluster.ns1", "cluster.ns2"}, names; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	reports := listReports()
+	if errors, _, _ := unstructured.NestedInt64(reports["cluster.ns1"].Object, "status", "errors"); errors != 1 {
+		t.Errorf("expected 1 error in cluster.ns1, got %d", errors)
+	}
+	if warnings, _, _ := unstructured.NestedInt64(reports["cluster.ns2"].Object, "status", "warnings"); warnings != 1 {
+		t.Errorf("expected 1 warning in cluster.ns2, got %d", warnings)
+	}
+	if namespace, _, _ := unstructured.NestedString(reports["cluster.ns2"].Object, "spec", "namespace"); namespace != "ns2" {
+		t.Errorf("expected spec.namespace ns2, got %q", namespace)
+	}
+
+	// reports for namespaces without findings are removed
+	if _, err := opts.publish(context.Background(), &Report{Findings: []Finding{
+		{Resource: pods, Namespace: "ns1", Name: "pod1", Level: LevelError, Code: CodeOwnerNotFound},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	names = []string{}
+	for name := range listReports() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if e, a := []string{"cluster", "cluster.ns1"}, names; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+// TestReportCRDSchema ensures every field of a report is described by the CRD schema, since the API server prunes other fields
+func TestReportCRDSchema(t *testing.T) {
+	crd := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(ReportCRD), &crd); err != nil {
+		t.Fatal(err)
+	}
+	version := crd["spec"].(map[string]interface{})["versions"].([]interface{})[0].(map[string]interface{})
+	schema := version["schema"].(map[string]interface{})["openAPIV3Schema"].(map[string]interface{})
+
+	controller := true
+	cluster := &ClusterInfo{Server: "https://example.com", Context: "prod", UID: "uid5"}
+	finding := Finding{
+		Cluster:           "prod",
+		Resource:          metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"},
+		Kind:              metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"},
+		Namespace:         "ns1",
+		Name:              "rs1",
+		UID:               "uid1",
+		OwnerReference:    metav1.OwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "d1", UID: "uid2", Controller: &controller, BlockOwnerDeletion: &controller},
+		Level:             LevelError,
+		Code:              CodeOwnerNotFound,
+		Message:           "owner not found",
+		Audit:             &AuditEntry{User: "admin", UserAgent: "kubectl", Verb: "create", Time: metav1.Now(), AuditID: "id1", OwnerReferencesWritten: true},
+		CreationTimestamp: &metav1.Time{Time: time.Now()},
+		NamespaceLabels:   map[string]string{"team": "a"},
+		ManagedBy:         &ManagedBy{Tool: ToolArgoCD, Instance: "app1"},
+		Candidates:        []OwnerCandidate{{Namespace: "ns1", Name: "d1", UID: "uid3"}},
+		Delta:             DeltaNew,
+		Scan:              &ScanIdentity{ID: "scan1", Cluster: cluster},
+	}
+	report := ownerReferenceReport{
+		TypeMeta:   metav1.TypeMeta{APIVersion: ReportGroupVersionResource.GroupVersion().String(), Kind: "OwnerReferenceReport"},
+		ObjectMeta: metav1.ObjectMeta{Name: "report"},
+		Spec:       ownerReferenceReportSpec{Namespace: "ns1"},
+		Status: ownerReferenceReportStatus{
+			ScanID:            "scan1",
+			Cluster:           cluster,
+			CompletionTime:    metav1.Now(),
+			Duration:          "1s",
+			Errors:            1,
+			FindingsTruncated: true,
+			Findings:          []Finding{finding},
+		},
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	document := map[string]interface{}{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatal(err)
+	}
+	// metadata is not described beyond its type
+	delete(document, "metadata")
+	checkSchemaProperties(t, "OwnerReferenceReport", schema, schema, document)
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// gcAbsentOwnerCodes are the codes of findings whose owner the garbage collector considers absent,
+// since looking up the owner by its reference does not find an object with the referenced uid
+var gcAbsentOwnerCodes = map[string]bool{
+	CodeOwnerNotFound:                       true,
+	CodeNamespaceMismatch:                   true,
+	CodeNameMismatch:                        true,
+	CodeGroupKindMismatch:                   true,
+	CodeNamespacedOwnerOfClusterScopedChild: true,
+}
+
+// DeletionEstimate is the number of objects of a resource in a namespace the garbage collector is expected to delete
+type DeletionEstimate struct {
+	Resource  schema.GroupVersionResource
+	Namespace string
+	// Deletes is the number of objects expected to be deleted
+	Deletes int
+	// Cascaded is how many of those have an owner that is itself deleted, rather than only owners that are absent already
+	Cascaded int
+}
+
+// estimateDeletions returns the number of DELETE requests the garbage collector is expected to make for the children of gvrs,
+// per resource and namespace, sorted, once it processes the ownerReferences of findings:
+// children whose owners are all absent are deleted, and then children whose owners are all absent or deleted, in cascade.
+// Children already being deleted are not counted.
+func estimateDeletions(gvrs []schema.GroupVersionResource, children *ObjectIndex, findings []Finding) []DeletionEstimate {
+	type refKey struct {
+		child, owner types.UID
+	}
+	absent := map[refKey]bool{}
+	for _, finding := range findings {
+		if gcAbsentOwnerCodes[finding.Code] {
+			absent[refKey{finding.UID, finding.OwnerReference.UID}] = true
+		}
+	}
+	if len(absent) == 0 {
+		return nil
+	}
+
+	type key struct {
+		resource  schema.GroupVersionResource
+		namespace string
+	}
+	estimates := map[key]*DeletionEstimate{}
+	deleted := map[types.UID]bool{}
+	for changed := true; changed; {
+		changed = false
+		for _, gvr := range gvrs {
+			for _, child := range children.ByResource(gvr) {
+				if deleted[child.UID] || child.DeletionTimestamp != nil || len(child.OwnerReferences) == 0 {
+					continue
+				}
+				collected, cascaded := true, false
+				for _, ownerRef := range child.OwnerReferences {
+					if deleted[ownerRef.UID] {
+						cascaded = true
+					} else if !absent[refKey{child.UID, ownerRef.UID}] {
+						collected = false
+						break
+					}
+				}
+				if !collected {
+					continue
+				}
+				deleted[child.UID], changed = true, true
+				estimate := estimates[key{gvr, child.Namespace}]
+				if estimate == nil {
+					estimate = &DeletionEstimate{Resource: gvr, Namespace: child.Namespace}
+					estimates[key{gvr, child.Namespace}] = estimate
+				}
+				estimate.Deletes++
+				if cascaded {
+					estimate.Cascaded++
+				}
+			}
+		}
+	}
+
+	sorted := make([]DeletionEstimate, 0, len(estimates))
+	for _, estimate := range estimates {
+		sorted = append(sorted, *estimate)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Resource != sorted[j].Resource {
+			gvrs := []schema.GroupVersionResource{sorted[i].Resource, sorted[j].Resource}
+			sortGVRs(gvrs)
+			return gvrs[0] == sorted[i].Resource
+		}
+		return sorted[i].Namespace < sorted[j].Namespace
+	})
+	return sorted
+}
+
+// printDeletionEstimate writes the number of DELETE requests the garbage collector is expected to make to w,
+// in total and as a table per resource and namespace, so the load of remediating findings can be planned for
+func printDeletionEstimate(w io.Writer, estimates []DeletionEstimate) error {
+	total, namespaces := 0, map[string]bool{}
+	for _, estimate := range estimates {
+		total += estimate.Deletes
+		namespaces[estimate.Namespace] = true
+	}
+	if total == 0 {
+		fmt.Fprintf(w, "garbage collection is not expected to delete any objects\n")
+		return nil
+	}
+	fmt.Fprintf(w, "garbage collection is expected to delete %s in %s once it processes these ownerReferences:\n",
+		pluralize(total, "object", "objects"), pluralize(len(namespaces), "namespace", "namespaces"))
+	tabwriter := printers.GetNewTabWriter(w)
+	tabwriter.Write([]byte("GROUP\tRESOURCE\tNAMESPACE\tDELETES\tCASCADED\n"))
+	for _, estimate := range estimates {
+		fmt.Fprintf(tabwriter, "%s\t%s\t%s\t%d\t%d\n", estimate.Resource.Group, estimate.Resource.Resource, estimate.Namespace, estimate.Deletes, estimate.Cascaded)
+	}
+	return tabwriter.Flush()
+}
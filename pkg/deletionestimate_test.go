@@ -0,0 +1,97 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestEstimateDeletions(t *testing.T) {
+	replicasets := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	children := newObjectIndex()
+	add := func(gvr schema.GroupVersionResource, namespace, uid string, deleting bool, owners ...string) {
+		object := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: uid, UID: types.UID(uid)}}
+		if deleting {
+			now := metav1.Now()
+			object.DeletionTimestamp = &now
+		}
+		for _, owner := range owners {
+			object.OwnerReferences = append(object.OwnerReferences, metav1.OwnerReference{UID: types.UID(owner)})
+		}
+		children.add(gvr, object)
+	}
+	// rs1 is orphaned, and its pods are deleted in cascade unless they have another owner
+	add(replicasets, "ns1", "rs1", false, "missing-deployment")
+	add(pods, "ns1", "pod1", false, "rs1")
+	add(pods, "ns1", "pod2", false, "rs1", "rs2")
+	// rs2 has one owner in another namespace, which the garbage collector cannot find, and one it can
+	add(replicasets, "ns1", "rs2", false, "deployment-elsewhere", "deployment")
+	// already being deleted
+	add(pods, "ns2", "pod3", false, "missing-rs")
+	add(pods, "ns2", "pod4", true, "missing-rs")
+	// the owner kind could not be resolved, so the garbage collector does not delete it
+	add(pods, "ns2", "pod5", false, "unresolvable")
+
+	finding := func(child, owner, code string) Finding {
+		return Finding{UID: types.UID(child), OwnerReference: metav1.OwnerReference{UID: types.UID(owner)}, Code: code}
+	}
+	findings := []Finding{
+		finding("rs1", "missing-deployment", CodeOwnerNotFound),
+		finding("rs2", "deployment-elsewhere", CodeNamespaceMismatch),
+		finding("pod3", "missing-rs", CodeOwnerNotFound),
+		finding("pod4", "missing-rs", CodeOwnerNotFound),
+		finding("pod5", "unresolvable", CodeUnresolvableOwner),
+	}
+	estimates := estimateDeletions([]schema.GroupVersionResource{replicasets, pods}, children, findings)
+	expected := []DeletionEstimate{
+		{Resource: pods, Namespace: "ns1", Deletes: 1, Cascaded: 1},
+		{Resource: pods, Namespace: "ns2", Deletes: 1},
+		{Resource: replicasets, Namespace: "ns1", Deletes: 1},
+	}
+	if diff := cmp.Diff(expected, estimates); diff != "" {
+		t.Errorf("unexpected estimates (-want +got):\n%s", diff)
+	}
+
+	out := &bytes.Buffer{}
+	if err := printDeletionEstimate(out, estimates); err != nil {
+		t.Fatal(err)
+	}
+	expectedOutput := `garbage collection is expected to delete 3 objects in 2 namespaces once it processes these ownerReferences:
+GROUP   RESOURCE      NAMESPACE   DELETES   CASCADED
+        pods          ns1         1         1
+        pods          ns2         1         0
+apps    replicasets   ns1         1         0
+`
+	if out.String() != expectedOutput {
+		t.Errorf("expected\n%s\ngot\n%s", expectedOutput, out.String())
+	}
+
+	out.Reset()
+	if err := printDeletionEstimate(out, estimateDeletions([]schema.GroupVersionResource{pods}, children, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "garbage collection is not expected to delete any objects\n" {
+		t.Errorf("unexpected output without findings: %q", out.String())
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// CodeDeprecatedOwnerAPIVersion indicates the ownerReference apiVersion is a deprecated version of a built-in type,
+// which resolves for now but stops resolving once the version is removed
+const CodeDeprecatedOwnerAPIVersion = "DeprecatedOwnerAPIVersion"
+
+// deprecatedAPIVersion is a deprecated version of built-in kinds, with the Kubernetes version it is removed in
+// and the version to migrate to, if any
+type deprecatedAPIVersion struct {
+	groupVersion string
+	kinds        []string
+	removedIn    string
+	replacement  string
+}
+
+// deprecatedAPIVersions are the deprecated versions of built-in kinds that can be owners,
+// from https://kubernetes.io/docs/reference/using-api/deprecation-guide/
+var deprecatedAPIVersions = []deprecatedAPIVersion{
+	{"extensions/v1beta1", []string{"DaemonSet", "Deployment", "ReplicaSet"}, "v1.16", "apps/v1"},
+	{"extensions/v1beta1", []string{"NetworkPolicy"}, "v1.16", "networking.k8s.io/v1"},
+	{"extensions/v1beta1", []string{"Ingress"}, "v1.22", "networking.k8s.io/v1"},
+	{"extensions/v1beta1", []string{"PodSecurityPolicy"}, "v1.16", "policy/v1beta1"},
+	{"apps/v1beta1", []string{"ControllerRevision", "Deployment", "StatefulSet"}, "v1.16", "apps/v1"},
+	{"apps/v1beta2", []string{"ControllerRevision", "DaemonSet", "Deployment", "ReplicaSet", "StatefulSet"}, "v1.16", "apps/v1"},
+	{"admissionregistration.k8s.io/v1beta1", []string{"MutatingWebhookConfiguration", "ValidatingWebhookConfiguration"}, "v1.22", "admissionregistration.k8s.io/v1"},
+	{"apiextensions.k8s.io/v1beta1", []string{"CustomResourceDefinition"}, "v1.22", "apiextensions.k8s.io/v1"},
+	{"apiregistration.k8s.io/v1beta1", []string{"APIService"}, "v1.22", "apiregistration.k8s.io/v1"},
+	{"certificates.k8s.io/v1beta1", []string{"CertificateSigningRequest"}, "v1.22", "certificates.k8s.io/v1"},
+	{"coordination.k8s.io/v1beta1", []string{"Lease"}, "v1.22", "coordination.k8s.io/v1"},
+	{"networking.k8s.io/v1beta1", []string{"Ingress", "IngressClass"}, "v1.22", "networking.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", []string{"ClusterRole", "ClusterRoleBinding", "Role", "RoleBinding"}, "v1.22", "rbac.authorization.k8s.io/v1"},
+	{"scheduling.k8s.io/v1beta1", []string{"PriorityClass"}, "v1.22", "scheduling.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", []string{"CSIDriver", "CSINode", "StorageClass", "VolumeAttachment"}, "v1.22", "storage.k8s.io/v1"},
+	{"batch/v1beta1", []string{"CronJob"}, "v1.25", "batch/v1"},
+	{"discovery.k8s.io/v1beta1", []string{"EndpointSlice"}, "v1.25", "discovery.k8s.io/v1"},
+	{"events.k8s.io/v1beta1", []string{"Event"}, "v1.25", "events.k8s.io/v1"},
+	{"autoscaling/v2beta1", []string{"HorizontalPodAutoscaler"}, "v1.25", "autoscaling/v2"},
+	{"node.k8s.io/v1beta1", []string{"RuntimeClass"}, "v1.25", "node.k8s.io/v1"},
+	{"policy/v1beta1", []string{"PodDisruptionBudget"}, "v1.25", "policy/v1"},
+	{"policy/v1beta1", []string{"PodSecurityPolicy"}, "v1.25", ""},
+	{"autoscaling/v2beta2", []string{"HorizontalPodAutoscaler"}, "v1.26", "autoscaling/v2"},
+	{"flowcontrol.apiserver.k8s.io/v1beta1", []string{"FlowSchema", "PriorityLevelConfiguration"}, "v1.26", "flowcontrol.apiserver.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", []string{"CSIStorageCapacity"}, "v1.27", "storage.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta2", []string{"FlowSchema", "PriorityLevelConfiguration"}, "v1.29", "flowcontrol.apiserver.k8s.io/v1"},
+}
+
+// deprecatedOwnerAPIVersion returns the deprecation of the apiVersion and kind of ownerRef, or nil if it is not deprecated
+func deprecatedOwnerAPIVersion(ownerRef metav1.OwnerReference) *deprecatedAPIVersion {
+	for i, deprecated := range deprecatedAPIVersions {
+		if deprecated.groupVersion != ownerRef.APIVersion {
+			continue
+		}
+		gv, _ := schema.ParseGroupVersion(deprecated.groupVersion)
+		for _, kind := range deprecated.kinds {
+			if OwnerGroupKindMatches(ownerRef, gv.WithKind(kind).GroupKind()) {
+				return &deprecatedAPIVersions[i]
+			}
+		}
+	}
+	return nil
+}
+
+// String describes the removal and replacement of the version
+func (d *deprecatedAPIVersion) String() string {
+	if d.replacement == "" {
+		return fmt.Sprintf("%s is removed in Kubernetes %s without a replacement", d.groupVersion, d.removedIn)
+	}
+	return fmt.Sprintf("%s is removed in Kubernetes %s, migrate to %s", d.groupVersion, d.removedIn, d.replacement)
+}
+
+func checkOwnerAPIVersionDeprecated(ctx *RuleContext) []Problem {
+	if deprecated := deprecatedOwnerAPIVersion(ctx.OwnerReference); deprecated != nil {
+		return problem(LevelWarning, CodeDeprecatedOwnerAPIVersion, fmt.Sprintf("deprecated owner apiVersion: %s", deprecated))
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckOwnerAPIVersionDeprecated(t *testing.T) {
+	for _, tc := range []struct {
+		apiVersion string
+		kind       string
+		expected   []Problem
+	}{
+		{apiVersion: "apps/v1", kind: "Deployment"},
+		{apiVersion: "extensions/v1beta1", kind: "Deployment", expected: []Problem{
+			{Level: LevelWarning, Code: CodeDeprecatedOwnerAPIVersion, Message: "deprecated owner apiVersion: extensions/v1beta1 is removed in Kubernetes v1.16, migrate to apps/v1"},
+		}},
+		// kinds are matched as the garbage collector matches them
+		{apiVersion: "extensions/v1beta1", kind: "ingress", expected: []Problem{
+			{Level: LevelWarning, Code: CodeDeprecatedOwnerAPIVersion, Message: "deprecated owner apiVersion: extensions/v1beta1 is removed in Kubernetes v1.22, migrate to networking.k8s.io/v1"},
+		}},
+		{apiVersion: "policy/v1beta1", kind: "PodSecurityPolicy", expected: []Problem{
+			{Level: LevelWarning, Code: CodeDeprecatedOwnerAPIVersion, Message: "deprecated owner apiVersion: policy/v1beta1 is removed in Kubernetes v1.25 without a replacement"},
+		}},
+		// custom resources in a group named like a built-in version are not matched by kind
+		{apiVersion: "batch/v1beta1", kind: "Widget"},
+	} {
+		ctx := &RuleContext{OwnerReference: metav1.OwnerReference{APIVersion: tc.apiVersion, Kind: tc.kind}}
+		if diff := cmp.Diff(tc.expected, checkOwnerAPIVersionDeprecated(ctx)); diff != "" {
+			t.Errorf("%s %s: unexpected problems (-want +got):\n%s", tc.apiVersion, tc.kind, diff)
+		}
+	}
+
+	// versions that are no longer served cannot be resolved, and the replacement is suggested
+	ctx := &RuleContext{
+		OwnerReference:    metav1.OwnerReference{APIVersion: "batch/v1beta1", Kind: "CronJob"},
+		OwnerMappingError: fmt.Errorf("no matches for kind \"CronJob\" in version \"batch/v1beta1\""),
+	}
+	expected := []Problem{{Level: LevelError, Code: CodeUnresolvableOwner, Message: "cannot resolve owner apiVersion/kind: no matches for kind \"CronJob\" in version \"batch/v1beta1\" (batch/v1beta1 is removed in Kubernetes v1.25, migrate to batch/v1)"}}
+	if diff := cmp.Diff(expected, checkOwnerResolvable(ctx)); diff != "" {
+		t.Errorf("unexpected problems (-want +got):\n%s", diff)
+	}
+}
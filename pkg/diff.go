@@ -0,0 +1,225 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// DiffOptions contains options controlling how findings from two scans are compared
+type DiffOptions struct {
+	// Old is read for the findings of the earlier scan, either a report document or a stream of findings written by -o json
+	Old io.Reader
+	// New is read for the findings of the later scan
+	New io.Reader
+	// Output is the format the diff is written to Stdout in, either '' or 'json'
+	Output string
+	// ShowUnchanged includes unchanged findings in the table output
+	ShowUnchanged bool
+	// FailOnIntroduced returns an error from Run if any findings were introduced
+	FailOnIntroduced bool
+	Stderr           io.Writer
+	Stdout           io.Writer
+}
+
+// Validate ensures the specified options are valid
+func (o *DiffOptions) Validate() error {
+	if o.Old == nil || o.New == nil {
+		return fmt.Errorf("old and new findings are required")
+	}
+	if o.Output != "" && o.Output != "json" {
+		return fmt.Errorf("invalid output format, only '' and 'json' are supported: %v", o.Output)
+	}
+	if o.Stderr == nil {
+		return fmt.Errorf("stderr is required")
+	}
+	if o.Stdout == nil {
+		return fmt.Errorf("stdout is required")
+	}
+	return nil
+}
+
+// Run reads the old and new findings, writes the diff to Stdout, and a summary to Stderr
+func (o *DiffOptions) Run() error {
+	oldFindings, err := readFindings(o.Old)
+	if err != nil {
+		return fmt.Errorf("old: %v", err)
+	}
+	newFindings, err := readFindings(o.New)
+	if err != nil {
+		return fmt.Errorf("new: %v", err)
+	}
+	diff := DiffFindings(oldFindings, newFindings)
+	if err := diff.Print(o.Stdout, o.Output, o.ShowUnchanged); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Stderr, "%d introduced, %d resolved, %d unchanged\n", len(diff.Introduced), len(diff.Resolved), len(diff.Unchanged))
+	if o.FailOnIntroduced && len(diff.Introduced) > 0 {
+		return fmt.Errorf("%s introduced", pluralize(len(diff.Introduced), "finding", "findings"))
+	}
+	return nil
+}
+
+// FindingsDiff holds the findings that differ between two scans
+type FindingsDiff struct {
+	// Introduced are findings only in the new scan
+	Introduced []Finding `json:"introduced"`
+	// Resolved are findings only in the old scan
+	Resolved []Finding `json:"resolved"`
+	// Unchanged are findings in both scans, as reported by the new scan
+	Unchanged []Finding `json:"unchanged"`
+}
+
+// findingKey identifies a finding across scans.
+// The resource version, level, and message are excluded, since they can change without the problem changing.
+type findingKey struct {
+	cluster   string
+	group     string
+	resource  string
+	namespace string
+	name      string
+	owner     string
+	code      string
+}
+
+// keyOf identifies finding across scans of the same cluster, by its owner uid
+func keyOf(finding Finding) findingKey {
+	return findingKey{
+		cluster:   finding.Cluster,
+		group:     finding.Resource.Group,
+		resource:  finding.Resource.Resource,
+		namespace: finding.Namespace,
+		name:      finding.Name,
+		owner:     string(finding.OwnerReference.UID),
+		code:      finding.Code,
+	}
+}
+
+// DiffFindings compares the findings of two scans, independent of the order they were reported in
+func DiffFindings(oldFindings, newFindings []Finding) *FindingsDiff {
+	resolved, introduced, unchanged := matchFindings(oldFindings, newFindings, keyOf)
+	return &FindingsDiff{Introduced: introduced, Resolved: resolved, Unchanged: unchanged}
+}
+
+// matchFindings matches findings in a and b with the same key, returning the sorted findings only in a, only in b,
+// and in both (as reported in b). Findings with the same key are matched once each.
+func matchFindings(a, b []Finding, key func(Finding) findingKey) (onlyA, onlyB, both []Finding) {
+	onlyA, onlyB, both = []Finding{}, []Finding{}, []Finding{}
+	remaining := map[findingKey]int{}
+	for _, finding := range a {
+		remaining[key(finding)]++
+	}
+	for _, finding := range b {
+		k := key(finding)
+		if remaining[k] > 0 {
+			remaining[k]--
+			both = append(both, finding)
+		} else {
+			onlyB = append(onlyB, finding)
+		}
+	}
+	for _, finding := range a {
+		k := key(finding)
+		if remaining[k] > 0 {
+			remaining[k]--
+			onlyA = append(onlyA, finding)
+		}
+	}
+	sortFindings(onlyA)
+	sortFindings(onlyB)
+	sortFindings(both)
+	return onlyA, onlyB, both
+}
+
+// sortFindings sorts findings by cluster, group, resource, namespace, name, and code
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := keyOf(findings[i]), keyOf(findings[j])
+		for _, cmp := range [][2]string{
+			{a.cluster, b.cluster}, {a.group, b.group}, {a.resource, b.resource}, {a.namespace, b.namespace}, {a.name, b.name}, {a.code, b.code},
+		} {
+			if cmp[0] != cmp[1] {
+				return cmp[0] < cmp[1]
+			}
+		}
+		return false
+	})
+}
+
+// diffRows are findings printed with the same status
+type diffRows struct {
+	status   string
+	findings []Finding
+}
+
+// Print writes d to w, either as a table if output is ”, or as a JSON object if output is 'json'.
+// Unchanged findings are only included in the table if showUnchanged is true.
+func (d *FindingsDiff) Print(w io.Writer, output string, showUnchanged bool) error {
+	switch output {
+	case "":
+		rows := []diffRows{{"introduced", d.Introduced}, {"resolved", d.Resolved}}
+		if showUnchanged {
+			rows = append(rows, diffRows{"unchanged", d.Unchanged})
+		}
+		return printDiffRows(w, rows)
+	case "json":
+		return json.NewEncoder(w).Encode(d)
+	default:
+		return fmt.Errorf("invalid output format, only '' and 'json' are supported: %v", output)
+	}
+}
+
+// printDiffRows writes rows to w as a table, with a status column
+func printDiffRows(w io.Writer, rows []diffRows) error {
+	withCluster := false
+	empty := true
+	for _, row := range rows {
+		for _, finding := range row.findings {
+			empty = false
+			withCluster = withCluster || finding.Cluster != ""
+		}
+	}
+	if empty {
+		return nil
+	}
+	tabwriter := printers.GetNewTabWriter(w)
+	tabwriter.Write([]byte("STATUS\t"))
+	if withCluster {
+		tabwriter.Write([]byte("CLUSTER\t"))
+	}
+	tabwriter.Write([]byte("GROUP\tRESOURCE\tNAMESPACE\tNAME\tOWNER_UID\tLEVEL\tMESSAGE\n"))
+	for _, row := range rows {
+		for _, finding := range row.findings {
+			tabwriter.Write([]byte(row.status + "\t"))
+			if withCluster {
+				tabwriter.Write([]byte(finding.Cluster + "\t"))
+			}
+			tabwriter.Write([]byte(
+				strings.Join([]string{
+					finding.Resource.Group, finding.Resource.Resource, finding.Namespace, finding.Name, string(finding.OwnerReference.UID), finding.Level, finding.Message,
+				}, "\t") + "\n",
+			))
+		}
+	}
+	return tabwriter.Flush()
+}
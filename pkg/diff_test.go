@@ -0,0 +1,147 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func diffFinding(name, code, message string) Finding {
+	return Finding{
+		Resource:       metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"},
+		Kind:           metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"},
+		Namespace:      "ns1",
+		Name:           name,
+		OwnerReference: metav1.OwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "d", UID: "owneruid"},
+		Level:          LevelError,
+		Code:           code,
+		Message:        message,
+	}
+}
+
+func TestDiffFindings(t *testing.T) {
+	a := diffFinding("a", CodeOwnerNotFound, "no object found for uid")
+	b := diffFinding("b", CodeOwnerNotFound, "no object found for uid")
+	c := diffFinding("c", CodeNameMismatch, "ownerReference name (d) does not match owner name (e)")
+	aWarning := a
+	aWarning.Level = LevelWarning
+	aWarning.Message = "changed"
+	aNewVersion := a
+	aNewVersion.Resource.Version = "v2"
+	aOtherCode := a
+	aOtherCode.Code = CodeNamespaceMismatch
+
+	testcases := []struct {
+		name   string
+		old    []Finding
+		new    []Finding
+		expect *FindingsDiff
+	}{
+		{
+			name:   "empty",
+			expect: &FindingsDiff{Introduced: []Finding{}, Resolved: []Finding{}, Unchanged: []Finding{}},
+		},
+		{
+			name:   "order independent",
+			old:    []Finding{a, b, c},
+			new:    []Finding{c, b, a},
+			expect: &FindingsDiff{Introduced: []Finding{}, Resolved: []Finding{}, Unchanged: []Finding{a, b, c}},
+		},
+		{
+			name:   "introduced and resolved",
+			old:    []Finding{c, a},
+			new:    []Finding{b, a},
+			expect: &FindingsDiff{Introduced: []Finding{b}, Resolved: []Finding{c}, Unchanged: []Finding{a}},
+		},
+		{
+			name:   "level and message changes are unchanged",
+			old:    []Finding{a},
+			new:    []Finding{aWarning},
+			expect: &FindingsDiff{Introduced: []Finding{}, Resolved: []Finding{}, Unchanged: []Finding{aWarning}},
+		},
+		{
+			name:   "version changes are unchanged",
+			old:    []Finding{a},
+			new:    []Finding{aNewVersion},
+			expect: &FindingsDiff{Introduced: []Finding{}, Resolved: []Finding{}, Unchanged: []Finding{aNewVersion}},
+		},
+		{
+			name:   "duplicates are matched once",
+			old:    []Finding{a},
+			new:    []Finding{a, a},
+			expect: &FindingsDiff{Introduced: []Finding{a}, Resolved: []Finding{}, Unchanged: []Finding{a}},
+		},
+		{
+			name:   "code changes are new findings",
+			old:    []Finding{a},
+			new:    []Finding{aOtherCode},
+			expect: &FindingsDiff{Introduced: []Finding{aOtherCode}, Resolved: []Finding{a}, Unchanged: []Finding{}},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expect, DiffFindings(tc.old, tc.new)); diff != "" {
+				t.Errorf("unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiffRun(t *testing.T) {
+	a := diffFinding("a", CodeOwnerNotFound, "no object found for uid")
+	b := diffFinding("b", CodeOwnerNotFound, "no object found for uid")
+	c := diffFinding("c", CodeOwnerNotFound, "no object found for uid")
+
+	// old is a stream of findings as written by -o json, new is a report document
+	oldInput := &bytes.Buffer{}
+	if err := (&Report{Findings: []Finding{a, b}}).Print(oldInput, "json"); err != nil {
+		t.Fatal(err)
+	}
+	newInput := &bytes.Buffer{}
+	if err := json.NewEncoder(newInput).Encode(newReportDocument(&Report{Findings: []Finding{b, c}}, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	opts := &DiffOptions{Old: oldInput, New: newInput, FailOnIntroduced: true, Stdout: stdout, Stderr: stderr}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	err := opts.Run()
+	if err == nil || err.Error() != "1 finding introduced" {
+		t.Errorf("expected error for introduced finding, got %v", err)
+	}
+
+	expectStdout := `
+STATUS     GROUP  RESOURCE     NAMESPACE  NAME  OWNER_UID  LEVEL  MESSAGE
+introduced apps   replicasets  ns1        c     owneruid   Error  no object found for uid
+resolved   apps   replicasets  ns1        a     owneruid   Error  no object found for uid
+`
+	if diff := cmp.Diff(normalize(expectStdout), normalize(stdout.String())); diff != "" {
+		t.Errorf("unexpected stdout (-want +got):\n%s", diff)
+	}
+	if got := strings.TrimSpace(stderr.String()); got != "1 introduced, 1 resolved, 1 unchanged" {
+		t.Errorf("unexpected summary: %s", got)
+	}
+}
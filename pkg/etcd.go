@@ -0,0 +1,215 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/encoding/protowire"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var (
+	// etcdKeyBucket is the bbolt bucket holding the revisions of each key
+	etcdKeyBucket = []byte("key")
+	// protobufPrefix precedes objects stored as protobuf by the apiserver
+	protobufPrefix = []byte("k8s\x00")
+	// encryptedPrefix precedes objects encrypted at rest by the apiserver
+	encryptedPrefix = []byte("k8s:enc:")
+)
+
+// ReadEtcdSnapshot reads the metadata of the objects stored under prefix in an etcd snapshot, as written by "etcdctl snapshot save".
+// The latest revision of each key is read. Values that cannot be decoded, such as objects encrypted at rest, are reported to stderr and skipped.
+// resources is returned with resources added for kinds it does not contain, such as custom resources, named from their keys.
+func ReadEtcdSnapshot(path, prefix string, resources []*metav1.APIResourceList, stderr io.Writer) ([]*metav1.APIResourceList, []*unstructured.Unstructured, error) {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	db, err := bolt.Open(path, 0400, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening etcd snapshot %s: %v", path, err)
+	}
+	defer db.Close()
+
+	// revisions are sorted, so later revisions of a key replace earlier ones
+	latest := map[string][]byte{}
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(etcdKeyBucket)
+		if bucket == nil {
+			return fmt.Errorf("no %q bucket found", etcdKeyBucket)
+		}
+		return bucket.ForEach(func(revision, data []byte) error {
+			key, value, err := parseKeyValue(data)
+			if err != nil {
+				return fmt.Errorf("error decoding revision %x: %v", revision, err)
+			}
+			if !strings.HasPrefix(string(key), prefix) {
+				return nil
+			}
+			// deletions are recorded as revisions ending in 't', for tombstone
+			if len(revision) == 18 && revision[17] == 't' {
+				delete(latest, string(key))
+				return nil
+			}
+			latest[string(key)] = append([]byte(nil), value...)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading etcd snapshot %s: %v", path, err)
+	}
+
+	keys := []string{}
+	for key := range latest {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	inferred := newInferredResources(resources)
+	objects := []*unstructured.Unstructured{}
+	for _, key := range keys {
+		segments := strings.Split(strings.TrimPrefix(key, prefix), "/")
+		// IP and port range allocations and apiserver endpoint leases are not API objects
+		if segments[0] == "ranges" || segments[0] == "masterleases" {
+			continue
+		}
+		object, err := decodeEtcdValue(latest[key])
+		if err != nil {
+			fmt.Fprintf(stderr, "warning: skipping %s: %v\n", key, err)
+			continue
+		}
+		objects = append(objects, object)
+		// keys are <prefix><resource>/... for the core group and <prefix><group>/<resource>/... for other groups
+		resource := segments[0]
+		if object.GroupVersionKind().Group != "" && len(segments) > 1 {
+			resource = segments[1]
+		}
+		inferred.add(object, resource)
+	}
+	return inferred.extend(resources), objects, nil
+}
+
+// parseKeyValue returns the key and value fields of an etcd mvccpb.KeyValue message
+func parseKeyValue(data []byte) ([]byte, []byte, error) {
+	var key, value []byte
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+		if typ == protowire.BytesType && (num == 1 || num == 5) {
+			field, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return nil, nil, protowire.ParseError(n)
+			}
+			if num == 1 {
+				key = field
+			} else {
+				value = field
+			}
+			data = data[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, data)
+		if n < 0 {
+			return nil, nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+	}
+	return key, value, nil
+}
+
+// decodeEtcdValue returns the apiVersion, kind, and metadata of an object stored by the apiserver as protobuf or JSON
+func decodeEtcdValue(value []byte) (*unstructured.Unstructured, error) {
+	switch {
+	case bytes.HasPrefix(value, encryptedPrefix):
+		return nil, fmt.Errorf("object is encrypted at rest")
+
+	case bytes.HasPrefix(value, protobufPrefix):
+		unknown := &runtime.Unknown{}
+		if err := unknown.Unmarshal(value[len(protobufPrefix):]); err != nil {
+			return nil, err
+		}
+		if unknown.APIVersion == "" || unknown.Kind == "" {
+			return nil, fmt.Errorf("object is missing apiVersion or kind")
+		}
+		// metadata is the first field of every built-in type
+		objectMeta := &metav1.ObjectMeta{}
+		for data := unknown.Raw; len(data) > 0; {
+			num, typ, n := protowire.ConsumeTag(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			if num == 1 && typ == protowire.BytesType {
+				field, n := protowire.ConsumeBytes(data)
+				if n < 0 {
+					return nil, protowire.ParseError(n)
+				}
+				if err := objectMeta.Unmarshal(field); err != nil {
+					return nil, err
+				}
+				break
+			}
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+		metadata, err := runtime.DefaultUnstructuredConverter.ToUnstructured(objectMeta)
+		if err != nil {
+			return nil, err
+		}
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": unknown.APIVersion,
+			"kind":       unknown.Kind,
+			"metadata":   metadata,
+		}}, nil
+
+	case bytes.HasPrefix(value, []byte("{")):
+		var object struct {
+			APIVersion string                 `json:"apiVersion"`
+			Kind       string                 `json:"kind"`
+			Metadata   map[string]interface{} `json:"metadata"`
+		}
+		if err := json.Unmarshal(value, &object); err != nil {
+			return nil, err
+		}
+		if object.APIVersion == "" || object.Kind == "" {
+			return nil, fmt.Errorf("object is missing apiVersion or kind")
+		}
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": object.APIVersion,
+			"kind":       object.Kind,
+			"metadata":   object.Metadata,
+		}}, nil
+
+	default:
+		return nil, fmt.Errorf("unrecognized encoding")
+	}
+}
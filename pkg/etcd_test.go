@@ -0,0 +1,145 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/encoding/protowire"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// etcdRevision is a key and value written to etcd, or a deletion if value is nil
+type etcdRevision struct {
+	key   string
+	value []byte
+}
+
+// writeEtcdSnapshot writes revisions to a bbolt database laid out like an etcd snapshot
+func writeEtcdSnapshot(t *testing.T, path string, revisions []etcdRevision) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket(etcdKeyBucket)
+		if err != nil {
+			return err
+		}
+		for i, revision := range revisions {
+			revisionKey := make([]byte, 17, 18)
+			binary.BigEndian.PutUint64(revisionKey, uint64(i+1))
+			revisionKey[8] = '_'
+			var keyValue []byte
+			keyValue = protowire.AppendTag(keyValue, 1, protowire.BytesType)
+			keyValue = protowire.AppendBytes(keyValue, []byte(revision.key))
+			keyValue = protowire.AppendTag(keyValue, 2, protowire.VarintType)
+			keyValue = protowire.AppendVarint(keyValue, uint64(i+1))
+			if revision.value == nil {
+				revisionKey = append(revisionKey, 't')
+			} else {
+				keyValue = protowire.AppendTag(keyValue, 5, protowire.BytesType)
+				keyValue = protowire.AppendBytes(keyValue, revision.value)
+			}
+			if err := bucket.Put(revisionKey, keyValue); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// protobufValue encodes object as the apiserver stores it in etcd
+func protobufValue(t *testing.T, apiVersion, kind string, object interface{ Marshal() ([]byte, error) }) []byte {
+	raw, err := object.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := (&runtime.Unknown{TypeMeta: runtime.TypeMeta{APIVersion: apiVersion, Kind: kind}, Raw: raw}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append([]byte("k8s\x00"), data...)
+}
+
+func TestEtcdSnapshotScan(t *testing.T) {
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "d1", Namespace: "app", UID: "d1uid"}}
+	pod := func(name string, ownerUID string) []byte {
+		return protobufValue(t, "v1", "Pod", &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "app", UID: types.UID("uid-" + name),
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "d1", UID: types.UID(ownerUID)}}}})
+	}
+	path := filepath.Join(t.TempDir(), "snapshot.db")
+	writeEtcdSnapshot(t, path, []etcdRevision{
+		{key: "/registry/deployments/app/d1", value: protobufValue(t, "apps/v1", "Deployment", deployment)},
+		{key: "/registry/pods/app/p1", value: pod("p1", "wronguid")},
+		// deleted objects are not scanned
+		{key: "/registry/pods/app/p2", value: pod("p2", "wronguid")},
+		{key: "/registry/pods/app/p2"},
+		// the latest revision is scanned
+		{key: "/registry/pods/app/p3", value: pod("p3", "wronguid")},
+		{key: "/registry/pods/app/p3", value: pod("p3", "d1uid")},
+		// custom resources are stored as JSON, and their resource is named by the key
+		{key: "/registry/example.com/widgets/app/w1", value: []byte(`{"apiVersion":"example.com/v1","kind":"Widget","metadata":{"name":"w1","namespace":"app","uid":"w1uid","ownerReferences":[{"apiVersion":"apps/v1","kind":"Deployment","name":"d1","uid":"missinguid"}]},"spec":{}}`)},
+		{key: "/registry/secrets/app/s1", value: []byte("k8s:enc:aescbc:v1:key1:...")},
+		{key: "/registry/ranges/serviceips", value: []byte("range allocations are skipped")},
+		{key: "/other/pods/app/p4", value: pod("p4", "wronguid")},
+	})
+
+	stderr := &bytes.Buffer{}
+	resources, objects, err := ReadEtcdSnapshot(path, "/registry", DefaultDiscoverySnapshot(), stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("warning: skipping /registry/secrets/app/s1: object is encrypted at rest\n", stderr.String()); diff != "" {
+		t.Errorf("unexpected warnings (-want +got):\n%s", diff)
+	}
+	discoveryClient, metadataClient, err := OfflineClients(resources, objects, stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &VerifyGCOptions{DiscoveryClient: discoveryClient, MetadataClient: metadataClient, Stderr: ioutil.Discard, Stdout: ioutil.Discard}
+	report, err := opts.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := report.Print(out, ""); err != nil {
+		t.Fatal(err)
+	}
+	expect := `
+GROUP RESOURCE NAMESPACE NAME OWNER_UID LEVEL MESSAGE
+pods app p1 wronguid Error no object found for uid; did you mean app/d1 (uid d1uid)?
+example.com widgets app w1 missinguid Error no object found for uid; did you mean app/d1 (uid d1uid)?
+`
+	if diff := cmp.Diff(normalize(expect), normalize(out.String())); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+}
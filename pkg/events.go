@@ -0,0 +1,123 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// eventComponent is the source component recorded in Events
+const eventComponent = "kubectl-check-ownerreferences"
+
+// EventOptions contains options controlling how Events are recorded for findings
+type EventOptions struct {
+	// Client is used to write Events
+	Client corev1client.EventsGetter
+}
+
+// Validate ensures the specified options are valid
+func (o *EventOptions) Validate() error {
+	if o.Client == nil {
+		return fmt.Errorf("events client is required")
+	}
+	return nil
+}
+
+// publish records a Warning Event on the child object of each Error-level finding.
+// Events for the same finding in subsequent scans update the existing Event count.
+// Warning-level findings describe incomplete scan coverage rather than a problem with the object, and are not recorded.
+// Every finding is recorded even if some fail, such as in namespaces where Events are forbidden or over quota,
+// and the failures are returned together.
+func (o *EventOptions) publish(ctx context.Context, result *Report) error {
+	var errs []error
+	total := 0
+	for _, finding := range result.Findings {
+		if finding.Level != LevelError {
+			continue
+		}
+		total++
+		if err := o.record(ctx, finding); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("could not record %d of %s: %v", len(errs), pluralize(total, "event", "events"), utilerrors.NewAggregate(errs))
+}
+
+func (o *EventOptions) record(ctx context.Context, finding Finding) error {
+	// events for cluster-scoped objects are recorded in the default namespace
+	namespace := finding.Namespace
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	name := eventName(finding)
+	message := fmt.Sprintf("invalid ownerReference to %s %s (uid %s): %s", finding.OwnerReference.Kind, finding.OwnerReference.Name, finding.OwnerReference.UID, finding.Message)
+	now := metav1.Now()
+
+	client := o.Client.Events(namespace)
+	existing, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		existing.Count++
+		existing.LastTimestamp = now
+		existing.Message = message
+		_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+	} else if apierrors.IsNotFound(err) {
+		_, err = client.Create(ctx, &corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			InvolvedObject: corev1.ObjectReference{
+				APIVersion: metav1.GroupVersion{Group: finding.Kind.Group, Version: finding.Kind.Version}.String(),
+				Kind:       finding.Kind.Kind,
+				Namespace:  finding.Namespace,
+				Name:       finding.Name,
+				UID:        finding.UID,
+			},
+			Reason:              finding.Code,
+			Message:             message,
+			Source:              corev1.EventSource{Component: eventComponent},
+			ReportingController: eventComponent,
+			FirstTimestamp:      now,
+			LastTimestamp:       now,
+			Count:               1,
+			Type:                corev1.EventTypeWarning,
+		}, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("error recording event for %s %s/%s: %v", finding.Kind.Kind, finding.Namespace, finding.Name, err)
+	}
+	return nil
+}
+
+// eventName returns a name for the Event recording finding that is stable across scans
+func eventName(finding Finding) string {
+	hash := sha256.Sum256([]byte(string(finding.UID) + "/" + string(finding.OwnerReference.UID) + "/" + finding.Code))
+	suffix := fmt.Sprintf(".%x", hash[:8])
+	prefix := finding.Name
+	if max := 253 - len(suffix); len(prefix) > max {
+		prefix = prefix[:max]
+	}
+	return prefix + suffix
+}
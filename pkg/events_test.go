@@ -0,0 +1,122 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestEventsPublish(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	opts := &EventOptions{Client: client.CoreV1()}
+	result := &Report{Findings: []Finding{
+		{
+			Resource:       metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+			Kind:           metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Namespace:      "ns1",
+			Name:           "pod1",
+			UID:            "poduid1",
+			OwnerReference: metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs1", UID: "rsuid1"},
+			Level:          LevelError,
+			Code:           CodeOwnerNotFound,
+			Message:        "no object found for uid",
+		},
+		{
+			Resource:       metav1.GroupVersionResource{Version: "v1", Resource: "nodes"},
+			Kind:           metav1.GroupVersionKind{Version: "v1", Kind: "Node"},
+			Name:           "node1",
+			UID:            "nodeuid1",
+			OwnerReference: metav1.OwnerReference{APIVersion: "example.com/v1", Kind: "Machine", Name: "machine1", UID: "machineuid1"},
+			Level:          LevelWarning,
+			Code:           CodeOwnerListFailed,
+			Message:        "could not list parent resource machines.example.com",
+		},
+	}}
+
+	// publish twice, the second should update the existing event
+	for i := 0; i < 2; i++ {
+		if err := opts.publish(context.Background(), result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := client.CoreV1().Events("").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events.Items) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events.Items))
+	}
+	event := events.Items[0]
+	if event.Namespace != "ns1" || event.InvolvedObject.UID != "poduid1" || event.InvolvedObject.Kind != "Pod" || event.InvolvedObject.APIVersion != "v1" {
+		t.Errorf("unexpected event: %#v", event)
+	}
+	if event.Reason != CodeOwnerNotFound || event.Type != "Warning" || event.Count != 2 {
+		t.Errorf("unexpected event: %#v", event)
+	}
+	// the update keeps the owner in the message
+	if expect := "invalid ownerReference to ReplicaSet rs1 (uid rsuid1): no object found for uid"; event.Message != expect {
+		t.Errorf("expected message %q, got %q", expect, event.Message)
+	}
+}
+
+func TestEventsPublishFailures(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "events", func(action coretesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() == "restricted" {
+			return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "events"}, "", fmt.Errorf("denied"))
+		}
+		return false, nil, nil
+	})
+	opts := &EventOptions{Client: client.CoreV1()}
+	finding := func(namespace string) Finding {
+		return Finding{
+			Resource:       metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+			Kind:           metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Namespace:      namespace,
+			Name:           "pod1",
+			UID:            types.UID("uid-" + namespace),
+			OwnerReference: metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs1", UID: "rsuid1"},
+			Level:          LevelError,
+			Code:           CodeOwnerNotFound,
+		}
+	}
+
+	// a namespace where events are forbidden does not stop events in later namespaces
+	err := opts.publish(context.Background(), &Report{Findings: []Finding{finding("restricted"), finding("ns1")}})
+	if err == nil || !strings.Contains(err.Error(), "could not record 1 of 2 events") {
+		t.Errorf("expected the forbidden event to be reported, got %v", err)
+	}
+	events, err := client.CoreV1().Events("ns1").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events.Items) != 1 {
+		t.Errorf("expected the event in ns1 to be recorded, got %d", len(events.Items))
+	}
+}
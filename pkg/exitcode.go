@@ -0,0 +1,55 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+// Exit codes distinguishing why a scan failed, for automation.
+// Other errors, including error-level findings with FailOnErrors, exit with 1.
+const (
+	// ExitCodeWarnings is returned if only warning-level findings failed a complete scan, with FailOnWarnings
+	ExitCodeWarnings = 2
+	// ExitCodeIncomplete is returned if any resources could not be discovered or listed, or the scan was interrupted,
+	// and no error-level findings failed the scan, so automation does not mistake a partial scan for a clean one
+	ExitCodeIncomplete = 3
+)
+
+// ExitError is an error that should cause the process to exit with Code
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// PublishError is returned with the report of a completed scan if it could not be published to some destinations,
+// so callers can keep the results
+type PublishError struct {
+	Err error
+}
+
+func (e *PublishError) Error() string {
+	return "error publishing results: " + e.Err.Error()
+}
+
+func (e *PublishError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2/google"
+)
+
+// ExportOptions contains options controlling how reports are exported to object storage
+type ExportOptions struct {
+	// URL is the location to export reports to, as s3://bucket/prefix, gcs://bucket/prefix, or file:///path/to/dir.
+	//
+	// S3 credentials are read from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN environment variables.
+	// The region is read from the AWS_REGION environment variable, or a region query parameter.
+	// An endpoint query parameter may be used to export to S3-compatible storage.
+	//
+	// GCS credentials are located using Application Default Credentials.
+	URL string
+	// Cluster identifies the cluster in exported reports
+	Cluster *ClusterInfo
+	// Client is used to upload reports. If nil, a client with a 60 second timeout is used.
+	Client *http.Client
+	// SignKey optionally signs exported reports, writing a detached ReportSignature next to each report with a .sig suffix
+	SignKey *ReportKey
+}
+
+// Validate ensures the specified options are valid
+func (o *ExportOptions) Validate() error {
+	if o.SignKey != nil && o.SignKey.private == nil && o.SignKey.secret == nil {
+		return fmt.Errorf("reports cannot be signed with a public key")
+	}
+	_, err := o.parse()
+	return err
+}
+
+// objectWriter writes an object to storage
+type objectWriter interface {
+	write(ctx context.Context, key string, data []byte) error
+}
+
+func (o *ExportOptions) parse() (objectWriter, error) {
+	u, err := url.Parse(o.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid export url %q: %v", o.URL, err)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "file":
+		if u.Host != "" {
+			return nil, fmt.Errorf("invalid export url %q, expected file:///path/to/dir", o.URL)
+		}
+		return &fileWriter{dir: u.Path}, nil
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid export url %q, expected s3://bucket/prefix", o.URL)
+		}
+		region := u.Query().Get("region")
+		if region == "" {
+			region = os.Getenv("AWS_REGION")
+		}
+		if region == "" {
+			region = os.Getenv("AWS_DEFAULT_REGION")
+		}
+		if region == "" {
+			region = "us-east-1"
+		}
+		return &s3Writer{bucket: u.Host, prefix: prefix, region: region, endpoint: u.Query().Get("endpoint"), client: o.Client}, nil
+	case "gcs", "gs":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid export url %q, expected gcs://bucket/prefix", o.URL)
+		}
+		return &gcsWriter{bucket: u.Host, prefix: prefix, client: o.Client}, nil
+	default:
+		return nil, fmt.Errorf("invalid export url %q, only s3://, gcs://, and file:// are supported", o.URL)
+	}
+}
+
+// publish writes the report for result to storage with a timestamped key
+func (o *ExportOptions) publish(ctx context.Context, result *Report) (string, error) {
+	writer, err := o.parse()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(newReportDocument(result, o.Cluster), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	key := result.CompletionTime.UTC().Format("20060102T150405Z") + ".json"
+	if err := writer.write(ctx, key, data); err != nil {
+		return "", fmt.Errorf("error exporting report to %s: %v", o.URL, err)
+	}
+	if o.SignKey != nil {
+		signature, err := o.SignKey.Sign(data)
+		if err != nil {
+			return "", err
+		}
+		// the report is written first, so a signature is never exported without its report
+		if err := writer.write(ctx, key+".sig", signature); err != nil {
+			return "", fmt.Errorf("error exporting report signature to %s: %v", o.URL, err)
+		}
+	}
+	return key, nil
+}
+
+type fileWriter struct {
+	dir string
+}
+
+func (f *fileWriter) write(ctx context.Context, key string, data []byte) error {
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(f.dir, key), data, 0644)
+}
+
+type gcsWriter struct {
+	bucket string
+	prefix string
+	client *http.Client
+}
+
+func (g *gcsWriter) write(ctx context.Context, key string, data []byte) error {
+	client := g.client
+	if client == nil {
+		var err error
+		client, err = google.DefaultClient(ctx, "https://www.googleapis.com/auth/devstorage.read_write")
+		if err != nil {
+			return err
+		}
+		client.Timeout = 60 * time.Second
+	}
+	uploadURL := fmt.Sprintf("https://storage.googleapis.com/upload/storage/v1/b/%s/o?uploadType=media&name=%s", url.PathEscape(g.bucket), url.QueryEscape(path.Join(g.prefix, key)))
+	req, err := http.NewRequestWithContext(ctx, "POST", uploadURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doUpload(client, req)
+}
+
+// doUpload sends req, returning an error if the response is not successful
+func doUpload(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
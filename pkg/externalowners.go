@@ -0,0 +1,118 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// OwnerRecord identifies an object that may be referenced as an owner, in an inventory built outside the scanned cluster
+type OwnerRecord struct {
+	UID        types.UID `json:"uid"`
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Namespace  string    `json:"namespace,omitempty"`
+	Name       string    `json:"name"`
+}
+
+// ExternalOwners supplies owners from an inventory, such as a snapshot of the cluster before a migration,
+// to find in addition to or instead of the owners listed from the cluster
+type ExternalOwners struct {
+	// Owners are the owners in the inventory
+	Owners []OwnerRecord
+	// Replace skips listing owners from the cluster, so only the owners in the inventory are found.
+	// Only supported when checking specific objects, which are otherwise the only objects not listed.
+	Replace bool
+}
+
+// LoadOwners reads an owner inventory from path: a gzip-compressed snapshot written by --snapshot-out,
+// or OwnerRecords as JSON, either as arrays or as a stream of records
+func LoadOwners(path string) ([]OwnerRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	if magic, _ := r.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		snapshot, err := LoadScanSnapshot(path)
+		if err != nil {
+			return nil, err
+		}
+		owners := make([]OwnerRecord, 0, len(snapshot.Objects))
+		for _, object := range snapshot.Objects {
+			owners = append(owners, OwnerRecord{UID: object.UID, APIVersion: object.APIVersion, Kind: object.Kind, Namespace: object.Namespace, Name: object.Name})
+		}
+		return owners, nil
+	}
+
+	owners := []OwnerRecord{}
+	decoder := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading owners %s: %v", path, err)
+		}
+		records := []OwnerRecord{}
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+			if err := json.Unmarshal(raw, &records); err != nil {
+				return nil, fmt.Errorf("error reading owners %s: %v", path, err)
+			}
+		} else {
+			record := OwnerRecord{}
+			if err := json.Unmarshal(raw, &record); err != nil {
+				return nil, fmt.Errorf("error reading owners %s: %v", path, err)
+			}
+			records = append(records, record)
+		}
+		for _, record := range records {
+			if record.UID == "" || record.APIVersion == "" || record.Kind == "" || record.Name == "" {
+				return nil, fmt.Errorf("error reading owners %s: uid, apiVersion, kind, and name are required: %+v", path, record)
+			}
+		}
+		owners = append(owners, records...)
+	}
+	return owners, nil
+}
+
+// index adds the owners not already in objects to it, found by uid only, so they are not checked as children.
+// It returns the number of owners added.
+func (e *ExternalOwners) index(objects *ObjectIndex) int {
+	added := 0
+	for _, owner := range e.Owners {
+		if len(objects.ByUID(owner.UID)) > 0 {
+			// owners listed from the cluster are current
+			continue
+		}
+		objects.byUID[owner.UID] = append(objects.byUID[owner.UID], &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: owner.APIVersion, Kind: owner.Kind},
+			ObjectMeta: metav1.ObjectMeta{Namespace: owner.Namespace, Name: owner.Name, UID: owner.UID},
+		})
+		added++
+	}
+	return added
+}
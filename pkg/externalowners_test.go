@@ -0,0 +1,135 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+func TestLoadOwners(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "owners.json")
+	data := `{"uid": "uid1", "apiVersion": "apps/v1", "kind": "Deployment", "namespace": "ns1", "name": "web"}
+[{"uid": "uid2", "apiVersion": "v1", "kind": "Namespace", "name": "ns1"}]
+`
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	owners, err := LoadOwners(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []OwnerRecord{
+		{UID: "uid1", APIVersion: "apps/v1", Kind: "Deployment", Namespace: "ns1", Name: "web"},
+		{UID: "uid2", APIVersion: "v1", Kind: "Namespace", Name: "ns1"},
+	}
+	if diff := cmp.Diff(expected, owners); diff != "" {
+		t.Errorf("unexpected owners (-want +got):\n%s", diff)
+	}
+
+	snapshotPath := filepath.Join(dir, "snapshot.json.gz")
+	snapshot := &ScanSnapshot{Objects: []*metav1.PartialObjectMetadata{{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{UID: "uid1", Namespace: "ns1", Name: "web"},
+	}}}
+	if err := snapshot.write(snapshotPath); err != nil {
+		t.Fatal(err)
+	}
+	if owners, err = LoadOwners(snapshotPath); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected[:1], owners); diff != "" {
+		t.Errorf("unexpected owners from snapshot (-want +got):\n%s", diff)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"uid": "uid1", "kind": "Deployment"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOwners(path); err == nil {
+		t.Errorf("expected error for an incomplete record")
+	}
+}
+
+func TestRunExternalOwners(t *testing.T) {
+	// the pod of the cluster is owned by a pod that no longer exists, but is in the inventory
+	cluster := newFakeCluster(t, "c", "owner")
+	inventory := []OwnerRecord{{UID: "missinguid-c", APIVersion: "v1", Kind: "Pod", Namespace: "ns1", Name: "missing"}}
+	stdout := &bytes.Buffer{}
+	opts := cluster.Verify
+	opts.Output, opts.Stdout, opts.Stderr = "json", stdout, &bytes.Buffer{}
+	opts.Owners = &ExternalOwners{Owners: inventory}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opts.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if findings, err := readFindings(stdout); err != nil || len(findings) != 0 {
+		t.Errorf("expected no findings with the owner in the inventory, got %#v, %v", findings, err)
+	}
+
+	// only the inventory is used to find the owners of an export, even though the live owner exists
+	cluster = newFakeCluster(t, "c", "owner")
+	pod := func(name string, ownerUID string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"metadata": map[string]interface{}{
+				"namespace":       "ns1",
+				"name":            name,
+				"ownerReferences": []interface{}{map[string]interface{}{"apiVersion": "v1", "kind": "Pod", "name": "missing", "uid": ownerUID}},
+			},
+		}}
+	}
+	stdout.Reset()
+	opts = cluster.Verify
+	opts.Output, opts.Stdout, opts.Stderr = "json", stdout, &bytes.Buffer{}
+	opts.Objects = []*unstructured.Unstructured{pod("inventoried", "missinguid-c"), pod("live", "uid-c-owner")}
+	opts.Owners = &ExternalOwners{Owners: inventory, Replace: true}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opts.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	findings, err := readFindings(stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 || findings[0].Name != "live" || findings[0].Code != CodeNameMismatch && findings[0].Code != CodeOwnerNotFound {
+		t.Errorf("expected a single finding for the pod owned by a live owner, got %#v", findings)
+	}
+	for _, action := range opts.MetadataClient.(*metadatafake.FakeMetadataClient).Actions() {
+		if action.GetVerb() == "list" {
+			t.Errorf("expected no owners listed, got %v", action)
+		}
+	}
+
+	opts.Objects = nil
+	if err := opts.Validate(); err == nil {
+		t.Errorf("expected error replacing listed owners in a full scan")
+	}
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/pager"
+)
+
+// FinalizersOptions contains options controlling how the finalizers of the objects scans list are inventoried
+type FinalizersOptions struct {
+	DiscoveryClient discovery.DiscoveryInterface
+	MetadataClient  metadata.Interface
+	Output          string
+	Stderr          io.Writer
+	Stdout          io.Writer
+
+	// RequiredVerbs are the verbs a resource must support to be listed, DefaultRequiredVerbs if empty
+	RequiredVerbs []string
+}
+
+// Validate ensures the specified options are valid
+func (o *FinalizersOptions) Validate() error {
+	if o.DiscoveryClient == nil {
+		return fmt.Errorf("discovery client is required")
+	}
+	if o.MetadataClient == nil {
+		return fmt.Errorf("metadata client is required")
+	}
+	if o.Stderr == nil {
+		return fmt.Errorf("stderr is required")
+	}
+	if o.Stdout == nil {
+		return fmt.Errorf("stdout is required")
+	}
+	if o.Output != "" && o.Output != "json" {
+		return fmt.Errorf("invalid output format, only '' and 'json' are supported: %v", o.Output)
+	}
+	return validateRequiredVerbs(o.RequiredVerbs)
+}
+
+// FinalizerCount describes the objects a finalizer is present on
+type FinalizerCount struct {
+	Finalizer string `json:"finalizer"`
+	// Objects is the number of objects with the finalizer
+	Objects int `json:"objects"`
+	// Deleting is the number of those objects that are being deleted, waiting for the finalizer to be removed
+	Deleting int `json:"deleting"`
+	// Namespaces is the number of objects with the finalizer in each namespace, with cluster-scoped objects under ""
+	Namespaces map[string]int `json:"namespaces"`
+	// Orphaned is true if no API group the cluster serves matches the domain of the finalizer,
+	// so the controller that would remove it, or its CRDs, likely no longer exist
+	Orphaned bool `json:"orphaned,omitempty"`
+}
+
+// Run lists the resources scans list, and writes the finalizers present on their objects with their counts to Stdout,
+// either as a table if Output is ”, or as a JSON array if Output is 'json', sorted by finalizer
+func (o *FinalizersOptions) Run(ctx context.Context) error {
+	gvrs, _, err := discoverGCResources(o.DiscoveryClient, o.RequiredVerbs, o.Stderr)
+	if err != nil {
+		return err
+	}
+	groupList, err := o.DiscoveryClient.ServerGroups()
+	if err != nil {
+		return err
+	}
+	groups := []string{}
+	for _, group := range groupList.Groups {
+		groups = append(groups, group.Name)
+	}
+
+	counts := map[string]*FinalizerCount{}
+	for _, gvr := range gvrs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err := pager.New(func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return o.MetadataClient.Resource(gvr).List(ctx, opts)
+		}).EachListItem(ctx, metav1.ListOptions{}, func(object runtime.Object) error {
+			item, ok := object.(*metav1.PartialObjectMetadata)
+			if !ok {
+				return fmt.Errorf("expected type *metav1.PartialObjectMetadata, got type %T", item)
+			}
+			for _, finalizer := range item.Finalizers {
+				count := counts[finalizer]
+				if count == nil {
+					count = &FinalizerCount{Finalizer: finalizer, Namespaces: map[string]int{}, Orphaned: !finalizerServed(finalizer, groups)}
+					counts[finalizer] = count
+				}
+				count.Objects++
+				count.Namespaces[item.Namespace]++
+				if item.DeletionTimestamp != nil {
+					count.Deleting++
+				}
+			}
+			return nil
+		})
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		} else if err != nil {
+			fmt.Fprintf(o.Stderr, "warning: could not list %v: %v\n", gvr, err)
+		}
+	}
+
+	finalizers := make([]FinalizerCount, 0, len(counts))
+	orphaned := 0
+	for _, count := range counts {
+		finalizers = append(finalizers, *count)
+		if count.Orphaned {
+			orphaned++
+		}
+	}
+	sort.Slice(finalizers, func(i, j int) bool { return finalizers[i].Finalizer < finalizers[j].Finalizer })
+	if orphaned > 0 {
+		fmt.Fprintf(o.Stderr, "warning: %s no served API group matches, their controllers may no longer exist\n", pluralize(orphaned, "finalizer", "finalizers"))
+	}
+
+	if o.Output == "json" {
+		encoder := json.NewEncoder(o.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(finalizers)
+	}
+	tabwriter := printers.GetNewTabWriter(o.Stdout)
+	fmt.Fprintln(tabwriter, "FINALIZER\tOBJECTS\tDELETING\tNAMESPACES\tCONTROLLER")
+	for _, finalizer := range finalizers {
+		controller := "-"
+		if finalizer.Orphaned {
+			controller = "missing"
+		}
+		fmt.Fprintf(tabwriter, "%s\t%d\t%d\t%s\t%s\n", finalizer.Finalizer, finalizer.Objects, finalizer.Deleting, namespaceCounts(finalizer.Namespaces, 3), controller)
+	}
+	return tabwriter.Flush()
+}
+
+// finalizerServed returns false if finalizer is qualified by a domain, such as example.com/cleanup,
+// and none of groups is the domain, a subdomain of it, or a parent domain of it.
+// Unqualified finalizers, such as foregroundDeletion, and finalizers of Kubernetes domains are built in, and always served.
+func finalizerServed(finalizer string, groups []string) bool {
+	domain := finalizer
+	if i := strings.Index(finalizer, "/"); i >= 0 {
+		domain = finalizer[:i]
+	}
+	if !strings.Contains(domain, ".") {
+		return true
+	}
+	for _, builtin := range []string{"kubernetes.io", "k8s.io"} {
+		if domain == builtin || strings.HasSuffix(domain, "."+builtin) {
+			return true
+		}
+	}
+	for _, group := range groups {
+		if group == "" {
+			continue
+		}
+		if domain == group || strings.HasSuffix(domain, "."+group) || strings.HasSuffix(group, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceCounts returns the max namespaces with the most objects as namespace=count, noting how many more there are.
+// Cluster-scoped objects are counted as <cluster>.
+func namespaceCounts(namespaces map[string]int, max int) string {
+	names := make([]string, 0, len(namespaces))
+	for namespace := range namespaces {
+		names = append(names, namespace)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if namespaces[names[i]] != namespaces[names[j]] {
+			return namespaces[names[i]] > namespaces[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	counts := []string{}
+	for i, namespace := range names {
+		if i == max {
+			counts = append(counts, fmt.Sprintf("+%d more", len(names)-max))
+			break
+		}
+		name := namespace
+		if name == "" {
+			name = "<cluster>"
+		}
+		counts = append(counts, fmt.Sprintf("%s=%d", name, namespaces[namespace]))
+	}
+	return strings.Join(counts, ",")
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestFinalizers(t *testing.T) {
+	gcVerbs := []string{"get", "list", "delete"}
+	discoveryClient := &fake.FakeDiscovery{Fake: &coretesting.Fake{}}
+	discoveryClient.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: gcVerbs},
+			{Name: "namespaces", Kind: "Namespace", Verbs: gcVerbs},
+		}},
+		{GroupVersion: "widgets.example.com/v1", APIResources: []metav1.APIResource{{Name: "widgets", Namespaced: true, Kind: "Widget", Verbs: gcVerbs}}},
+	}
+	metadataClient := metadatafake.NewSimpleMetadataClient(runtime.NewScheme())
+	deleting := metav1.Now()
+	create := func(gvr schema.GroupVersionResource, namespace, name string, deletionTimestamp *metav1.Time, finalizers ...string) {
+		t.Helper()
+		client := metadataClient.Resource(gvr).Namespace(namespace)
+		if _, err := client.(metadatafake.MetadataClient).CreateFake(&metav1.PartialObjectMetadata{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, DeletionTimestamp: deletionTimestamp, Finalizers: finalizers},
+		}, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configmaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	create(schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}, "", "ns1", nil, "kubernetes")
+	create(configmaps, "ns1", "cm1", nil, "example.com/cleanup", "gone.io/cleanup")
+	create(configmaps, "ns1", "cm2", &deleting, "gone.io/cleanup")
+	create(configmaps, "ns2", "cm3", nil, "gone.io/cleanup")
+	create(schema.GroupVersionResource{Group: "widgets.example.com", Version: "v1", Resource: "widgets"}, "ns1", "widget1", nil, "widgets.example.com/finalizer")
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	opts := &FinalizersOptions{
+		DiscoveryClient: discoveryClient,
+		MetadataClient:  metadataClient,
+		Output:          "json",
+		Stderr:          stderr,
+		Stdout:          stdout,
+	}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := opts.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	finalizers := []FinalizerCount{}
+	if err := json.Unmarshal(stdout.Bytes(), &finalizers); err != nil {
+		t.Fatal(err)
+	}
+	expected := []FinalizerCount{
+		{Finalizer: "example.com/cleanup", Objects: 1, Namespaces: map[string]int{"ns1": 1}},
+		{Finalizer: "gone.io/cleanup", Objects: 3, Deleting: 1, Namespaces: map[string]int{"ns1": 2, "ns2": 1}, Orphaned: true},
+		{Finalizer: "kubernetes", Objects: 1, Namespaces: map[string]int{"": 1}},
+		{Finalizer: "widgets.example.com/finalizer", Objects: 1, Namespaces: map[string]int{"ns1": 1}},
+	}
+	if diff := cmp.Diff(expected, finalizers); diff != "" {
+		t.Errorf("unexpected finalizers (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(stderr.String(), "warning: 1 finalizer no served API group matches") {
+		t.Errorf("expected orphaned finalizer warning, got %q", stderr.String())
+	}
+
+	stdout.Reset()
+	opts.Output = ""
+	if err := opts.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "gone.io/cleanup                 3         1          ns1=2,ns2=1   missing") {
+		t.Errorf("unexpected table:\n%s", stdout.String())
+	}
+}
+
+func TestFinalizerServed(t *testing.T) {
+	groups := []string{"", "apps", "widgets.example.com", "cert-manager.io"}
+	for finalizer, expected := range map[string]bool{
+		"foregroundDeletion":                         true,
+		"kubernetes.io/pvc-protection":               true,
+		"batch.kubernetes.io/job-tracking":           true,
+		"customresourcecleanup.apiextensions.k8s.io": true,
+		"example.com/cleanup":                        true,
+		"widgets.example.com/finalizer":              true,
+		"acme.cert-manager.io/finalizer":             true,
+		"gone.io/cleanup":                            false,
+		"cluster.x-k8s.io":                           false,
+	} {
+		if actual := finalizerServed(finalizer, groups); actual != expected {
+			t.Errorf("%s: expected served %v, got %v", finalizer, expected, actual)
+		}
+	}
+}
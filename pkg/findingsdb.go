@@ -0,0 +1,360 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/printers"
+
+	// registers the pure Go "sqlite" database/sql driver, so binaries can still be cross-compiled without cgo
+	_ "modernc.org/sqlite"
+)
+
+// findingsDBSchema creates the tables of a findings database, if they do not exist
+const findingsDBSchema = `
+CREATE TABLE IF NOT EXISTS scans (
+	cluster TEXT NOT NULL,
+	scan_id TEXT NOT NULL,
+	completion_time INTEGER NOT NULL,
+	duration_seconds REAL NOT NULL,
+	errors INTEGER NOT NULL,
+	warnings INTEGER NOT NULL,
+	PRIMARY KEY (cluster, scan_id)
+);
+CREATE TABLE IF NOT EXISTS findings (
+	cluster TEXT NOT NULL,
+	scan_id TEXT NOT NULL,
+	resource_group TEXT NOT NULL,
+	resource_version TEXT NOT NULL,
+	resource TEXT NOT NULL,
+	namespace TEXT NOT NULL,
+	name TEXT NOT NULL,
+	uid TEXT NOT NULL,
+	owner_api_version TEXT NOT NULL,
+	owner_kind TEXT NOT NULL,
+	owner_name TEXT NOT NULL,
+	owner_uid TEXT NOT NULL,
+	level TEXT NOT NULL,
+	code TEXT NOT NULL,
+	message TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS findings_scan ON findings (cluster, scan_id);
+`
+
+// FindingsDBOptions controls recording the findings of every scan in a SQLite database, for trend reporting
+type FindingsDBOptions struct {
+	// Path is the database file, created if it does not exist
+	Path string
+	// Cluster identifies the cluster scans are recorded for
+	Cluster string
+}
+
+// Validate ensures the specified options are valid
+func (o *FindingsDBOptions) Validate() error {
+	if o.Path == "" {
+		return fmt.Errorf("findings database path is required")
+	}
+	if o.Cluster == "" {
+		return fmt.Errorf("findings database cluster is required")
+	}
+	return nil
+}
+
+// openFindingsDB opens the findings database at path, creating its tables if needed
+func openFindingsDB(ctx context.Context, path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, findingsDBSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating tables in %s: %v", path, err)
+	}
+	return db, nil
+}
+
+// scanID identifies a scan of a cluster by the ID of the scan, or by its completion time for reports without one
+func scanID(result *Report) string {
+	if result.Scan.ID != "" {
+		return result.Scan.ID
+	}
+	return result.CompletionTime.UTC().Format("20060102T150405Z")
+}
+
+// publish records the scan and findings of result, replacing a scan of the cluster with the same id
+func (o *FindingsDBOptions) publish(ctx context.Context, result *Report) error {
+	db, err := openFindingsDB(ctx, o.Path)
+	if err != nil {
+		return fmt.Errorf("error recording findings in %s: %v", o.Path, err)
+	}
+	defer db.Close()
+	if err := o.record(ctx, db, result); err != nil {
+		return fmt.Errorf("error recording findings in %s: %v", o.Path, err)
+	}
+	return nil
+}
+
+func (o *FindingsDBOptions) record(ctx context.Context, db *sql.DB, result *Report) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	id := scanID(result)
+	if _, err := tx.ExecContext(ctx, `DELETE FROM findings WHERE cluster = ? AND scan_id = ?`, o.Cluster, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT OR REPLACE INTO scans (cluster, scan_id, completion_time, duration_seconds, errors, warnings) VALUES (?, ?, ?, ?, ?, ?)`,
+		o.Cluster, id, result.CompletionTime.Unix(), result.Duration.Seconds(), result.Errors, result.Warnings,
+	); err != nil {
+		return err
+	}
+	insert, err := tx.PrepareContext(ctx, `INSERT INTO findings (
+		cluster, scan_id, resource_group, resource_version, resource, namespace, name, uid,
+		owner_api_version, owner_kind, owner_name, owner_uid, level, code, message
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+	for _, finding := range result.Findings {
+		if _, err := insert.ExecContext(ctx,
+			o.Cluster, id, finding.Resource.Group, finding.Resource.Version, finding.Resource.Resource, finding.Namespace, finding.Name, string(finding.UID),
+			finding.OwnerReference.APIVersion, finding.OwnerReference.Kind, finding.OwnerReference.Name, string(finding.OwnerReference.UID),
+			finding.Level, finding.Code, finding.Message,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// TrendOptions contains options controlling how trends are reported from a findings database
+type TrendOptions struct {
+	// Path is the findings database file
+	Path string
+	// Cluster optionally limits trends to one cluster
+	Cluster string
+	// By is the finding field counts are grouped by, either 'code' or 'namespace'
+	By string
+	// Period is the length of each point in the trend, one of 'day', 'week', 'month', or 'quarter'.
+	// The last scan of each cluster in a period is counted.
+	Period string
+	// Since optionally excludes scans that completed before this time
+	Since time.Time
+	// Output is the format trends are written to Stdout in, either '' or 'json'
+	Output string
+	Stdout io.Writer
+}
+
+// Validate ensures the specified options are valid
+func (o *TrendOptions) Validate() error {
+	if o.Path == "" {
+		return fmt.Errorf("findings database path is required")
+	}
+	if o.By != "code" && o.By != "namespace" {
+		return fmt.Errorf("invalid grouping, only 'code' and 'namespace' are supported: %v", o.By)
+	}
+	if periodOf(time.Time{}, o.Period) == "" {
+		return fmt.Errorf("invalid period, only 'day', 'week', 'month', and 'quarter' are supported: %v", o.Period)
+	}
+	if o.Output != "" && o.Output != "json" {
+		return fmt.Errorf("invalid output format, only '' and 'json' are supported: %v", o.Output)
+	}
+	if o.Stdout == nil {
+		return fmt.Errorf("stdout is required")
+	}
+	return nil
+}
+
+// TrendPoint counts the findings of one group in the last scan of a cluster in a period
+type TrendPoint struct {
+	Period   string `json:"period"`
+	Cluster  string `json:"cluster"`
+	ScanID   string `json:"scanID"`
+	Key      string `json:"key"`
+	Errors   int    `json:"errors"`
+	Warnings int    `json:"warnings"`
+}
+
+// periodOf returns the label of the period t is in, or ” if period is invalid
+func periodOf(t time.Time, period string) string {
+	t = t.UTC()
+	switch period {
+	case "day":
+		return t.Format("2006-01-02")
+	case "week":
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case "month":
+		return t.Format("2006-01")
+	case "quarter":
+		return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+	default:
+		return ""
+	}
+}
+
+// Run reads the database and writes the trend to Stdout
+func (o *TrendOptions) Run(ctx context.Context) error {
+	points, err := o.Trend(ctx)
+	if err != nil {
+		return err
+	}
+	switch o.Output {
+	case "":
+		if len(points) == 0 {
+			return nil
+		}
+		tabwriter := printers.GetNewTabWriter(o.Stdout)
+		tabwriter.Write([]byte("PERIOD\tCLUSTER\t" + strings.ToUpper(o.By) + "\tERRORS\tWARNINGS\n"))
+		for _, point := range points {
+			tabwriter.Write([]byte(fmt.Sprintf("%s\t%s\t%s\t%d\t%d\n", point.Period, point.Cluster, point.Key, point.Errors, point.Warnings)))
+		}
+		return tabwriter.Flush()
+	case "json":
+		encoder := json.NewEncoder(o.Stdout)
+		for _, point := range points {
+			if err := encoder.Encode(point); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid output format, only '' and 'json' are supported: %v", o.Output)
+	}
+}
+
+// Trend returns the counts of findings in the last scan of each cluster in each period, ordered by period, cluster, and key.
+// Every key found in any counted scan of a cluster is included in each of its periods, so groups that drop to zero are reported.
+func (o *TrendOptions) Trend(ctx context.Context) ([]TrendPoint, error) {
+	// opening a missing database would create it
+	if _, err := os.Stat(o.Path); err != nil {
+		return nil, err
+	}
+	db, err := openFindingsDB(ctx, o.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	// find the last scan of each cluster in each period
+	type periodScan struct {
+		period  string
+		cluster string
+		scanID  string
+	}
+	rows, err := db.QueryContext(ctx,
+		`SELECT cluster, scan_id, completion_time FROM scans WHERE (? = '' OR cluster = ?) AND completion_time >= ? ORDER BY completion_time`,
+		o.Cluster, o.Cluster, o.Since.Unix(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	lastScans := map[[2]string]string{}
+	for rows.Next() {
+		var cluster, id string
+		var completionTime int64
+		if err := rows.Scan(&cluster, &id, &completionTime); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		lastScans[[2]string{periodOf(time.Unix(completionTime, 0), o.Period), cluster}] = id
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	scans := []periodScan{}
+	for key, id := range lastScans {
+		scans = append(scans, periodScan{period: key[0], cluster: key[1], scanID: id})
+	}
+	sort.Slice(scans, func(i, j int) bool {
+		if scans[i].period != scans[j].period {
+			return scans[i].period < scans[j].period
+		}
+		return scans[i].cluster < scans[j].cluster
+	})
+
+	// count findings in each scan by key and level
+	counts := make([]map[string]*TrendPoint, len(scans))
+	clusterKeys := map[string]map[string]bool{}
+	for i, scan := range scans {
+		counts[i] = map[string]*TrendPoint{}
+		if clusterKeys[scan.cluster] == nil {
+			clusterKeys[scan.cluster] = map[string]bool{}
+		}
+		rows, err := db.QueryContext(ctx,
+			// o.By is validated to be a column name
+			`SELECT `+o.By+`, level, COUNT(*) FROM findings WHERE cluster = ? AND scan_id = ? GROUP BY `+o.By+`, level`,
+			scan.cluster, scan.scanID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var key, level string
+			var count int
+			if err := rows.Scan(&key, &level, &count); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			clusterKeys[scan.cluster][key] = true
+			point := counts[i][key]
+			if point == nil {
+				point = &TrendPoint{}
+				counts[i][key] = point
+			}
+			if level == LevelError {
+				point.Errors += count
+			} else {
+				point.Warnings += count
+			}
+		}
+		if err := rows.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	points := []TrendPoint{}
+	for i, scan := range scans {
+		keys := []string{}
+		for key := range clusterKeys[scan.cluster] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			point := TrendPoint{}
+			if counts[i][key] != nil {
+				point = *counts[i][key]
+			}
+			point.Period, point.Cluster, point.ScanID, point.Key = scan.period, scan.cluster, scan.scanID, key
+			points = append(points, point)
+		}
+	}
+	return points, nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFindingsDBTrend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "findings.db")
+	record := func(cluster string, completionTime time.Time, findings ...Finding) {
+		t.Helper()
+		opts := &FindingsDBOptions{Path: path, Cluster: cluster}
+		if err := opts.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if err := opts.publish(context.Background(), &Report{CompletionTime: completionTime, Findings: findings}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notFound := Finding{Namespace: "ns1", Name: "pod1", Level: LevelError, Code: CodeOwnerNotFound}
+	listFailed := Finding{Namespace: "ns2", Name: "pod2", Level: LevelWarning, Code: CodeOwnerListFailed}
+	jan := time.Date(2021, 1, 10, 0, 0, 0, 0, time.UTC)
+	// only the last scan in a period is counted
+	record("prod", jan, notFound)
+	record("prod", jan.Add(24*time.Hour), notFound, notFound, listFailed)
+	record("prod", jan.AddDate(0, 3, 0), notFound)
+	record("staging", jan.AddDate(0, 3, 0))
+	// recording a scan again replaces it
+	record("prod", jan.AddDate(0, 3, 0), listFailed)
+
+	out := &bytes.Buffer{}
+	opts := &TrendOptions{Path: path, By: "code", Period: "quarter", Stdout: out}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	points, err := opts.Trend(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []TrendPoint{
+		{Period: "2021-Q1", Cluster: "prod", ScanID: "20210111T000000Z", Key: CodeOwnerListFailed, Warnings: 1},
+		{Period: "2021-Q1", Cluster: "prod", ScanID: "20210111T000000Z", Key: CodeOwnerNotFound, Errors: 2},
+		{Period: "2021-Q2", Cluster: "prod", ScanID: "20210410T000000Z", Key: CodeOwnerListFailed, Warnings: 1},
+		{Period: "2021-Q2", Cluster: "prod", ScanID: "20210410T000000Z", Key: CodeOwnerNotFound},
+	}
+	if diff := cmp.Diff(expect, points); diff != "" {
+		t.Errorf("unexpected trend (-want +got):\n%s", diff)
+	}
+
+	opts.By, opts.Cluster, opts.Period = "namespace", "prod", "month"
+	if points, err = opts.Trend(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 4 || points[0].Period != "2021-01" || points[0].Key != "ns1" || points[0].Errors != 2 {
+		t.Errorf("unexpected trend by namespace: %#v", points)
+	}
+
+	if err := opts.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(normalize(`
+PERIOD CLUSTER NAMESPACE ERRORS WARNINGS
+2021-01 prod ns1 2 0
+2021-01 prod ns2 0 1
+2021-04 prod ns1 0 0
+2021-04 prod ns2 0 1
+`), normalize(out.String())); diff != "" {
+		t.Errorf("unexpected output (-want +got):\n%s", diff)
+	}
+}
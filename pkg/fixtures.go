@@ -0,0 +1,259 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// redacted replaces sanitized values in recorded responses
+const redacted = "<redacted>"
+
+// Fixtures holds recorded API responses, for reproducing a scan without the cluster
+type Fixtures struct {
+	// Responses are the recorded responses to GET requests, in the order they were first requested
+	Responses []FixtureResponse `json:"responses"`
+
+	byPath map[string]*FixtureResponse
+}
+
+// FixtureResponse is a recorded response to a GET request
+type FixtureResponse struct {
+	// Path is the request path and query, without the server address
+	Path string `json:"path"`
+	// StatusCode is the response status code
+	StatusCode int `json:"statusCode"`
+	// ContentType is the response content type
+	ContentType string `json:"contentType,omitempty"`
+	// Body is the sanitized response body, if it is JSON
+	Body json.RawMessage `json:"body,omitempty"`
+	// Text is the response body, if it is not JSON
+	Text string `json:"text,omitempty"`
+}
+
+// LoadFixtures reads fixtures written by FixtureRecorder.Write from path. Files ending in .gz are decompressed.
+func LoadFixtures(path string) (*Fixtures, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("error reading fixtures %s: %v", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	fixtures := &Fixtures{}
+	if err := json.NewDecoder(r).Decode(fixtures); err != nil {
+		return nil, fmt.Errorf("error reading fixtures %s: %v", path, err)
+	}
+	fixtures.byPath = map[string]*FixtureResponse{}
+	for i := range fixtures.Responses {
+		fixtures.byPath[fixtures.Responses[i].Path] = &fixtures.Responses[i]
+	}
+	return fixtures, nil
+}
+
+// RoundTrip serves the recorded response to req, or a NotFound status if no response was recorded.
+// Fixtures are suitable for use as a rest.Config#Transport.
+func (f *Fixtures) RoundTrip(req *http.Request) (*http.Response, error) {
+	response, ok := f.byPath[fixturePath(req)]
+	if !ok || req.Method != http.MethodGet {
+		message, _ := json.Marshal(fmt.Sprintf("no response recorded for %s %s", req.Method, fixturePath(req)))
+		response = &FixtureResponse{
+			StatusCode:  http.StatusNotFound,
+			ContentType: "application/json",
+			Body:        json.RawMessage(fmt.Sprintf(`{"kind":"Status","apiVersion":"v1","status":"Failure","message":%s,"reason":"NotFound","code":404}`, message)),
+		}
+	}
+	body := []byte(response.Text)
+	if len(response.Body) > 0 {
+		body = response.Body
+	}
+	header := http.Header{}
+	if response.ContentType != "" {
+		header.Set("Content-Type", response.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode)),
+		StatusCode:    response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// FixtureRecorder records the responses to GET requests made through wrapped transports.
+//
+// Responses are requested as JSON and sanitized: annotation values, managed fields, and server addresses are removed,
+// and the server address is not recorded. Object names, namespaces, labels, and ownerReferences are kept.
+type FixtureRecorder struct {
+	lock     sync.Mutex
+	fixtures Fixtures
+}
+
+// NewFixtureRecorder returns a recorder with no recorded responses
+func NewFixtureRecorder() *FixtureRecorder {
+	return &FixtureRecorder{fixtures: Fixtures{Responses: []FixtureResponse{}, byPath: map[string]*FixtureResponse{}}}
+}
+
+// Wrap returns a RoundTripper that records responses to requests made through rt.
+// It is suitable for use as a rest.Config#WrapTransport function.
+func (r *FixtureRecorder) Wrap(rt http.RoundTripper) http.RoundTripper {
+	return &recordingRoundTripper{recorder: r, delegate: rt}
+}
+
+// Write writes the recorded responses to path as JSON, gzip-compressed if path ends in .gz
+func (r *FixtureRecorder) Write(path string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	var w io.WriteCloser = f
+	if strings.HasSuffix(path, ".gz") {
+		w = gzip.NewWriter(f)
+	}
+	if err := json.NewEncoder(w).Encode(&r.fixtures); err != nil {
+		f.Close()
+		return err
+	}
+	if w != f {
+		if err := w.Close(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+func (r *FixtureRecorder) record(response FixtureResponse) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	// retried requests replace earlier responses
+	if existing, ok := r.fixtures.byPath[response.Path]; ok {
+		*existing = response
+		return
+	}
+	r.fixtures.Responses = append(r.fixtures.Responses, response)
+	// appending may move responses, so reindex
+	for i := range r.fixtures.Responses {
+		r.fixtures.byPath[r.fixtures.Responses[i].Path] = &r.fixtures.Responses[i]
+	}
+}
+
+type recordingRoundTripper struct {
+	recorder *FixtureRecorder
+	delegate http.RoundTripper
+}
+
+func (c *recordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return c.delegate.RoundTrip(req)
+	}
+	// request JSON so responses can be sanitized
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept", withoutProtobuf(req.Header.Get("Accept")))
+	resp, err := c.delegate.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	response := FixtureResponse{Path: fixturePath(req), StatusCode: resp.StatusCode, ContentType: resp.Header.Get("Content-Type")}
+	var content interface{}
+	if json.Unmarshal(body, &content) == nil {
+		sanitized, err := json.Marshal(sanitize(content))
+		if err != nil {
+			return nil, err
+		}
+		response.Body = sanitized
+	} else {
+		response.Text = string(body)
+	}
+	c.recorder.record(response)
+	return resp, nil
+}
+
+// fixturePath returns the path and canonical query of req
+func fixturePath(req *http.Request) string {
+	if query := req.URL.Query(); len(query) > 0 {
+		return req.URL.Path + "?" + query.Encode()
+	}
+	return req.URL.Path
+}
+
+// withoutProtobuf removes protobuf media types from an Accept header
+func withoutProtobuf(accept string) string {
+	kept := []string{}
+	for _, mediaType := range strings.Split(accept, ",") {
+		if !strings.HasPrefix(strings.TrimSpace(mediaType), "application/vnd.kubernetes.protobuf") {
+			kept = append(kept, mediaType)
+		}
+	}
+	if len(kept) == 0 {
+		return "application/json"
+	}
+	return strings.Join(kept, ",")
+}
+
+// sanitize removes annotation values, managed fields, and server addresses from decoded JSON content
+func sanitize(content interface{}) interface{} {
+	switch content := content.(type) {
+	case map[string]interface{}:
+		delete(content, "serverAddressByClientCIDRs")
+		if metadata, ok := content["metadata"].(map[string]interface{}); ok {
+			delete(metadata, "managedFields")
+			if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+				for key := range annotations {
+					annotations[key] = redacted
+				}
+			}
+		}
+		for key, value := range content {
+			content[key] = sanitize(value)
+		}
+	case []interface{}:
+		for i, value := range content {
+			content[i] = sanitize(value)
+		}
+	}
+	return content
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+)
+
+func TestRecordReplayFixtures(t *testing.T) {
+	responses := map[string]string{
+		"/api":  `{"kind":"APIVersions","versions":["v1"],"serverAddressByClientCIDRs":[{"clientCIDR":"0.0.0.0/0","serverAddress":"10.0.0.1:6443"}]}`,
+		"/apis": `{"kind":"APIGroupList","apiVersion":"v1","groups":[]}`,
+		"/api/v1": `{"kind":"APIResourceList","groupVersion":"v1","resources":[
+{"name":"pods","singularName":"","namespaced":true,"kind":"Pod","verbs":["delete","get","list","watch"]}
+]}`,
+		"/api/v1/pods": `{"kind":"PartialObjectMetadataList","apiVersion":"meta.k8s.io/v1","metadata":{},"items":[
+{"kind":"PartialObjectMetadata","apiVersion":"meta.k8s.io/v1","metadata":{"name":"p1","namespace":"app","uid":"p1uid","annotations":{"token":"secret-value"},
+ "managedFields":[{"manager":"kubectl","operation":"Update"}]}},
+{"kind":"PartialObjectMetadata","apiVersion":"meta.k8s.io/v1","metadata":{"name":"p2","namespace":"app","uid":"p2uid","labels":{"app":"web"},
+ "ownerReferences":[{"apiVersion":"v1","kind":"Pod","name":"p1","uid":"wronguid"}]}}
+]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.Header.Get("Accept"), "protobuf") {
+			t.Errorf("unexpected protobuf Accept header for %s: %s", r.URL.Path, r.Header.Get("Accept"))
+		}
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	scan := func(config *rest.Config) string {
+		t.Helper()
+		config.AcceptContentTypes = "application/vnd.kubernetes.protobuf,application/json"
+		discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		metadataClient, err := metadata.NewForConfig(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := &VerifyGCOptions{DiscoveryClient: discoveryClient, MetadataClient: metadataClient, Stderr: ioutil.Discard, Stdout: ioutil.Discard}
+		report, err := opts.Scan(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := &bytes.Buffer{}
+		if err := report.Print(out, ""); err != nil {
+			t.Fatal(err)
+		}
+		return out.String()
+	}
+
+	recorder := NewFixtureRecorder()
+	recorded := scan(&rest.Config{Host: server.URL, WrapTransport: recorder.Wrap})
+	expect := `
+GROUP RESOURCE NAMESPACE NAME OWNER_UID LEVEL MESSAGE
+pods app p2 wronguid Error no object found for uid; did you mean app/p1 (uid p1uid)?
+`
+	if diff := cmp.Diff(normalize(expect), normalize(recorded)); diff != "" {
+		t.Errorf("unexpected findings while recording (-want +got):\n%s", diff)
+	}
+
+	path := filepath.Join(t.TempDir(), "fixtures.json.gz")
+	if err := recorder.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	fixtures, err := LoadFixtures(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, response := range fixtures.Responses {
+		for _, sensitive := range []string{"secret-value", "managedFields", "10.0.0.1", server.URL} {
+			if strings.Contains(string(response.Body), sensitive) {
+				t.Errorf("recorded response for %s contains %q: %s", response.Path, sensitive, response.Body)
+			}
+		}
+	}
+
+	replayed := scan(&rest.Config{Host: "http://fixtures.invalid", Transport: fixtures})
+	if diff := cmp.Diff(recorded, replayed); diff != "" {
+		t.Errorf("unexpected findings while replaying (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// gcSyncErrorsMetric counts the failures of the garbage collector to sync its monitors with discovery,
+// which stop it from processing any ownerReferences until a sync succeeds
+const gcSyncErrorsMetric = "garbagecollector_controller_resources_sync_error_total"
+
+// GCHealthOptions contains options controlling how the health of the garbage collector is checked before a scan.
+// The signals checked are not accessible on every cluster, such as managed control planes, and are skipped if not.
+type GCHealthOptions struct {
+	// Leases gets the kube-controller-manager leader election Lease in kube-system
+	Leases coordinationv1client.LeasesGetter
+	// Pods finds kube-controller-manager pods in kube-system, labeled component=kube-controller-manager as kubeadm does,
+	// and proxies to their metrics endpoints, if set
+	Pods corev1client.PodsGetter
+	// SampleInterval, if positive, is how long to wait between two samples of the sync error metric, warning only if it
+	// increased in between. The metric counts every failure since the controller manager started, so without a second
+	// sample a warning may only reflect failures that have long been resolved.
+	SampleInterval time.Duration
+	// Now returns the current time, time.Now if nil
+	Now func() time.Time
+}
+
+// Validate ensures the specified options are valid
+func (o *GCHealthOptions) Validate() error {
+	if o.Leases == nil {
+		return fmt.Errorf("lease client is required")
+	}
+	if o.SampleInterval < 0 {
+		return fmt.Errorf("sample interval must not be negative")
+	}
+	return nil
+}
+
+// check writes a warning to stderr for each sign that the garbage collector is not running or is stuck,
+// in which case findings reflect what the garbage collector has not processed yet rather than invalid ownerReferences.
+// It returns the number of warnings written.
+func (o *GCHealthOptions) check(ctx context.Context, stderr io.Writer) int {
+	now := time.Now
+	if o.Now != nil {
+		now = o.Now
+	}
+	warnings := 0
+	lease, err := o.Leases.Leases(metav1.NamespaceSystem).Get(ctx, "kube-controller-manager", metav1.GetOptions{})
+	switch {
+	case err != nil:
+		// managed control planes often hide the controller manager
+		klog.V(2).Infof("could not check the kube-controller-manager lease: %v", err)
+	case lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == "" || lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil:
+		fmt.Fprintf(stderr, "warning: the kube-controller-manager lease has no holder, the garbage collector may not be running and findings may be stale\n")
+		warnings++
+	default:
+		// allow for a missed renewal and clock skew
+		since := now().Sub(lease.Spec.RenewTime.Time)
+		if since > 2*time.Duration(*lease.Spec.LeaseDurationSeconds)*time.Second {
+			fmt.Fprintf(stderr, "warning: the kube-controller-manager lease was last renewed %v ago, the garbage collector may not be running and findings may be stale\n", since.Round(time.Second))
+			warnings++
+		}
+	}
+
+	if o.Pods == nil {
+		return warnings
+	}
+	pods, err := o.Pods.Pods(metav1.NamespaceSystem).List(ctx, metav1.ListOptions{LabelSelector: "component=kube-controller-manager"})
+	if err != nil {
+		// the check is visibly skipped, rather than silently passing
+		fmt.Fprintf(stderr, "garbage collector sync metrics not accessible, skipped: could not list kube-controller-manager pods: %v\n", err)
+		return warnings
+	}
+	syncErrors := map[string]float64{}
+	var read []string
+	readErr := fmt.Errorf("no running kube-controller-manager pods labeled component=kube-controller-manager")
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		value, err := o.syncErrors(ctx, pod.Name)
+		if err != nil {
+			klog.V(2).Infof("%v", err)
+			readErr = err
+			continue
+		}
+		syncErrors[pod.Name] = value
+		read = append(read, pod.Name)
+	}
+	if len(read) == 0 {
+		// the secure port of kube-controller-manager usually rejects requests proxied by the API server
+		fmt.Fprintf(stderr, "garbage collector sync metrics not accessible, skipped: %v\n", readErr)
+		return warnings
+	}
+	if o.SampleInterval <= 0 {
+		for _, name := range read {
+			if syncErrors[name] > 0 {
+				fmt.Fprintf(stderr, "warning: the garbage collector of %s has failed to sync with discovery %d times since it started, possibly long ago, it does not process ownerReferences while it cannot sync, often because of unavailable aggregated APIs, and findings may be stale\n", name, int64(syncErrors[name]))
+				warnings++
+			}
+		}
+		return warnings
+	}
+	select {
+	case <-ctx.Done():
+		return warnings
+	case <-time.After(o.SampleInterval):
+	}
+	for _, name := range read {
+		value, err := o.syncErrors(ctx, name)
+		// a lower value means the controller manager restarted in between
+		if err != nil || value <= syncErrors[name] {
+			continue
+		}
+		fmt.Fprintf(stderr, "warning: the garbage collector of %s failed to sync with discovery %d times in the last %v, it does not process ownerReferences while it cannot sync, often because of unavailable aggregated APIs, and findings may be stale\n", name, int64(value-syncErrors[name]), o.SampleInterval)
+		warnings++
+	}
+	return warnings
+}
+
+// syncErrors returns the value of the sync error metric of the named kube-controller-manager pod
+func (o *GCHealthOptions) syncErrors(ctx context.Context, name string) (float64, error) {
+	data, err := o.Pods.Pods(metav1.NamespaceSystem).ProxyGet("https", name, "10257", "metrics", nil).DoRaw(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("could not get the metrics of %s: %v", name, err)
+	}
+	return counterValue(data, gcSyncErrorsMetric), nil
+}
+
+// counterValue returns the sum of the samples of the named metric in data, in the Prometheus text format
+func counterValue(data []byte, name string) float64 {
+	total := 0.0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, name) {
+			continue
+		}
+		rest := line[len(name):]
+		if !strings.HasPrefix(rest, " ") && !strings.HasPrefix(rest, "{") {
+			continue
+		}
+		fields := strings.Fields(rest[strings.LastIndex(rest, "}")+1:])
+		if len(fields) == 0 {
+			continue
+		}
+		if value, err := strconv.ParseFloat(fields[0], 64); err == nil {
+			total += value
+		}
+	}
+	return total
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	coretesting "k8s.io/client-go/testing"
+)
+
+// metricsResponse serves metrics as the response of a proxied request
+type metricsResponse string
+
+func (r metricsResponse) DoRaw(context.Context) ([]byte, error) { return []byte(r), nil }
+
+func (r metricsResponse) Stream(context.Context) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(string(r))), nil
+}
+
+// proxyError fails proxied requests, as the secure port of kube-controller-manager usually does
+type proxyError struct{ err error }
+
+func (r proxyError) DoRaw(context.Context) ([]byte, error) { return nil, r.err }
+
+func (r proxyError) Stream(context.Context) (io.ReadCloser, error) { return nil, r.err }
+
+func TestGCHealth(t *testing.T) {
+	now := time.Now()
+	lease := func(holder string, renewed time.Duration) *coordinationv1.Lease {
+		duration := int32(15)
+		renewTime := metav1.NewMicroTime(now.Add(-renewed))
+		return &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "kube-controller-manager"},
+			Spec:       coordinationv1.LeaseSpec{HolderIdentity: &holder, LeaseDurationSeconds: &duration, RenewTime: &renewTime},
+		}
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "kube-controller-manager-node1", Labels: map[string]string{"component": "kube-controller-manager"}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	metrics := `# HELP garbagecollector_controller_resources_sync_error_total [ALPHA] Number of garbage collector resources sync errors
+# TYPE garbagecollector_controller_resources_sync_error_total counter
+garbagecollector_controller_resources_sync_error_total 3
+garbagecollector_controller_resources_sync_error_total_other 100
+`
+	increased := "garbagecollector_controller_resources_sync_error_total 5\n"
+
+	testcases := []struct {
+		name     string
+		objects  []runtime.Object
+		interval time.Duration
+		// metrics are served in turn for each proxied request, the last one repeatedly
+		metrics  []string
+		proxyErr error
+		expected []string
+		// skipped is written when the metrics could not be read
+		skipped string
+	}{
+		{name: "healthy", objects: []runtime.Object{lease("node1", 5*time.Second), pod}, metrics: []string{"garbagecollector_controller_resources_sync_error_total 0\n"}},
+		{name: "inaccessible", skipped: "no running kube-controller-manager pods"},
+		{
+			name:     "metrics rejected",
+			objects:  []runtime.Object{lease("node1", time.Second), pod},
+			proxyErr: fmt.Errorf("Unauthorized"),
+			skipped:  "could not get the metrics of kube-controller-manager-node1: Unauthorized",
+		},
+		{
+			name:     "lease not renewed",
+			skipped:  "no running kube-controller-manager pods",
+			objects:  []runtime.Object{lease("node1", 10*time.Minute)},
+			expected: []string{"warning: the kube-controller-manager lease was last renewed 10m0s ago"},
+		},
+		{
+			name:     "lease released",
+			skipped:  "no running kube-controller-manager pods",
+			objects:  []runtime.Object{lease("", time.Second)},
+			expected: []string{"warning: the kube-controller-manager lease has no holder"},
+		},
+		{
+			name:     "sync errors",
+			objects:  []runtime.Object{lease("node1", time.Second), pod},
+			metrics:  []string{metrics},
+			expected: []string{"warning: the garbage collector of kube-controller-manager-node1 has failed to sync with discovery 3 times since it started"},
+		},
+		{
+			name:     "past sync errors",
+			objects:  []runtime.Object{lease("node1", time.Second), pod},
+			interval: time.Millisecond,
+			metrics:  []string{metrics},
+		},
+		{
+			name:     "ongoing sync errors",
+			objects:  []runtime.Object{lease("node1", time.Second), pod},
+			interval: time.Millisecond,
+			metrics:  []string{metrics, increased},
+			expected: []string{"warning: the garbage collector of kube-controller-manager-node1 failed to sync with discovery 2 times in the last 1ms"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(tc.objects...)
+			requests := 0
+			client.AddProxyReactor("pods", func(action coretesting.Action) (bool, rest.ResponseWrapper, error) {
+				if tc.proxyErr != nil {
+					return true, proxyError{tc.proxyErr}, nil
+				}
+				if len(tc.metrics) == 0 {
+					return true, metricsResponse(""), nil
+				}
+				response := tc.metrics[len(tc.metrics)-1]
+				if requests < len(tc.metrics) {
+					response = tc.metrics[requests]
+				}
+				requests++
+				return true, metricsResponse(response), nil
+			})
+			opts := &GCHealthOptions{Leases: client.CoordinationV1(), Pods: client.CoreV1(), SampleInterval: tc.interval, Now: func() time.Time { return now }}
+			if err := opts.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			stderr := &bytes.Buffer{}
+			if warnings := opts.check(context.Background(), stderr); warnings != len(tc.expected) {
+				t.Errorf("expected %d warnings, got %d: %s", len(tc.expected), warnings, stderr.String())
+			}
+			for _, expected := range tc.expected {
+				if !strings.Contains(stderr.String(), expected) {
+					t.Errorf("expected %q, got %q", expected, stderr.String())
+				}
+			}
+			if skipped := strings.Contains(stderr.String(), "garbage collector sync metrics not accessible, skipped"); skipped != (tc.skipped != "") || !strings.Contains(stderr.String(), tc.skipped) {
+				t.Errorf("expected skipped %q, got %q", tc.skipped, stderr.String())
+			}
+		})
+	}
+}
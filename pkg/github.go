@@ -0,0 +1,308 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DefaultGitHubIssueTemplate renders the body of GitHub issues from a GitHubIssue
+const DefaultGitHubIssueTemplate = `Invalid ownerReferences were found {{.Description}}.
+The garbage collector may delete these objects unexpectedly, or never delete them.
+
+| Resource | Namespace | Name | Owner | Code | Message |
+| --- | --- | --- | --- | --- | --- |
+{{range .Findings}}| {{.Resource.Resource}}{{if .Resource.Group}}.{{.Resource.Group}}{{end}} | {{.Namespace}} | {{.Name}} | {{.OwnerReference.Kind}} {{.OwnerReference.Name}} ({{.OwnerReference.UID}}) | {{.Code}} | {{.Message}} |
+{{end}}`
+
+// githubIssueMarker identifies issues opened for a group of findings, and the findings already reported in them
+const githubIssueMarker = "kubectl-check-ownerreferences"
+
+var (
+	githubIssueKeyPattern     = regexp.MustCompile(`<!-- ` + githubIssueMarker + ` key=(\S+) -->`)
+	githubIssueFindingPattern = regexp.MustCompile(`<!-- ` + githubIssueMarker + ` finding=(\S+) -->`)
+)
+
+// GitHubIssueOptions contains options controlling how GitHub issues are opened for new Error-level findings
+type GitHubIssueOptions struct {
+	// Repository is the repository to open issues in, as owner/name
+	Repository string
+	// Token authenticates to the GitHub API, and must be allowed to read and write issues
+	Token string
+	// GroupBy is how findings are grouped into issues, either 'namespace' or 'owner'
+	GroupBy string
+	// Labels are added to opened issues. Open issues are looked up by the first label, so at least one is required.
+	Labels []string
+	// Template is the text/template issue bodies are rendered from, given a GitHubIssue. If empty, DefaultGitHubIssueTemplate is used.
+	Template string
+	// APIURL is the GitHub API URL. If empty, https://api.github.com is used.
+	APIURL string
+	// Client is used to call the GitHub API. If nil, a client with a 30 second timeout is used.
+	Client *http.Client
+
+	template *template.Template
+}
+
+// GitHubIssue is the data issue templates are rendered with
+type GitHubIssue struct {
+	// Namespace is the namespace of the child objects, or "" for cluster-scoped objects
+	Namespace string
+	// Owner is the kind and name of the referenced owner, if findings are grouped by owner
+	Owner string
+	// Description describes the group, such as "in namespace ns1"
+	Description string
+	// Findings are the Error-level findings of the group
+	Findings []Finding
+}
+
+// Validate ensures the specified options are valid
+func (o *GitHubIssueOptions) Validate() error {
+	if parts := strings.Split(o.Repository, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("invalid github repository %q, expected owner/name", o.Repository)
+	}
+	if o.Token == "" {
+		return fmt.Errorf("github token is required")
+	}
+	if o.GroupBy != "namespace" && o.GroupBy != "owner" {
+		return fmt.Errorf("invalid github issue grouping, only 'namespace' and 'owner' are supported: %v", o.GroupBy)
+	}
+	if len(o.Labels) == 0 || o.Labels[0] == "" {
+		return fmt.Errorf("at least one github issue label is required")
+	}
+	if o.APIURL != "" {
+		if u, err := url.Parse(o.APIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid github api url, must be an http or https url: %v", o.APIURL)
+		}
+	}
+	text := o.Template
+	if text == "" {
+		text = DefaultGitHubIssueTemplate
+	}
+	t, err := template.New("issue").Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid github issue template: %v", err)
+	}
+	o.template = t
+	return nil
+}
+
+// githubIssue is an issue in the GitHub REST API
+type githubIssue struct {
+	Number      int             `json:"number,omitempty"`
+	Title       string          `json:"title,omitempty"`
+	Body        string          `json:"body"`
+	Labels      []string        `json:"labels,omitempty"`
+	PullRequest json.RawMessage `json:"pull_request,omitempty"`
+}
+
+// publish opens an issue for each group of Error-level findings in result without an open issue,
+// and updates open issues with findings they do not already report, commenting with the new findings.
+// Issues are not closed when their findings are resolved.
+func (o *GitHubIssueOptions) publish(ctx context.Context, result *Report) error {
+	if o.template == nil {
+		if err := o.Validate(); err != nil {
+			return err
+		}
+	}
+	groups := o.group(result.Findings)
+	if len(groups) == 0 {
+		return nil
+	}
+	open, err := o.openIssues(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing github issues: %v", err)
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		group := groups[key]
+		body, err := o.body(key, group)
+		if err != nil {
+			return err
+		}
+		existing, ok := open[key]
+		if !ok {
+			issue := githubIssue{Title: "Invalid ownerReferences " + group.Description, Body: body, Labels: o.Labels}
+			if err := o.call(ctx, "POST", "/issues", issue, nil); err != nil {
+				return fmt.Errorf("error opening github issue for findings %s: %v", group.Description, err)
+			}
+			continue
+		}
+
+		reported := map[string]bool{}
+		for _, match := range githubIssueFindingPattern.FindAllStringSubmatch(existing.Body, -1) {
+			reported[match[1]] = true
+		}
+		newFindings := []Finding{}
+		for _, finding := range group.Findings {
+			if !reported[findingFingerprint(finding)] {
+				newFindings = append(newFindings, finding)
+			}
+		}
+		if len(newFindings) == 0 {
+			continue
+		}
+		path := fmt.Sprintf("/issues/%d", existing.Number)
+		if err := o.call(ctx, "PATCH", path, githubIssue{Body: body}, nil); err != nil {
+			return fmt.Errorf("error updating github issue #%d: %v", existing.Number, err)
+		}
+		comment := &strings.Builder{}
+		fmt.Fprintf(comment, "%s found:\n\n", pluralize(len(newFindings), "new invalid ownerReference", "new invalid ownerReferences"))
+		for _, finding := range newFindings {
+			resource := schema.GroupResource{Group: finding.Resource.Group, Resource: finding.Resource.Resource}
+			fmt.Fprintf(comment, "* `%s` %s: %s\n", resource, namespacedName(finding.Namespace, finding.Name), finding.Message)
+		}
+		if err := o.call(ctx, "POST", path+"/comments", map[string]string{"body": comment.String()}, nil); err != nil {
+			return fmt.Errorf("error commenting on github issue #%d: %v", existing.Number, err)
+		}
+	}
+	return nil
+}
+
+// group returns the Error-level findings by issue key
+func (o *GitHubIssueOptions) group(findings []Finding) map[string]*GitHubIssue {
+	groups := map[string]*GitHubIssue{}
+	for _, finding := range findings {
+		if finding.Level != LevelError {
+			continue
+		}
+		issue := &GitHubIssue{Namespace: finding.Namespace}
+		key := "namespace/" + finding.Namespace
+		switch {
+		case o.GroupBy == "owner":
+			issue.Owner = finding.OwnerReference.Kind + " " + finding.OwnerReference.Name
+			key = "owner/" + finding.OwnerReference.APIVersion + "/" + finding.OwnerReference.Kind + "/" + finding.Namespace + "/" + finding.OwnerReference.Name
+			issue.Description = fmt.Sprintf("to %s %s", finding.OwnerReference.Kind, namespacedName(finding.Namespace, finding.OwnerReference.Name))
+		case finding.Namespace == "":
+			issue.Description = "in cluster-scoped objects"
+		default:
+			issue.Description = "in namespace " + finding.Namespace
+		}
+		if groups[key] == nil {
+			groups[key] = issue
+		}
+		groups[key].Findings = append(groups[key].Findings, finding)
+	}
+	return groups
+}
+
+// body renders the body of the issue for a group, with markers identifying the group and its findings
+func (o *GitHubIssueOptions) body(key string, group *GitHubIssue) (string, error) {
+	b := &strings.Builder{}
+	if err := o.template.Execute(b, group); err != nil {
+		return "", fmt.Errorf("error rendering github issue template: %v", err)
+	}
+	fmt.Fprintf(b, "\n<!-- %s key=%s -->\n", githubIssueMarker, url.PathEscape(key))
+	for _, finding := range group.Findings {
+		fmt.Fprintf(b, "<!-- %s finding=%s -->\n", githubIssueMarker, findingFingerprint(finding))
+	}
+	return b.String(), nil
+}
+
+// openIssues returns the open issues with the first label, by the key in their marker
+func (o *GitHubIssueOptions) openIssues(ctx context.Context) (map[string]githubIssue, error) {
+	open := map[string]githubIssue{}
+	for page := 1; ; page++ {
+		issues := []githubIssue{}
+		path := fmt.Sprintf("/issues?state=open&labels=%s&per_page=100&page=%d", url.QueryEscape(o.Labels[0]), page)
+		if err := o.call(ctx, "GET", path, nil, &issues); err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			if issue.PullRequest != nil {
+				continue
+			}
+			if match := githubIssueKeyPattern.FindStringSubmatch(issue.Body); match != nil {
+				if key, err := url.PathUnescape(match[1]); err == nil {
+					open[key] = issue
+				}
+			}
+		}
+		if len(issues) < 100 {
+			return open, nil
+		}
+	}
+}
+
+// call makes a request to path in the repository, encoding in as the request body if set, and decoding the response into out if set
+func (o *GitHubIssueOptions) call(ctx context.Context, method, path string, in, out interface{}) error {
+	apiURL := o.APIURL
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(apiURL, "/")+"/repos/"+o.Repository+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Authorization", "token "+o.Token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := o.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// findingFingerprint identifies a finding across scans in issue markers
+func findingFingerprint(finding Finding) string {
+	k := keyOf(finding)
+	sum := sha256.Sum256([]byte(strings.Join([]string{k.cluster, k.group, k.resource, k.namespace, k.name, k.owner, k.code}, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
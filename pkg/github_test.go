@@ -0,0 +1,134 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeGitHub serves the issues API for one repository
+type fakeGitHub struct {
+	lock     sync.Mutex
+	issues   []githubIssue
+	comments map[int][]string
+	requests []string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if r.Header.Get("Authorization") != "token secret" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	body := map[string]interface{}{}
+	if r.Body != nil && r.Method != "GET" {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	var number int
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/repos/o/r/issues":
+		if r.URL.Query().Get("labels") != "invalid-ownerreferences" || r.URL.Query().Get("state") != "open" {
+			http.Error(w, "unexpected query", http.StatusBadRequest)
+			return
+		}
+		issues := append([]githubIssue{{Number: 100, Body: "<!-- kubectl-check-ownerreferences key=namespace%2Fns1 -->", PullRequest: json.RawMessage(`{}`)}}, f.issues...)
+		json.NewEncoder(w).Encode(issues)
+	case r.Method == "POST" && r.URL.Path == "/repos/o/r/issues":
+		f.issues = append(f.issues, githubIssue{Number: len(f.issues) + 1, Title: body["title"].(string), Body: body["body"].(string)})
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, "{}")
+	default:
+		if _, err := fmt.Sscanf(r.URL.Path, "/repos/o/r/issues/%d", &number); err != nil || number < 1 || number > len(f.issues) {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == "PATCH" {
+			f.issues[number-1].Body = body["body"].(string)
+		} else if r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/comments") {
+			f.comments[number] = append(f.comments[number], body["body"].(string))
+		} else {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "{}")
+	}
+}
+
+func TestGitHubIssues(t *testing.T) {
+	github := &fakeGitHub{comments: map[int][]string{}}
+	server := httptest.NewServer(github)
+	defer server.Close()
+
+	opts := &GitHubIssueOptions{Repository: "o/r", Token: "secret", GroupBy: "namespace", Labels: []string{"invalid-ownerreferences"}, APIURL: server.URL}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	pods := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	finding := func(namespace, name, level string) Finding {
+		return Finding{Resource: pods, Namespace: namespace, Name: name, OwnerReference: metav1.OwnerReference{Kind: "ReplicaSet", Name: "rs", UID: "rsuid"}, Level: level, Code: CodeOwnerNotFound, Message: "no object found for uid"}
+	}
+	publish := func(findings ...Finding) {
+		t.Helper()
+		github.requests = nil
+		if err := opts.publish(context.Background(), &Report{Findings: findings}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// an issue is opened per namespace with errors
+	publish(finding("ns1", "pod1", LevelError), finding("ns2", "pod2", LevelWarning))
+	if len(github.issues) != 1 || github.issues[0].Title != "Invalid ownerReferences in namespace ns1" || !strings.Contains(github.issues[0].Body, "| pods | ns1 | pod1 | ReplicaSet rs (rsuid) | OwnerNotFound |") {
+		t.Fatalf("unexpected issues: %#v", github.issues)
+	}
+
+	// findings already reported by an open issue do not update it
+	publish(finding("ns1", "pod1", LevelError))
+	if diff := cmp.Diff([]string{"GET /repos/o/r/issues"}, github.requests); diff != "" {
+		t.Errorf("unexpected requests (-want +got):\n%s", diff)
+	}
+
+	// new findings update the open issue and are commented on
+	publish(finding("ns1", "pod1", LevelError), finding("ns1", "pod3", LevelError))
+	if diff := cmp.Diff([]string{"GET /repos/o/r/issues", "PATCH /repos/o/r/issues/1", "POST /repos/o/r/issues/1/comments"}, github.requests); diff != "" {
+		t.Errorf("unexpected requests (-want +got):\n%s", diff)
+	}
+	if len(github.issues) != 1 || !strings.Contains(github.issues[0].Body, "pod3") {
+		t.Errorf("expected the issue to be updated with pod3: %#v", github.issues)
+	}
+	if diff := cmp.Diff([]string{"1 new invalid ownerReference found:\n\n* `pods` ns1/pod3: no object found for uid\n"}, github.comments[1]); diff != "" {
+		t.Errorf("unexpected comments (-want +got):\n%s", diff)
+	}
+
+	// grouping by owner opens an issue per owner
+	opts.GroupBy = "owner"
+	publish(finding("ns1", "pod1", LevelError))
+	if len(github.issues) != 2 || github.issues[1].Title != "Invalid ownerReferences to ReplicaSet ns1/rs" {
+		t.Errorf("unexpected issues: %#v", github.issues)
+	}
+}
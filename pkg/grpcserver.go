@@ -0,0 +1,212 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"crypto/subtle"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg/api/v1alpha1"
+)
+
+// watchBuffer is the number of events buffered for each Watch stream before it is ended for not keeping up
+const watchBuffer = 1000
+
+// findingsServer implements the Findings gRPC service in serve mode
+type findingsServer struct {
+	v1alpha1.UnimplementedFindingsServer
+
+	serve *ServeOptions
+	// scans holds a token while an on-demand scan runs, so they run one at a time
+	scans chan struct{}
+
+	lock     sync.Mutex
+	watchers map[chan *v1alpha1.ScanEvent]bool
+}
+
+func newFindingsServer(s *ServeOptions) *findingsServer {
+	return &findingsServer{
+		serve:    s,
+		scans:    make(chan struct{}, 1),
+		watchers: map[chan *v1alpha1.ScanEvent]bool{},
+	}
+}
+
+// server returns a gRPC server for the Findings service, requiring the configured bearer token if one is set
+func (f *findingsServer) server() *grpc.Server {
+	opts := []grpc.ServerOption{}
+	if f.serve.AuthToken != "" {
+		opts = append(opts, grpc.StreamInterceptor(f.authorize))
+	}
+	server := grpc.NewServer(opts...)
+	v1alpha1.RegisterFindingsServer(server, f)
+	return server
+}
+
+func (f *findingsServer) authorize(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	md, _ := grpcmetadata.FromIncomingContext(stream.Context())
+	values := md.Get("authorization")
+	if len(values) != 1 || subtle.ConstantTimeCompare([]byte(values[0]), []byte("Bearer "+f.serve.AuthToken)) != 1 {
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	return handler(srv, stream)
+}
+
+// Watch streams the events of scheduled scans, starting with the next scan
+func (f *findingsServer) Watch(req *v1alpha1.WatchRequest, stream v1alpha1.Findings_WatchServer) error {
+	events := make(chan *v1alpha1.ScanEvent, watchBuffer)
+	f.lock.Lock()
+	f.watchers[events] = true
+	f.lock.Unlock()
+	defer func() {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+		delete(f.watchers, events)
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "scan events were not received fast enough")
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// broadcast sends event to all Watch streams, ending streams that have not kept up
+func (f *findingsServer) broadcast(event *v1alpha1.ScanEvent) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for events := range f.watchers {
+		select {
+		case events <- event:
+		default:
+			close(events)
+			delete(f.watchers, events)
+		}
+	}
+}
+
+// Scan runs an on-demand scan limited to the requested scope, streaming its events
+func (f *findingsServer) Scan(req *v1alpha1.ScanRequest, stream v1alpha1.Findings_ScanServer) error {
+	ctx := stream.Context()
+	select {
+	case f.scans <- struct{}{}:
+		defer func() { <-f.scans }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	scope := &ScanScope{Namespaces: req.Namespaces}
+	for _, resource := range req.Resources {
+		scope.Resources = append(scope.Resources, schema.GroupResource{Group: resource.Group, Resource: resource.Resource})
+	}
+	opts := *f.serve.Verify
+	opts.Stdout = io.Discard
+	opts.Scope = scope
+
+	// the first error sending an event ends the stream
+	var sendErr error
+	send := func(event *v1alpha1.ScanEvent) {
+		if sendErr == nil {
+			sendErr = stream.Send(event)
+		}
+	}
+	opts.onListed = func(gvr schema.GroupVersionResource, objects int, err error) {
+		send(listedEvent(gvr, objects, err))
+	}
+	send(startedEvent(time.Now()))
+	result, err := opts.Scan(ctx)
+	for _, event := range resultEvents(result, err) {
+		send(event)
+	}
+	return sendErr
+}
+
+func startedEvent(start time.Time) *v1alpha1.ScanEvent {
+	return &v1alpha1.ScanEvent{Event: &v1alpha1.ScanEvent_Started{Started: &v1alpha1.ScanStarted{StartTime: timestamppb.New(start)}}}
+}
+
+func listedEvent(gvr schema.GroupVersionResource, objects int, err error) *v1alpha1.ScanEvent {
+	listed := &v1alpha1.ResourceListed{
+		Resource: &v1alpha1.GroupVersionResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource},
+		Objects:  int64(objects),
+	}
+	if err != nil {
+		listed.Error = err.Error()
+	}
+	return &v1alpha1.ScanEvent{Event: &v1alpha1.ScanEvent_Listed{Listed: listed}}
+}
+
+// resultEvents returns the finding events and completed event of a scan that returned result and err
+func resultEvents(result *Report, err error) []*v1alpha1.ScanEvent {
+	if err != nil || result == nil {
+		completed := &v1alpha1.ScanCompleted{CompletionTime: timestamppb.Now()}
+		if err != nil {
+			completed.Error = err.Error()
+		}
+		return []*v1alpha1.ScanEvent{{Event: &v1alpha1.ScanEvent_Completed{Completed: completed}}}
+	}
+	events := make([]*v1alpha1.ScanEvent, 0, len(result.Findings)+1)
+	for _, finding := range result.Findings {
+		events = append(events, &v1alpha1.ScanEvent{Event: &v1alpha1.ScanEvent_Finding{Finding: findingMessage(finding)}})
+	}
+	return append(events, &v1alpha1.ScanEvent{Event: &v1alpha1.ScanEvent_Completed{Completed: &v1alpha1.ScanCompleted{
+		CompletionTime:  timestamppb.New(result.CompletionTime),
+		DurationSeconds: result.Duration.Seconds(),
+		Errors:          int64(result.Errors),
+		Warnings:        int64(result.Warnings),
+		Interrupted:     result.Interrupted,
+	}}})
+}
+
+func findingMessage(finding Finding) *v1alpha1.Finding {
+	ownerRef := finding.OwnerReference
+	return &v1alpha1.Finding{
+		Resource:  &v1alpha1.GroupVersionResource{Group: finding.Resource.Group, Version: finding.Resource.Version, Resource: finding.Resource.Resource},
+		Kind:      &v1alpha1.GroupVersionKind{Group: finding.Kind.Group, Version: finding.Kind.Version, Kind: finding.Kind.Kind},
+		Namespace: finding.Namespace,
+		Name:      finding.Name,
+		Uid:       string(finding.UID),
+		OwnerReference: &v1alpha1.OwnerReference{
+			ApiVersion:         ownerRef.APIVersion,
+			Kind:               ownerRef.Kind,
+			Name:               ownerRef.Name,
+			Uid:                string(ownerRef.UID),
+			Controller:         ownerRef.Controller != nil && *ownerRef.Controller,
+			BlockOwnerDeletion: ownerRef.BlockOwnerDeletion != nil && *ownerRef.BlockOwnerDeletion,
+		},
+		Level:   finding.Level,
+		Code:    finding.Code,
+		Message: finding.Message,
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg/api/v1alpha1"
+)
+
+// describeEvents summarizes a stream of scan events for comparison
+func describeEvents(t *testing.T, stream interface {
+	Recv() (*v1alpha1.ScanEvent, error)
+}) []string {
+	t.Helper()
+	got := []string{}
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return got
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch e := event.Event.(type) {
+		case *v1alpha1.ScanEvent_Started:
+			got = append(got, "started")
+		case *v1alpha1.ScanEvent_Listed:
+			got = append(got, fmt.Sprintf("listed %s %d", e.Listed.Resource.Resource, e.Listed.Objects))
+		case *v1alpha1.ScanEvent_Finding:
+			got = append(got, fmt.Sprintf("finding %s/%s %s", e.Finding.Namespace, e.Finding.Name, e.Finding.Code))
+		case *v1alpha1.ScanEvent_Completed:
+			got = append(got, fmt.Sprintf("completed %d errors %q", e.Completed.Errors, e.Completed.Error))
+			return got
+		}
+	}
+}
+
+func TestFindingsServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	verify := newFakeCluster(t, "c", "pod1", "pod2").Verify
+	verify.Stderr = io.Discard
+	verify.Stdout = io.Discard
+	s := &ServeOptions{Verify: verify, Interval: time.Hour, Address: ":0", AuthToken: "secret"}
+	s.findings = newFindingsServer(s)
+	server := s.findings.server()
+	defer server.Stop()
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+
+	conn, err := grpc.DialContext(ctx, "bufconn",
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := v1alpha1.NewFindingsClient(conn)
+
+	// calls require the token
+	stream, err := client.Scan(ctx, &v1alpha1.ScanRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected unauthenticated error, got %v", err)
+	}
+	authCtx := grpcmetadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+
+	// on-demand scans only check objects in scope
+	stream, err = client.Scan(authCtx, &v1alpha1.ScanRequest{Namespaces: []string{"ns2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"started", "listed pods 2", `completed 0 errors ""`}, describeEvents(t, stream)); diff != "" {
+		t.Errorf("unexpected scan events (-want +got):\n%s", diff)
+	}
+	stream, err = client.Scan(authCtx, &v1alpha1.ScanRequest{Namespaces: []string{"ns1"}, Resources: []*v1alpha1.GroupResource{{Resource: "pods"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"started", "listed pods 2", "finding ns1/pod1 OwnerNotFound", "finding ns1/pod2 OwnerNotFound", `completed 2 errors ""`}
+	if diff := cmp.Diff(expect, describeEvents(t, stream)); diff != "" {
+		t.Errorf("unexpected scan events (-want +got):\n%s", diff)
+	}
+
+	// watchers receive the events of scheduled scans
+	watch, err := client.Watch(authCtx, &v1alpha1.WatchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		s.findings.lock.Lock()
+		defer s.findings.lock.Unlock()
+		return len(s.findings.watchers) == 1, nil
+	}); err != nil {
+		t.Fatal("timed out waiting for the watch to start")
+	}
+	s.scan(ctx)
+	if diff := cmp.Diff(expect, describeEvents(t, watch)); diff != "" {
+		t.Errorf("unexpected watch events (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HistoryOptions controls how many completed scans are retained in serve mode
+type HistoryOptions struct {
+	// Size is the number of completed scans to retain. Older scans are pruned.
+	Size int
+	// Dir is optionally a directory to persist retained scans to as report documents, so history survives restarts
+	Dir string
+}
+
+// Validate ensures the specified options are valid
+func (o *HistoryOptions) Validate() error {
+	if o.Size <= 0 {
+		return fmt.Errorf("invalid history size, must be > 0")
+	}
+	return nil
+}
+
+// historyEntry is a retained scan
+type historyEntry struct {
+	id  string
+	doc reportDocument
+}
+
+// scanHistory holds the last completed scans, oldest first
+type scanHistory struct {
+	options *HistoryOptions
+
+	lock  sync.Mutex
+	scans []historyEntry
+}
+
+// historySummary summarizes a retained scan, as served at /results/history
+type historySummary struct {
+	ID              string         `json:"id"`
+	CompletionTime  metav1.Time    `json:"completionTime"`
+	DurationSeconds float64        `json:"durationSeconds"`
+	Errors          int            `json:"errors"`
+	Warnings        int            `json:"warnings"`
+	Complete        bool           `json:"complete"`
+	Codes           map[string]int `json:"codes"`
+}
+
+// historyID identifies a scan in history by its completion time, as reports exported to storage are named
+func historyID(doc reportDocument) string {
+	return doc.CompletionTime.UTC().Format("20060102T150405Z")
+}
+
+// loadHistory returns a history holding the scans retained in options.Dir, if set, pruning scans beyond options.Size
+func loadHistory(options *HistoryOptions) (*scanHistory, error) {
+	h := &scanHistory{options: options}
+	if options.Dir == "" {
+		return h, nil
+	}
+	if err := os.MkdirAll(options.Dir, 0755); err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(options.Dir)
+	if err != nil {
+		return nil, err
+	}
+	// files are named by completion time, and sorted by name
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(options.Dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		doc := reportDocument{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("error reading scan history %s: %v", file.Name(), err)
+		}
+		h.scans = append(h.scans, historyEntry{id: strings.TrimSuffix(file.Name(), ".json"), doc: doc})
+	}
+	return h, h.prune()
+}
+
+// add retains the report document of result, pruning the oldest scans beyond the configured size
+func (h *scanHistory) add(result *Report) error {
+	doc := newReportDocument(result, nil)
+	entry := historyEntry{id: historyID(doc), doc: doc}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.options.Dir != "" {
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomically(filepath.Join(h.options.Dir, entry.id+".json"), data); err != nil {
+			return err
+		}
+	}
+	if n := len(h.scans); n > 0 && h.scans[n-1].id == entry.id {
+		// completed in the same second as the previous scan
+		h.scans[n-1] = entry
+		return nil
+	}
+	h.scans = append(h.scans, entry)
+	return h.prune()
+}
+
+// prune removes the oldest scans beyond the configured size. h.lock must be held, or h not yet shared.
+func (h *scanHistory) prune() error {
+	for len(h.scans) > h.options.Size {
+		if h.options.Dir != "" {
+			if err := os.Remove(filepath.Join(h.options.Dir, h.scans[0].id+".json")); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		h.scans = h.scans[1:]
+	}
+	return nil
+}
+
+// summaries returns summaries of the retained scans, newest first
+func (h *scanHistory) summaries() []historySummary {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	summaries := make([]historySummary, 0, len(h.scans))
+	for i := len(h.scans) - 1; i >= 0; i-- {
+		doc := h.scans[i].doc
+		codes := map[string]int{}
+		for _, finding := range doc.Findings {
+			codes[finding.Code]++
+		}
+		summaries = append(summaries, historySummary{
+			ID:              h.scans[i].id,
+			CompletionTime:  doc.CompletionTime,
+			DurationSeconds: doc.DurationSeconds,
+			Errors:          doc.Errors,
+			Warnings:        doc.Warnings,
+			Complete:        doc.Complete,
+			Codes:           codes,
+		})
+	}
+	return summaries
+}
+
+// get returns the report document of the retained scan with the given id
+func (h *scanHistory) get(id string) (reportDocument, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	i := sort.Search(len(h.scans), func(i int) bool { return h.scans[i].id >= id })
+	if i < len(h.scans) && h.scans[i].id == id {
+		return h.scans[i].doc, true
+	}
+	return reportDocument{}, false
+}
+
+// writeFileAtomically replaces the file at path with data
+func writeFileAtomically(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestScanHistory(t *testing.T) {
+	dir := t.TempDir()
+	history, err := loadHistory(&HistoryOptions{Size: 2, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		report := &Report{CompletionTime: start.Add(time.Duration(i) * time.Hour), Errors: i}
+		for j := 0; j < i; j++ {
+			report.Findings = append(report.Findings, Finding{Name: "pod", Level: LevelError, Code: CodeOwnerNotFound})
+		}
+		if err := history.add(report); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the oldest scan is pruned from memory and disk
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	if diff := cmp.Diff([]string{"20210203T050506Z.json", "20210203T060506Z.json"}, names); diff != "" {
+		t.Errorf("unexpected files (-want +got):\n%s", diff)
+	}
+
+	// history is reloaded after a restart, and served newest first
+	history, err = loadHistory(&HistoryOptions{Size: 2, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &ServeOptions{history: history}
+	server := httptest.NewServer(s.handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/results/history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	summaries := []historySummary{}
+	if err := json.NewDecoder(resp.Body).Decode(&summaries); err != nil {
+		t.Fatal(err)
+	}
+	expect := []historySummary{
+		{ID: "20210203T060506Z", CompletionTime: metav1.NewTime(start.Add(2 * time.Hour)), Errors: 2, Complete: true, Codes: map[string]int{CodeOwnerNotFound: 2}},
+		{ID: "20210203T050506Z", CompletionTime: metav1.NewTime(start.Add(time.Hour)), Errors: 1, Complete: true, Codes: map[string]int{CodeOwnerNotFound: 1}},
+	}
+	if diff := cmp.Diff(expect, summaries, cmp.Comparer(func(a, b metav1.Time) bool { return a.Equal(&b) })); diff != "" {
+		t.Errorf("unexpected summaries (-want +got):\n%s", diff)
+	}
+
+	for path, status := range map[string]int{
+		"/results/history/20210203T050506Z": http.StatusOK,
+		"/results/history/20210203T040506Z": http.StatusNotFound,
+	} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("%s: expected %d, got %d", path, status, resp.StatusCode)
+		}
+	}
+}
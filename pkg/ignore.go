@@ -0,0 +1,83 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// IgnoreFilter drops known-noise findings and failures from reports and counts.
+// Each pattern is a finding code, or a regular expression matched against the messages of findings and failures,
+// and against the resources of failures as resource.group and group/version.
+type IgnoreFilter struct {
+	codes   map[string]bool
+	regexps []*regexp.Regexp
+}
+
+// NewIgnoreFilter compiles patterns into a filter
+func NewIgnoreFilter(patterns []string) (*IgnoreFilter, error) {
+	f := &IgnoreFilter{codes: map[string]bool{}}
+	for _, pattern := range patterns {
+		if pattern == "" {
+			return nil, fmt.Errorf("invalid ignore pattern, must not be empty")
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore pattern %q: %v", pattern, err)
+		}
+		f.codes[pattern] = true
+		f.regexps = append(f.regexps, re)
+	}
+	return f, nil
+}
+
+// matches returns true if any regular expression matches any of values
+func (f *IgnoreFilter) matches(values ...string) bool {
+	for _, re := range f.regexps {
+		for _, value := range values {
+			if value != "" && re.MatchString(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// finding returns true if finding should be ignored
+func (f *IgnoreFilter) finding(finding Finding) bool {
+	return f.codes[finding.Code] || f.matches(finding.Message)
+}
+
+// failure returns true if failure should be ignored
+func (f *IgnoreFilter) failure(failure ScanFailure) bool {
+	gvr := schema.GroupVersionResource{Group: failure.GroupVersionResource.Group, Version: failure.GroupVersionResource.Version, Resource: failure.GroupVersionResource.Resource}
+	return f.matches(failure.Message, gvr.GroupResource().String(), gvr.GroupVersion().String())
+}
+
+// apply returns findings without the ignored findings, and the number of findings ignored
+func (f *IgnoreFilter) apply(findings []Finding) ([]Finding, int) {
+	var kept []Finding
+	for _, finding := range findings {
+		if !f.finding(finding) {
+			kept = append(kept, finding)
+		}
+	}
+	return kept, len(findings) - len(kept)
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestIgnore(t *testing.T) {
+	if _, err := NewIgnoreFilter([]string{"("}); err == nil {
+		t.Error("expected an invalid regular expression to be rejected")
+	}
+
+	scan := func(patterns ...string) (*Report, string) {
+		t.Helper()
+		cluster := newFakeCluster(t, "prod", "pod1")
+		discoveryClient := cluster.Verify.DiscoveryClient.(*fake.FakeDiscovery)
+		discoveryClient.Resources = append(discoveryClient.Resources, &metav1.APIResourceList{
+			GroupVersion: "metrics.k8s.io/v1beta1",
+			APIResources: []metav1.APIResource{{Name: "pods", Namespaced: true, Kind: "PodMetrics", Verbs: []string{"get", "list", "delete"}}},
+		})
+		cluster.Verify.MetadataClient.(*metadatafake.FakeMetadataClient).PrependReactor("list", "pods", func(action coretesting.Action) (bool, runtime.Object, error) {
+			if action.GetResource().Group == "metrics.k8s.io" {
+				return true, nil, fmt.Errorf("the server is currently unable to handle the request")
+			}
+			return false, nil, nil
+		})
+		stderr := &bytes.Buffer{}
+		opts := cluster.Verify
+		opts.Stdout, opts.Stderr = ioutil.Discard, stderr
+		if len(patterns) > 0 {
+			ignore, err := NewIgnoreFilter(patterns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts.Ignore = ignore
+		}
+		if err := opts.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		report, err := opts.run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report, stderr.String()
+	}
+
+	report, _ := scan()
+	if report.Errors != 1 || report.Warnings != 1 || report.Ignored != 0 {
+		t.Fatalf("expected 1 error and 1 warning, got %d errors, %d warnings, %d ignored", report.Errors, report.Warnings, report.Ignored)
+	}
+
+	// a code drops findings, and a regular expression matching the group drops the list failure
+	report, stderr := scan("OwnerNotFound", `metrics\.k8s\.io`)
+	if report.Errors != 0 || report.Warnings != 0 || len(report.Findings) != 0 || len(report.Failures) != 0 || report.Ignored != 2 {
+		t.Errorf("expected everything to be ignored, got %d errors, %d warnings, %d ignored", report.Errors, report.Warnings, report.Ignored)
+	}
+	if strings.Contains(stderr, "could not list") || !strings.Contains(stderr, "2 findings and failures ignored") {
+		t.Errorf("expected ignored failures not to be warned about, got:\n%s", stderr)
+	}
+
+	// regular expressions match messages
+	report, _ = scan("no object found", "unable to handle")
+	if report.Errors != 0 || report.Warnings != 0 || report.Ignored != 2 {
+		t.Errorf("expected messages to match, got %d errors, %d warnings, %d ignored", report.Errors, report.Warnings, report.Ignored)
+	}
+}
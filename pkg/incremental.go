@@ -0,0 +1,245 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+)
+
+// objectKey identifies an object of a resource
+type objectKey struct {
+	resource schema.GroupVersionResource
+	uid      types.UID
+}
+
+// incrementalScan keeps the findings of a completed scan up to date from metadata informers,
+// rechecking only the objects whose metadata changed and the objects that reference them as owners.
+//
+// Rules that look at objects other than the child and its owners through RuleContext.Objects
+// are not re-evaluated when those objects change.
+type incrementalScan struct {
+	verify *VerifyGCOptions
+
+	lock    sync.Mutex
+	checker *checker
+	// objects are the objects in checker.objects
+	objects map[objectKey]*metav1.PartialObjectMetadata
+	// findings are the findings of each object with ownerReferences
+	findings map[objectKey][]Finding
+	// dependents are the objects with ownerReferences to each uid
+	dependents map[types.UID]map[objectKey]bool
+	// failures are the failures of the initial scan
+	failures []ScanFailure
+	stats    ScanStats
+}
+
+// newIncrementalScan starts keeping the findings of report, a scan by v with incremental set, up to date
+// until ctx is done
+func newIncrementalScan(ctx context.Context, v *VerifyGCOptions, report *Report) *incrementalScan {
+	s := &incrementalScan{
+		verify:     v,
+		checker:    report.checker,
+		objects:    map[objectKey]*metav1.PartialObjectMetadata{},
+		findings:   map[objectKey][]Finding{},
+		dependents: map[types.UID]map[objectKey]bool{},
+		failures:   report.Failures,
+		stats:      report.Stats,
+	}
+	for _, gvr := range report.resources {
+		for _, object := range s.checker.objects.ByResource(gvr) {
+			key := objectKey{resource: gvr, uid: object.UID}
+			s.objects[key] = object
+			s.indexDependent(key, object)
+			s.recheck(key)
+		}
+	}
+
+	factory := metadatainformer.NewSharedInformerFactory(v.MetadataClient, 0)
+	for _, gvr := range report.resources {
+		gvr := gvr
+		informer := factory.ForResource(gvr).Informer()
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { s.update(gvr, obj) },
+			UpdateFunc: func(_, obj interface{}) { s.update(gvr, obj) },
+			DeleteFunc: func(obj interface{}) { s.delete(gvr, obj) },
+		})
+		go func() {
+			// objects deleted between the scan and the informer's initial list are not reported as deleted
+			if cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+				s.prune(gvr, informer.GetStore())
+			}
+		}()
+	}
+	factory.Start(ctx.Done())
+	return s
+}
+
+// update indexes and rechecks a gvr object that was added or updated, and rechecks its dependents
+func (s *incrementalScan) update(gvr schema.GroupVersionResource, obj interface{}) {
+	object, ok := obj.(*metav1.PartialObjectMetadata)
+	if !ok {
+		klog.Warningf("expected type *metav1.PartialObjectMetadata, got type %T", obj)
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := objectKey{resource: gvr, uid: object.UID}
+	existing := s.objects[key]
+	if existing != nil && existing.ResourceVersion == object.ResourceVersion {
+		// unchanged since the scan, as delivered by the informer's initial list
+		return
+	}
+	if object.APIVersion == "" && object.Kind == "" {
+		if gvk, err := s.checker.restMapper.KindFor(gvr); err == nil {
+			// the informer's cached object is shared, and must not be modified
+			object = object.DeepCopy()
+			object.APIVersion, object.Kind = gvk.GroupVersion().String(), gvk.Kind
+		}
+	}
+	if existing != nil {
+		s.unindexDependent(key, existing)
+		s.checker.objects.remove(gvr, existing)
+	}
+	s.objects[key] = object
+	s.checker.objects.add(gvr, object)
+	s.indexDependent(key, object)
+	s.recheck(key)
+	s.recheckDependents(object.UID)
+}
+
+// delete removes a deleted gvr object, and rechecks its dependents
+func (s *incrementalScan) delete(gvr schema.GroupVersionResource, obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	object, ok := obj.(*metav1.PartialObjectMetadata)
+	if !ok {
+		klog.Warningf("expected type *metav1.PartialObjectMetadata, got type %T", obj)
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.remove(objectKey{resource: gvr, uid: object.UID})
+}
+
+// prune removes the gvr objects that are not in store, which holds all current gvr objects
+func (s *incrementalScan) prune(gvr schema.GroupVersionResource, store cache.Store) {
+	current := map[types.UID]bool{}
+	for _, obj := range store.List() {
+		if object, ok := obj.(*metav1.PartialObjectMetadata); ok {
+			current[object.UID] = true
+		}
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for key := range s.objects {
+		if key.resource == gvr && !current[key.uid] {
+			s.remove(key)
+		}
+	}
+}
+
+// remove removes the object with key from the index and findings, and rechecks its dependents
+func (s *incrementalScan) remove(key objectKey) {
+	existing := s.objects[key]
+	if existing == nil {
+		return
+	}
+	s.unindexDependent(key, existing)
+	s.checker.objects.remove(key.resource, existing)
+	delete(s.objects, key)
+	delete(s.findings, key)
+	s.recheckDependents(key.uid)
+}
+
+// recheck replaces the findings of the object with key
+func (s *incrementalScan) recheck(key objectKey) {
+	if findings := s.checker.check(key.resource, s.objects[key]); len(findings) > 0 {
+		s.findings[key] = findings
+	} else {
+		delete(s.findings, key)
+	}
+}
+
+// recheckDependents replaces the findings of the objects with ownerReferences to uid
+func (s *incrementalScan) recheckDependents(uid types.UID) {
+	for key := range s.dependents[uid] {
+		s.recheck(key)
+	}
+}
+
+func (s *incrementalScan) indexDependent(key objectKey, object *metav1.PartialObjectMetadata) {
+	for _, ownerRef := range object.OwnerReferences {
+		if s.dependents[ownerRef.UID] == nil {
+			s.dependents[ownerRef.UID] = map[objectKey]bool{}
+		}
+		s.dependents[ownerRef.UID][key] = true
+	}
+}
+
+func (s *incrementalScan) unindexDependent(key objectKey, object *metav1.PartialObjectMetadata) {
+	for _, ownerRef := range object.OwnerReferences {
+		if delete(s.dependents[ownerRef.UID], key); len(s.dependents[ownerRef.UID]) == 0 {
+			delete(s.dependents, ownerRef.UID)
+		}
+	}
+}
+
+// report returns the current findings, with thresholds and the baseline applied as in a full scan.
+// Failures and stats are those of the initial scan.
+func (s *incrementalScan) report() *Report {
+	start := time.Now()
+	s.lock.Lock()
+	findings := []Finding{}
+	for _, objectFindings := range s.findings {
+		findings = append(findings, objectFindings...)
+	}
+	s.lock.Unlock()
+	sortFindings(findings)
+
+	report := &Report{Findings: findings, Failures: s.failures, Stats: s.stats, Warnings: len(s.failures)}
+	if s.verify.RuleConfig != nil {
+		report.Findings = s.verify.RuleConfig.applyThresholds(report.Findings)
+	}
+	if s.verify.Ignore != nil {
+		report.Findings, report.Ignored = s.verify.Ignore.apply(report.Findings)
+	}
+	if s.verify.Baseline != nil {
+		report.unbaselinedFindings = report.Findings
+		report.Findings, report.Baselined = s.verify.Baseline.apply(report.Findings)
+	}
+	for _, finding := range report.Findings {
+		if finding.Level == LevelError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	report.CompletionTime = time.Now()
+	report.Duration = report.CompletionTime.Sub(start)
+	return report
+}
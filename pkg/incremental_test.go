@@ -0,0 +1,80 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+func TestIncrementalScan(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opts := newFakeCluster(t, "c", "pod1", "pod2").Verify
+	opts.Stderr = io.Discard
+	opts.incremental = true
+	report, err := opts.Scan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Errors != 2 {
+		t.Fatalf("expected 2 errors, got %d", report.Errors)
+	}
+	s := newIncrementalScan(ctx, opts, report)
+
+	expectErrors := func(errors int) {
+		t.Helper()
+		if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+			return s.report().Errors == errors, nil
+		}); err != nil {
+			t.Fatalf("expected %d errors, got %d", errors, s.report().Errors)
+		}
+	}
+
+	// creating the missing owner rechecks its dependents
+	podClient := opts.MetadataClient.(*metadatafake.FakeMetadataClient).Resource(schema.GroupVersionResource{Version: "v1", Resource: "pods"}).Namespace("ns1")
+	if _, err := podClient.(metadatafake.MetadataClient).CreateFake(&metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "missing", Namespace: "ns1", UID: "missinguid-c", ResourceVersion: "1"},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectErrors(0)
+
+	// deleting it reports them again
+	if err := podClient.Delete(ctx, "missing", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectErrors(2)
+
+	// a new child with an invalid ownerReference is checked
+	if _, err := podClient.(metadatafake.MetadataClient).CreateFake(&metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "pod3", Namespace: "ns1", UID: "uid-c-pod3", ResourceVersion: "1", OwnerReferences: []metav1.OwnerReference{report.Findings[0].OwnerReference}},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectErrors(3)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// InventoryRecord describes an ownerReference observed by a scan, valid or not
+type InventoryRecord struct {
+	// Resource, Namespace, Name, and UID identify the child object with the ownerReference
+	Resource  metav1.GroupVersionResource `json:"resource"`
+	Namespace string                      `json:"namespace,omitempty"`
+	Name      string                      `json:"name"`
+	UID       types.UID                   `json:"uid"`
+	// Owner is the ownerReference
+	Owner InventoryOwner `json:"owner"`
+	// Codes are the codes of the findings reported for the ownerReference, empty if none were
+	Codes []string `json:"codes,omitempty"`
+}
+
+// InventoryOwner is an ownerReference in an InventoryRecord
+type InventoryOwner struct {
+	APIVersion         string    `json:"apiVersion"`
+	Kind               string    `json:"kind"`
+	Name               string    `json:"name"`
+	UID                types.UID `json:"uid"`
+	Controller         bool      `json:"controller"`
+	BlockOwnerDeletion bool      `json:"blockOwnerDeletion"`
+}
+
+// inventoryOf returns a record for each ownerReference of the children of gvrs in scope, with the codes of findings
+func inventoryOf(gvrs []schema.GroupVersionResource, children *ObjectIndex, scope *ScanScope, findings []Finding) []InventoryRecord {
+	type refKey struct {
+		child, owner types.UID
+	}
+	codes := map[refKey][]string{}
+	for _, finding := range findings {
+		key := refKey{finding.UID, finding.OwnerReference.UID}
+		codes[key] = append(codes[key], finding.Code)
+	}
+	records := []InventoryRecord{}
+	for _, gvr := range gvrs {
+		for _, child := range children.ByResource(gvr) {
+			if !scope.includes(gvr, child.Namespace) {
+				continue
+			}
+			for _, ownerRef := range child.OwnerReferences {
+				records = append(records, InventoryRecord{
+					Resource:  metav1.GroupVersionResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource},
+					Namespace: child.Namespace,
+					Name:      child.Name,
+					UID:       child.UID,
+					Owner: InventoryOwner{
+						APIVersion:         ownerRef.APIVersion,
+						Kind:               ownerRef.Kind,
+						Name:               ownerRef.Name,
+						UID:                ownerRef.UID,
+						Controller:         ownerRef.Controller != nil && *ownerRef.Controller,
+						BlockOwnerDeletion: ownerRef.BlockOwnerDeletion != nil && *ownerRef.BlockOwnerDeletion,
+					},
+					Codes: codes[refKey{child.UID, ownerRef.UID}],
+				})
+			}
+		}
+	}
+	return records
+}
+
+// writeInventory replaces the file at path with records, as newline-delimited JSON
+func writeInventory(path string, records []InventoryRecord) error {
+	data := &bytes.Buffer{}
+	encoder := json.NewEncoder(data)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomically(path, data.Bytes())
+}
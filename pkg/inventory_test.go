@@ -0,0 +1,85 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+func TestRunInventory(t *testing.T) {
+	cluster := newFakeCluster(t, "prod", "pod1")
+	controller := true
+	podClient := cluster.Verify.MetadataClient.Resource(schema.GroupVersionResource{Version: "v1", Resource: "pods"}).Namespace("ns1")
+	if _, err := podClient.(metadatafake.MetadataClient).CreateFake(&metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "pod2", Namespace: "ns1", UID: "uid-prod-pod2", OwnerReferences: []metav1.OwnerReference{
+			{APIVersion: "v1", Kind: "Pod", Name: "pod1", UID: "uid-prod-pod1", Controller: &controller},
+		}},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "inventory.json")
+	opts := *cluster.Verify
+	opts.Stdout, opts.Stderr, opts.Inventory = ioutil.Discard, ioutil.Discard, path
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opts.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records := []InventoryRecord{}
+	decoder := json.NewDecoder(f)
+	for decoder.More() {
+		record := InventoryRecord{}
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	pods := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	expected := []InventoryRecord{
+		{
+			Resource: pods, Namespace: "ns1", Name: "pod1", UID: "uid-prod-pod1",
+			Owner: InventoryOwner{APIVersion: "v1", Kind: "Pod", Name: "missing", UID: "missinguid-prod"},
+			Codes: []string{CodeOwnerNotFound},
+		},
+		{
+			Resource: pods, Namespace: "ns1", Name: "pod2", UID: "uid-prod-pod2",
+			Owner: InventoryOwner{APIVersion: "v1", Kind: "Pod", Name: "pod1", UID: "uid-prod-pod1", Controller: true},
+		},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected inventory\n%#v\ngot\n%#v", expected, records)
+	}
+}
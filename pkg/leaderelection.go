@@ -0,0 +1,123 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	klog "k8s.io/klog/v2"
+)
+
+// LeaderElectionOptions controls electing a single instance to scan among replicas in serve mode, using a Lease
+type LeaderElectionOptions struct {
+	// Client is used to get and update the Lease
+	Client coordinationv1client.LeasesGetter
+	// Namespace and Name identify the Lease
+	Namespace string
+	Name      string
+	// Identity identifies this instance as the holder of the Lease, and must be unique among replicas
+	Identity string
+	// LeaseDuration is how long standby instances wait before taking over a Lease that was not renewed
+	LeaseDuration time.Duration
+	// RenewDeadline is how long the leader retries renewing the Lease before it stops scanning
+	RenewDeadline time.Duration
+	// RetryPeriod is the time between attempts to acquire or renew the Lease
+	RetryPeriod time.Duration
+}
+
+// Validate ensures the specified options are valid
+func (o *LeaderElectionOptions) Validate() error {
+	if o.Client == nil {
+		return fmt.Errorf("leader election client is required")
+	}
+	if o.Namespace == "" || o.Name == "" {
+		return fmt.Errorf("leader election lease namespace and name are required")
+	}
+	if o.Identity == "" {
+		return fmt.Errorf("leader election identity is required")
+	}
+	if o.RetryPeriod <= 0 {
+		return fmt.Errorf("invalid leader election retry period, must be > 0")
+	}
+	if o.RenewDeadline <= o.RetryPeriod {
+		return fmt.Errorf("invalid leader election renew deadline, must be greater than the retry period")
+	}
+	if o.LeaseDuration <= o.RenewDeadline {
+		return fmt.Errorf("invalid leader election lease duration, must be greater than the renew deadline")
+	}
+	return nil
+}
+
+// run campaigns for the Lease until ctx is done, calling lead while this instance holds it.
+// The context passed to lead is cancelled when leadership is lost, and lead must return promptly once it is.
+// setLeader is called as leadership is gained and lost. The Lease is released when ctx is done.
+func (o *LeaderElectionOptions) run(ctx context.Context, lead func(context.Context), setLeader func(bool)) error {
+	// held while lead runs, so a lead that has not returned after leadership was lost never overlaps the next
+	leading := sync.Mutex{}
+	for {
+		elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+			Lock: &resourcelock.LeaseLock{
+				LeaseMeta:  metav1.ObjectMeta{Namespace: o.Namespace, Name: o.Name},
+				Client:     o.Client,
+				LockConfig: resourcelock.ResourceLockConfig{Identity: o.Identity},
+			},
+			LeaseDuration:   o.LeaseDuration,
+			RenewDeadline:   o.RenewDeadline,
+			RetryPeriod:     o.RetryPeriod,
+			ReleaseOnCancel: true,
+			Name:            o.Namespace + "/" + o.Name,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(ctx context.Context) {
+					leading.Lock()
+					defer leading.Unlock()
+					if ctx.Err() != nil {
+						return
+					}
+					klog.Infof("acquired lease %s/%s, scanning", o.Namespace, o.Name)
+					setLeader(true)
+					lead(ctx)
+				},
+				OnStoppedLeading: func() {
+					setLeader(false)
+				},
+				OnNewLeader: func(identity string) {
+					if identity != o.Identity {
+						klog.Infof("lease %s/%s is held by %s, standing by", o.Namespace, o.Name, identity)
+					}
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		elector.Run(ctx)
+		if ctx.Err() != nil {
+			// the elector does not wait for lead to return
+			leading.Lock()
+			defer leading.Unlock()
+			return nil
+		}
+		klog.Warningf("lost lease %s/%s, standing by", o.Namespace, o.Name)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLeaderElection(t *testing.T) {
+	client := fake.NewSimpleClientset().CoordinationV1()
+	replica := func(identity string) (*ServeOptions, chan string, context.CancelFunc, chan error) {
+		s := &ServeOptions{LeaderElection: &LeaderElectionOptions{
+			Client:        client,
+			Namespace:     "ns1",
+			Name:          "lease",
+			Identity:      identity,
+			LeaseDuration: time.Second,
+			RenewDeadline: 500 * time.Millisecond,
+			RetryPeriod:   50 * time.Millisecond,
+		}}
+		if err := s.LeaderElection.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		s.setLeader(false)
+		leading := make(chan string, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- s.LeaderElection.run(ctx, func(ctx context.Context) {
+				leading <- identity
+				<-ctx.Done()
+			}, s.setLeader)
+		}()
+		return s, leading, cancel, done
+	}
+	expectLeading := func(leading chan string) {
+		t.Helper()
+		select {
+		case <-leading:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for leadership")
+		}
+	}
+
+	a, aLeading, cancelA, aDone := replica("a")
+	expectLeading(aLeading)
+	b, bLeading, cancelB, bDone := replica("b")
+	defer func() {
+		cancelB()
+		<-bDone
+	}()
+
+	// the standby replica does not scan, but reports ready
+	select {
+	case <-bLeading:
+		t.Fatal("expected only one replica to lead")
+	case <-time.After(200 * time.Millisecond):
+	}
+	if !a.state().leader || b.state().leader {
+		t.Errorf("expected a to lead, got a=%v b=%v", a.state().leader, b.state().leader)
+	}
+	server := httptest.NewServer(b.handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected standby replica to be ready, got %d", resp.StatusCode)
+	}
+
+	// stopping the leader releases the lease to the standby replica
+	cancelA()
+	if err := <-aDone; err != nil {
+		t.Fatal(err)
+	}
+	expectLeading(bLeading)
+	if a.state().leader || !b.state().leader {
+		t.Errorf("expected b to lead, got a=%v b=%v", a.state().leader, b.state().leader)
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// Tools that manage objects, detected from their labels and annotations
+const (
+	ToolVelero = "velero"
+	ToolArgoCD = "argocd"
+	ToolFlux   = "flux"
+	ToolHelm   = "helm"
+)
+
+// ManagedBy identifies the tool that manages an object, which determines how its ownerReferences are remediated:
+// fixed in the source of a GitOps application or Helm chart, or in the backup a restore created the object from
+type ManagedBy struct {
+	// Tool is the managing tool, such as velero or argocd, or the value of the app.kubernetes.io/managed-by label
+	Tool string `json:"tool"`
+	// Instance identifies what the tool manages the object as, such as the Velero restore, Argo CD application, or Helm release
+	Instance string `json:"instance,omitempty"`
+}
+
+func (m *ManagedBy) String() string {
+	if m == nil {
+		return "<none>"
+	}
+	if m.Instance == "" {
+		return m.Tool
+	}
+	return m.Tool + "/" + m.Instance
+}
+
+// managedByOf returns the tool managing object according to its labels and annotations, or nil if none is detected.
+// Tools that copy or reuse the labels of others are checked first: restores keep the labels of the objects backed up,
+// Argo CD and Flux deploy Helm charts, and all of them may set app.kubernetes.io/managed-by.
+func managedByOf(object *metav1.PartialObjectMetadata) *ManagedBy {
+	labels, annotations := object.Labels, object.Annotations
+	if restore := labels["velero.io/restore-name"]; restore != "" {
+		return &ManagedBy{Tool: ToolVelero, Instance: "restore " + restore}
+	}
+	if backup := labels["velero.io/backup-name"]; backup != "" {
+		return &ManagedBy{Tool: ToolVelero, Instance: "backup " + backup}
+	}
+	if trackingID := annotations["argocd.argoproj.io/tracking-id"]; trackingID != "" {
+		// <application>:<group>/<kind>:<namespace>/<name>
+		return &ManagedBy{Tool: ToolArgoCD, Instance: strings.SplitN(trackingID, ":", 2)[0]}
+	}
+	for _, instance := range []string{labels["argocd.argoproj.io/instance"], annotations["argocd.argoproj.io/instance"]} {
+		if instance != "" {
+			return &ManagedBy{Tool: ToolArgoCD, Instance: instance}
+		}
+	}
+	for _, prefix := range []string{"kustomize.toolkit.fluxcd.io/", "helm.toolkit.fluxcd.io/"} {
+		if name := labels[prefix+"name"]; name != "" {
+			return &ManagedBy{Tool: ToolFlux, Instance: namespacedName(labels[prefix+"namespace"], name)}
+		}
+	}
+	if release := annotations["meta.helm.sh/release-name"]; release != "" {
+		return &ManagedBy{Tool: ToolHelm, Instance: namespacedName(annotations["meta.helm.sh/release-namespace"], release)}
+	}
+	if tool := labels["app.kubernetes.io/managed-by"]; tool != "" {
+		if strings.EqualFold(tool, ToolHelm) {
+			tool = ToolHelm
+		}
+		return &ManagedBy{Tool: tool, Instance: labels["app.kubernetes.io/instance"]}
+	}
+	return nil
+}
+
+// managerSummary counts the findings of children managed by one tool instance
+type managerSummary struct {
+	managedBy string
+	errors    int
+	warnings  int
+}
+
+// printManagerSummary writes a table of the number of findings for the children managed by each tool instance to w, most errors first,
+// since the findings of objects created by one application, release, or restore share their remediation
+func printManagerSummary(w io.Writer, findings []Finding) error {
+	if len(findings) == 0 {
+		return nil
+	}
+	summaries := map[string]*managerSummary{}
+	for _, finding := range findings {
+		managedBy := finding.ManagedBy.String()
+		summary := summaries[managedBy]
+		if summary == nil {
+			summary = &managerSummary{managedBy: managedBy}
+			summaries[managedBy] = summary
+		}
+		if finding.Level == LevelError {
+			summary.errors++
+		} else {
+			summary.warnings++
+		}
+	}
+	sorted := make([]managerSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		sorted = append(sorted, *summary)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].errors != sorted[j].errors {
+			return sorted[i].errors > sorted[j].errors
+		}
+		if sorted[i].warnings != sorted[j].warnings {
+			return sorted[i].warnings > sorted[j].warnings
+		}
+		return sorted[i].managedBy < sorted[j].managedBy
+	})
+	tabwriter := printers.GetNewTabWriter(w)
+	tabwriter.Write([]byte("MANAGED_BY\tERRORS\tWARNINGS\n"))
+	for _, summary := range sorted {
+		fmt.Fprintf(tabwriter, "%s\t%d\t%d\n", summary.managedBy, summary.errors, summary.warnings)
+	}
+	return tabwriter.Flush()
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestManagedByOf(t *testing.T) {
+	testcases := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		expected    *ManagedBy
+	}{
+		{name: "unmanaged", labels: map[string]string{"app": "web"}},
+		{
+			name:     "velero restore of an argo cd application",
+			labels:   map[string]string{"velero.io/backup-name": "nightly", "velero.io/restore-name": "nightly-20201001", "argocd.argoproj.io/instance": "web"},
+			expected: &ManagedBy{Tool: ToolVelero, Instance: "restore nightly-20201001"},
+		},
+		{
+			name:        "argo cd tracking id",
+			annotations: map[string]string{"argocd.argoproj.io/tracking-id": "web:apps/Deployment:ns1/web"},
+			labels:      map[string]string{"app.kubernetes.io/managed-by": "Helm"},
+			expected:    &ManagedBy{Tool: ToolArgoCD, Instance: "web"},
+		},
+		{
+			name:     "argo cd instance label",
+			labels:   map[string]string{"argocd.argoproj.io/instance": "web"},
+			expected: &ManagedBy{Tool: ToolArgoCD, Instance: "web"},
+		},
+		{
+			name:        "flux helm release",
+			labels:      map[string]string{"helm.toolkit.fluxcd.io/name": "web", "helm.toolkit.fluxcd.io/namespace": "flux-system"},
+			annotations: map[string]string{"meta.helm.sh/release-name": "web"},
+			expected:    &ManagedBy{Tool: ToolFlux, Instance: "flux-system/web"},
+		},
+		{
+			name:        "helm release",
+			annotations: map[string]string{"meta.helm.sh/release-name": "web", "meta.helm.sh/release-namespace": "ns1"},
+			expected:    &ManagedBy{Tool: ToolHelm, Instance: "ns1/web"},
+		},
+		{
+			name:     "managed-by label",
+			labels:   map[string]string{"app.kubernetes.io/managed-by": "Helm", "app.kubernetes.io/instance": "web"},
+			expected: &ManagedBy{Tool: ToolHelm, Instance: "web"},
+		},
+		{
+			name:     "other tool",
+			labels:   map[string]string{"app.kubernetes.io/managed-by": "kustomize"},
+			expected: &ManagedBy{Tool: "kustomize"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			object := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Labels: tc.labels, Annotations: tc.annotations}}
+			if diff := cmp.Diff(tc.expected, managedByOf(object)); diff != "" {
+				t.Errorf("unexpected tool (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrintManagerSummary(t *testing.T) {
+	argo := &ManagedBy{Tool: ToolArgoCD, Instance: "web"}
+	findings := []Finding{
+		{Level: LevelWarning},
+		{Level: LevelError, ManagedBy: argo},
+		{Level: LevelError, ManagedBy: argo},
+		{Level: LevelError, ManagedBy: &ManagedBy{Tool: ToolVelero, Instance: "restore r1"}},
+	}
+	out := &bytes.Buffer{}
+	if err := printManagerSummary(out, findings); err != nil {
+		t.Fatal(err)
+	}
+	expected := `MANAGED_BY          ERRORS   WARNINGS
+argocd/web          2        0
+velero/restore r1   1        0
+<none>              0        1
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/metadata"
+)
+
+// NewMetadataFallbackClient returns a metadata client that gets and lists objects with metadataClient,
+// falling back to getting and listing full objects with dynamicClient, and extracting their metadata,
+// for resources whose API servers reject metadata-only requests, such as some older aggregated API servers.
+// Resources in the groups of groupDynamicClients fall back to the client of their group instead, so they keep their
+// own rate limits. A warning is written to stderr the first time each resource falls back.
+func NewMetadataFallbackClient(metadataClient metadata.Interface, dynamicClient dynamic.Interface, groupDynamicClients map[string]dynamic.Interface, stderr io.Writer) metadata.Interface {
+	return &metadataFallbackClient{metadata: metadataClient, dynamic: dynamicClient, groupDynamic: groupDynamicClients, stderr: stderr, fallback: map[schema.GroupVersionResource]bool{}}
+}
+
+type metadataFallbackClient struct {
+	metadata     metadata.Interface
+	dynamic      dynamic.Interface
+	groupDynamic map[string]dynamic.Interface
+	stderr       io.Writer
+
+	lock sync.Mutex
+	// fallback holds the resources whose API servers rejected metadata-only requests
+	fallback map[schema.GroupVersionResource]bool
+}
+
+func (c *metadataFallbackClient) Resource(gvr schema.GroupVersionResource) metadata.Getter {
+	return &metadataFallbackResource{ResourceInterface: c.metadata.Resource(gvr), client: c, gvr: gvr}
+}
+
+// dynamicResource returns the dynamic client for gvr, using the client of its group if there is one
+func (c *metadataFallbackClient) dynamicResource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	if client, ok := c.groupDynamic[gvr.Group]; ok {
+		return client.Resource(gvr)
+	}
+	return c.dynamic.Resource(gvr)
+}
+
+// useFallback returns true if gvr is known to reject metadata-only requests, or if err shows it does,
+// warning the first time it is found to
+func (c *metadataFallbackClient) useFallback(gvr schema.GroupVersionResource, err error) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.fallback[gvr] {
+		return true
+	}
+	if err == nil || !rejectsPartialObjectMetadata(err) {
+		return false
+	}
+	c.fallback[gvr] = true
+	fmt.Fprintf(c.stderr, "warning: %v does not support metadata-only requests, getting full objects instead: %v\n", gvr, err)
+	return true
+}
+
+type metadataFallbackResource struct {
+	metadata.ResourceInterface
+	client    *metadataFallbackClient
+	gvr       schema.GroupVersionResource
+	namespace string
+}
+
+func (r *metadataFallbackResource) Namespace(namespace string) metadata.ResourceInterface {
+	return &metadataFallbackResource{ResourceInterface: r.client.metadata.Resource(r.gvr).Namespace(namespace), client: r.client, gvr: r.gvr, namespace: namespace}
+}
+
+func (r *metadataFallbackResource) List(ctx context.Context, opts metav1.ListOptions) (*metav1.PartialObjectMetadataList, error) {
+	if !r.client.useFallback(r.gvr, nil) {
+		list, err := r.ResourceInterface.List(ctx, opts)
+		if !r.client.useFallback(r.gvr, err) {
+			return list, err
+		}
+	}
+	objects, err := r.client.dynamicResource(r.gvr).Namespace(r.namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	list := &metav1.PartialObjectMetadataList{
+		TypeMeta: metav1.TypeMeta{APIVersion: "meta.k8s.io/v1", Kind: "PartialObjectMetadataList"},
+		ListMeta: metav1.ListMeta{ResourceVersion: objects.GetResourceVersion(), Continue: objects.GetContinue(), RemainingItemCount: objects.GetRemainingItemCount()},
+		Items:    make([]metav1.PartialObjectMetadata, 0, len(objects.Items)),
+	}
+	for i := range objects.Items {
+		list.Items = append(list.Items, *partialObjectMetadataOf(&objects.Items[i]))
+	}
+	return list, nil
+}
+
+func (r *metadataFallbackResource) Get(ctx context.Context, name string, options metav1.GetOptions, subresources ...string) (*metav1.PartialObjectMetadata, error) {
+	if !r.client.useFallback(r.gvr, nil) {
+		object, err := r.ResourceInterface.Get(ctx, name, options, subresources...)
+		if !r.client.useFallback(r.gvr, err) {
+			return object, err
+		}
+	}
+	object, err := r.client.dynamicResource(r.gvr).Namespace(r.namespace).Get(ctx, name, options, subresources...)
+	if err != nil {
+		return nil, err
+	}
+	return partialObjectMetadataOf(object), nil
+}
+
+// partialObjectMetadataOf returns the type and object metadata of object
+func partialObjectMetadataOf(object *unstructured.Unstructured) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: object.GetAPIVersion(), Kind: object.GetKind()},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:                       object.GetName(),
+			GenerateName:               object.GetGenerateName(),
+			Namespace:                  object.GetNamespace(),
+			UID:                        object.GetUID(),
+			ResourceVersion:            object.GetResourceVersion(),
+			Generation:                 object.GetGeneration(),
+			CreationTimestamp:          object.GetCreationTimestamp(),
+			DeletionTimestamp:          object.GetDeletionTimestamp(),
+			DeletionGracePeriodSeconds: object.GetDeletionGracePeriodSeconds(),
+			Labels:                     object.GetLabels(),
+			Annotations:                object.GetAnnotations(),
+			OwnerReferences:            object.GetOwnerReferences(),
+			Finalizers:                 object.GetFinalizers(),
+			ManagedFields:              object.GetManagedFields(),
+		},
+	}
+}
+
+// rejectsPartialObjectMetadata returns true if err shows the API server does not support metadata-only responses:
+// either it rejected the requested content type, or it responded with full objects the metadata client could not decode
+func rejectsPartialObjectMetadata(err error) bool {
+	return apierrors.IsNotAcceptable(err) || apierrors.IsUnsupportedMediaType(err) || strings.Contains(err.Error(), "PartialObjectMetadata")
+}
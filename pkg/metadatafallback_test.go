@@ -0,0 +1,110 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestMetadataFallbackClient(t *testing.T) {
+	widgets := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	widget := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata": map[string]interface{}{
+			"name":            "widget1",
+			"namespace":       "ns1",
+			"uid":             "uid1",
+			"ownerReferences": []interface{}{map[string]interface{}{"apiVersion": "v1", "kind": "Pod", "name": "pod1", "uid": "uid2"}},
+		},
+		"spec": map[string]interface{}{"size": int64(1)},
+	}}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{widgets: "WidgetList"}, widget)
+
+	metadataClient := metadatafake.NewSimpleMetadataClient(runtime.NewScheme())
+	if _, err := metadataClient.Resource(pods).Namespace("ns1").(metadatafake.MetadataClient).CreateFake(&metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns1", UID: "uid2"},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// the API server of widgets responds with full objects the metadata client cannot decode
+	metadataClient.PrependReactor("*", "widgets", func(action coretesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("unable to decode returned object as PartialObjectMetadataList: unexpected end of JSON input")
+	})
+
+	stderr := &bytes.Buffer{}
+	client := NewMetadataFallbackClient(metadataClient, dynamicClient, nil, stderr)
+	for i := 0; i < 2; i++ {
+		list, err := client.Resource(widgets).Namespace("ns1").List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Items) != 1 || list.Items[0].Name != "widget1" || list.Items[0].UID != "uid1" || len(list.Items[0].OwnerReferences) != 1 || list.Items[0].Kind != "Widget" {
+			t.Errorf("expected the metadata of widget1, got %#v", list.Items)
+		}
+	}
+	if _, err := client.Resource(widgets).Namespace("ns1").Get(context.Background(), "widget1", metav1.GetOptions{}); err != nil {
+		t.Error(err)
+	}
+	if list, err := client.Resource(pods).Namespace("ns1").List(context.Background(), metav1.ListOptions{}); err != nil || len(list.Items) != 1 {
+		t.Errorf("expected pods to be listed as metadata, got %v, %v", list, err)
+	}
+
+	if strings.Count(stderr.String(), "does not support metadata-only requests") != 1 {
+		t.Errorf("expected one warning, got %q", stderr.String())
+	}
+	widgetRequests := 0
+	for _, action := range metadataClient.Actions() {
+		if action.GetResource() == widgets {
+			widgetRequests++
+		}
+	}
+	if widgetRequests != 1 {
+		t.Errorf("expected metadata-only requests for widgets to stop after the first was rejected, got %d", widgetRequests)
+	}
+	for _, action := range dynamicClient.Actions() {
+		if action.GetResource() != widgets {
+			t.Errorf("unexpected full object request %v", action)
+		}
+	}
+
+	// groups with their own rate limits fall back to the client of their group
+	defaultDynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	client = NewMetadataFallbackClient(metadataClient, defaultDynamicClient, map[string]dynamic.Interface{widgets.Group: dynamicClient}, ioutil.Discard)
+	if list, err := client.Resource(widgets).Namespace("ns1").List(context.Background(), metav1.ListOptions{}); err != nil || len(list.Items) != 1 {
+		t.Errorf("expected widgets to be listed through the client of their group, got %v, %v", list, err)
+	}
+	if actions := defaultDynamicClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected no requests through the default client, got %v", actions)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// metricsState holds the values exposed as metrics after each scan
+type metricsState struct {
+	lastResult  *Report
+	lastSuccess time.Time
+	failures    int
+	// leaderElection is set once the leader election Lease has been campaigned for, and leader while it is held
+	leaderElection bool
+	leader         bool
+}
+
+type findingCountKey struct {
+	resource  string
+	namespace string
+	code      string
+	level     string
+}
+
+// writeMetrics writes the metrics in the Prometheus text exposition format
+func writeMetrics(w io.Writer, state metricsState) error {
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "# HELP invalid_ownerreferences Number of invalid ownerReferences found by the last completed scan.\n")
+	fmt.Fprintf(b, "# TYPE invalid_ownerreferences gauge\n")
+	if state.lastResult != nil {
+		counts := map[findingCountKey]int{}
+		for _, finding := range state.lastResult.Findings {
+			key := findingCountKey{
+				resource:  schema.GroupResource{Group: finding.Resource.Group, Resource: finding.Resource.Resource}.String(),
+				namespace: finding.Namespace,
+				code:      finding.Code,
+				level:     finding.Level,
+			}
+			counts[key]++
+		}
+		keys := make([]findingCountKey, 0, len(counts))
+		for key := range counts {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].resource != keys[j].resource {
+				return keys[i].resource < keys[j].resource
+			}
+			if keys[i].namespace != keys[j].namespace {
+				return keys[i].namespace < keys[j].namespace
+			}
+			if keys[i].code != keys[j].code {
+				return keys[i].code < keys[j].code
+			}
+			return keys[i].level < keys[j].level
+		})
+		for _, key := range keys {
+			fmt.Fprintf(b, "invalid_ownerreferences{resource=%q,namespace=%q,code=%q,level=%q} %d\n", key.resource, key.namespace, key.code, key.level, counts[key])
+		}
+	}
+
+	if state.lastResult != nil {
+		fmt.Fprintf(b, "# HELP ownerreferences_scan_duration_seconds Duration of the last completed scan.\n")
+		fmt.Fprintf(b, "# TYPE ownerreferences_scan_duration_seconds gauge\n")
+		fmt.Fprintf(b, "ownerreferences_scan_duration_seconds %g\n", state.lastResult.Duration.Seconds())
+
+		fmt.Fprintf(b, "# HELP ownerreferences_scan_failed_resources Number of API group versions that could not be discovered and resources that could not be listed by the last completed scan.\n")
+		fmt.Fprintf(b, "# TYPE ownerreferences_scan_failed_resources gauge\n")
+		fmt.Fprintf(b, "ownerreferences_scan_failed_resources %d\n", len(state.lastResult.Failures))
+	}
+
+	if !state.lastSuccess.IsZero() {
+		fmt.Fprintf(b, "# HELP ownerreferences_last_success_timestamp_seconds Unix time the last scan completed successfully.\n")
+		fmt.Fprintf(b, "# TYPE ownerreferences_last_success_timestamp_seconds gauge\n")
+		fmt.Fprintf(b, "ownerreferences_last_success_timestamp_seconds %d\n", state.lastSuccess.Unix())
+	}
+
+	fmt.Fprintf(b, "# HELP ownerreferences_scan_failures_total Number of scans that failed to complete.\n")
+	fmt.Fprintf(b, "# TYPE ownerreferences_scan_failures_total counter\n")
+	fmt.Fprintf(b, "ownerreferences_scan_failures_total %d\n", state.failures)
+
+	if state.leaderElection {
+		leader := 0
+		if state.leader {
+			leader = 1
+		}
+		fmt.Fprintf(b, "# HELP ownerreferences_leader Whether this replica holds the leader election lease and scans.\n")
+		fmt.Fprintf(b, "# TYPE ownerreferences_leader gauge\n")
+		fmt.Fprintf(b, "ownerreferences_leader %d\n", leader)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	pods := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	replicaSets := metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}
+
+	state := metricsState{
+		lastResult: &Report{
+			Findings: []Finding{
+				{Resource: replicaSets, Namespace: "ns1", Name: "rs1", Level: LevelError, Code: CodeOwnerNotFound},
+				{Resource: pods, Namespace: "ns1", Name: "pod1", Level: LevelError, Code: CodeOwnerNotFound},
+				{Resource: pods, Namespace: "ns1", Name: "pod2", Level: LevelError, Code: CodeOwnerNotFound},
+				{Resource: pods, Namespace: "ns2", Name: "pod3", Level: LevelWarning, Code: CodeOwnerListFailed},
+			},
+			Failures: []ScanFailure{
+				{GroupVersionResource: metav1.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1"}, Message: "unavailable"},
+			},
+			Duration: 1500 * time.Millisecond,
+		},
+		lastSuccess: time.Unix(1600000000, 0),
+		failures:    2,
+	}
+
+	out := bytes.NewBuffer(nil)
+	if err := writeMetrics(out, state); err != nil {
+		t.Fatal(err)
+	}
+	expect := `
+# HELP invalid_ownerreferences Number of invalid ownerReferences found by the last completed scan.
+# TYPE invalid_ownerreferences gauge
+invalid_ownerreferences{resource="pods",namespace="ns1",code="OwnerNotFound",level="Error"} 2
+invalid_ownerreferences{resource="pods",namespace="ns2",code="OwnerListFailed",level="Warning"} 1
+invalid_ownerreferences{resource="replicasets.apps",namespace="ns1",code="OwnerNotFound",level="Error"} 1
+# HELP ownerreferences_scan_duration_seconds Duration of the last completed scan.
+# TYPE ownerreferences_scan_duration_seconds gauge
+ownerreferences_scan_duration_seconds 1.5
+# HELP ownerreferences_scan_failed_resources Number of API group versions that could not be discovered and resources that could not be listed by the last completed scan.
+# TYPE ownerreferences_scan_failed_resources gauge
+ownerreferences_scan_failed_resources 1
+# HELP ownerreferences_last_success_timestamp_seconds Unix time the last scan completed successfully.
+# TYPE ownerreferences_last_success_timestamp_seconds gauge
+ownerreferences_last_success_timestamp_seconds 1600000000
+# HELP ownerreferences_scan_failures_total Number of scans that failed to complete.
+# TYPE ownerreferences_scan_failures_total counter
+ownerreferences_scan_failures_total 2
+`
+	if e, a := normalize(expect), normalize(out.String()); !cmp.Equal(e, a) {
+		t.Errorf("unexpected metrics diff:\n%s", cmp.Diff(e, a))
+	}
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// ClusterScan identifies one of several clusters to scan
+type ClusterScan struct {
+	// Name identifies the cluster in findings and summaries, e.g. the kubeconfig context name
+	Name string
+	// Verify holds the clients used to scan the cluster. Only the options used by Scan are used.
+	Verify *VerifyGCOptions
+	// Err is set if clients could not be built for the cluster. The cluster is reported as failed without scanning.
+	Err error
+}
+
+// MultiClusterOptions contains options controlling how several clusters are scanned
+type MultiClusterOptions struct {
+	// Clusters are the clusters to scan
+	Clusters []ClusterScan
+	// Parallelism is the number of clusters to scan at once
+	Parallelism int
+	// Output is the format findings are written to Stdout in, either '' or 'json'
+	Output string
+	// FailOnErrors returns an error from Run if any error-level findings are reported in any cluster
+	FailOnErrors bool
+	// FailOnWarnings returns an ExitError with ExitCodeWarnings from Run if any warning-level findings are reported in any cluster
+	FailOnWarnings bool
+	// MaxFindings optionally limits the number of findings written to Stdout. Further findings are still counted.
+	MaxFindings int
+	// Color colors the level of findings in table output, for terminals
+	Color bool
+	// SummarizeOwnerKinds writes the number of findings in all clusters for each owner group kind to Stderr after the summary
+	SummarizeOwnerKinds bool
+	// SummarizeManagers writes the number of findings in all clusters for the children managed by each tool instance to Stderr after the summary
+	SummarizeManagers bool
+	Stderr            io.Writer
+	Stdout            io.Writer
+}
+
+// Validate ensures the specified options are valid
+func (o *MultiClusterOptions) Validate() error {
+	if len(o.Clusters) == 0 {
+		return fmt.Errorf("at least one cluster is required")
+	}
+	if o.MaxFindings < 0 {
+		return fmt.Errorf("invalid max findings, must be >= 0")
+	}
+	names := map[string]bool{}
+	for _, cluster := range o.Clusters {
+		if cluster.Name == "" {
+			return fmt.Errorf("cluster name is required")
+		}
+		if names[cluster.Name] {
+			return fmt.Errorf("duplicate cluster %s", cluster.Name)
+		}
+		names[cluster.Name] = true
+		if cluster.Err != nil {
+			continue
+		}
+		if cluster.Verify == nil || cluster.Verify.DiscoveryClient == nil || cluster.Verify.MetadataClient == nil {
+			return fmt.Errorf("clients are required for cluster %s", cluster.Name)
+		}
+	}
+	if o.Parallelism < 1 {
+		return fmt.Errorf("invalid parallelism, must be >= 1")
+	}
+	if o.Output != "" && o.Output != "json" {
+		return fmt.Errorf("invalid output format, only '' and 'json' are supported when scanning multiple clusters: %v", o.Output)
+	}
+	if o.Stderr == nil {
+		return fmt.Errorf("stderr is required")
+	}
+	if o.Stdout == nil {
+		return fmt.Errorf("stdout is required")
+	}
+	return nil
+}
+
+// ClusterResult is the outcome of scanning one of several clusters
+type ClusterResult struct {
+	// Name identifies the cluster
+	Name string
+	// Report is the result of the scan, or nil if it failed
+	Report *Report
+	// Err is set if the scan failed
+	Err error
+}
+
+// Run scans all clusters, writes their combined findings to Stdout, and writes a summary for each cluster to Stderr.
+// An error is returned if any cluster could not be scanned.
+func (o *MultiClusterOptions) Run(ctx context.Context) error {
+	results := o.Scan(ctx)
+
+	combined := &Report{color: o.Color}
+	failed, incomplete := 0, 0
+	tabwriter := printers.GetNewTabWriter(o.Stderr)
+	tabwriter.Write([]byte("CLUSTER\tERRORS\tWARNINGS\tSTATUS\n"))
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(tabwriter, "%s\t\t\tscan failed: %v\n", result.Name, result.Err)
+			continue
+		}
+		status := "complete"
+		if result.Report.Interrupted {
+			status = "interrupted, results are partial"
+		} else if !result.Report.Complete() {
+			status = fmt.Sprintf("incomplete, %s could not be discovered or listed", pluralize(len(result.Report.Failures), "resource", "resources"))
+			incomplete++
+		}
+		fmt.Fprintf(tabwriter, "%s\t%d\t%d\t%s\n", result.Name, result.Report.Errors, result.Report.Warnings, status)
+		scan := result.Report.identity()
+		for _, finding := range result.Report.Findings {
+			finding.Cluster = result.Name
+			finding.Scan = scan
+			combined.Findings = append(combined.Findings, finding)
+		}
+		combined.Errors += result.Report.Errors
+		combined.Warnings += result.Report.Warnings
+	}
+
+	printed, omitted := combined.limited(o.MaxFindings)
+	if err := printed.Print(o.Stdout, o.Output); err != nil {
+		return err
+	}
+	tabwriter.Flush()
+	fmt.Fprintf(o.Stderr, "%s, %s across %s%s\n", pluralize(combined.Errors, "error", "errors"), pluralize(combined.Warnings, "warning", "warnings"), pluralize(len(results), "cluster", "clusters"), omittedSummary(omitted))
+	if o.SummarizeOwnerKinds {
+		if err := printOwnerKindSummary(o.Stderr, combined.Findings); err != nil {
+			return err
+		}
+	}
+	if o.SummarizeManagers {
+		if err := printManagerSummary(o.Stderr, combined.Findings); err != nil {
+			return err
+		}
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("scan interrupted: %v", ctx.Err())
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s could not be scanned", pluralize(failed, "cluster", "clusters"))
+	}
+	if o.FailOnErrors && combined.Errors > 0 {
+		return fmt.Errorf("%s found", pluralize(combined.Errors, "error", "errors"))
+	}
+	if incomplete > 0 {
+		return &ExitError{Code: ExitCodeIncomplete, Err: fmt.Errorf("%s could not be scanned completely", pluralize(incomplete, "cluster", "clusters"))}
+	}
+	if o.FailOnWarnings && combined.Warnings > 0 {
+		return &ExitError{Code: ExitCodeWarnings, Err: fmt.Errorf("%s found", pluralize(combined.Warnings, "warning", "warnings"))}
+	}
+	return nil
+}
+
+// Scan scans all clusters, Parallelism at a time, returning results in the order of Clusters.
+// Warnings written by each scan are prefixed with the cluster name.
+func (o *MultiClusterOptions) Scan(ctx context.Context) []ClusterResult {
+	results := make([]ClusterResult, len(o.Clusters))
+	stderrLock := &sync.Mutex{}
+	work := make(chan int)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < o.Parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				cluster := o.Clusters[i]
+				if cluster.Err != nil {
+					results[i] = ClusterResult{Name: cluster.Name, Err: cluster.Err}
+					continue
+				}
+				opts := *cluster.Verify
+				opts.Stderr = &prefixWriter{prefix: "[" + cluster.Name + "] ", w: o.Stderr, lock: stderrLock}
+				report, err := opts.Scan(ctx)
+				results[i] = ClusterResult{Name: cluster.Name, Report: report, Err: err}
+			}
+		}()
+	}
+	for i := range o.Clusters {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	return results
+}
+
+// prefixWriter writes complete lines to w, each prefixed with prefix.
+// Writes to w are serialized with lock, so prefixWriters may share w.
+type prefixWriter struct {
+	prefix string
+	w      io.Writer
+	lock   *sync.Mutex
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.buf = append(p.buf, data...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(data), nil
+		}
+		p.lock.Lock()
+		_, err := fmt.Fprintf(p.w, "%s%s", p.prefix, p.buf[:i+1])
+		p.lock.Unlock()
+		p.buf = p.buf[i+1:]
+		if err != nil {
+			return len(data), err
+		}
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+// newFakeCluster returns a cluster with pods in ns1, each referencing a missing owner
+func newFakeCluster(t *testing.T, name string, pods ...string) ClusterScan {
+	ownerRef := metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: "missing", UID: types.UID("missinguid-" + name)}
+	discoveryClient := &fake.FakeDiscovery{Fake: &coretesting.Fake{}}
+	discoveryClient.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: []string{"get", "list", "delete"}}},
+	}}
+	metadataClient := metadatafake.NewSimpleMetadataClient(runtime.NewScheme())
+	podClient := metadataClient.Resource(schema.GroupVersionResource{Version: "v1", Resource: "pods"}).Namespace("ns1")
+	for _, pod := range pods {
+		if _, err := podClient.(metadatafake.MetadataClient).CreateFake(&metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: pod, Namespace: "ns1", UID: types.UID("uid-" + name + "-" + pod), OwnerReferences: []metav1.OwnerReference{ownerRef}},
+		}, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ClusterScan{Name: name, Verify: &VerifyGCOptions{DiscoveryClient: discoveryClient, MetadataClient: metadataClient}}
+}
+
+func TestMultiClusterRun(t *testing.T) {
+	broken := newFakeCluster(t, "broken")
+	// an unparseable group version fails discovery
+	broken.Verify.DiscoveryClient.(*fake.FakeDiscovery).Resources[0].GroupVersion = "a/b/c"
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	opts := &MultiClusterOptions{
+		Clusters:    []ClusterScan{newFakeCluster(t, "prod", "pod1", "pod2"), broken, newFakeCluster(t, "staging", "pod3"), {Name: "unreachable", Err: fmt.Errorf("no kubeconfig")}},
+		Parallelism: 2,
+		Output:      "json",
+		Stderr:      stderr,
+		Stdout:      stdout,
+	}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	err := opts.Run(context.Background())
+	if err == nil || err.Error() != "2 clusters could not be scanned" {
+		t.Errorf("expected error for the broken cluster, got %v", err)
+	}
+
+	findings, err := readFindings(stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, finding := range findings {
+		got = append(got, finding.Cluster+"/"+finding.Name+"/"+finding.Code)
+	}
+	expect := []string{"prod/pod1/OwnerNotFound", "prod/pod2/OwnerNotFound", "staging/pod3/OwnerNotFound"}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+
+	summary := normalize(stderr.String())
+	for _, line := range []string{
+		"CLUSTER ERRORS WARNINGS STATUS",
+		"prod 2 0 complete",
+		"staging 1 0 complete",
+		"3 errors, 0 warnings across 4 clusters",
+	} {
+		found := false
+		for _, l := range summary {
+			found = found || l == line
+		}
+		if !found {
+			t.Errorf("expected summary line %q, got:\n%s", line, stderr.String())
+		}
+	}
+	for _, failed := range []string{"broken scan failed:", "unreachable scan failed: no kubeconfig"} {
+		if !strings.Contains(strings.Join(summary, "\n"), failed) {
+			t.Errorf("expected %q, got:\n%s", failed, stderr.String())
+		}
+	}
+}
+
+func TestMultiClusterValidate(t *testing.T) {
+	cluster := ClusterScan{Name: "a", Verify: &VerifyGCOptions{
+		DiscoveryClient: &fake.FakeDiscovery{Fake: &coretesting.Fake{}},
+		MetadataClient:  metadatafake.NewSimpleMetadataClient(runtime.NewScheme()),
+	}}
+	testcases := []struct {
+		name   string
+		opts   MultiClusterOptions
+		expect string
+	}{
+		{name: "no clusters", opts: MultiClusterOptions{Parallelism: 1}, expect: "at least one cluster is required"},
+		{name: "duplicate", opts: MultiClusterOptions{Clusters: []ClusterScan{cluster, cluster}, Parallelism: 1}, expect: "duplicate cluster a"},
+		{name: "parallelism", opts: MultiClusterOptions{Clusters: []ClusterScan{cluster}}, expect: "invalid parallelism, must be >= 1"},
+		{name: "crd output", opts: MultiClusterOptions{Clusters: []ClusterScan{cluster}, Parallelism: 1, Output: "crd"}, expect: "invalid output format, only '' and 'json' are supported when scanning multiple clusters: crd"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.opts.Validate()
+			if err == nil || err.Error() != tc.expect {
+				t.Errorf("expected %q, got %v", tc.expect, err)
+			}
+		})
+	}
+}
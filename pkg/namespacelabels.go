@@ -0,0 +1,84 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/metadata"
+)
+
+var namespacesResource = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+// NamespaceLabelOptions controls enriching findings with the labels and annotations of their namespaces,
+// so findings can be routed to the teams that own each namespace
+type NamespaceLabelOptions struct {
+	// Keys are the label or annotation keys to include, e.g. team. Labels take precedence over annotations with the same key.
+	Keys []string
+}
+
+// Validate ensures the specified options are valid
+func (o *NamespaceLabelOptions) Validate() error {
+	if len(o.Keys) == 0 {
+		return fmt.Errorf("at least one namespace label key is required")
+	}
+	seen := map[string]bool{}
+	for _, key := range o.Keys {
+		if key == "" {
+			return fmt.Errorf("namespace label keys cannot be empty")
+		}
+		if seen[key] {
+			return fmt.Errorf("duplicate namespace label key %s", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// enrich sets the NamespaceLabels of the findings and resolved findings of namespaced objects in report
+// to the values of Keys in the labels and annotations of their namespaces
+func (o *NamespaceLabelOptions) enrich(ctx context.Context, client metadata.Interface, report *Report) error {
+	namespaces, err := client.Resource(namespacesResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	labels := map[string]map[string]string{}
+	for _, namespace := range namespaces.Items {
+		values := map[string]string{}
+		for _, key := range o.Keys {
+			if value, ok := namespace.Labels[key]; ok {
+				values[key] = value
+			} else if value, ok := namespace.Annotations[key]; ok {
+				values[key] = value
+			}
+		}
+		labels[namespace.Name] = values
+	}
+	for _, findings := range [][]Finding{report.Findings, report.Resolved} {
+		for i := range findings {
+			if findings[i].Namespace == "" {
+				continue
+			}
+			findings[i].NamespaceLabels = labels[findings[i].Namespace]
+		}
+	}
+	report.namespaceLabelKeys = o.Keys
+	return nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+func TestRunNamespaceLabels(t *testing.T) {
+	cluster := newFakeCluster(t, "prod", "pod1")
+	namespaceClient := cluster.Verify.MetadataClient.Resource(namespacesResource)
+	if _, err := namespaceClient.(metadatafake.MetadataClient).CreateFake(&metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "ns1",
+			Labels:      map[string]string{"team": "payments", "owner": "label-owner"},
+			Annotations: map[string]string{"owner": "annotation-owner", "oncall": "#payments"},
+		},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, output := range []string{"json", ""} {
+		stdout := &bytes.Buffer{}
+		opts := *cluster.Verify
+		opts.Stdout, opts.Stderr, opts.Output = stdout, ioutil.Discard, output
+		opts.NamespaceLabels = &NamespaceLabelOptions{Keys: []string{"team", "owner", "oncall", "missing"}}
+		if err := opts.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := opts.run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if output == "json" {
+			findings, err := readFindings(stdout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := map[string]string{"team": "payments", "owner": "label-owner", "oncall": "#payments"}
+			if len(findings) != 1 || !reflect.DeepEqual(findings[0].NamespaceLabels, expected) {
+				t.Errorf("expected namespace labels %v, got %#v", expected, findings)
+			}
+			continue
+		}
+		lines := strings.Split(stdout.String(), "\n")
+		if header := strings.Fields(lines[0]); !reflect.DeepEqual(header[:6], []string{"GROUP", "RESOURCE", "NAMESPACE", "TEAM", "OWNER", "ONCALL"}) {
+			t.Errorf("expected namespace label columns, got %q", lines[0])
+		}
+		if row := strings.Fields(lines[1]); !reflect.DeepEqual(row[:5], []string{"pods", "ns1", "payments", "label-owner", "#payments"}) {
+			t.Errorf("expected namespace label values, got %q", lines[1])
+		}
+	}
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// notifyTopFindings is the number of findings included in a notification
+const notifyTopFindings = 10
+
+// NotifyOptions contains options controlling how a summary of results is posted to a webhook
+type NotifyOptions struct {
+	// URL is the webhook URL to POST notifications to
+	URL string
+	// Format is the payload format, either 'json' or 'slack'
+	Format string
+	// Threshold is the minimum number of Error-level findings required to send a notification
+	Threshold int
+	// Client is used to send notifications. If nil, a client with a 30 second timeout is used.
+	Client *http.Client
+}
+
+// Validate ensures the specified options are valid
+func (o *NotifyOptions) Validate() error {
+	if o.URL == "" {
+		return fmt.Errorf("notification url is required")
+	}
+	if u, err := url.Parse(o.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid notification url, must be an http or https url: %v", o.URL)
+	}
+	if o.Format != "json" && o.Format != "slack" {
+		return fmt.Errorf("invalid notification format, only 'json' and 'slack' are supported: %v", o.Format)
+	}
+	if o.Threshold < 1 {
+		return fmt.Errorf("invalid notification threshold, must be >= 1")
+	}
+	return nil
+}
+
+// notification is the payload posted in 'json' format
+type notification struct {
+	ScanID          string       `json:"scanID,omitempty"`
+	Cluster         *ClusterInfo `json:"cluster,omitempty"`
+	Errors          int          `json:"errors"`
+	Warnings        int          `json:"warnings"`
+	DurationSeconds float64      `json:"durationSeconds"`
+	Findings        []Finding    `json:"findings"`
+}
+
+// publish posts a summary and the top findings of result to the webhook, if the error threshold is met
+func (o *NotifyOptions) publish(ctx context.Context, result *Report) error {
+	if result.Errors < o.Threshold {
+		return nil
+	}
+
+	// errors first, then in scan order
+	findings := append([]Finding{}, result.Findings...)
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Level == LevelError && findings[j].Level != LevelError
+	})
+	if len(findings) > notifyTopFindings {
+		findings = findings[:notifyTopFindings]
+	}
+
+	var payload interface{}
+	switch o.Format {
+	case "slack":
+		payload = map[string]string{"text": slackText(result, findings)}
+	default:
+		payload = notification{
+			ScanID:          result.Scan.ID,
+			Cluster:         result.Scan.Cluster,
+			Errors:          result.Errors,
+			Warnings:        result.Warnings,
+			DurationSeconds: result.Duration.Seconds(),
+			Findings:        findings,
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := o.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending notification: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("error sending notification: %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// slackText formats a summary and findings as Slack mrkdwn
+func slackText(result *Report, findings []Finding) string {
+	b := &strings.Builder{}
+	in := ""
+	if cluster := result.Scan.Cluster; cluster != nil && cluster.Server != "" {
+		in = " in " + cluster.Server
+	}
+	fmt.Fprintf(b, "*Invalid ownerReferences found%s:* %s, %s\n", in, pluralize(result.Errors, "error", "errors"), pluralize(result.Warnings, "warning", "warnings"))
+	for _, finding := range findings {
+		resource := schema.GroupResource{Group: finding.Resource.Group, Resource: finding.Resource.Resource}
+		fmt.Fprintf(b, "• %s `%s` %s: %s\n", finding.Level, resource, namespacedName(finding.Namespace, finding.Name), finding.Message)
+	}
+	if more := len(result.Findings) - len(findings); more > 0 {
+		fmt.Fprintf(b, "_and %d more_\n", more)
+	}
+	return b.String()
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNotifyPublish(t *testing.T) {
+	var received []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		received = append(received, payload)
+	}))
+	defer server.Close()
+
+	pods := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	result := &Report{Findings: []Finding{
+		{Resource: pods, Namespace: "ns2", Name: "pod0", Level: LevelWarning, Code: CodeOwnerListFailed, Message: "could not list parent resource"},
+	}, Warnings: 1}
+	for i := 1; i <= 12; i++ {
+		result.Findings = append(result.Findings, Finding{Resource: pods, Namespace: "ns1", Name: fmt.Sprintf("pod%d", i), Level: LevelError, Code: CodeOwnerNotFound, Message: "no object found for uid"})
+		result.Errors++
+	}
+
+	// below threshold
+	opts := &NotifyOptions{URL: server.URL, Format: "json", Threshold: 20}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := opts.publish(context.Background(), result); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 0 {
+		t.Fatalf("expected no notification below threshold, got %v", received)
+	}
+
+	// json
+	opts.Threshold = 1
+	if err := opts.publish(context.Background(), result); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(received))
+	}
+	findings := received[0]["findings"].([]interface{})
+	if received[0]["errors"] != float64(12) || len(findings) != notifyTopFindings {
+		t.Errorf("unexpected notification: %v", received[0])
+	}
+	if level := findings[0].(map[string]interface{})["level"]; level != LevelError {
+		t.Errorf("expected errors first, got %v", level)
+	}
+
+	// slack
+	opts.Format = "slack"
+	if err := opts.publish(context.Background(), result); err != nil {
+		t.Fatal(err)
+	}
+	text, _ := received[1]["text"].(string)
+	if !strings.Contains(text, "12 errors, 1 warning") || !strings.Contains(text, "ns1/pod1: no object found for uid") || !strings.Contains(text, "and 3 more") {
+		t.Errorf("unexpected slack text:\n%s", text)
+	}
+}
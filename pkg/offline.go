@@ -0,0 +1,360 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/metadata"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	"k8s.io/client-go/restmapper"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultDiscoverySnapshot returns the built-in resources of Kubernetes 1.22, for scanning objects offline
+func DefaultDiscoverySnapshot() []*metav1.APIResourceList {
+	resources, err := parseDiscoverySnapshot([]byte(defaultDiscoverySnapshot))
+	if err != nil {
+		panic(err)
+	}
+	return resources
+}
+
+// LoadDiscoverySnapshot reads a YAML or JSON list of APIResourceLists from path, as written by "discovery-snapshot".
+// Within a group, the first version listed is preferred.
+func LoadDiscoverySnapshot(path string) ([]*metav1.APIResourceList, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := parseDiscoverySnapshot(data)
+	if err != nil {
+		return nil, fmt.Errorf("error reading discovery snapshot %s: %v", path, err)
+	}
+	return resources, nil
+}
+
+func parseDiscoverySnapshot(data []byte) ([]*metav1.APIResourceList, error) {
+	resources := []*metav1.APIResourceList{}
+	if err := yaml.UnmarshalStrict(data, &resources); err != nil {
+		return nil, err
+	}
+	for _, list := range resources {
+		if _, err := schema.ParseGroupVersion(list.GroupVersion); err != nil {
+			return nil, err
+		}
+	}
+	return resources, nil
+}
+
+// WriteDiscoverySnapshot writes the resources served by discoveryClient to w as YAML, for use with LoadDiscoverySnapshot
+func WriteDiscoverySnapshot(discoveryClient discovery.DiscoveryInterface, w io.Writer) error {
+	groupResources, err := restmapper.GetAPIGroupResources(discoveryClient)
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(discoverySnapshotOf(groupResources))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// discoverySnapshotOf returns the resources in groupResources, listing the preferred version of each group first and omitting subresources
+func discoverySnapshotOf(groupResources []*restmapper.APIGroupResources) []*metav1.APIResourceList {
+	snapshot := []*metav1.APIResourceList{}
+	for _, group := range groupResources {
+		versions := []string{group.Group.PreferredVersion.Version}
+		for _, version := range group.Group.Versions {
+			if version.Version != group.Group.PreferredVersion.Version {
+				versions = append(versions, version.Version)
+			}
+		}
+		for _, version := range versions {
+			apiResources, ok := group.VersionedResources[version]
+			if !ok {
+				continue
+			}
+			resources := []metav1.APIResource{}
+			for _, resource := range apiResources {
+				if !strings.Contains(resource.Name, "/") {
+					resources = append(resources, metav1.APIResource{Name: resource.Name, Namespaced: resource.Namespaced, Kind: resource.Kind, Verbs: resource.Verbs})
+				}
+			}
+			groupVersion := schema.GroupVersion{Group: group.Group.Name, Version: version}
+			snapshot = append(snapshot, &metav1.APIResourceList{GroupVersion: groupVersion.String(), APIResources: resources})
+		}
+	}
+	return snapshot
+}
+
+// ReadObjectsFromDir reads objects from the YAML and JSON files in dir and its subdirectories, skipping hidden directories
+func ReadObjectsFromDir(dir string) ([]*unstructured.Unstructured, error) {
+	objects := []*unstructured.Unstructured{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fileObjects, err := ReadObjects(f, path)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, fileObjects...)
+		return nil
+	})
+	return objects, err
+}
+
+// ReadObjects reads objects from a stream of YAML documents or JSON objects, expanding Lists.
+// source identifies the stream in errors.
+func ReadObjects(r io.Reader, source string) ([]*unstructured.Unstructured, error) {
+	objects := []*unstructured.Unstructured{}
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		content := map[string]interface{}{}
+		if err := decoder.Decode(&content); err == io.EOF {
+			return objects, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", source, err)
+		}
+		if len(content) == 0 {
+			// empty document
+			continue
+		}
+		object := &unstructured.Unstructured{Object: content}
+		if object.GetAPIVersion() == "" || object.GetKind() == "" {
+			return nil, fmt.Errorf("error reading %s: object %q is missing apiVersion or kind", source, object.GetName())
+		}
+		if !object.IsList() {
+			objects = append(objects, object)
+			continue
+		}
+		if err := object.EachListItem(func(item runtime.Object) error {
+			u := item.(*unstructured.Unstructured)
+			if u.GetAPIVersion() == "" || u.GetKind() == "" {
+				return fmt.Errorf("list item %q is missing apiVersion or kind", u.GetName())
+			}
+			objects = append(objects, u)
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", source, err)
+		}
+	}
+}
+
+// offlineResource is the resource objects of a kind are stored in
+type offlineResource struct {
+	gvr        schema.GroupVersionResource
+	namespaced bool
+}
+
+// OfflineClients returns discovery and metadata clients serving objects, described by resources.
+// Resources are added for CustomResourceDefinitions in objects, and guessed for other kinds missing from resources.
+// Namespaced objects without a namespace are placed in the default namespace.
+// Warnings about guessed resources and duplicate objects are written to stderr.
+func OfflineClients(resources []*metav1.APIResourceList, objects []*unstructured.Unstructured, stderr io.Writer) (discovery.DiscoveryInterface, metadata.Interface, error) {
+	// copy resources so they can be extended
+	snapshot := []*metav1.APIResourceList{}
+	for _, list := range resources {
+		snapshot = append(snapshot, list.DeepCopy())
+	}
+	addResource := func(groupVersion string, resource metav1.APIResource) {
+		for _, list := range snapshot {
+			if list.GroupVersion == groupVersion {
+				list.APIResources = append(list.APIResources, resource)
+				return
+			}
+		}
+		snapshot = append(snapshot, &metav1.APIResourceList{GroupVersion: groupVersion, APIResources: []metav1.APIResource{resource}})
+	}
+	kinds := map[schema.GroupKind]offlineResource{}
+	indexKinds := func() {
+		kinds = map[schema.GroupKind]offlineResource{}
+		for _, list := range snapshot {
+			gv, _ := schema.ParseGroupVersion(list.GroupVersion)
+			for _, resource := range list.APIResources {
+				gk := schema.GroupKind{Group: gv.Group, Kind: resource.Kind}
+				// the first version listed is the one scanned
+				if _, ok := kinds[gk]; !ok && !strings.Contains(resource.Name, "/") {
+					kinds[gk] = offlineResource{gvr: gv.WithResource(resource.Name), namespaced: resource.Namespaced}
+				}
+			}
+		}
+	}
+	indexKinds()
+
+	// add resources defined by CustomResourceDefinitions
+	for _, object := range objects {
+		if object.GroupVersionKind().GroupKind() != (schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}) {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(object.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(object.Object, "spec", "names", "kind")
+		plural, _, _ := unstructured.NestedString(object.Object, "spec", "names", "plural")
+		scope, _, _ := unstructured.NestedString(object.Object, "spec", "scope")
+		if _, ok := kinds[schema.GroupKind{Group: group, Kind: kind}]; ok || group == "" || kind == "" || plural == "" {
+			continue
+		}
+		versions, _, _ := unstructured.NestedSlice(object.Object, "spec", "versions")
+		for _, v := range versions {
+			version, _ := v.(map[string]interface{})
+			name, _, _ := unstructured.NestedString(version, "name")
+			served, found, _ := unstructured.NestedBool(version, "served")
+			if name != "" && (served || !found) {
+				addResource(schema.GroupVersion{Group: group, Version: name}.String(), metav1.APIResource{Name: plural, Namespaced: scope == "Namespaced", Kind: kind, Verbs: []string{"delete", "get", "list"}})
+			}
+		}
+		// v1beta1 CustomResourceDefinitions may only specify a single version
+		if version, _, _ := unstructured.NestedString(object.Object, "spec", "version"); version != "" && len(versions) == 0 {
+			addResource(schema.GroupVersion{Group: group, Version: version}.String(), metav1.APIResource{Name: plural, Namespaced: scope == "Namespaced", Kind: kind, Verbs: []string{"delete", "get", "list"}})
+		}
+		indexKinds()
+	}
+
+	// guess resources for kinds that are still unknown
+	for _, object := range objects {
+		gvk := object.GroupVersionKind()
+		if _, ok := kinds[gvk.GroupKind()]; ok {
+			continue
+		}
+		plural, _ := meta.UnsafeGuessKindToResource(gvk)
+		namespaced := object.GetNamespace() != ""
+		scope := "cluster-scoped"
+		if namespaced {
+			scope = "namespaced"
+		}
+		fmt.Fprintf(stderr, "warning: %s is not in the discovery snapshot, assuming it is %s with resource %s\n", gvk.GroupKind(), scope, plural.Resource)
+		addResource(gvk.GroupVersion().String(), metav1.APIResource{Name: plural.Resource, Namespaced: namespaced, Kind: gvk.Kind, Verbs: []string{"delete", "get", "list"}})
+		kinds[gvk.GroupKind()] = offlineResource{gvr: plural, namespaced: namespaced}
+	}
+
+	metadataClient := metadatafake.NewSimpleMetadataClient(runtime.NewScheme())
+	for _, object := range objects {
+		resource := kinds[object.GroupVersionKind().GroupKind()]
+		partial := &metav1.PartialObjectMetadata{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object.Object, partial); err != nil {
+			return nil, nil, fmt.Errorf("error reading metadata of %s %s: %v", object.GetKind(), object.GetName(), err)
+		}
+		if !resource.namespaced {
+			partial.Namespace = ""
+		} else if partial.Namespace == "" {
+			partial.Namespace = metav1.NamespaceDefault
+		}
+		client := metadataClient.Resource(resource.gvr).Namespace(partial.Namespace).(metadatafake.MetadataClient)
+		if _, err := client.CreateFake(partial, metav1.CreateOptions{}); apierrors.IsAlreadyExists(err) {
+			fmt.Fprintf(stderr, "warning: ignoring duplicate %s %s\n", resource.gvr.GroupResource(), namespacedName(partial.Namespace, partial.Name))
+		} else if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	discoveryClient := &discoveryfake.FakeDiscovery{Fake: &clienttesting.Fake{}}
+	discoveryClient.Resources = snapshot
+	return discoveryClient, metadataClient, nil
+}
+
+// inferredResources collects resources for kinds missing from a discovery snapshot, named from where their objects are stored
+type inferredResources struct {
+	known map[schema.GroupKind]bool
+	added map[string]*metav1.APIResourceList
+}
+
+func newInferredResources(resources []*metav1.APIResourceList) *inferredResources {
+	known := map[schema.GroupKind]bool{}
+	for _, list := range resources {
+		gv, _ := schema.ParseGroupVersion(list.GroupVersion)
+		for _, resource := range list.APIResources {
+			known[schema.GroupKind{Group: gv.Group, Kind: resource.Kind}] = true
+		}
+	}
+	return &inferredResources{known: known, added: map[string]*metav1.APIResourceList{}}
+}
+
+// add records resource as the resource of object's kind, if the kind is not already known
+func (r *inferredResources) add(object *unstructured.Unstructured, resource string) {
+	gvk := object.GroupVersionKind()
+	if r.known[gvk.GroupKind()] {
+		return
+	}
+	list, ok := r.added[gvk.GroupVersion().String()]
+	if !ok {
+		list = &metav1.APIResourceList{GroupVersion: gvk.GroupVersion().String()}
+		r.added[list.GroupVersion] = list
+	}
+	list.APIResources = append(list.APIResources, metav1.APIResource{Name: resource, Namespaced: object.GetNamespace() != "", Kind: gvk.Kind, Verbs: []string{"delete", "get", "list"}})
+	r.known[gvk.GroupKind()] = true
+}
+
+// extend returns resources with the recorded resources added
+func (r *inferredResources) extend(resources []*metav1.APIResourceList) []*metav1.APIResourceList {
+	added := map[string]*metav1.APIResourceList{}
+	for groupVersion, list := range r.added {
+		added[groupVersion] = list
+	}
+	extended := []*metav1.APIResourceList{}
+	for _, list := range resources {
+		if addedList, ok := added[list.GroupVersion]; ok {
+			// extend existing group versions rather than listing them twice
+			list = list.DeepCopy()
+			list.APIResources = append(list.APIResources, addedList.APIResources...)
+			delete(added, list.GroupVersion)
+		}
+		extended = append(extended, list)
+	}
+	groupVersions := []string{}
+	for groupVersion := range added {
+		groupVersions = append(groupVersions, groupVersion)
+	}
+	sort.Strings(groupVersions)
+	for _, groupVersion := range groupVersions {
+		extended = append(extended, added[groupVersion])
+	}
+	return extended
+}
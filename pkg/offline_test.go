@@ -0,0 +1,172 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOfflineScan(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"apps.yaml": `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: d1
+  namespace: app
+  uid: d1uid
+---
+apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  name: rs1
+  namespace: app
+  uid: rs1uid
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: d1
+    uid: wronguid
+---
+`,
+		// namespaced objects without a namespace are in the default namespace, away from their owner
+		"pods/list.json": `{"apiVersion":"v1","kind":"List","items":[
+{"apiVersion":"v1","kind":"Pod","metadata":{"name":"p1","uid":"p1uid","ownerReferences":[{"apiVersion":"apps/v1","kind":"ReplicaSet","name":"rs1","uid":"rs1uid"}]}}
+]}`,
+		"crd.yml": `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+  uid: crduid
+spec:
+  group: example.com
+  scope: Namespaced
+  names:
+    kind: Widget
+    plural: widgets
+  versions:
+  - name: v1
+    served: true
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: w1
+  namespace: app
+  uid: w1uid
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: d1
+    uid: d1uid
+`,
+		"gadget.yaml": `
+apiVersion: other.io/v1
+kind: Gadget
+metadata:
+  name: g1
+  uid: g1uid
+  ownerReferences:
+  - apiVersion: example.com/v1
+    kind: Widget
+    name: w1
+    uid: w1uid
+`,
+		// hidden directories and other files are skipped
+		".git/bad.yaml": `not: [valid`,
+		"README.md":     `not: [valid`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	objects, err := ReadObjectsFromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := &bytes.Buffer{}
+	discoveryClient, metadataClient, err := OfflineClients(DefaultDiscoverySnapshot(), objects, stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectWarnings := "warning: Gadget.other.io is not in the discovery snapshot, assuming it is cluster-scoped with resource gadgets\n"
+	if diff := cmp.Diff(expectWarnings, stderr.String()); diff != "" {
+		t.Errorf("unexpected warnings (-want +got):\n%s", diff)
+	}
+
+	opts := &VerifyGCOptions{DiscoveryClient: discoveryClient, MetadataClient: metadataClient, Stderr: ioutil.Discard, Stdout: ioutil.Discard}
+	report, err := opts.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := report.Print(out, ""); err != nil {
+		t.Fatal(err)
+	}
+	// the Widget owned by the Deployment is valid
+	expect := `
+GROUP RESOURCE NAMESPACE NAME OWNER_UID LEVEL MESSAGE
+pods default p1 rs1uid Error child namespace does not match owner namespace (app)
+apps replicasets app rs1 wronguid Error no object found for uid; did you mean app/d1 (uid d1uid)?
+other.io gadgets g1 w1uid Error cannot reference namespaced type as owner (apiVersion=example.com/v1,kind=Widget)
+`
+	if diff := cmp.Diff(normalize(expect), normalize(out.String())); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadObjectsErrors(t *testing.T) {
+	testcases := []struct {
+		name   string
+		input  string
+		expect string
+	}{
+		{
+			name:   "missing kind",
+			input:  "apiVersion: v1\nmetadata:\n  name: p1\n",
+			expect: `error reading test: object "p1" is missing apiVersion or kind`,
+		},
+		{
+			name:   "list item missing apiVersion",
+			input:  `{"apiVersion":"v1","kind":"List","items":[{"kind":"Pod","metadata":{"name":"p1"}}]}`,
+			expect: `error reading test: list item "p1" is missing apiVersion or kind`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadObjects(bytes.NewBufferString(tc.input), "test")
+			if err == nil || err.Error() != tc.expect {
+				t.Errorf("expected error %q, got %v", tc.expect, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// OutputSchemaVersion is the version of the finding and report formats described by OutputSchema.
+// It is incremented when fields are removed or change shape. Adding optional fields does not change the version.
+const OutputSchemaVersion = "v1"
+
+// Output formats described by OutputSchema
+const (
+	// OutputSchemaFinding is the format of each finding written by -o json
+	OutputSchemaFinding = "finding"
+	// OutputSchemaReport is the format of the reports served at /results and exported to storage
+	OutputSchemaReport = "report"
+	// OutputSchemaSummary is the format of the summary written by --summary-file
+	OutputSchemaSummary = "summary"
+)
+
+// OutputSchemas are the output formats described by OutputSchema
+var OutputSchemas = []string{OutputSchemaFinding, OutputSchemaReport, OutputSchemaSummary}
+
+// jsonSchema is a JSON Schema document or subschema
+type jsonSchema map[string]interface{}
+
+func objectSchema(description string, properties jsonSchema, required ...string) jsonSchema {
+	schema := jsonSchema{"type": "object", "description": description, "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func typeSchema(typ, description string) jsonSchema {
+	return jsonSchema{"type": typ, "description": description}
+}
+
+// findingDefinitions are the definitions of a finding and the types it refers to
+var findingDefinitions = jsonSchema{
+	"finding": objectSchema("An invalid ownerReference", jsonSchema{
+		"cluster": typeSchema("string", "The cluster the finding is from, when scanning multiple clusters"),
+		"resource": objectSchema("The resource of the object with the ownerReference", jsonSchema{
+			"group":    typeSchema("string", "API group, empty for the core group"),
+			"version":  typeSchema("string", "API version"),
+			"resource": typeSchema("string", "Plural resource name"),
+		}, "group", "version", "resource"),
+		"kind": objectSchema("The kind of the object with the ownerReference", jsonSchema{
+			"group":   typeSchema("string", "API group, empty for the core group"),
+			"version": typeSchema("string", "API version"),
+			"kind":    typeSchema("string", "Kind"),
+		}, "group", "version", "kind"),
+		"namespace": typeSchema("string", "The namespace of the object, empty for cluster-scoped objects"),
+		"name":      typeSchema("string", "The name of the object"),
+		"uid":       typeSchema("string", "The uid of the object"),
+		"ownerReference": objectSchema("The invalid ownerReference", jsonSchema{
+			"apiVersion":         typeSchema("string", "API version of the owner"),
+			"kind":               typeSchema("string", "Kind of the owner"),
+			"name":               typeSchema("string", "Name of the owner"),
+			"uid":                typeSchema("string", "UID of the owner"),
+			"controller":         typeSchema("boolean", "True if the owner is the managing controller"),
+			"blockOwnerDeletion": typeSchema("boolean", "True if the owner cannot be deleted from storage until this reference is removed"),
+		}, "apiVersion", "kind", "name", "uid"),
+		"level":             jsonSchema{"type": "string", "description": "Severity of the finding", "enum": []string{LevelError, LevelWarning}},
+		"code":              typeSchema("string", "Identifies the problem, such as "+CodeOwnerNotFound+", or the code of a custom rule"),
+		"message":           typeSchema("string", "Describes the problem"),
+		"audit":             jsonSchema{"$ref": "#/definitions/auditEntry"},
+		"creationTimestamp": jsonSchema{"type": "string", "format": "date-time", "description": "When the object was created, if known"},
+		"namespaceLabels": jsonSchema{
+			"type":                 "object",
+			"description":          "The values of the selected labels and annotations of the namespace of the object, if requested",
+			"additionalProperties": jsonSchema{"type": "string"},
+		},
+		"managedBy": objectSchema("The tool managing the object, if detected from its labels and annotations", jsonSchema{
+			"tool":     typeSchema("string", "The managing tool, such as velero, argocd, flux, or helm, or the value of the app.kubernetes.io/managed-by label"),
+			"instance": typeSchema("string", "What the tool manages the object as, such as the Velero restore, Argo CD application, or Helm release"),
+		}, "tool"),
+		"candidates": jsonSchema{
+			"type":        "array",
+			"description": "Objects of the referenced kind and name that may be the intended owner, if the owner was not found",
+			"items": objectSchema("An object that may be the intended owner", jsonSchema{
+				"namespace": typeSchema("string", "Namespace of the object, empty for cluster-scoped objects"),
+				"name":      typeSchema("string", "Name of the object"),
+				"uid":       typeSchema("string", "UID of the object"),
+			}, "name", "uid"),
+		},
+		"scan": objectSchema("The scan the finding is from, in -o json output", jsonSchema{
+			"id":      typeSchema("string", "Unique to each scan"),
+			"cluster": jsonSchema{"$ref": "#/definitions/cluster"},
+		}, "id"),
+		"delta": jsonSchema{
+			"type":        "string",
+			"description": "Whether the finding is new, persisting, or resolved, if findings were compared to a previous run",
+			"enum":        []string{DeltaNew, DeltaPersisting, DeltaResolved},
+		},
+	}, "resource", "kind", "namespace", "name", "ownerReference", "level", "code", "message"),
+	"cluster": objectSchema("The scanned cluster", jsonSchema{
+		"server":  typeSchema("string", "URL of the API server"),
+		"context": typeSchema("string", "Name of the kubeconfig context used"),
+		"uid":     typeSchema("string", "UID of the kube-system namespace, which identifies the cluster regardless of how it is reached"),
+	}),
+	"auditEntry": objectSchema("The request that last wrote the object, if audit logs were correlated and a request was found", jsonSchema{
+		"user":                   typeSchema("string", "Username of the requester"),
+		"userAgent":              typeSchema("string", "User agent of the requester"),
+		"verb":                   typeSchema("string", "Request verb, e.g. create, update, or patch"),
+		"time":                   jsonSchema{"type": "string", "format": "date-time", "description": "When the request completed"},
+		"auditID":                typeSchema("string", "Identifies the request in the audit log"),
+		"ownerReferencesWritten": typeSchema("boolean", "True if the request body set ownerReferences"),
+	}, "user", "verb", "time", "ownerReferencesWritten"),
+}
+
+// reportProperties are the properties of a report document
+var reportProperties = jsonSchema{
+	"scanID":          typeSchema("string", "Unique to each scan"),
+	"cluster":         jsonSchema{"$ref": "#/definitions/cluster"},
+	"completionTime":  jsonSchema{"type": "string", "format": "date-time", "description": "When the scan completed"},
+	"durationSeconds": typeSchema("number", "How long the scan took"),
+	"errors":          typeSchema("integer", "Number of error-level findings"),
+	"warnings":        typeSchema("integer", "Number of warning-level findings, plus the number of resources that could not be discovered or listed"),
+	"complete":        typeSchema("boolean", "False if any resources could not be discovered or listed"),
+	"consistency": objectSchema("Estimates how much the cluster changed while listing, since findings may be artifacts of changes made while listing", jsonSchema{
+		"resourceVersionSpread": typeSchema("integer", "Difference between the highest and lowest resourceVersion listed, approximating the number of writes while listing"),
+		"created":               typeSchema("integer", "Number of listed objects created after the scan started"),
+		"deleting":              typeSchema("integer", "Number of listed objects being deleted"),
+		"confidence":            jsonSchema{"type": "string", "description": "Confidence that findings are not artifacts of changes made while listing", "enum": []string{ConfidenceHigh, ConfidenceMedium, ConfidenceLow}},
+	}, "created", "deleting", "confidence"),
+	"findings": jsonSchema{"type": "array", "description": "The findings of the scan", "items": jsonSchema{"$ref": "#/definitions/finding"}},
+}
+
+// levelCountsSchema describes findings counted by a key
+func levelCountsSchema(description string) jsonSchema {
+	return jsonSchema{
+		"type":        "object",
+		"description": description,
+		"additionalProperties": objectSchema("The number of findings by level", jsonSchema{
+			"errors":   typeSchema("integer", "Number of error-level findings"),
+			"warnings": typeSchema("integer", "Number of warning-level findings"),
+		}, "errors", "warnings"),
+	}
+}
+
+// summaryProperties are the properties of a summary
+var summaryProperties = jsonSchema{
+	"scanID":          typeSchema("string", "Unique to each scan"),
+	"cluster":         jsonSchema{"$ref": "#/definitions/cluster"},
+	"completionTime":  jsonSchema{"type": "string", "format": "date-time", "description": "When the scan completed"},
+	"durationSeconds": typeSchema("number", "How long the scan took"),
+	"complete":        typeSchema("boolean", "False if any resources could not be discovered or listed"),
+	"errors":          typeSchema("integer", "Number of error-level findings"),
+	"warnings":        typeSchema("integer", "Number of warning-level findings, plus the number of resources that could not be discovered or listed"),
+	"findings":        typeSchema("integer", "Number of findings"),
+	"failures":        typeSchema("integer", "Number of API group versions that could not be discovered and resources that could not be listed"),
+	"objects":         typeSchema("integer", "Number of objects listed"),
+	"ignored":         typeSchema("integer", "Number of findings and failures dropped by ignore rules"),
+	"newer":           typeSchema("integer", "Number of findings of objects newer than --older-than, which were dropped"),
+	"baselined":       typeSchema("integer", "Number of findings that matched the baseline"),
+	"byCode":          levelCountsSchema("Findings by code"),
+	"byResource":      levelCountsSchema("Findings by resource[.group] of the object with the ownerReference"),
+	"byNamespace":     levelCountsSchema("Findings by namespace of the object with the ownerReference, empty for cluster-scoped objects"),
+}
+
+// OutputSchema returns the JSON Schema of an output format, one of OutputSchemas, for validating and generating code for consumers
+func OutputSchema(name string) ([]byte, error) {
+	schema := jsonSchema{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"$id":         fmt.Sprintf("urn:kubectl-check-ownerreferences:%s:%s", name, OutputSchemaVersion),
+		"definitions": findingDefinitions,
+	}
+	switch name {
+	case OutputSchemaFinding:
+		schema["title"] = "Finding"
+		// siblings of $ref are ignored in draft-07
+		schema["allOf"] = []jsonSchema{{"$ref": "#/definitions/finding"}}
+	case OutputSchemaReport:
+		schema["title"] = "Report"
+		for key, value := range objectSchema("A completed scan", reportProperties, "completionTime", "durationSeconds", "errors", "warnings", "complete", "findings") {
+			schema[key] = value
+		}
+	case OutputSchemaSummary:
+		schema["title"] = "Summary"
+		for key, value := range objectSchema("The summary of a scan", summaryProperties, "completionTime", "durationSeconds", "complete", "errors", "warnings", "findings", "failures", "objects", "byCode", "byResource", "byNamespace") {
+			schema[key] = value
+		}
+	default:
+		return nil, fmt.Errorf("unknown output schema %q, must be one of %v", name, OutputSchemas)
+	}
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestOutputSchema ensures every field written in each output format is described by its schema, so the schema does not drift
+func TestOutputSchema(t *testing.T) {
+	controller := true
+	finding := Finding{
+		Cluster:           "prod",
+		Resource:          metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"},
+		Kind:              metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"},
+		Namespace:         "ns1",
+		Name:              "rs1",
+		UID:               "uid1",
+		OwnerReference:    metav1.OwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "d1", UID: "uid2", Controller: &controller, BlockOwnerDeletion: &controller},
+		Level:             LevelError,
+		Code:              CodeOwnerNotFound,
+		Message:           "owner not found",
+		Audit:             &AuditEntry{User: "admin", UserAgent: "kubectl", Verb: "create", Time: metav1.Now(), AuditID: "id1", OwnerReferencesWritten: true},
+		Delta:             DeltaNew,
+		NamespaceLabels:   map[string]string{"team": "a"},
+		CreationTimestamp: &metav1.Time{Time: time.Now()},
+		ManagedBy:         &ManagedBy{Tool: ToolArgoCD, Instance: "app1"},
+		Candidates:        []OwnerCandidate{{Namespace: "ns1", Name: "d1", UID: "uid3"}},
+		Scan:              &ScanIdentity{ID: "scan1", Cluster: &ClusterInfo{Server: "https://example.com", Context: "prod", UID: "uid4"}},
+	}
+	result := &Report{Findings: []Finding{finding}, CompletionTime: time.Now(), Consistency: &Consistency{ResourceVersionSpread: 10, Created: 1, Deleting: 1, Confidence: ConfidenceLow}, Ignored: 1, Newer: 1, Baselined: 1, Scan: *finding.Scan}
+	report := newReportDocument(result, &ClusterInfo{Server: "https://example.com", Context: "prod"})
+
+	for name, value := range map[string]interface{}{OutputSchemaFinding: finding, OutputSchemaReport: report, OutputSchemaSummary: summarize(result)} {
+		data, err := OutputSchema(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema := map[string]interface{}{}
+		if err := json.Unmarshal(data, &schema); err != nil {
+			t.Fatalf("%s: invalid schema: %v", name, err)
+		}
+		if !strings.HasSuffix(schema["$id"].(string), ":"+OutputSchemaVersion) {
+			t.Errorf("%s: expected a versioned $id, got %v", name, schema["$id"])
+		}
+		data, err = json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		document := map[string]interface{}{}
+		if err := json.Unmarshal(data, &document); err != nil {
+			t.Fatal(err)
+		}
+		checkSchemaProperties(t, name, schema, schema, document)
+	}
+
+	if _, err := OutputSchema("unknown"); err == nil {
+		t.Errorf("expected error for unknown schema")
+	}
+}
+
+// checkSchemaProperties reports fields of document that are not properties of schema, recursively
+func checkSchemaProperties(t *testing.T, path string, root, schema map[string]interface{}, document map[string]interface{}) {
+	t.Helper()
+	schema = resolveSchemaRef(root, schema)
+	properties, _ := schema["properties"].(map[string]interface{})
+	for key, value := range document {
+		property, ok := properties[key].(map[string]interface{})
+		if !ok {
+			t.Errorf("%s.%s is not described by the schema", path, key)
+			continue
+		}
+		property = resolveSchemaRef(root, property)
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if _, ok := property["additionalProperties"]; ok {
+				continue
+			}
+			checkSchemaProperties(t, path+"."+key, root, property, value)
+		case []interface{}:
+			items, _ := property["items"].(map[string]interface{})
+			for _, item := range value {
+				if item, ok := item.(map[string]interface{}); ok {
+					checkSchemaProperties(t, path+"."+key+"[]", root, items, item)
+				}
+			}
+		}
+	}
+}
+
+// resolveSchemaRef returns the definition referred to by schema, or by the single schema in its allOf
+func resolveSchemaRef(root, schema map[string]interface{}) map[string]interface{} {
+	if allOf, ok := schema["allOf"].([]interface{}); ok && len(allOf) == 1 {
+		schema = allOf[0].(map[string]interface{})
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		definitions := root["definitions"].(map[string]interface{})
+		return definitions[strings.TrimPrefix(ref, "#/definitions/")].(map[string]interface{})
+	}
+	return schema
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// ownerKindSummary counts the findings of ownerReferences to one owner group kind
+type ownerKindSummary struct {
+	ownerKind schema.GroupKind
+	errors    int
+	warnings  int
+}
+
+// summarizeOwnerKinds counts findings by the group kind of their ownerReference, most errors first.
+// The group of an ownerReference with an invalid apiVersion is its apiVersion.
+func summarizeOwnerKinds(findings []Finding) []ownerKindSummary {
+	summaries := map[schema.GroupKind]*ownerKindSummary{}
+	for _, finding := range findings {
+		ownerKind := schema.GroupKind{Group: finding.OwnerReference.APIVersion, Kind: finding.OwnerReference.Kind}
+		if gv, err := schema.ParseGroupVersion(finding.OwnerReference.APIVersion); err == nil {
+			ownerKind.Group = gv.Group
+		}
+		summary := summaries[ownerKind]
+		if summary == nil {
+			summary = &ownerKindSummary{ownerKind: ownerKind}
+			summaries[ownerKind] = summary
+		}
+		if finding.Level == LevelError {
+			summary.errors++
+		} else {
+			summary.warnings++
+		}
+	}
+	sorted := make([]ownerKindSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		sorted = append(sorted, *summary)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].errors != sorted[j].errors {
+			return sorted[i].errors > sorted[j].errors
+		}
+		if sorted[i].warnings != sorted[j].warnings {
+			return sorted[i].warnings > sorted[j].warnings
+		}
+		return sorted[i].ownerKind.String() < sorted[j].ownerKind.String()
+	})
+	return sorted
+}
+
+// printOwnerKindSummary writes a table of the number of findings for each owner group kind to w, most errors first,
+// since findings for many children usually share the controller that wrote them
+func printOwnerKindSummary(w io.Writer, findings []Finding) error {
+	if len(findings) == 0 {
+		return nil
+	}
+	tabwriter := printers.GetNewTabWriter(w)
+	tabwriter.Write([]byte("OWNER_KIND\tERRORS\tWARNINGS\n"))
+	for _, summary := range summarizeOwnerKinds(findings) {
+		fmt.Fprintf(tabwriter, "%s\t%d\t%d\n", summary.ownerKind, summary.errors, summary.warnings)
+	}
+	return tabwriter.Flush()
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPrintOwnerKindSummary(t *testing.T) {
+	finding := func(apiVersion, kind, level string) Finding {
+		return Finding{OwnerReference: metav1.OwnerReference{APIVersion: apiVersion, Kind: kind}, Level: level}
+	}
+	findings := []Finding{
+		finding("apps/v1", "ReplicaSet", LevelWarning),
+		finding("example.com/v1", "Widget", LevelError),
+		finding("example.com/v1beta1", "Widget", LevelError),
+		finding("v1", "Pod", LevelError),
+		finding("a/b/c", "Thing", LevelWarning),
+		finding("apps/v1", "ReplicaSet", LevelWarning),
+	}
+	out := &bytes.Buffer{}
+	if err := printOwnerKindSummary(out, findings); err != nil {
+		t.Fatal(err)
+	}
+	expected := `OWNER_KIND           ERRORS   WARNINGS
+Widget.example.com   2        0
+Pod                  1        0
+ReplicaSet.apps      0        2
+Thing.a/b/c          0        1
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+
+	out.Reset()
+	if err := printOwnerKindSummary(out, nil); err != nil || out.Len() != 0 {
+		t.Errorf("expected nothing written without findings, got %q, %v", out.String(), err)
+	}
+}
@@ -0,0 +1,365 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+)
+
+// PolicyOptions contains options controlling how admission policies are generated from findings
+type PolicyOptions struct {
+	// Input is read for findings, either a report document or a stream of findings written by -o json
+	Input io.Reader
+	// Format is the policy format, either 'vap' (ValidatingAdmissionPolicy), 'kyverno', or 'gatekeeper'
+	Format string
+	// Name is the name of the generated policy objects
+	Name string
+	// Stdout is written to with the generated policy manifests
+	Stdout io.Writer
+}
+
+// Validate ensures the specified options are valid
+func (o *PolicyOptions) Validate() error {
+	if o.Input == nil {
+		return fmt.Errorf("input is required")
+	}
+	if o.Stdout == nil {
+		return fmt.Errorf("stdout is required")
+	}
+	if o.Format != "vap" && o.Format != "kyverno" && o.Format != "gatekeeper" {
+		return fmt.Errorf("invalid policy format, only 'vap', 'kyverno', and 'gatekeeper' are supported: %v", o.Format)
+	}
+	if o.Name == "" {
+		return fmt.Errorf("policy name is required")
+	}
+	return nil
+}
+
+// Run reads findings and writes policy manifests preventing the classes of invalid ownerReferences observed
+func (o *PolicyOptions) Run() error {
+	findings, err := readFindings(o.Input)
+	if err != nil {
+		return err
+	}
+	rules := newPolicyRules(findings)
+
+	fmt.Fprintf(o.Stdout, "# Generated by kubectl-check-ownerreferences from %s\n", pluralize(len(findings), "finding", "findings"))
+	if len(rules.unpreventable) > 0 {
+		codes := sets.StringKeySet(rules.unpreventable).List()
+		fmt.Fprintf(o.Stdout, "# Findings with these codes depend on the owner objects in the cluster and cannot be prevented by a stateless admission policy:\n")
+		for _, code := range codes {
+			fmt.Fprintf(o.Stdout, "#   %s: %d\n", code, rules.unpreventable[code])
+		}
+	}
+	if rules.empty() {
+		fmt.Fprintf(o.Stdout, "# No findings can be prevented by an admission policy\n")
+		return nil
+	}
+
+	var objects []interface{}
+	switch o.Format {
+	case "vap":
+		objects = rules.validatingAdmissionPolicy(o.Name)
+	case "kyverno":
+		objects = rules.kyvernoPolicy(o.Name)
+	case "gatekeeper":
+		objects = rules.gatekeeperPolicy(o.Name)
+	}
+	for _, object := range objects {
+		data, err := yaml.Marshal(object)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(o.Stdout, "---\n%s", data)
+	}
+	return nil
+}
+
+// policyRules describes the classes of invalid ownerReferences observed in findings
+type policyRules struct {
+	// unresolvableOwners are owner apiVersion/kind values that are not served by the cluster
+	unresolvableOwners sets.String
+	// namespacedOwners are owner apiVersion/kind values of namespaced types referenced by cluster-scoped objects
+	namespacedOwners sets.String
+	// children are the resources and kinds of the objects the findings were reported for
+	children map[metav1.GroupVersionResource]metav1.GroupVersionKind
+	// unpreventable counts the findings that cannot be prevented by code
+	unpreventable map[string]int
+}
+
+func newPolicyRules(findings []Finding) *policyRules {
+	rules := &policyRules{
+		unresolvableOwners: sets.NewString(),
+		namespacedOwners:   sets.NewString(),
+		children:           map[metav1.GroupVersionResource]metav1.GroupVersionKind{},
+		unpreventable:      map[string]int{},
+	}
+	for _, finding := range findings {
+		owner := finding.OwnerReference.APIVersion + "/" + finding.OwnerReference.Kind
+		switch finding.Code {
+		case CodeInvalidAPIVersion, CodeUnresolvableOwner:
+			rules.unresolvableOwners.Insert(owner)
+		case CodeNamespacedOwnerOfClusterScopedChild:
+			rules.namespacedOwners.Insert(owner)
+		default:
+			rules.unpreventable[finding.Code]++
+			continue
+		}
+		rules.children[finding.Resource] = finding.Kind
+	}
+	return rules
+}
+
+func (r *policyRules) empty() bool {
+	return len(r.unresolvableOwners) == 0 && len(r.namespacedOwners) == 0
+}
+
+const (
+	unresolvableOwnerMessage = "ownerReferences must refer to an apiVersion and kind served by the cluster"
+	namespacedOwnerMessage   = "cluster-scoped objects cannot have namespaced owners"
+)
+
+// childResources returns the groups and resources of the child objects, sorted by group
+func (r *policyRules) childResources() ([]string, map[string][]string) {
+	resources := map[string]sets.String{}
+	for gvr := range r.children {
+		if resources[gvr.Group] == nil {
+			resources[gvr.Group] = sets.NewString()
+		}
+		resources[gvr.Group].Insert(gvr.Resource)
+	}
+	groups := make([]string, 0, len(resources))
+	sortedResources := map[string][]string{}
+	for group, groupResources := range resources {
+		groups = append(groups, group)
+		sortedResources[group] = groupResources.List()
+	}
+	sort.Strings(groups)
+	return groups, sortedResources
+}
+
+// celList returns values as a CEL list literal
+func celList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strconv.Quote(value)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func (r *policyRules) validatingAdmissionPolicy(name string) []interface{} {
+	groups, resources := r.childResources()
+	resourceRules := []interface{}{}
+	for _, group := range groups {
+		resourceRules = append(resourceRules, map[string]interface{}{
+			"apiGroups":   []string{group},
+			"apiVersions": []string{"*"},
+			"operations":  []string{"CREATE", "UPDATE"},
+			"resources":   resources[group],
+		})
+	}
+
+	validations := []interface{}{}
+	if len(r.unresolvableOwners) > 0 {
+		validations = append(validations, map[string]interface{}{
+			"expression": fmt.Sprintf("!has(object.metadata.ownerReferences) || object.metadata.ownerReferences.all(ref, !(ref.apiVersion + '/' + ref.kind in %s))", celList(r.unresolvableOwners.List())),
+			"message":    unresolvableOwnerMessage,
+		})
+	}
+	if len(r.namespacedOwners) > 0 {
+		validations = append(validations, map[string]interface{}{
+			"expression": fmt.Sprintf("(has(object.metadata.namespace) && object.metadata.namespace != '') || !has(object.metadata.ownerReferences) || object.metadata.ownerReferences.all(ref, !(ref.apiVersion + '/' + ref.kind in %s))", celList(r.namespacedOwners.List())),
+			"message":    namespacedOwnerMessage,
+		})
+	}
+
+	return []interface{}{
+		map[string]interface{}{
+			"apiVersion": "admissionregistration.k8s.io/v1",
+			"kind":       "ValidatingAdmissionPolicy",
+			"metadata":   map[string]interface{}{"name": name},
+			"spec": map[string]interface{}{
+				"failurePolicy":    "Fail",
+				"matchConstraints": map[string]interface{}{"resourceRules": resourceRules},
+				"validations":      validations,
+			},
+		},
+		map[string]interface{}{
+			"apiVersion": "admissionregistration.k8s.io/v1",
+			"kind":       "ValidatingAdmissionPolicyBinding",
+			"metadata":   map[string]interface{}{"name": name},
+			"spec": map[string]interface{}{
+				"policyName":        name,
+				"validationActions": []string{"Deny"},
+			},
+		},
+	}
+}
+
+func (r *policyRules) kyvernoPolicy(name string) []interface{} {
+	kinds := sets.NewString()
+	for _, gvk := range r.children {
+		kinds.Insert(strings.TrimPrefix(gvk.Group+"/"+gvk.Version+"/"+gvk.Kind, "/"))
+	}
+	match := map[string]interface{}{
+		"any": []interface{}{
+			map[string]interface{}{"resources": map[string]interface{}{"kinds": kinds.List()}},
+		},
+	}
+	denyOwners := func(owners sets.String, message string) map[string]interface{} {
+		return map[string]interface{}{
+			"message": message,
+			"foreach": []interface{}{
+				map[string]interface{}{
+					"list": "request.object.metadata.ownerReferences",
+					"deny": map[string]interface{}{
+						"conditions": map[string]interface{}{
+							"any": []interface{}{
+								map[string]interface{}{
+									"key":      "{{ element.apiVersion }}/{{ element.kind }}",
+									"operator": "AnyIn",
+									"value":    owners.List(),
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	rules := []interface{}{}
+	if len(r.unresolvableOwners) > 0 {
+		rules = append(rules, map[string]interface{}{
+			"name":     "unresolvable-owners",
+			"match":    match,
+			"validate": denyOwners(r.unresolvableOwners, unresolvableOwnerMessage),
+		})
+	}
+	if len(r.namespacedOwners) > 0 {
+		rules = append(rules, map[string]interface{}{
+			"name":  "namespaced-owners-of-cluster-scoped-objects",
+			"match": match,
+			"preconditions": map[string]interface{}{
+				"all": []interface{}{
+					map[string]interface{}{
+						"key":      "{{ request.object.metadata.namespace || '' }}",
+						"operator": "Equals",
+						"value":    "",
+					},
+				},
+			},
+			"validate": denyOwners(r.namespacedOwners, namespacedOwnerMessage),
+		})
+	}
+
+	return []interface{}{
+		map[string]interface{}{
+			"apiVersion": "kyverno.io/v1",
+			"kind":       "ClusterPolicy",
+			"metadata":   map[string]interface{}{"name": name},
+			"spec": map[string]interface{}{
+				"validationFailureAction": "Enforce",
+				"background":              false,
+				"rules":                   rules,
+			},
+		},
+	}
+}
+
+// gatekeeperRego is the Rego source of the Gatekeeper ConstraintTemplate
+const gatekeeperRego = `package ownerreferencedenylist
+
+violation[{"msg": msg}] {
+  ref := input.review.object.metadata.ownerReferences[_]
+  owner := sprintf("%v/%v", [ref.apiVersion, ref.kind])
+  owner == input.parameters.unresolvableOwners[_]
+  msg := sprintf("` + unresolvableOwnerMessage + ` (%v %v)", [owner, ref.name])
+}
+
+violation[{"msg": msg}] {
+  not input.review.object.metadata.namespace
+  ref := input.review.object.metadata.ownerReferences[_]
+  owner := sprintf("%v/%v", [ref.apiVersion, ref.kind])
+  owner == input.parameters.namespacedOwners[_]
+  msg := sprintf("` + namespacedOwnerMessage + ` (%v %v)", [owner, ref.name])
+}
+`
+
+func (r *policyRules) gatekeeperPolicy(name string) []interface{} {
+	groups := sets.NewString()
+	kinds := sets.NewString()
+	for _, gvk := range r.children {
+		groups.Insert(gvk.Group)
+		kinds.Insert(gvk.Kind)
+	}
+	stringList := map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+
+	return []interface{}{
+		map[string]interface{}{
+			"apiVersion": "templates.gatekeeper.sh/v1",
+			"kind":       "ConstraintTemplate",
+			"metadata":   map[string]interface{}{"name": "ownerreferencedenylist"},
+			"spec": map[string]interface{}{
+				"crd": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"names": map[string]interface{}{"kind": "OwnerReferenceDenyList"},
+						"validation": map[string]interface{}{
+							"openAPIV3Schema": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"unresolvableOwners": stringList,
+									"namespacedOwners":   stringList,
+								},
+							},
+						},
+					},
+				},
+				"targets": []interface{}{
+					map[string]interface{}{
+						"target": "admission.k8s.gatekeeper.sh",
+						"rego":   gatekeeperRego,
+					},
+				},
+			},
+		},
+		map[string]interface{}{
+			"apiVersion": "constraints.gatekeeper.sh/v1beta1",
+			"kind":       "OwnerReferenceDenyList",
+			"metadata":   map[string]interface{}{"name": name},
+			"spec": map[string]interface{}{
+				"match": map[string]interface{}{
+					"kinds": []interface{}{
+						map[string]interface{}{"apiGroups": groups.List(), "kinds": kinds.List()},
+					},
+				},
+				"parameters": map[string]interface{}{
+					"unresolvableOwners": r.unresolvableOwners.List(),
+					"namespacedOwners":   r.namespacedOwners.List(),
+				},
+			},
+		},
+	}
+}
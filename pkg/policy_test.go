@@ -0,0 +1,163 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestReadFindings(t *testing.T) {
+	findings := []Finding{
+		{Resource: metav1.GroupVersionResource{Version: "v1", Resource: "pods"}, Namespace: "ns1", Name: "pod1", Level: LevelError, Code: CodeOwnerNotFound},
+		{Resource: metav1.GroupVersionResource{Version: "v1", Resource: "pods"}, Namespace: "ns1", Name: "pod2", Level: LevelWarning, Code: CodeOwnerListFailed},
+	}
+
+	stream := &bytes.Buffer{}
+	for _, finding := range findings {
+		json.NewEncoder(stream).Encode(finding)
+	}
+	document, err := json.Marshal(newReportDocument(&Report{Findings: findings}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name  string
+		input string
+	}{
+		{name: "stream", input: stream.String()},
+		{name: "report", input: string(document)},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := readFindings(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(findings, got); diff != "" {
+				t.Errorf("unexpected findings (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := readFindings(strings.NewReader("not json")); err == nil {
+		t.Error("expected error reading invalid input")
+	}
+}
+
+func TestPolicyRun(t *testing.T) {
+	replicasets := metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}
+	clusterroles := metav1.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}
+	findings := []Finding{
+		{
+			Resource: replicasets, Kind: metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}, Namespace: "ns1", Name: "rs1",
+			OwnerReference: metav1.OwnerReference{APIVersion: "extensions/v1beta1", Kind: "Deployment", Name: "d1"},
+			Level:          LevelError, Code: CodeUnresolvableOwner,
+		},
+		{
+			Resource: clusterroles, Kind: metav1.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}, Name: "role1",
+			OwnerReference: metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "cm1"},
+			Level:          LevelError, Code: CodeNamespacedOwnerOfClusterScopedChild,
+		},
+		{
+			Resource: metav1.GroupVersionResource{Version: "v1", Resource: "pods"}, Kind: metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}, Namespace: "ns1", Name: "pod1",
+			OwnerReference: metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "cm1"},
+			Level:          LevelError, Code: CodeNamespaceMismatch,
+		},
+	}
+	input := &bytes.Buffer{}
+	for _, finding := range findings {
+		json.NewEncoder(input).Encode(finding)
+	}
+
+	testcases := []struct {
+		format      string
+		expectKinds []string
+		expectText  []string
+	}{
+		{
+			format:      "vap",
+			expectKinds: []string{"ValidatingAdmissionPolicy", "ValidatingAdmissionPolicyBinding"},
+			expectText:  []string{`["extensions/v1beta1/Deployment"]`, `["v1/ConfigMap"]`, "- replicasets", "- clusterroles"},
+		},
+		{
+			format:      "kyverno",
+			expectKinds: []string{"ClusterPolicy"},
+			expectText:  []string{"- extensions/v1beta1/Deployment", "- v1/ConfigMap", "- apps/v1/ReplicaSet", "- rbac.authorization.k8s.io/v1/ClusterRole"},
+		},
+		{
+			format:      "gatekeeper",
+			expectKinds: []string{"ConstraintTemplate", "OwnerReferenceDenyList"},
+			expectText:  []string{"- extensions/v1beta1/Deployment", "- v1/ConfigMap", "- ReplicaSet", "- ClusterRole"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.format, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			opts := &PolicyOptions{Input: bytes.NewReader(input.Bytes()), Format: tc.format, Name: "test", Stdout: stdout}
+			if err := opts.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			if err := opts.Run(); err != nil {
+				t.Fatal(err)
+			}
+			output := stdout.String()
+			if !strings.Contains(output, "#   NamespaceMismatch: 1\n") {
+				t.Errorf("expected unpreventable findings to be listed, got:\n%s", output)
+			}
+
+			kinds := []string{}
+			for _, document := range strings.Split(output, "---\n")[1:] {
+				object := map[string]interface{}{}
+				if err := yaml.Unmarshal([]byte(document), &object); err != nil {
+					t.Fatalf("invalid yaml: %v\n%s", err, document)
+				}
+				kinds = append(kinds, object["kind"].(string))
+			}
+			if diff := cmp.Diff(tc.expectKinds, kinds); diff != "" {
+				t.Errorf("unexpected kinds (-want +got):\n%s", diff)
+			}
+			for _, text := range tc.expectText {
+				if !strings.Contains(output, text) {
+					t.Errorf("expected output to contain %q, got:\n%s", text, output)
+				}
+			}
+			if strings.Contains(output, "pods") || strings.Contains(output, "Pod\n") {
+				t.Errorf("expected children with unpreventable findings not to be matched, got:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestPolicyRunNothingPreventable(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	opts := &PolicyOptions{Input: strings.NewReader(`{"findings":[{"level":"Error","code":"OwnerNotFound"}]}`), Format: "vap", Name: "test", Stdout: stdout}
+	if err := opts.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(stdout.String(), "---") {
+		t.Errorf("expected no policies, got:\n%s", stdout.String())
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+)
+
+// PreflightOptions contains options controlling how access to list each resource is reviewed before listing
+type PreflightOptions struct {
+	// Client creates SelfSubjectAccessReviews for the scanning user
+	Client authorizationv1client.SelfSubjectAccessReviewsGetter
+	// MinCoverage is the minimum percentage of resources that must be allowed to be listed.
+	// If fewer are, the scan is aborted before listing. 0 only reports the resources that will be skipped.
+	MinCoverage float64
+}
+
+// Validate ensures the specified options are valid
+func (o *PreflightOptions) Validate() error {
+	if o.Client == nil {
+		return fmt.Errorf("access review client is required")
+	}
+	if o.MinCoverage < 0 || o.MinCoverage > 100 {
+		return fmt.Errorf("invalid minimum coverage, must be between 0 and 100: %v", o.MinCoverage)
+	}
+	return nil
+}
+
+// review returns the resources in gvrs the scanning user is not allowed to list in all namespaces, with the reason.
+// An error is returned if access could not be reviewed.
+func (o *PreflightOptions) review(ctx context.Context, gvrs []schema.GroupVersionResource) (map[schema.GroupVersionResource]string, error) {
+	denied := map[schema.GroupVersionResource]string{}
+	for _, gvr := range gvrs {
+		allowed, reason, err := reviewList(ctx, o.Client, gvr, "")
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			denied[gvr] = reason
+		}
+	}
+	return denied, nil
+}
+
+// reviewList returns whether the user of client is allowed to list gvr in namespace, or in all namespaces if namespace is "",
+// and the reason if not
+func reviewList(ctx context.Context, client authorizationv1client.SelfSubjectAccessReviewsGetter, gvr schema.GroupVersionResource, namespace string) (bool, string, error) {
+	review, err := client.SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{Verb: "list", Namespace: namespace, Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, "", fmt.Errorf("error reviewing access to list %v: %v", gvr, err)
+	}
+	if review.Status.Allowed {
+		return true, "", nil
+	}
+	reason := "list is not allowed"
+	if review.Status.Reason != "" {
+		reason += ": " + review.Status.Reason
+	}
+	if review.Status.EvaluationError != "" {
+		reason += " (" + review.Status.EvaluationError + ")"
+	}
+	return false, reason, nil
+}
+
+// coverage returns the percentage of total resources that were not denied
+func coverage(total, denied int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(total-denied) * 100 / float64(total)
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestPreflight(t *testing.T) {
+	cluster := newFakeCluster(t, "prod", "pod1")
+	discoveryClient := cluster.Verify.DiscoveryClient.(*fake.FakeDiscovery)
+	discoveryClient.Resources[0].APIResources = append(discoveryClient.Resources[0].APIResources,
+		metav1.APIResource{Name: "secrets", Namespaced: true, Kind: "Secret", Verbs: []string{"get", "list", "delete"}})
+
+	client := kubefake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action coretesting.Action) (bool, runtime.Object, error) {
+		review := action.(coretesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		review.Status.Allowed = review.Spec.ResourceAttributes.Resource != "secrets"
+		if !review.Status.Allowed {
+			review.Status.Reason = "no RBAC policy matched"
+		}
+		return true, review, nil
+	})
+	// listing secrets would fail if attempted
+	listedSecrets := false
+	cluster.Verify.MetadataClient.(*metadatafake.FakeMetadataClient).PrependReactor("list", "secrets", func(action coretesting.Action) (bool, runtime.Object, error) {
+		listedSecrets = true
+		return false, nil, nil
+	})
+
+	stderr := &bytes.Buffer{}
+	opts := cluster.Verify
+	opts.Stderr = stderr
+	opts.Preflight = &PreflightOptions{Client: client.AuthorizationV1()}
+	report, err := opts.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listedSecrets {
+		t.Error("expected secrets not to be listed")
+	}
+	expectFailures := []ScanFailure{{
+		GroupVersionResource: metav1.GroupVersionResource{Version: "v1", Resource: "secrets"},
+		Message:              "skipped by pre-flight access review: list is not allowed: no RBAC policy matched",
+		Forbidden:            true,
+	}}
+	if diff := cmp.Diff(expectFailures, report.Failures); diff != "" {
+		t.Errorf("unexpected failures (-want +got):\n%s", diff)
+	}
+	if report.Errors != 1 || report.Warnings != 1 {
+		t.Errorf("expected 1 error and 1 warning, got %d errors and %d warnings", report.Errors, report.Warnings)
+	}
+	if !strings.Contains(stderr.String(), "pre-flight access review: 1 of 2 resources can be listed (50.0%)") {
+		t.Errorf("expected coverage to be reported, got %q", stderr.String())
+	}
+
+	opts.Preflight.MinCoverage = 75
+	if _, err := opts.Scan(context.Background()); err == nil || !strings.Contains(err.Error(), "below the minimum coverage of 75%") {
+		t.Errorf("expected scan to be aborted below the minimum coverage, got %v", err)
+	}
+}
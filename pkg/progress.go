@@ -0,0 +1,181 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Progress event phases
+const (
+	// ProgressPhaseDiscovery is emitted once resources are discovered, with the number of resources to list
+	ProgressPhaseDiscovery = "discovery"
+	// ProgressPhaseList is emitted for each page listed, and with Done set once a resource is listed
+	ProgressPhaseList = "list"
+	// ProgressPhaseCheck is emitted once listing is complete, before checking the ownerReferences of listed objects
+	ProgressPhaseCheck = "check"
+	// ProgressPhaseComplete is emitted once the scan is complete, with a summary
+	ProgressPhaseComplete = "complete"
+)
+
+// ProgressEvent describes the progress of a scan, for wrapping automation to display progress and detect stalls
+type ProgressEvent struct {
+	Time  metav1.Time `json:"time"`
+	Phase string      `json:"phase"`
+	// GroupVersionResource is the resource being listed, in the list phase
+	GroupVersionResource *metav1.GroupVersionResource `json:"gvr,omitempty"`
+	// Resource is the 1-based index of the resource being listed, of Resources to list
+	Resource  int `json:"resource,omitempty"`
+	Resources int `json:"resources,omitempty"`
+	// Page is the 1-based index of the page listed. Items is the number of objects in the page,
+	// or the number of objects in all pages if Done is set, or the number of objects to check in the check phase.
+	Page  int  `json:"page,omitempty"`
+	Items int  `json:"items,omitempty"`
+	Done  bool `json:"done,omitempty"`
+	// Error describes why the resource could not be listed
+	Error string `json:"error,omitempty"`
+	// Summary describes the result of the scan, in the complete phase
+	Summary *ProgressSummary `json:"summary,omitempty"`
+}
+
+// ProgressSummary describes the result of a scan
+type ProgressSummary struct {
+	Findings        int     `json:"findings"`
+	Errors          int     `json:"errors"`
+	Warnings        int     `json:"warnings"`
+	Objects         int     `json:"objects"`
+	Interrupted     bool    `json:"interrupted"`
+	Complete        bool    `json:"complete"`
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+// progressWriter writes progress events as NDJSON to w, if set, and draws them on display, if set. A nil writer discards events.
+type progressWriter struct {
+	encoder *json.Encoder
+	display *progressLine
+	stderr  io.Writer
+	failed  bool
+}
+
+func newProgressWriter(w io.Writer, display *progressLine, stderr io.Writer) *progressWriter {
+	if w == nil && display == nil {
+		return nil
+	}
+	p := &progressWriter{display: display, stderr: stderr}
+	if w != nil {
+		p.encoder = json.NewEncoder(w)
+	}
+	return p
+}
+
+// emit writes event, stamped with the current time. After a write fails, a warning is written and further events are discarded.
+func (p *progressWriter) emit(event ProgressEvent) {
+	if p == nil {
+		return
+	}
+	if p.display != nil {
+		p.display.show(event)
+	}
+	if p.encoder == nil || p.failed {
+		return
+	}
+	event.Time = metav1.NewTime(time.Now())
+	if err := p.encoder.Encode(event); err != nil {
+		p.failed = true
+		fmt.Fprintf(p.stderr, "warning: could not write progress events, no further events will be written: %v\n", err)
+	}
+}
+
+// list emits a list phase event for gvr
+func (p *progressWriter) list(gvr schema.GroupVersionResource, event ProgressEvent) {
+	event.Phase = ProgressPhaseList
+	event.GroupVersionResource = &metav1.GroupVersionResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource}
+	p.emit(event)
+}
+
+// progressLineWidth is the maximum width of a progress line, so it does not wrap on narrow terminals
+const progressLineWidth = 79
+
+// progressLine draws the progress of a scan as a single line on a terminal, redrawn in place as the scan progresses.
+// Complete lines written through it are written above the progress line.
+type progressLine struct {
+	w    io.Writer
+	line string
+	// drawn is true if line is currently drawn at the end of the last line written to w
+	drawn bool
+}
+
+func (p *progressLine) Write(data []byte) (int, error) {
+	p.erase()
+	n, err := p.w.Write(data)
+	if err == nil && bytes.HasSuffix(data, []byte("\n")) {
+		p.draw()
+	}
+	return n, err
+}
+
+// show draws event as the progress line, or clears it once the scan is complete
+func (p *progressLine) show(event ProgressEvent) {
+	switch event.Phase {
+	case ProgressPhaseDiscovery:
+		p.line = fmt.Sprintf("discovered %s to list", pluralize(event.Resources, "resource", "resources"))
+	case ProgressPhaseList:
+		resource := schema.GroupResource{Group: event.GroupVersionResource.Group, Resource: event.GroupVersionResource.Resource}
+		if event.Done {
+			p.line = fmt.Sprintf("listed %d/%d %s: %s", event.Resource, event.Resources, resource, pluralize(event.Items, "object", "objects"))
+		} else {
+			p.line = fmt.Sprintf("listing %d/%d %s: page %d", event.Resource, event.Resources, resource, event.Page)
+		}
+	case ProgressPhaseCheck:
+		p.line = fmt.Sprintf("checking %s", pluralize(event.Items, "object", "objects"))
+	default:
+		p.clear()
+		return
+	}
+	if len(p.line) > progressLineWidth {
+		p.line = p.line[:progressLineWidth-3] + "..."
+	}
+	p.erase()
+	p.draw()
+}
+
+// clear erases the progress line, and stops redrawing it
+func (p *progressLine) clear() {
+	p.erase()
+	p.line = ""
+}
+
+func (p *progressLine) erase() {
+	if p.drawn {
+		fmt.Fprint(p.w, "\r\x1b[K")
+		p.drawn = false
+	}
+}
+
+func (p *progressLine) draw() {
+	if p.line != "" {
+		fmt.Fprint(p.w, p.line)
+		p.drawn = true
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestProgress(t *testing.T) {
+	cluster := newFakeCluster(t, "prod", "pod1", "pod2")
+	discoveryClient := cluster.Verify.DiscoveryClient.(*fake.FakeDiscovery)
+	discoveryClient.Resources[0].APIResources = append(discoveryClient.Resources[0].APIResources,
+		metav1.APIResource{Name: "secrets", Namespaced: true, Kind: "Secret", Verbs: []string{"get", "list", "delete"}})
+	cluster.Verify.MetadataClient.(*metadatafake.FakeMetadataClient).PrependReactor("list", "secrets", func(action coretesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("forbidden")
+	})
+
+	progress := &bytes.Buffer{}
+	opts := cluster.Verify
+	opts.Stdout, opts.Stderr, opts.Progress = ioutil.Discard, ioutil.Discard, progress
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opts.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []ProgressEvent
+	decoder := json.NewDecoder(progress)
+	for {
+		event := ProgressEvent{}
+		if err := decoder.Decode(&event); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if event.Time.IsZero() {
+			t.Errorf("expected events to be timestamped, got %#v", event)
+		}
+		event.Time = metav1.Time{}
+		events = append(events, event)
+	}
+	pods := &metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	secrets := &metav1.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	expect := []ProgressEvent{
+		{Phase: ProgressPhaseDiscovery, Resources: 2},
+		{Phase: ProgressPhaseList, GroupVersionResource: pods, Resource: 1, Resources: 2, Page: 1, Items: 2},
+		{Phase: ProgressPhaseList, GroupVersionResource: pods, Resource: 1, Resources: 2, Items: 2, Done: true},
+		{Phase: ProgressPhaseList, GroupVersionResource: secrets, Resource: 2, Resources: 2, Done: true, Error: "forbidden"},
+		{Phase: ProgressPhaseCheck, Items: 2},
+		{Phase: ProgressPhaseComplete, Summary: &ProgressSummary{Findings: 2, Errors: 2, Warnings: 1, Objects: 2}},
+	}
+	// durations vary
+	if len(events) > 0 && events[len(events)-1].Summary != nil {
+		events[len(events)-1].Summary.DurationSeconds = 0
+	}
+	if diff := cmp.Diff(expect, events); diff != "" {
+		t.Errorf("unexpected progress events (-want +got):\n%s", diff)
+	}
+}
+
+func TestShowProgress(t *testing.T) {
+	cluster := newFakeCluster(t, "prod", "pod1")
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	opts := cluster.Verify
+	opts.Stdout, opts.Stderr, opts.ShowProgress, opts.Color = stdout, stderr, true, true
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opts.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"discovered 1 resource to list", "listing 1/1 pods: page 1", "listed 1/1 pods: 1 object", "checking 1 object"} {
+		if !bytes.Contains(stderr.Bytes(), []byte("\r\x1b[K"+line)) && !bytes.HasPrefix(stderr.Bytes(), []byte(line)) {
+			t.Errorf("expected progress line %q, got %q", line, stderr.String())
+		}
+	}
+	// the progress line is erased before the summary
+	if !bytes.HasSuffix(stderr.Bytes(), []byte("\r\x1b[K1 error, 0 warnings\n")) {
+		t.Errorf("expected the progress line to be cleared before the summary, got %q", stderr.String())
+	}
+	if !bytes.Contains(stdout.Bytes(), []byte(colorRed+LevelError+colorDefault)) {
+		t.Errorf("expected a colored level, got %q", stdout.String())
+	}
+
+	// complete lines are written above the progress line, which is redrawn
+	out := &bytes.Buffer{}
+	display := &progressLine{w: out}
+	display.show(ProgressEvent{Phase: ProgressPhaseCheck, Items: 2})
+	fmt.Fprintf(display, "warning: something\n")
+	display.clear()
+	if expected := "checking 2 objects\r\x1b[Kwarning: something\nchecking 2 objects\r\x1b[K"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultPushgatewayJob is the job label metrics are pushed with if none is specified
+const DefaultPushgatewayJob = "kubectl-check-ownerreferences"
+
+var pushgatewayLabelPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// PushgatewayOptions contains options controlling how the metrics of each scan are pushed to a Prometheus Pushgateway
+type PushgatewayOptions struct {
+	// URL is the Pushgateway URL
+	URL string
+	// Job is the job label metrics are grouped by. If empty, DefaultPushgatewayJob is used.
+	Job string
+	// Grouping are additional labels metrics are grouped by, such as the cluster name
+	Grouping map[string]string
+	// Client is used to push metrics. If nil, a client with a 30 second timeout is used.
+	Client *http.Client
+}
+
+// Validate ensures the specified options are valid
+func (o *PushgatewayOptions) Validate() error {
+	if o.URL == "" {
+		return fmt.Errorf("pushgateway url is required")
+	}
+	if u, err := url.Parse(o.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid pushgateway url, must be an http or https url: %v", o.URL)
+	}
+	for name := range o.Grouping {
+		if !pushgatewayLabelPattern.MatchString(name) || name == "job" {
+			return fmt.Errorf("invalid pushgateway grouping label name: %q", name)
+		}
+	}
+	return nil
+}
+
+// publish replaces the metrics in the group of this job with the metrics of result,
+// so series for findings that were resolved are removed
+func (o *PushgatewayOptions) publish(ctx context.Context, result *Report) error {
+	body := &bytes.Buffer{}
+	if err := writeMetrics(body, metricsState{lastResult: result, lastSuccess: result.CompletionTime}); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", o.groupURL(), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	client := o.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error pushing metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("error pushing metrics: %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// groupURL returns the URL of the metrics group of the job and grouping labels
+func (o *PushgatewayOptions) groupURL() string {
+	job := o.Job
+	if job == "" {
+		job = DefaultPushgatewayJob
+	}
+	b := &strings.Builder{}
+	b.WriteString(strings.TrimSuffix(o.URL, "/"))
+	b.WriteString("/metrics")
+	b.WriteString(pushgatewayPathSegment("job", job))
+	names := make([]string, 0, len(o.Grouping))
+	for name := range o.Grouping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString(pushgatewayPathSegment(name, o.Grouping[name]))
+	}
+	return b.String()
+}
+
+// pushgatewayPathSegment returns the path segment for a grouping label,
+// base64-encoding values the path cannot otherwise hold
+func pushgatewayPathSegment(name, value string) string {
+	switch {
+	case value == "":
+		return "/" + name + "@base64/="
+	case strings.Contains(value, "/"):
+		return "/" + name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return "/" + name + "/" + url.PathEscape(value)
+}
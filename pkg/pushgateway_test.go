@@ -0,0 +1,93 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPushgatewayPublish(t *testing.T) {
+	var method, path, body string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		method, path, body = r.Method, r.URL.EscapedPath(), string(data)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	pods := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	result := &Report{
+		Findings: []Finding{
+			{Resource: pods, Namespace: "ns1", Name: "pod1", Level: LevelError, Code: CodeOwnerNotFound},
+		},
+		Errors:         1,
+		Duration:       2 * time.Second,
+		CompletionTime: time.Unix(1600000000, 0),
+	}
+
+	opts := &PushgatewayOptions{URL: server.URL + "/", Grouping: map[string]string{"cluster": "prod/east", "zone": ""}}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := opts.publish(context.Background(), result); err != nil {
+		t.Fatal(err)
+	}
+	if method != "PUT" {
+		t.Errorf("expected PUT to replace the group, got %s", method)
+	}
+	if expect := "/metrics/job/kubectl-check-ownerreferences/cluster@base64/cHJvZC9lYXN0/zone@base64/="; path != expect {
+		t.Errorf("expected path %s, got %s", expect, path)
+	}
+	for _, expect := range []string{
+		`invalid_ownerreferences{resource="pods",namespace="ns1",code="OwnerNotFound",level="Error"} 1`,
+		`ownerreferences_scan_duration_seconds 2`,
+		`ownerreferences_scan_failed_resources 0`,
+		`ownerreferences_last_success_timestamp_seconds 1600000000`,
+	} {
+		if !strings.Contains(body, expect+"\n") {
+			t.Errorf("expected pushed metrics to contain %q, got:\n%s", expect, body)
+		}
+	}
+
+	status = http.StatusBadRequest
+	if err := opts.publish(context.Background(), result); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("expected error for failed push, got %v", err)
+	}
+
+	for _, invalid := range []*PushgatewayOptions{
+		{},
+		{URL: "ftp://pushgateway"},
+		{URL: server.URL, Grouping: map[string]string{"job": "other"}},
+		{URL: server.URL, Grouping: map[string]string{"not-a-label": "x"}},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("expected %#v to be invalid", invalid)
+		}
+	}
+}
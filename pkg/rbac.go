@@ -0,0 +1,363 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/restmapper"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultRequiredVerbs are the verbs a resource must support to be listed by scans, the verbs garbage collection requires
+var DefaultRequiredVerbs = []string{"get", "list", "delete"}
+
+// RBACOptions contains options controlling the RBAC manifests generated for running scans in-cluster
+type RBACOptions struct {
+	DiscoveryClient discovery.DiscoveryInterface
+	Stderr          io.Writer
+	Stdout          io.Writer
+
+	// Name is the name of the generated ClusterRole and ClusterRoleBinding, and the Role and RoleBinding if any
+	Name string
+	// ServiceAccount and Namespace identify the ServiceAccount the tool runs as
+	ServiceAccount string
+	Namespace      string
+
+	// RequiredVerbs are the verbs a resource must support to be scanned, DefaultRequiredVerbs if empty
+	RequiredVerbs []string
+	// Fix also grants patch on the scanned resources, for removing invalid ownerReferences
+	Fix bool
+	// Events grants writing Events, for --emit-events
+	Events bool
+	// Reports grants writing OwnerReferenceReport objects, for -o crd and --publish-reports
+	Reports bool
+	// LeaderElection grants a Role writing Leases in Namespace, for --leader-elect
+	LeaderElection bool
+	// GCHealth grants a Role in kube-system reading the kube-controller-manager Lease and metrics, for --check-gc-health
+	GCHealth bool
+}
+
+// Validate ensures the specified options are valid
+func (o *RBACOptions) Validate() error {
+	if o.DiscoveryClient == nil {
+		return fmt.Errorf("discovery client is required")
+	}
+	if o.Stderr == nil {
+		return fmt.Errorf("stderr is required")
+	}
+	if o.Stdout == nil {
+		return fmt.Errorf("stdout is required")
+	}
+	if o.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if o.ServiceAccount == "" || o.Namespace == "" {
+		return fmt.Errorf("service account name and namespace are required")
+	}
+	return validateRequiredVerbs(o.RequiredVerbs)
+}
+
+// Run discovers the resources scans list, and writes the RBAC manifests granting access to them to Stdout
+func (o *RBACOptions) Run() error {
+	gvrs, _, err := discoverGCResources(o.DiscoveryClient, o.RequiredVerbs, o.Stderr)
+	if err != nil {
+		return err
+	}
+
+	// get is needed to check individual objects, and watch for --watch --incremental
+	verbs := []string{"get", "list", "watch"}
+	if o.Fix {
+		verbs = append(verbs, "patch")
+	}
+	clusterRole := &rbacv1.ClusterRole{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole"},
+		ObjectMeta: metav1.ObjectMeta{Name: o.Name},
+		Rules:      resourcePolicyRules(gvrs, verbs),
+	}
+	groups := len(clusterRole.Rules)
+	if o.Events {
+		clusterRole.Rules = append(clusterRole.Rules, rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"get", "create", "update"}})
+	}
+	if o.Reports {
+		clusterRole.Rules = append(clusterRole.Rules, rbacv1.PolicyRule{
+			APIGroups: []string{ReportGroupVersionResource.Group},
+			Resources: []string{ReportGroupVersionResource.Resource},
+			Verbs:     []string{"get", "list", "create", "update", "delete"},
+		})
+	}
+	subjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: o.ServiceAccount, Namespace: o.Namespace}}
+	objects := []interface{}{
+		clusterRole,
+		&rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRoleBinding"},
+			ObjectMeta: metav1.ObjectMeta{Name: o.Name},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: o.Name},
+			Subjects:   subjects,
+		},
+	}
+	if o.LeaderElection {
+		objects = append(objects,
+			&rbacv1.Role{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"},
+				ObjectMeta: metav1.ObjectMeta{Namespace: o.Namespace, Name: o.Name},
+				Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"coordination.k8s.io"}, Resources: []string{"leases"}, Verbs: []string{"get", "create", "update"}}},
+			},
+			&rbacv1.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Namespace: o.Namespace, Name: o.Name},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: o.Name},
+				Subjects:   subjects,
+			},
+		)
+	}
+
+	if o.GCHealth {
+		name := o.Name + "-gc-health"
+		objects = append(objects,
+			&rbacv1.Role{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"},
+				ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceSystem, Name: name},
+				Rules: []rbacv1.PolicyRule{
+					{APIGroups: []string{"coordination.k8s.io"}, Resources: []string{"leases"}, ResourceNames: []string{"kube-controller-manager"}, Verbs: []string{"get"}},
+					{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"list"}},
+					{APIGroups: []string{""}, Resources: []string{"pods/proxy"}, Verbs: []string{"get"}},
+				},
+			},
+			&rbacv1.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceSystem, Name: name},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name},
+				Subjects:   subjects,
+			},
+		)
+	}
+
+	fmt.Fprintf(o.Stdout, "# Generated by kubectl-check-ownerreferences for %s in %s\n", pluralize(len(gvrs), "resource", "resources"), pluralize(groups, "API group", "API groups"))
+	if o.GCHealth {
+		// proxying reaches every pod in kube-system, so the grant is called out rather than buried in the Role
+		fmt.Fprintf(o.Stdout, "# The %s-gc-health Role grants get on pods/proxy in kube-system, which reaches every pod there,\n# to read kube-controller-manager metrics. Remove that rule to only check the kube-controller-manager Lease.\n", o.Name)
+	}
+	for _, object := range objects {
+		data, err := yaml.Marshal(object)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(o.Stdout, "---\n%s", data)
+	}
+	return nil
+}
+
+// discoverGCResources returns the versions of the resources scans list and the resources listed at a fallback version,
+// as gcResourceVersions does, sorted.
+// Partial discovery is tolerated, warning about the API group versions that could not be discovered on stderr.
+func discoverGCResources(client discovery.DiscoveryInterface, verbs []string, stderr io.Writer) ([]schema.GroupVersionResource, []schema.GroupVersionResource, error) {
+	groupDiscoveryError := &discovery.ErrGroupDiscoveryFailed{}
+	groupResources, err := restmapper.GetAPIGroupResources(client)
+	if err != nil && !errors.As(err, &groupDiscoveryError) {
+		return nil, nil, err
+	}
+	preferredResources, err := discovery.ServerPreferredResources(client)
+	if errors.As(err, &groupDiscoveryError) {
+		failedGVs := []schema.GroupVersion{}
+		for failedGV := range groupDiscoveryError.Groups {
+			failedGVs = append(failedGVs, failedGV)
+		}
+		sort.Slice(failedGVs, func(i, j int) bool { return failedGVs[i].String() < failedGVs[j].String() })
+		for _, failedGV := range failedGVs {
+			fmt.Fprintf(stderr, "warning: could not discover resources in %s: %v\n", failedGV, groupDiscoveryError.Groups[failedGV])
+		}
+	} else if err != nil {
+		return nil, nil, err
+	}
+	return gcResourceVersions(preferredResources, groupResources, verbs)
+}
+
+// ScannedResources returns the resources a scan requiring verbs (DefaultRequiredVerbs if empty) lists, sorted:
+// the versions returned by discoverGCResources, without virtual resources and those serving the objects of other resources.
+// Partial discovery is tolerated, for completing resources from what could be discovered.
+func ScannedResources(client discovery.DiscoveryInterface, verbs []string) ([]schema.GroupVersionResource, error) {
+	cachedClient := memory.NewMemCacheClient(client)
+	gvrs, _, err := discoverGCResources(cachedClient, verbs, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	groupDiscoveryError := &discovery.ErrGroupDiscoveryFailed{}
+	groupResources, err := restmapper.GetAPIGroupResources(cachedClient)
+	if err != nil && !errors.As(err, &groupDiscoveryError) {
+		return nil, err
+	}
+	gvrs, _, _ = dedupeAliases(gvrs, groupResources)
+	return gvrs, nil
+}
+
+// validateRequiredVerbs ensures verbs, if any, include list, since scans list each resource
+func validateRequiredVerbs(verbs []string) error {
+	if len(verbs) == 0 {
+		return nil
+	}
+	for _, verb := range verbs {
+		if verb == "" {
+			return fmt.Errorf("required verbs cannot be empty")
+		}
+	}
+	if !sets.NewString(verbs...).Has("list") {
+		return fmt.Errorf("required verbs must include list: %v", verbs)
+	}
+	return nil
+}
+
+// requiredVerbs returns a filter matching resources that support verbs, or DefaultRequiredVerbs if empty
+func requiredVerbs(verbs []string) discovery.SupportsAllVerbs {
+	if len(verbs) == 0 {
+		verbs = DefaultRequiredVerbs
+	}
+	return discovery.SupportsAllVerbs{Verbs: verbs}
+}
+
+// gcResourceVersions returns the versions of the resources supporting verbs (DefaultRequiredVerbs if empty) that scans list, sorted:
+// the preferred versions reported by discovery.ServerPreferredResources, and a served version of each other resource in groupResources.
+// Preferred resources can omit resources in groups in odd states, such as groups with no preferred version,
+// or aggregated APIs whose preferred version could not be discovered mid-upgrade.
+// The resources listed at a served version instead are also returned as fallbacks.
+func gcResourceVersions(preferredResources []*metav1.APIResourceList, groupResources []*restmapper.APIGroupResources, verbs []string) ([]schema.GroupVersionResource, []schema.GroupVersionResource, error) {
+	gcVerbs := requiredVerbs(verbs)
+	gvrMap, err := discovery.GroupVersionResources(discovery.FilteredBy(gcVerbs, preferredResources))
+	if err != nil {
+		return nil, nil, err
+	}
+	gvrs := make([]schema.GroupVersionResource, 0, len(gvrMap))
+	listed := map[schema.GroupResource]bool{}
+	for gvr := range gvrMap {
+		gvrs = append(gvrs, gvr)
+		listed[gvr.GroupResource()] = true
+	}
+	fallbacks := []schema.GroupVersionResource{}
+	for _, group := range groupResources {
+		// prefer the preferred version, if any, then the versions in the order the server prioritizes them
+		versions := []string{group.Group.PreferredVersion.Version}
+		for _, version := range group.Group.Versions {
+			versions = append(versions, version.Version)
+		}
+		for _, version := range versions {
+			gv := schema.GroupVersion{Group: group.Group.Name, Version: version}
+			for i := range group.VersionedResources[version] {
+				resource := &group.VersionedResources[version][i]
+				gr := schema.GroupResource{Group: group.Group.Name, Resource: resource.Name}
+				if listed[gr] || strings.Contains(resource.Name, "/") || !gcVerbs.Match(gv.String(), resource) {
+					continue
+				}
+				listed[gr] = true
+				gvrs = append(gvrs, gv.WithResource(resource.Name))
+				fallbacks = append(fallbacks, gv.WithResource(resource.Name))
+			}
+		}
+	}
+	sortGVRs(gvrs)
+	sortGVRs(fallbacks)
+	return gvrs, fallbacks, nil
+}
+
+// resourcePolicyRules returns rules granting verbs on gvrs, with a rule per API group
+func resourcePolicyRules(gvrs []schema.GroupVersionResource, verbs []string) []rbacv1.PolicyRule {
+	resourcesByGroup := map[string]sets.String{}
+	for _, gvr := range gvrs {
+		if resourcesByGroup[gvr.Group] == nil {
+			resourcesByGroup[gvr.Group] = sets.NewString()
+		}
+		resourcesByGroup[gvr.Group].Insert(gvr.Resource)
+	}
+	groups := make([]string, 0, len(resourcesByGroup))
+	for group := range resourcesByGroup {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	rules := make([]rbacv1.PolicyRule, 0, len(groups))
+	for _, group := range groups {
+		rules = append(rules, rbacv1.PolicyRule{APIGroups: []string{group}, Resources: resourcesByGroup[group].List(), Verbs: verbs})
+	}
+	return rules
+}
+
+// printForbiddenHint writes the resources that could not be listed for lack of permissions in failures to w,
+// with a ClusterRole granting the missing permissions
+func printForbiddenHint(w io.Writer, failures []ScanFailure) error {
+	gvrs := []schema.GroupVersionResource{}
+	for _, failure := range failures {
+		if failure.Forbidden && failure.GroupVersionResource.Resource != "" {
+			gvrs = append(gvrs, schema.GroupVersionResource(failure.GroupVersionResource))
+		}
+	}
+	if len(gvrs) == 0 {
+		return nil
+	}
+	sortGVRs(gvrs)
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%s could not be listed for lack of permission:\n", pluralize(len(gvrs), "resource", "resources"))
+	for _, gvr := range gvrs {
+		fmt.Fprintf(b, "  list %s\n", gvr.GroupResource())
+	}
+	data, err := yaml.Marshal(&rbacv1.ClusterRole{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole"},
+		ObjectMeta: metav1.ObjectMeta{Name: "kubectl-check-ownerreferences-missing"},
+		Rules:      resourcePolicyRules(gvrs, []string{"get", "list", "watch"}),
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(b, "Grant the missing permissions by binding this ClusterRole to the scanning user, or regenerate all permissions with the rbac command:\n%s", data)
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// otherServedVersions returns the versions of the resources in gvrs in groupResources supporting verbs
+// (DefaultRequiredVerbs if empty) that are not in gvrs, sorted
+func otherServedVersions(groupResources []*restmapper.APIGroupResources, gvrs []schema.GroupVersionResource, verbs []string) []schema.GroupVersionResource {
+	gcVerbs := requiredVerbs(verbs)
+	listed := sets.NewString()
+	resources := map[schema.GroupResource]bool{}
+	for _, gvr := range gvrs {
+		listed.Insert(gvr.String())
+		resources[gvr.GroupResource()] = true
+	}
+	other := []schema.GroupVersionResource{}
+	for _, group := range groupResources {
+		for version, versionResources := range group.VersionedResources {
+			gv := schema.GroupVersion{Group: group.Group.Name, Version: version}
+			for i := range versionResources {
+				gvr := gv.WithResource(versionResources[i].Name)
+				if resources[gvr.GroupResource()] && !listed.Has(gvr.String()) && gcVerbs.Match(gv.String(), &versionResources[i]) {
+					listed.Insert(gvr.String())
+					other = append(other, gvr)
+				}
+			}
+		}
+	}
+	sortGVRs(other)
+	return other
+}
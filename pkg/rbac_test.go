@@ -0,0 +1,214 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/restmapper"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestRBACRun(t *testing.T) {
+	gcVerbs := []string{"get", "list", "watch", "create", "delete"}
+	discoveryClient := &fake.FakeDiscovery{Fake: &coretesting.Fake{}}
+	discoveryClient.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: gcVerbs},
+			{Name: "pods/log", Namespaced: true, Kind: "Pod", Verbs: []string{"get"}},
+			{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: gcVerbs},
+		}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{
+			{Name: "deployments", Namespaced: true, Kind: "Deployment", Verbs: gcVerbs},
+		}},
+		{GroupVersion: "authentication.k8s.io/v1", APIResources: []metav1.APIResource{
+			{Name: "tokenreviews", Kind: "TokenReview", Verbs: []string{"create"}},
+		}},
+	}
+
+	stdout := &bytes.Buffer{}
+	opts := &RBACOptions{
+		DiscoveryClient: discoveryClient,
+		Stderr:          ioutil.Discard,
+		Stdout:          stdout,
+		Name:            "checker",
+		ServiceAccount:  "checker",
+		Namespace:       "monitoring",
+		Fix:             true,
+		LeaderElection:  true,
+		GCHealth:        true,
+	}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := opts.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(stdout.String(), "# The checker-gc-health Role grants get on pods/proxy in kube-system") {
+		t.Errorf("expected the pods/proxy grant to be called out, got %s", stdout.String())
+	}
+	objects, err := ReadObjects(stdout, "rbac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := []string{}
+	for _, object := range objects {
+		kinds = append(kinds, object.GetKind()+" "+object.GetNamespace()+"/"+object.GetName())
+	}
+	expectKinds := []string{"ClusterRole /checker", "ClusterRoleBinding /checker", "Role monitoring/checker", "RoleBinding monitoring/checker", "Role kube-system/checker-gc-health", "RoleBinding kube-system/checker-gc-health"}
+	if diff := cmp.Diff(expectKinds, kinds); diff != "" {
+		t.Fatalf("unexpected objects (-want +got):\n%s", diff)
+	}
+
+	clusterRole := &rbacv1.ClusterRole{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(objects[0].Object, clusterRole); err != nil {
+		t.Fatal(err)
+	}
+	verbs := []string{"get", "list", "watch", "patch"}
+	expectRules := []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"configmaps", "pods"}, Verbs: verbs},
+		{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: verbs},
+	}
+	if diff := cmp.Diff(expectRules, clusterRole.Rules); diff != "" {
+		t.Errorf("unexpected rules (-want +got):\n%s", diff)
+	}
+
+	binding := &rbacv1.ClusterRoleBinding{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(objects[1].Object, binding); err != nil {
+		t.Fatal(err)
+	}
+	expectSubjects := []rbacv1.Subject{{Kind: "ServiceAccount", Name: "checker", Namespace: "monitoring"}}
+	if diff := cmp.Diff(expectSubjects, binding.Subjects); diff != "" {
+		t.Errorf("unexpected subjects (-want +got):\n%s", diff)
+	}
+}
+
+func TestGCResourceVersions(t *testing.T) {
+	gcVerbs := []string{"get", "list", "delete"}
+	preferredResources := []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods", Verbs: gcVerbs}}},
+	}
+	groupResources := []*restmapper.APIGroupResources{
+		{
+			Group:              metav1.APIGroup{Versions: []metav1.GroupVersionForDiscovery{{Version: "v1"}}, PreferredVersion: metav1.GroupVersionForDiscovery{Version: "v1"}},
+			VersionedResources: map[string][]metav1.APIResource{"v1": {{Name: "pods", Verbs: gcVerbs}, {Name: "pods/status", Verbs: gcVerbs}}},
+		},
+		{
+			// no preferred version, as for a group whose versions are all deprecated
+			Group: metav1.APIGroup{Name: "example.com", Versions: []metav1.GroupVersionForDiscovery{{Version: "v1beta2"}, {Version: "v1beta1"}}},
+			VersionedResources: map[string][]metav1.APIResource{
+				"v1beta1": {{Name: "widgets", Verbs: gcVerbs}, {Name: "gadgets", Verbs: gcVerbs}, {Name: "reviews", Verbs: []string{"create"}}},
+				"v1beta2": {{Name: "widgets", Verbs: gcVerbs}},
+			},
+		},
+	}
+	gvrs, fallbacks, err := gcResourceVersions(preferredResources, groupResources, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedFallbacks := []schema.GroupVersionResource{
+		{Group: "example.com", Version: "v1beta1", Resource: "gadgets"},
+		{Group: "example.com", Version: "v1beta2", Resource: "widgets"},
+	}
+	expectedGVRs := append([]schema.GroupVersionResource{{Version: "v1", Resource: "pods"}}, expectedFallbacks...)
+	if diff := cmp.Diff(expectedGVRs, gvrs); diff != "" {
+		t.Errorf("unexpected resources (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(expectedFallbacks, fallbacks); diff != "" {
+		t.Errorf("unexpected fallbacks (-want +got):\n%s", diff)
+	}
+
+	other := otherServedVersions(groupResources, gvrs, nil)
+	expectedOther := []schema.GroupVersionResource{{Group: "example.com", Version: "v1beta1", Resource: "widgets"}}
+	if diff := cmp.Diff(expectedOther, other); diff != "" {
+		t.Errorf("unexpected other versions (-want +got):\n%s", diff)
+	}
+}
+
+func TestRequiredVerbs(t *testing.T) {
+	preferredResources := []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "pods", Verbs: []string{"get", "list", "watch", "delete"}},
+			{Name: "componentstatuses", Verbs: []string{"get", "list"}},
+		}},
+		{GroupVersion: "metrics.k8s.io/v1beta1", APIResources: []metav1.APIResource{{Name: "pods", Verbs: []string{"get", "list"}}}},
+	}
+	for _, tc := range []struct {
+		verbs    []string
+		expected []schema.GroupVersionResource
+	}{
+		{verbs: nil, expected: []schema.GroupVersionResource{{Version: "v1", Resource: "pods"}}},
+		{verbs: []string{"get", "list"}, expected: []schema.GroupVersionResource{
+			{Version: "v1", Resource: "componentstatuses"},
+			{Version: "v1", Resource: "pods"},
+			{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "pods"},
+		}},
+		{verbs: []string{"list", "watch"}, expected: []schema.GroupVersionResource{{Version: "v1", Resource: "pods"}}},
+	} {
+		gvrs, _, err := gcResourceVersions(preferredResources, nil, tc.verbs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(tc.expected, gvrs); diff != "" {
+			t.Errorf("%v: unexpected resources (-want +got):\n%s", tc.verbs, diff)
+		}
+	}
+
+	if err := validateRequiredVerbs([]string{"get", "delete"}); err == nil {
+		t.Errorf("expected error for verbs without list")
+	}
+	if err := validateRequiredVerbs(nil); err != nil {
+		t.Errorf("unexpected error for default verbs: %v", err)
+	}
+}
+
+func TestScannedResources(t *testing.T) {
+	client := &fake.FakeDiscovery{Fake: &coretesting.Fake{}}
+	client.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "events", Namespaced: true, Kind: "Event", Verbs: []string{"get", "list", "delete"}},
+			{Name: "componentstatuses", Kind: "ComponentStatus", Verbs: []string{"get", "list"}},
+		}},
+		{GroupVersion: "events.k8s.io/v1", APIResources: []metav1.APIResource{{Name: "events", Namespaced: true, Kind: "Event", Verbs: []string{"get", "list", "delete"}}}},
+	}
+	for _, tc := range []struct {
+		verbs    []string
+		expected []schema.GroupVersionResource
+	}{
+		// aliases are not listed
+		{verbs: nil, expected: []schema.GroupVersionResource{{Version: "v1", Resource: "events"}}},
+		{verbs: []string{"get", "list"}, expected: []schema.GroupVersionResource{{Version: "v1", Resource: "componentstatuses"}, {Version: "v1", Resource: "events"}}},
+	} {
+		gvrs, err := ScannedResources(client, tc.verbs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(tc.expected, gvrs); diff != "" {
+			t.Errorf("%v: unexpected resources (-want +got):\n%s", tc.verbs, diff)
+		}
+	}
+}
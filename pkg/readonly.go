@@ -0,0 +1,65 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// readOnlyPOSTPaths are the only paths requests other than GET, HEAD, and OPTIONS are sent to in read-only mode.
+// They are matched as suffixes, since API servers may be reached through a proxy under a path prefix.
+// Creating a SelfSubjectAccessReview evaluates access without persisting anything.
+var readOnlyPOSTPaths = []string{
+	"/apis/authorization.k8s.io/v1/selfsubjectaccessreviews",
+}
+
+// readOnlyPOST returns true if path is one of readOnlyPOSTPaths, under any prefix
+func readOnlyPOST(path string) bool {
+	for _, allowed := range readOnlyPOSTPaths {
+		if strings.HasSuffix(path, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadOnly returns a RoundTripper that refuses requests through rt that could modify the cluster,
+// failing them without sending them: anything but GET, HEAD, and OPTIONS requests,
+// except POSTs creating SelfSubjectAccessReviews.
+// It is suitable for use as a rest.Config#WrapTransport function.
+func ReadOnly(rt http.RoundTripper) http.RoundTripper {
+	return &readOnlyRoundTripper{delegate: rt}
+}
+
+type readOnlyRoundTripper struct {
+	delegate http.RoundTripper
+}
+
+func (r *readOnlyRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch {
+	case req.Method == http.MethodGet || req.Method == http.MethodHead || req.Method == http.MethodOptions:
+	case req.Method == http.MethodPost && readOnlyPOST(req.URL.Path):
+	default:
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("refusing %s %s in read-only mode", req.Method, req.URL.Path)
+	}
+	return r.delegate.RoundTrip(req)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestReadOnly(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/namespaces/ns1/configmaps":
+			w.Write([]byte(`{"apiVersion":"v1","kind":"ConfigMapList","items":[]}`))
+		default:
+			w.Write([]byte(`{"apiVersion":"authorization.k8s.io/v1","kind":"SelfSubjectAccessReview","status":{"allowed":true}}`))
+		}
+	}))
+	defer server.Close()
+
+	config := &rest.Config{Host: server.URL}
+	config.Wrap(ReadOnly)
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if _, err := client.CoreV1().ConfigMaps("ns1").List(ctx, metav1.ListOptions{}); err != nil {
+		t.Errorf("expected list to be allowed, got %v", err)
+	}
+	if allowed, _, err := reviewList(ctx, client.AuthorizationV1(), ReportGroupVersionResource, ""); err != nil || !allowed {
+		t.Errorf("expected access reviews to be allowed, got %v", err)
+	}
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cm1"}}
+	if _, err := client.CoreV1().ConfigMaps("ns1").Create(ctx, configMap, metav1.CreateOptions{}); err == nil || !strings.Contains(err.Error(), "refusing POST /api/v1/namespaces/ns1/configmaps in read-only mode") {
+		t.Errorf("expected create to be refused, got %v", err)
+	}
+	if _, err := client.CoreV1().ConfigMaps("ns1").Update(ctx, configMap, metav1.UpdateOptions{}); err == nil {
+		t.Error("expected update to be refused")
+	}
+	if err := client.CoreV1().ConfigMaps("ns1").Delete(ctx, "cm1", metav1.DeleteOptions{}); err == nil {
+		t.Error("expected delete to be refused")
+	}
+
+	// access reviews are allowed through a proxy serving the API server under a path prefix
+	config = &rest.Config{Host: server.URL + "/k8s/clusters/c-1"}
+	config.Wrap(ReadOnly)
+	if client, err = kubernetes.NewForConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	if allowed, _, err := reviewList(ctx, client.AuthorizationV1(), ReportGroupVersionResource, ""); err != nil || !allowed {
+		t.Errorf("expected access reviews through a proxy to be allowed, got %v", err)
+	}
+	if _, err := client.CoreV1().ConfigMaps("ns1").Create(ctx, configMap, metav1.CreateOptions{}); err == nil {
+		t.Error("expected create through a proxy to be refused")
+	}
+
+	expect := []string{"GET /api/v1/namespaces/ns1/configmaps", "POST /apis/authorization.k8s.io/v1/selfsubjectaccessreviews", "POST /k8s/clusters/c-1/apis/authorization.k8s.io/v1/selfsubjectaccessreviews"}
+	if strings.Join(requests, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("expected only %v to be sent, got %v", expect, requests)
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// RedactOptions contains options controlling how findings are anonymized for sharing
+type RedactOptions struct {
+	// Salt keys the hashes replacing names. The same salt produces the same hashes, so correlations hold across reports.
+	Salt []byte
+	// MappingPath is optionally the path to write a JSON object mapping each redacted value to the original value to,
+	// for de-anonymizing shared findings internally
+	MappingPath string
+}
+
+// Validate ensures the specified options are valid
+func (o *RedactOptions) Validate() error {
+	if len(o.Salt) < 16 {
+		return fmt.Errorf("redaction salt must be at least 16 bytes")
+	}
+	return nil
+}
+
+// LoadRedactSalt reads the salt in path, or generates a random salt and writes it to path if the file does not exist
+func LoadRedactSalt(path string) ([]byte, error) {
+	salt, err := ioutil.ReadFile(path)
+	if err == nil {
+		return salt, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	salt = make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	salt = []byte(hex.EncodeToString(salt))
+	if err := ioutil.WriteFile(path, salt, 0600); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// redactor replaces values with salted hashes, remembering the original values
+type redactor struct {
+	salt     []byte
+	original map[string]string
+	redacted map[string]string
+}
+
+// value returns the redacted form of value. Empty values are preserved, so cluster-scoped objects remain recognizable.
+func (r *redactor) value(value string) string {
+	if value == "" {
+		return ""
+	}
+	if redacted, ok := r.redacted[value]; ok {
+		return redacted
+	}
+	mac := hmac.New(sha256.New, r.salt)
+	mac.Write([]byte(value))
+	redacted := "r-" + hex.EncodeToString(mac.Sum(nil))[:12]
+	r.redacted[value] = redacted
+	r.original[redacted] = value
+	return redacted
+}
+
+// message replaces the values redacted so far in message, longest first so values containing others are replaced whole
+func (r *redactor) message(message string) string {
+	values := make([]string, 0, len(r.redacted))
+	for value := range r.redacted {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+	replacements := make([]string, 0, 2*len(values))
+	for _, value := range values {
+		replacements = append(replacements, value, r.redacted[value])
+	}
+	return strings.NewReplacer(replacements...).Replace(message)
+}
+
+// managedBy returns a copy of m with the namespace and name of its instance replaced by salted hashes, hashed separately
+// so they match those of objects. The tool, and whether a Velero instance is a backup or a restore, are preserved.
+func (r *redactor) managedBy(m *ManagedBy) *ManagedBy {
+	if m == nil {
+		return nil
+	}
+	prefix, instance := "", m.Instance
+	if m.Tool == ToolVelero {
+		for _, kind := range []string{"restore ", "backup "} {
+			if strings.HasPrefix(instance, kind) {
+				prefix, instance = kind, strings.TrimPrefix(instance, kind)
+			}
+		}
+	}
+	parts := strings.Split(instance, "/")
+	for i := range parts {
+		parts[i] = r.value(parts[i])
+	}
+	return &ManagedBy{Tool: m.Tool, Instance: prefix + strings.Join(parts, "/")}
+}
+
+// redactFindings returns copies of findings with the cluster, namespaces, names, and UIDs of objects and owners,
+// the instances managing them, and the users that wrote them, replaced by salted hashes, and user agents removed. Occurrences of the hashed values in
+// messages are replaced too.
+// Resources, kinds, apiVersions, and codes are preserved.
+func (r *redactor) redactFindings(findings []Finding) []Finding {
+	redacted := make([]Finding, 0, len(findings))
+	for _, finding := range findings {
+		finding.Cluster = r.value(finding.Cluster)
+		finding.Namespace = r.value(finding.Namespace)
+		finding.Name = r.value(finding.Name)
+		finding.UID = types.UID(r.value(string(finding.UID)))
+		ownerRef := finding.OwnerReference
+		ownerRef.Name = r.value(ownerRef.Name)
+		ownerRef.UID = types.UID(r.value(string(ownerRef.UID)))
+		finding.OwnerReference = ownerRef
+		finding.ManagedBy = r.managedBy(finding.ManagedBy)
+		if finding.Audit != nil {
+			audit := *finding.Audit
+			audit.User = r.value(audit.User)
+			audit.UserAgent = ""
+			finding.Audit = &audit
+		}
+		if finding.Candidates != nil {
+			candidates := make([]OwnerCandidate, 0, len(finding.Candidates))
+			for _, candidate := range finding.Candidates {
+				candidates = append(candidates, OwnerCandidate{Namespace: r.value(candidate.Namespace), Name: r.value(candidate.Name), UID: types.UID(r.value(string(candidate.UID)))})
+			}
+			finding.Candidates = candidates
+		}
+		redacted = append(redacted, finding)
+	}
+	// messages are redacted once all values are known, since a message may mention values of other findings
+	for i := range redacted {
+		redacted[i].Message = r.message(redacted[i].Message)
+	}
+	return redacted
+}
+
+// redact replaces the findings, resolved findings, and scanned cluster of report with redacted copies, and writes the mapping if configured
+func (o *RedactOptions) redact(report *Report) error {
+	r := &redactor{salt: o.Salt, original: map[string]string{}, redacted: map[string]string{}}
+	all := r.redactFindings(append(report.Findings[:len(report.Findings):len(report.Findings)], report.Resolved...))
+	report.Findings, report.Resolved = all[:len(report.Findings)], all[len(report.Findings):]
+	// failure messages may name the namespaces listed
+	failures := make([]ScanFailure, 0, len(report.Failures))
+	for _, failure := range report.Failures {
+		failure.Message = r.message(failure.Message)
+		failures = append(failures, failure)
+	}
+	report.Failures = failures
+	if cluster := report.Scan.Cluster; cluster != nil {
+		report.Scan.Cluster = &ClusterInfo{Server: r.value(cluster.Server), Context: r.value(cluster.Context), UID: r.value(cluster.UID)}
+	}
+	if o.MappingPath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(r.original, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomically(o.MappingPath, append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing redaction mapping: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	dir := t.TempDir()
+	salt, err := LoadRedactSalt(filepath.Join(dir, "salt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := LoadRedactSalt(filepath.Join(dir, "salt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(salt, reloaded) {
+		t.Fatal("expected the generated salt to be reused")
+	}
+
+	scan := func(salt []byte) []Finding {
+		t.Helper()
+		cluster := newFakeCluster(t, "prod", "pod1", "pod2")
+		opts := cluster.Verify
+		opts.Stdout, opts.Stderr, opts.Output = ioutil.Discard, ioutil.Discard, "json"
+		opts.Redact = &RedactOptions{Salt: salt, MappingPath: filepath.Join(dir, "mapping.json")}
+		if err := opts.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		report, err := opts.run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report.Findings
+	}
+
+	findings := scan(salt)
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %d", len(findings))
+	}
+	for _, finding := range findings {
+		data, err := json.Marshal(finding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, value := range []string{"ns1", "pod1", "pod2", "missing"} {
+			if strings.Contains(string(data), `"`+value+`"`) || strings.Contains(finding.Message, value) {
+				t.Errorf("expected %s to be redacted, got %s", value, data)
+			}
+		}
+		if finding.Resource.Resource != "pods" || finding.OwnerReference.Kind != "Pod" {
+			t.Errorf("expected resources and kinds to be preserved, got %s", data)
+		}
+	}
+	// correlations are preserved: both pods are in the same namespace and claim the same owner
+	if findings[0].Namespace != findings[1].Namespace || findings[0].OwnerReference.UID != findings[1].OwnerReference.UID {
+		t.Errorf("expected shared values to redact to the same hash, got %#v", findings)
+	}
+	if findings[0].Name == findings[1].Name {
+		t.Errorf("expected distinct names to redact to distinct hashes, got %q", findings[0].Name)
+	}
+
+	// the mapping de-anonymizes the findings
+	data, err := ioutil.ReadFile(filepath.Join(dir, "mapping.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping := map[string]string{}
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		t.Fatal(err)
+	}
+	if mapping[findings[0].Namespace] != "ns1" || mapping[string(findings[0].OwnerReference.UID)] != "missinguid-prod" {
+		t.Errorf("unexpected mapping: %v", mapping)
+	}
+
+	// hashes are stable for a salt, and differ between salts
+	if again := scan(salt); again[0].Name != findings[0].Name {
+		t.Errorf("expected the same salt to produce the same hashes, got %q and %q", findings[0].Name, again[0].Name)
+	}
+	if other := scan([]byte("another salt of sufficient length")); other[0].Name == findings[0].Name {
+		t.Errorf("expected a different salt to produce different hashes")
+	}
+
+	// the instances managing objects are hashed like namespaces and names, keeping the tool
+	r := &redactor{salt: salt, original: map[string]string{}, redacted: map[string]string{}}
+	managed := r.redactFindings([]Finding{
+		{Namespace: "team-payments", Name: "payments-api", ManagedBy: &ManagedBy{Tool: ToolHelm, Instance: "team-payments/payments-api"}},
+		{Name: "payments-api", ManagedBy: &ManagedBy{Tool: ToolVelero, Instance: "restore nightly"}},
+	})
+	if expected := "helm/" + managed[0].Namespace + "/" + managed[0].Name; managed[0].ManagedBy.String() != expected {
+		t.Errorf("expected managed by %s, got %s", expected, managed[0].ManagedBy)
+	}
+	if managedBy := managed[1].ManagedBy.String(); !strings.HasPrefix(managedBy, "velero/restore r-") || strings.Contains(managedBy, "nightly") {
+		t.Errorf("expected the restore name to be redacted, got %s", managedBy)
+	}
+
+	opts := newFakeCluster(t, "prod").Verify
+	opts.Stdout, opts.Stderr = ioutil.Discard, ioutil.Discard
+	opts.Redact = &RedactOptions{Salt: []byte("short")}
+	if err := opts.Validate(); err == nil || !strings.Contains(err.Error(), "salt") {
+		t.Errorf("expected a short salt to be rejected, got %v", err)
+	}
+	opts.Redact.Salt, opts.EmitTriage = salt, true
+	if err := opts.Validate(); err == nil {
+		t.Error("expected redaction to be rejected with triage commands")
+	}
+	opts.EmitTriage, opts.WriteBaseline = false, filepath.Join(dir, "baseline.json")
+	if err := opts.Validate(); err == nil || !strings.Contains(err.Error(), "baseline") {
+		t.Errorf("expected redaction to be rejected when writing a baseline, got %v", err)
+	}
+	opts.WriteBaseline, opts.Inventory = "", filepath.Join(dir, "inventory.json")
+	if err := opts.Validate(); err == nil || !strings.Contains(err.Error(), "inventory") {
+		t.Errorf("expected redaction to be rejected when writing an inventory, got %v", err)
+	}
+}
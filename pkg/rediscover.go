@@ -0,0 +1,58 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"errors"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/restmapper"
+)
+
+// rediscoverResources invalidates client and resets mapper, which uses it, so discovery runs again,
+// and returns the resources supporting verbs that are not in gvrs, sorted,
+// such as custom resources whose definitions were created by operators installed while the scan was listing.
+// Partial discovery is tolerated, since failures of the first discovery are already recorded.
+func rediscoverResources(client discovery.CachedDiscoveryInterface, mapper *restmapper.DeferredDiscoveryRESTMapper, gvrs []schema.GroupVersionResource, verbs []string) ([]schema.GroupVersionResource, error) {
+	client.Invalidate()
+	mapper.Reset()
+	groupDiscoveryError := &discovery.ErrGroupDiscoveryFailed{}
+	groupResources, err := restmapper.GetAPIGroupResources(client)
+	if err != nil && !errors.As(err, &groupDiscoveryError) {
+		return nil, err
+	}
+	preferredResources, err := discovery.ServerPreferredResources(client)
+	if err != nil && !errors.As(err, &groupDiscoveryError) {
+		return nil, err
+	}
+	discovered, _, err := gcResourceVersions(preferredResources, groupResources, verbs)
+	if err != nil {
+		return nil, err
+	}
+	listed := map[schema.GroupResource]bool{}
+	for _, gvr := range gvrs {
+		listed[gvr.GroupResource()] = true
+	}
+	added := []schema.GroupVersionResource{}
+	for _, gvr := range discovered {
+		if !listed[gvr.GroupResource()] {
+			added = append(added, gvr)
+		}
+	}
+	return added, nil
+}
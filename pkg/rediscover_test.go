@@ -0,0 +1,111 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestRunRediscovery(t *testing.T) {
+	gcVerbs := []string{"get", "list", "delete"}
+	widgets := &metav1.APIResourceList{
+		GroupVersion: "example.com/v1",
+		APIResources: []metav1.APIResource{{Name: "widgets", Namespaced: true, Kind: "Widget", Verbs: gcVerbs}},
+	}
+	metadataClient := metadatafake.NewSimpleMetadataClient(runtime.NewScheme())
+	for _, object := range []struct {
+		gvr    schema.GroupVersionResource
+		object *metav1.PartialObjectMetadata
+	}{
+		{
+			gvr: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"},
+			object: &metav1.PartialObjectMetadata{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "example.com/v1", Kind: "Widget"},
+				ObjectMeta: metav1.ObjectMeta{Name: "widget1", Namespace: "ns1", UID: "widget1"},
+			},
+		},
+		{
+			gvr: schema.GroupVersionResource{Version: "v1", Resource: "pods"},
+			object: &metav1.PartialObjectMetadata{
+				TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+				ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns1", UID: "pod1", OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: "example.com/v1", Kind: "Widget", Name: "widget1", UID: "widget1"},
+				}},
+			},
+		},
+	} {
+		client := metadataClient.Resource(object.gvr).Namespace("ns1")
+		if _, err := client.(metadatafake.MetadataClient).CreateFake(object.object, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, skip := range []bool{false, true} {
+		discoveryClient := &fake.FakeDiscovery{Fake: &coretesting.Fake{}}
+		discoveryClient.Resources = []*metav1.APIResourceList{{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: gcVerbs}},
+		}}
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		opts := &VerifyGCOptions{
+			DiscoveryClient: discoveryClient,
+			MetadataClient:  metadataClient,
+			SkipRediscovery: skip,
+			Output:          "json",
+			Stderr:          stderr,
+			Stdout:          stdout,
+			// the widget custom resource is installed while pods are listed
+			onListed: func(gvr schema.GroupVersionResource, objects int, err error) {
+				if gvr.Resource == "pods" {
+					discoveryClient.Resources = append(discoveryClient.Resources, widgets)
+				}
+			},
+		}
+		if err := opts.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := opts.run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		findings, err := readFindings(stdout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if skip {
+			if len(findings) != 1 {
+				t.Errorf("expected a finding for the owner of unknown kind without rediscovery, got %#v", findings)
+			}
+			continue
+		}
+		if len(findings) != 0 {
+			t.Errorf("expected no findings, got %#v", findings)
+		}
+		if !strings.Contains(stderr.String(), "1 resource was discovered while listing, listing widgets.v1.example.com\n") {
+			t.Errorf("expected rediscovered resources on stderr, got %q", stderr.String())
+		}
+	}
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/open-policy-agent/opa/rego"
+)
+
+const (
+	// regoQuery is evaluated for each ownerReference to collect violations
+	regoQuery = "data.ownerreferences.violation"
+	// CodeRegoViolation is the code of findings from Rego violations that do not specify a code
+	CodeRegoViolation = "RegoViolation"
+	// CodeRegoEvaluationFailed indicates a Rego policy could not be evaluated for an ownerReference
+	CodeRegoEvaluationFailed = "RegoEvaluationFailed"
+)
+
+// RegoPolicy is a Rego module defining custom checks.
+//
+// Modules are in the ownerreferences package, and define a violation set evaluated for each ownerReference,
+// with the same input fields as CELRule variables. Each violation is an object with a message,
+// and optionally a code (defaulting to RegoViolation) and level (Error or Warning, defaulting to Error):
+//
+//	package ownerreferences
+//
+//	violation[{"code": "TeamMismatch", "message": msg}] {
+//	  input.ownerFound
+//	  input.owner.labels.team != input.child.labels.team
+//	  msg := sprintf("owner team %v does not match child team %v", [input.owner.labels.team, input.child.labels.team])
+//	}
+type RegoPolicy struct {
+	// File is the path of a file containing the module. Relative paths in a rule config file are relative to the config file.
+	File string `json:"file,omitempty"`
+	// Module is the module source, if File is not set
+	Module string `json:"module,omitempty"`
+}
+
+// regoRule evaluates compiled Rego policies
+type regoRule struct {
+	query rego.PreparedEvalQuery
+}
+
+// compileRego compiles policies into a single rule
+func compileRego(policies []RegoPolicy) (*regoRule, error) {
+	options := []func(*rego.Rego){rego.Query(regoQuery)}
+	for i, policy := range policies {
+		if (policy.File == "") == (policy.Module == "") {
+			return nil, fmt.Errorf("exactly one of file or module is required for rego policy %d", i)
+		}
+		name, module := policy.File, policy.Module
+		if policy.File != "" {
+			data, err := ioutil.ReadFile(policy.File)
+			if err != nil {
+				return nil, err
+			}
+			module = string(data)
+		} else {
+			name = fmt.Sprintf("policy%d.rego", i)
+		}
+		options = append(options, rego.Module(name, module))
+	}
+	query, err := rego.New(options...).PrepareForEval(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("invalid rego policy: %v", err)
+	}
+	return &regoRule{query: query}, nil
+}
+
+// Check evaluates the policies, returning a problem for each violation
+func (r *regoRule) Check(ctx *RuleContext) []Problem {
+	results, err := r.query.Eval(context.Background(), rego.EvalInput(ruleInput(ctx)))
+	if err != nil {
+		return problem(LevelWarning, CodeRegoEvaluationFailed, fmt.Sprintf("error evaluating rego policies: %v", err))
+	}
+	var problems []Problem
+	for _, result := range results {
+		for _, expression := range result.Expressions {
+			violations, ok := expression.Value.([]interface{})
+			if !ok {
+				return problem(LevelWarning, CodeRegoEvaluationFailed, fmt.Sprintf("expected violation to be a set, got %T", expression.Value))
+			}
+			for _, violation := range violations {
+				p, err := regoProblem(violation)
+				if err != nil {
+					return problem(LevelWarning, CodeRegoEvaluationFailed, err.Error())
+				}
+				problems = append(problems, p)
+			}
+		}
+	}
+	// sets are unordered, report problems in a stable order
+	sort.Slice(problems, func(i, j int) bool {
+		if problems[i].Code != problems[j].Code {
+			return problems[i].Code < problems[j].Code
+		}
+		return problems[i].Message < problems[j].Message
+	})
+	return problems
+}
+
+// regoProblem converts a violation to a problem
+func regoProblem(violation interface{}) (Problem, error) {
+	fields, ok := violation.(map[string]interface{})
+	if !ok {
+		return Problem{}, fmt.Errorf("expected violation to be an object, got %T", violation)
+	}
+	p := Problem{Level: LevelError, Code: CodeRegoViolation}
+	for key, value := range fields {
+		s, ok := value.(string)
+		if !ok {
+			return Problem{}, fmt.Errorf("expected violation %s to be a string, got %T", key, value)
+		}
+		switch key {
+		case "message":
+			p.Message = s
+		case "code":
+			p.Code = s
+		case "level":
+			if s != LevelError && s != LevelWarning {
+				return Problem{}, fmt.Errorf("invalid violation level %q, only %q and %q are supported", s, LevelError, LevelWarning)
+			}
+			p.Level = s
+		}
+	}
+	if p.Message == "" {
+		return Problem{}, fmt.Errorf("violation message is required")
+	}
+	return p, nil
+}
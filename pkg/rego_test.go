@@ -0,0 +1,130 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const testRegoPolicy = `package ownerreferences
+
+violation[{"code": "TeamMismatch", "level": "Warning", "message": msg}] {
+  input.ownerFound
+  input.owner.labels.team != input.child.labels.team
+  msg := sprintf("owner team %v does not match child team %v", [input.owner.labels.team, input.child.labels.team])
+}
+
+violation[{"message": "controller ownerReference required"}] {
+  not input.ownerReference.controller
+}
+`
+
+func TestRegoRule(t *testing.T) {
+	child := &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "pod1", Labels: map[string]string{"team": "a"}},
+	}
+	owner := func(team string) []*metav1.PartialObjectMetadata {
+		return []*metav1.PartialObjectMetadata{{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "ReplicaSet"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "rs1", Labels: map[string]string{"team": team}},
+		}}
+	}
+	isController := true
+	controllerRef := metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs1", UID: "rsuid1", Controller: &isController}
+	ref := metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs1", UID: "rsuid1"}
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+	rule, err := compileRego([]RegoPolicy{{Module: testRegoPolicy}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name   string
+		ctx    *RuleContext
+		expect []Problem
+	}{
+		{
+			name: "valid",
+			ctx:  &RuleContext{Resource: pods, Child: child, OwnerReference: controllerRef, Owners: owner("a")},
+		},
+		{
+			name: "multiple violations",
+			ctx:  &RuleContext{Resource: pods, Child: child, OwnerReference: ref, Owners: owner("b")},
+			expect: []Problem{
+				{Level: LevelError, Code: CodeRegoViolation, Message: "controller ownerReference required"},
+				{Level: LevelWarning, Code: "TeamMismatch", Message: "owner team b does not match child team a"},
+			},
+		},
+		{
+			name: "owner not found",
+			ctx:  &RuleContext{Resource: pods, Child: child, OwnerReference: controllerRef},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expect, rule.Check(tc.ctx)); diff != "" {
+				t.Errorf("unexpected problems (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRegoPolicyConfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "policy.rego"), []byte(testRegoPolicy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(configPath, []byte("regoPolicies:\n- file: policy.rego\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadRuleConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := config.CustomRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 {
+		t.Fatalf("expected 1 rule, got %d", len(rules))
+	}
+	// compiled once, when the config was loaded
+	if again, err := config.CustomRules(); err != nil || again[0] != rules[0] {
+		t.Errorf("expected the compiled rules to be reused, got %v, %v", again, err)
+	}
+
+	for name, policies := range map[string][]RegoPolicy{
+		"syntax error":    {{Module: "package ownerreferences\nviolation[{"}},
+		"missing file":    {{File: filepath.Join(dir, "missing.rego")}},
+		"file and module": {{File: "policy.rego", Module: testRegoPolicy}},
+		"neither":         {{}},
+	} {
+		if _, err := compileRego(policies); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
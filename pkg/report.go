@@ -0,0 +1,103 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterInfo identifies the cluster a report was produced for
+type ClusterInfo struct {
+	// Server is the URL of the API server
+	Server string `json:"server,omitempty"`
+	// Context is the name of the kubeconfig context used, if any
+	Context string `json:"context,omitempty"`
+	// UID is the UID of the kube-system namespace, which identifies the cluster regardless of how it is reached
+	UID string `json:"uid,omitempty"`
+}
+
+// reportDocument is the JSON representation of a completed scan, served at /results and exported to storage
+type reportDocument struct {
+	// ScanID is unique to each scan
+	ScanID          string       `json:"scanID,omitempty"`
+	Cluster         *ClusterInfo `json:"cluster,omitempty"`
+	CompletionTime  metav1.Time  `json:"completionTime"`
+	DurationSeconds float64      `json:"durationSeconds"`
+	Errors          int          `json:"errors"`
+	Warnings        int          `json:"warnings"`
+	// Complete is false if any resources could not be discovered or listed
+	Complete bool `json:"complete"`
+	// Consistency estimates how much the cluster changed while listing
+	Consistency *Consistency `json:"consistency,omitempty"`
+	Findings    []Finding    `json:"findings"`
+}
+
+// newReportDocument returns the report document of result. The cluster identified by the scan takes precedence over cluster.
+func newReportDocument(result *Report, cluster *ClusterInfo) reportDocument {
+	if result.Scan.Cluster != nil {
+		cluster = result.Scan.Cluster
+	}
+	findings := result.Findings
+	if findings == nil {
+		findings = []Finding{}
+	}
+	return reportDocument{
+		ScanID:          result.Scan.ID,
+		Cluster:         cluster,
+		CompletionTime:  metav1.NewTime(result.CompletionTime),
+		DurationSeconds: result.Duration.Seconds(),
+		Errors:          result.Errors,
+		Warnings:        result.Warnings,
+		Complete:        result.Complete(),
+		Consistency:     result.Consistency,
+		Findings:        findings,
+	}
+}
+
+// readFindings reads findings from r, either a report document as served at /results and exported to storage,
+// or a stream of findings as written by -o json
+func readFindings(r io.Reader) ([]Finding, error) {
+	findings := []Finding{}
+	decoder := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			return findings, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading findings: %v", err)
+		}
+		var doc struct {
+			Findings *[]Finding `json:"findings"`
+		}
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return nil, fmt.Errorf("error reading findings: %v", err)
+		}
+		if doc.Findings != nil {
+			findings = append(findings, *doc.Findings...)
+			continue
+		}
+		var finding Finding
+		if err := json.Unmarshal(raw, &finding); err != nil {
+			return nil, fmt.Errorf("error reading finding: %v", err)
+		}
+		findings = append(findings, finding)
+	}
+}
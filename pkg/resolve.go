@@ -0,0 +1,124 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/restmapper"
+)
+
+// OwnerResolutionError describes why the garbage collector cannot resolve an ownerReference
+type OwnerResolutionError struct {
+	// Code is the code of the finding reported for the ownerReference:
+	// CodeInvalidAPIVersion, CodeUnresolvableOwner, or CodeNamespacedOwnerOfClusterScopedChild
+	Code string
+	// Err is the underlying parsing or mapping error, if any
+	Err error
+
+	message string
+}
+
+func (e *OwnerResolutionError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the underlying parsing or mapping error
+func (e *OwnerResolutionError) Unwrap() error {
+	return e.Err
+}
+
+// ResolveOwnerReference returns the resource ownerRef refers to, resolved as the garbage collector resolves it:
+// the apiVersion is parsed, the group, kind, and version are mapped by restMapper (which tolerates an all-lowercase kind,
+// and resolves ambiguity by its priority), and a namespaced owner cannot be referenced by a cluster-scoped child.
+// childNamespace is the namespace of the child, or "" if it is cluster-scoped.
+//
+// An *OwnerResolutionError is returned if the garbage collector would not resolve ownerRef.
+// restMapper should be built from discovery of all served resources as the garbage collector's is, with newGCRESTMapper.
+// Whether the owner exists is not checked.
+func ResolveOwnerReference(restMapper meta.RESTMapper, ownerRef metav1.OwnerReference, childNamespace string) (*meta.RESTMapping, error) {
+	ctx := &RuleContext{OwnerReference: ownerRef}
+	resolveOwner(restMapper, ctx)
+	switch {
+	case ctx.OwnerGroupVersionError != nil:
+		return nil, &OwnerResolutionError{Code: CodeInvalidAPIVersion, Err: ctx.OwnerGroupVersionError, message: fmt.Sprintf("invalid owner apiVersion %s: %v", ownerRef.APIVersion, ctx.OwnerGroupVersionError)}
+	case ctx.OwnerMappingError != nil:
+		return nil, &OwnerResolutionError{Code: CodeUnresolvableOwner, Err: ctx.OwnerMappingError, message: describeUnresolvableOwner(ctx)}
+	case ctx.OwnerMapping.Scope.Name() == meta.RESTScopeNameNamespace && childNamespace == "":
+		return nil, &OwnerResolutionError{Code: CodeNamespacedOwnerOfClusterScopedChild, message: fmt.Sprintf("cannot reference namespaced type as owner (apiVersion=%s,kind=%s)", ctx.OwnerGroupVersion.String(), ownerRef.Kind)}
+	}
+	return ctx.OwnerMapping, nil
+}
+
+// newGCRESTMapper returns a REST mapper built as the garbage collector in kube-controller-manager builds its own:
+// a deferred mapper over memory-cached discovery. Kinds and resources served by multiple groups or versions resolve
+// by the same priority (groups in the order discovery lists them, each group's preferred version first),
+// and discovery is only refreshed for kinds that cannot be resolved if the cache has been invalidated.
+func newGCRESTMapper(client discovery.CachedDiscoveryInterface) *restmapper.DeferredDiscoveryRESTMapper {
+	return restmapper.NewDeferredDiscoveryRESTMapper(client)
+}
+
+// OwnerGroupKindMatches returns true if an owner of kind ownerGroupKind matches the group and kind of ownerRef.
+// As with the garbage collector, which maps an all-lowercase kind to the same resource, an all-lowercase reference matches.
+// The version is not compared, since an owner can be referenced by any served version.
+func OwnerGroupKindMatches(ownerRef metav1.OwnerReference, ownerGroupKind schema.GroupKind) bool {
+	refGV, err := schema.ParseGroupVersion(ownerRef.APIVersion)
+	if err != nil || refGV.Group != ownerGroupKind.Group {
+		return false
+	}
+	// RESTMapper tolerates an all-lowercase kind as input to the lookup
+	// https://github.com/kubernetes/kubernetes/blob/release-1.20/staging/src/k8s.io/client-go/restmapper/discovery.go#L114
+	return ownerRef.Kind == ownerGroupKind.Kind || ownerRef.Kind == strings.ToLower(ownerGroupKind.Kind)
+}
+
+// resolveOwner parses the apiVersion of ctx.OwnerReference, and maps its group, kind, and version to a resource if it parses,
+// setting the OwnerGroupVersion and OwnerMapping fields of ctx and their errors
+func resolveOwner(restMapper meta.RESTMapper, ctx *RuleContext) {
+	ctx.OwnerGroupVersion, ctx.OwnerGroupVersionError = schema.ParseGroupVersion(ctx.OwnerReference.APIVersion)
+	if ctx.OwnerGroupVersionError != nil {
+		return
+	}
+	groupKind := schema.GroupKind{Group: ctx.OwnerGroupVersion.Group, Kind: ctx.OwnerReference.Kind}
+	ctx.OwnerMapping, ctx.OwnerMappingError = restMapper.RESTMapping(groupKind, ctx.OwnerGroupVersion.Version)
+	if ctx.OwnerMappingError != nil {
+		ctx.OwnerMapping = nil
+		// the kind may still be served at another version, which references should be updated to
+		if ctx.OwnerGroupVersion.Version != "" {
+			if mapping, err := restMapper.RESTMapping(groupKind); err == nil {
+				ctx.OwnerServedMapping = mapping
+			}
+		}
+	}
+}
+
+// describeUnresolvableOwner describes why the ownerReference of ctx cannot be resolved,
+// and the version to update it to if its version was removed
+func describeUnresolvableOwner(ctx *RuleContext) string {
+	message := fmt.Sprintf("cannot resolve owner apiVersion/kind: %v", ctx.OwnerMappingError)
+	if deprecated := deprecatedOwnerAPIVersion(ctx.OwnerReference); deprecated != nil {
+		message += fmt.Sprintf(" (%s)", deprecated)
+	} else if ctx.OwnerServedMapping != nil {
+		gvk := ctx.OwnerServedMapping.GroupVersionKind
+		message += fmt.Sprintf(" (%s is not served, %s is served at %s, update the ownerReference apiVersion)", ctx.OwnerReference.APIVersion, gvk.Kind, gvk.GroupVersion())
+	}
+	return message
+}
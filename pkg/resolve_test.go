@@ -0,0 +1,203 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/restmapper"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestResolveOwnerReference(t *testing.T) {
+	restMapper := restmapper.NewDiscoveryRESTMapper([]*restmapper.APIGroupResources{
+		{
+			Group: metav1.APIGroup{Name: "", Versions: []metav1.GroupVersionForDiscovery{{Version: "v1"}}, PreferredVersion: metav1.GroupVersionForDiscovery{Version: "v1"}},
+			VersionedResources: map[string][]metav1.APIResource{"v1": {
+				{Name: "pods", Namespaced: true, Kind: "Pod"},
+				{Name: "namespaces", Namespaced: false, Kind: "Namespace"},
+			}},
+		},
+		{
+			Group: metav1.APIGroup{Name: "apps", Versions: []metav1.GroupVersionForDiscovery{{GroupVersion: "apps/v1", Version: "v1"}}, PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: "apps/v1", Version: "v1"}},
+			VersionedResources: map[string][]metav1.APIResource{"v1": {
+				{Name: "deployments", Namespaced: true, Kind: "Deployment"},
+			}},
+		},
+	})
+
+	testcases := []struct {
+		name      string
+		ownerRef  metav1.OwnerReference
+		namespace string
+		resource  schema.GroupVersionResource
+		code      string
+		// message is expected in the error, if set
+		message string
+	}{
+		{name: "namespaced", ownerRef: metav1.OwnerReference{APIVersion: "apps/v1", Kind: "Deployment"}, namespace: "ns1", resource: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}},
+		{name: "lowercase kind", ownerRef: metav1.OwnerReference{APIVersion: "apps/v1", Kind: "deployment"}, namespace: "ns1", resource: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}},
+		{name: "cluster-scoped owner", ownerRef: metav1.OwnerReference{APIVersion: "v1", Kind: "Namespace"}, resource: schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}},
+		{name: "invalid apiVersion", ownerRef: metav1.OwnerReference{APIVersion: "a/b/c", Kind: "Pod"}, namespace: "ns1", code: CodeInvalidAPIVersion},
+		{name: "unserved version", ownerRef: metav1.OwnerReference{APIVersion: "apps/v1beta1", Kind: "Deployment"}, namespace: "ns1", code: CodeUnresolvableOwner, message: "(apps/v1beta1 is removed in Kubernetes v1.16, migrate to apps/v1)"},
+		{name: "unserved version of a served kind", ownerRef: metav1.OwnerReference{APIVersion: "v2", Kind: "Pod"}, namespace: "ns1", code: CodeUnresolvableOwner, message: "(v2 is not served, Pod is served at v1, update the ownerReference apiVersion)"},
+		{name: "wrong group", ownerRef: metav1.OwnerReference{APIVersion: "v1", Kind: "Deployment"}, namespace: "ns1", code: CodeUnresolvableOwner},
+		{name: "namespaced owner of cluster-scoped child", ownerRef: metav1.OwnerReference{APIVersion: "v1", Kind: "Pod"}, code: CodeNamespacedOwnerOfClusterScopedChild},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			mapping, err := ResolveOwnerReference(restMapper, tc.ownerRef, tc.namespace)
+			if tc.code == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if mapping.Resource != tc.resource {
+					t.Errorf("expected %v, got %v", tc.resource, mapping.Resource)
+				}
+				return
+			}
+			resolutionErr := &OwnerResolutionError{}
+			if !errors.As(err, &resolutionErr) || resolutionErr.Code != tc.code {
+				t.Errorf("expected %s error, got %v", tc.code, err)
+			} else if !strings.Contains(err.Error(), tc.message) {
+				t.Errorf("expected error to contain %q, got %v", tc.message, err)
+			}
+		})
+	}
+}
+
+func TestOwnerGroupKindMatches(t *testing.T) {
+	deployment := schema.GroupKind{Group: "apps", Kind: "Deployment"}
+	for apiVersion, kinds := range map[string]map[string]bool{
+		"apps/v1":      {"Deployment": true, "deployment": true, "DEPLOYMENT": false, "ReplicaSet": false},
+		"apps/v1beta2": {"Deployment": true},
+		"v1":           {"Deployment": false},
+		"a/b/c":        {"Deployment": false},
+	} {
+		for kind, expect := range kinds {
+			if got := OwnerGroupKindMatches(metav1.OwnerReference{APIVersion: apiVersion, Kind: kind}, deployment); got != expect {
+				t.Errorf("%s %s: expected %v, got %v", apiVersion, kind, expect, got)
+			}
+		}
+	}
+}
+
+// orderedDiscovery lists groups in the order of their resources, as API servers list groups in priority order
+type orderedDiscovery struct {
+	*fake.FakeDiscovery
+}
+
+func (d *orderedDiscovery) ServerGroups() (*metav1.APIGroupList, error) {
+	groups, err := d.FakeDiscovery.ServerGroups()
+	if err != nil {
+		return nil, err
+	}
+	order := map[string]int{}
+	for i, resources := range d.Resources {
+		gv, _ := schema.ParseGroupVersion(resources.GroupVersion)
+		if _, ok := order[gv.Group]; !ok {
+			order[gv.Group] = i
+		}
+	}
+	sort.Slice(groups.Groups, func(i, j int) bool { return order[groups.Groups[i].Name] < order[groups.Groups[j].Name] })
+	return groups, nil
+}
+
+// TestGCRESTMapper ensures ownerReferences and resources served by multiple groups and versions resolve
+// as they do for the garbage collector of kube-controller-manager
+func TestGCRESTMapper(t *testing.T) {
+	resources := func(groupVersion string, resources ...metav1.APIResource) *metav1.APIResourceList {
+		return &metav1.APIResourceList{GroupVersion: groupVersion, APIResources: resources}
+	}
+	widgets := metav1.APIResource{Name: "widgets", Namespaced: true, Kind: "Widget"}
+	gadgets := metav1.APIResource{Name: "gadgets", Namespaced: true, Kind: "Gadget"}
+	things := metav1.APIResource{Name: "things", Namespaced: true, Kind: "Thing"}
+	client := &orderedDiscovery{&fake.FakeDiscovery{Fake: &coretesting.Fake{}}}
+	client.Resources = []*metav1.APIResourceList{
+		resources("v1", metav1.APIResource{Name: "events", Namespaced: true, Kind: "Event"}),
+		resources("b.example.com/v1", things),
+		resources("a.example.com/v1", things),
+		// the first version of a group is its preferred version
+		resources("example.com/v2", widgets),
+		resources("example.com/v1", widgets, gadgets),
+		resources("events.k8s.io/v1", metav1.APIResource{Name: "events", Namespaced: true, Kind: "Event"}),
+	}
+	cached := memory.NewMemCacheClient(client)
+	restMapper := newGCRESTMapper(cached)
+
+	for _, tc := range []struct {
+		apiVersion, kind string
+		resource         schema.GroupVersionResource
+		code             string
+	}{
+		// the referenced version is resolved, not the preferred version
+		{apiVersion: "example.com/v1", kind: "Widget", resource: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}},
+		{apiVersion: "example.com/v2", kind: "Widget", resource: schema.GroupVersionResource{Group: "example.com", Version: "v2", Resource: "widgets"}},
+		{apiVersion: "example.com/v3", kind: "Widget", code: CodeUnresolvableOwner},
+		// a kind is not resolved at a version that does not serve it
+		{apiVersion: "example.com/v2", kind: "Gadget", code: CodeUnresolvableOwner},
+		{apiVersion: "v1", kind: "Event", resource: schema.GroupVersionResource{Version: "v1", Resource: "events"}},
+		{apiVersion: "events.k8s.io/v1", kind: "Event", resource: schema.GroupVersionResource{Group: "events.k8s.io", Version: "v1", Resource: "events"}},
+		{apiVersion: "a.example.com/v1", kind: "thing", resource: schema.GroupVersionResource{Group: "a.example.com", Version: "v1", Resource: "things"}},
+	} {
+		mapping, err := ResolveOwnerReference(restMapper, metav1.OwnerReference{APIVersion: tc.apiVersion, Kind: tc.kind}, "ns1")
+		if tc.code != "" {
+			resolutionErr := &OwnerResolutionError{}
+			if !errors.As(err, &resolutionErr) || resolutionErr.Code != tc.code {
+				t.Errorf("%s %s: expected %s error, got %v", tc.apiVersion, tc.kind, tc.code, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %s: %v", tc.apiVersion, tc.kind, err)
+		} else if mapping.Resource != tc.resource {
+			t.Errorf("%s %s: expected %v, got %v", tc.apiVersion, tc.kind, tc.resource, mapping.Resource)
+		}
+	}
+
+	// resources served by multiple groups and versions resolve to the first group listed, at its preferred version
+	for resource, expected := range map[schema.GroupVersionResource]schema.GroupVersionKind{
+		{Resource: "things"}:                        {Group: "b.example.com", Version: "v1", Kind: "Thing"},
+		{Resource: "events"}:                        {Version: "v1", Kind: "Event"},
+		{Group: "example.com", Resource: "widgets"}: {Group: "example.com", Version: "v2", Kind: "Widget"},
+	} {
+		gvk, err := restMapper.KindFor(resource)
+		if err != nil {
+			t.Errorf("%v: %v", resource, err)
+		} else if gvk != expected {
+			t.Errorf("%v: expected %v, got %v", resource, expected, gvk)
+		}
+	}
+
+	// kinds created after discovery are not resolved until discovery is invalidated
+	client.Resources = append(client.Resources, resources("new.example.com/v1", widgets))
+	newWidget := metav1.OwnerReference{APIVersion: "new.example.com/v1", Kind: "Widget"}
+	if _, err := ResolveOwnerReference(restMapper, newWidget, "ns1"); err == nil {
+		t.Errorf("expected new kind not to be resolved from cached discovery")
+	}
+	cached.Invalidate()
+	if _, err := ResolveOwnerReference(restMapper, newWidget, "ns1"); err != nil {
+		t.Errorf("expected new kind to be resolved after invalidating discovery: %v", err)
+	}
+}
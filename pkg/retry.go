@@ -0,0 +1,193 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// RetryConfig configures retrying failed list requests, per API group, so a flapping aggregated API can be retried
+// generously without retrying every group, or not at all without dominating the scan
+type RetryConfig struct {
+	// Default applies to groups not in Groups
+	Default RetryPolicy `json:"default,omitempty"`
+	// Groups applies to specific API groups. The core group may be specified as "core".
+	Groups map[string]RetryPolicy `json:"groups,omitempty"`
+}
+
+// RetryPolicy configures retrying the failed list requests of an API group
+type RetryPolicy struct {
+	// Retries is the number of times each failed list request is retried
+	Retries int `json:"retries,omitempty"`
+	// Budget is the number of retries of all list requests of the group allowed in a scan, unlimited if 0
+	Budget int `json:"budget,omitempty"`
+	// Backoff is the delay before the first retry of a request, doubled before each later retry
+	Backoff metav1.Duration `json:"backoff,omitempty"`
+	// Timeout bounds each list request, unbounded if 0
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+}
+
+// LoadRetryConfig reads a YAML or JSON retry config from path
+func LoadRetryConfig(path string) (*RetryConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &RetryConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("error reading retry config %s: %v", path, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid retry config %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// Validate ensures the config is valid
+func (c *RetryConfig) Validate() error {
+	if err := c.Default.validate(); err != nil {
+		return fmt.Errorf("default: %v", err)
+	}
+	for group, policy := range c.Groups {
+		if group == "" {
+			return fmt.Errorf("groups: use \"core\" for the core group")
+		}
+		if err := policy.validate(); err != nil {
+			return fmt.Errorf("%s: %v", group, err)
+		}
+	}
+	return nil
+}
+
+func (p RetryPolicy) validate() error {
+	if p.Retries < 0 {
+		return fmt.Errorf("invalid retries, must be >= 0")
+	}
+	if p.Budget < 0 {
+		return fmt.Errorf("invalid budget, must be >= 0")
+	}
+	if p.Backoff.Duration < 0 {
+		return fmt.Errorf("invalid backoff, must be >= 0")
+	}
+	if p.Timeout.Duration < 0 {
+		return fmt.Errorf("invalid timeout, must be >= 0")
+	}
+	return nil
+}
+
+// policy returns the policy for group
+func (c *RetryConfig) policy(group string) RetryPolicy {
+	if c == nil {
+		return RetryPolicy{}
+	}
+	if group == "" {
+		group = "core"
+	}
+	if policy, ok := c.Groups[group]; ok {
+		return policy
+	}
+	return c.Default
+}
+
+// listRetrier retries failed list requests as configured, tracking the retries of each group against its budget
+type listRetrier struct {
+	config *RetryConfig
+	// timeout bounds each list request of groups whose policy does not set a timeout, unbounded if 0
+	timeout time.Duration
+	stderr  io.Writer
+	// retries is the number of retries of each group
+	retries map[string]int
+	// exhausted holds the groups whose budget was used up
+	exhausted map[string]bool
+}
+
+func newListRetrier(config *RetryConfig, timeout time.Duration, stderr io.Writer) *listRetrier {
+	return &listRetrier{config: config, timeout: timeout, stderr: stderr, retries: map[string]int{}, exhausted: map[string]bool{}}
+}
+
+// list calls list, bounded by the timeout of the group of gvr or the default timeout, and retries it if it fails with a retryable error
+// until the retries of the request or the budget of the group are used up
+func (r *listRetrier) list(ctx context.Context, gvr schema.GroupVersionResource, list func(context.Context) (*metav1.PartialObjectMetadataList, error)) (*metav1.PartialObjectMetadataList, error) {
+	policy := r.config.policy(gvr.Group)
+	backoff := policy.Backoff.Duration
+	for attempt := 0; ; attempt++ {
+		result, err := r.attempt(ctx, policy, list)
+		if err == nil || ctx.Err() != nil || !retryable(err) || attempt >= policy.Retries {
+			return result, err
+		}
+		if policy.Budget > 0 && r.retries[gvr.Group] >= policy.Budget {
+			if !r.exhausted[gvr.Group] {
+				r.exhausted[gvr.Group] = true
+				fmt.Fprintf(r.stderr, "warning: retry budget of %s exhausted, not retrying failed requests of %s\n", pluralize(policy.Budget, "retry", "retries"), gvr.GroupVersion())
+			}
+			return result, err
+		}
+		r.retries[gvr.Group]++
+		if klog.V(2).Enabled() {
+			fmt.Fprintf(r.stderr, "retrying list of %v in %v: %v\n", gvr, backoff, err)
+		}
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (r *listRetrier) attempt(ctx context.Context, policy RetryPolicy, list func(context.Context) (*metav1.PartialObjectMetadataList, error)) (*metav1.PartialObjectMetadataList, error) {
+	timeout := policy.Timeout.Duration
+	if timeout == 0 {
+		timeout = r.timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return list(ctx)
+}
+
+// total returns the number of retries of all groups
+func (r *listRetrier) total() int {
+	total := 0
+	for _, retries := range r.retries {
+		total += retries
+	}
+	return total
+}
+
+// retryable returns true if err may be transient, such as an unavailable aggregated API or a timeout,
+// and false if retrying cannot succeed, such as when the request is forbidden or the resource does not exist
+func retryable(err error) bool {
+	switch {
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err), apierrors.IsNotFound(err), apierrors.IsMethodNotSupported(err),
+		apierrors.IsBadRequest(err), apierrors.IsInvalid(err), apierrors.IsGone(err), apierrors.IsResourceExpired(err), apierrors.IsNotAcceptable(err):
+		return false
+	}
+	return true
+}
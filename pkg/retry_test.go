@@ -0,0 +1,130 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestListRetrier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "retry.yaml")
+	if err := ioutil.WriteFile(path, []byte(`
+groups:
+  metrics.k8s.io:
+    retries: 2
+    budget: 3
+  example.com:
+    retries: 1
+    timeout: 10ms
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadRetryConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := &bytes.Buffer{}
+	retrier := newListRetrier(config, 0, stderr)
+
+	metrics := schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "pods"}
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	widgets := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	unavailable := apierrors.NewServiceUnavailable("flapping")
+	forbidden := apierrors.NewForbidden(metrics.GroupResource(), "", nil)
+	for _, tc := range []struct {
+		name     string
+		gvr      schema.GroupVersionResource
+		err      error
+		attempts int
+	}{
+		{name: "retried", gvr: metrics, err: unavailable, attempts: 3},
+		{name: "budget used up", gvr: metrics, err: unavailable, attempts: 2},
+		{name: "budget exhausted", gvr: metrics, err: unavailable, attempts: 1},
+		{name: "not retryable", gvr: widgets, err: forbidden, attempts: 1},
+		{name: "default policy", gvr: pods, err: unavailable, attempts: 1},
+		{name: "timeout", gvr: widgets, attempts: 2},
+	} {
+		attempts := 0
+		_, err := retrier.list(context.Background(), tc.gvr, func(ctx context.Context) (*metav1.PartialObjectMetadataList, error) {
+			attempts++
+			if tc.err != nil {
+				return nil, tc.err
+			}
+			// hang until the request times out
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		if err == nil {
+			t.Errorf("%s: expected error", tc.name)
+		}
+		if attempts != tc.attempts {
+			t.Errorf("%s: expected %d attempts, got %d", tc.name, tc.attempts, attempts)
+		}
+	}
+	if retrier.total() != 4 {
+		t.Errorf("expected 4 retries, got %d", retrier.total())
+	}
+	if strings.Count(stderr.String(), "retry budget of 3 retries exhausted") != 1 {
+		t.Errorf("expected one budget warning, got %q", stderr.String())
+	}
+
+	// the default timeout bounds the requests of groups without a timeout, which are then retried
+	config.Default = RetryPolicy{Retries: 1}
+	retrier = newListRetrier(config, 10*time.Millisecond, stderr)
+	attempts := 0
+	if _, err := retrier.list(context.Background(), pods, func(ctx context.Context) (*metav1.PartialObjectMetadataList, error) {
+		attempts++
+		if attempts == 1 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return &metav1.PartialObjectMetadataList{}, nil
+	}); err != nil {
+		t.Errorf("expected a timed out request to be retried, got %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+	// the timeout of a group takes precedence
+	retrier = newListRetrier(config, time.Hour, stderr)
+	if _, err := retrier.list(context.Background(), widgets, func(ctx context.Context) (*metav1.PartialObjectMetadataList, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}); err == nil {
+		t.Errorf("expected the group timeout to apply")
+	}
+
+	for _, invalid := range []*RetryConfig{
+		{Default: RetryPolicy{Retries: -1}},
+		{Groups: map[string]RetryPolicy{"": {Retries: 1}}},
+		{Groups: map[string]RetryPolicy{"metrics.k8s.io": {Timeout: metav1.Duration{Duration: -time.Second}}}},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("expected error for %#v", invalid)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Rule checks a single ownerReference of a child object.
+//
+// Rules are evaluated in order for each ownerReference,
+// and evaluation stops at the first rule that reports a problem.
+type Rule interface {
+	// Check returns the problems found with the ownerReference described by ctx, or nil if there are none
+	Check(ctx *RuleContext) []Problem
+}
+
+// RuleFunc adapts a function to a Rule
+type RuleFunc func(ctx *RuleContext) []Problem
+
+// Check calls f(ctx)
+func (f RuleFunc) Check(ctx *RuleContext) []Problem {
+	return f(ctx)
+}
+
+// Problem is reported by a Rule, and becomes a Finding for the child object
+type Problem struct {
+	// Level is LevelError or LevelWarning
+	Level string
+	// Code identifies the kind of problem
+	Code string
+	// Message describes the problem
+	Message string
+}
+
+// RuleContext describes the ownerReference being checked, what is known about its owner, and the objects in the cluster
+type RuleContext struct {
+	// Resource is the resource of the child object
+	Resource schema.GroupVersionResource
+	// Child is the object with the ownerReference
+	Child *metav1.PartialObjectMetadata
+	// OwnerReference is the ownerReference being checked
+	OwnerReference metav1.OwnerReference
+
+	// OwnerGroupVersion is parsed from the ownerReference apiVersion
+	OwnerGroupVersion schema.GroupVersion
+	// OwnerGroupVersionError is set if the ownerReference apiVersion could not be parsed
+	OwnerGroupVersionError error
+	// OwnerMapping is the resource the ownerReference apiVersion/kind resolves to, or nil if it could not be resolved
+	OwnerMapping *meta.RESTMapping
+	// OwnerMappingError is set if the ownerReference apiVersion/kind could not be resolved
+	OwnerMappingError error
+	// OwnerServedMapping is the resource the ownerReference group/kind resolves to at its preferred version,
+	// set if the ownerReference apiVersion/kind could not be resolved because the version is not served.
+	// The garbage collector does not fall back to another version, so the reference still cannot be resolved.
+	OwnerServedMapping *meta.RESTMapping
+	// OwnerDiscoveryError is set if resources could not be discovered for the ownerReference apiVersion
+	OwnerDiscoveryError error
+	// Owners are the objects found with the ownerReference uid
+	Owners []*metav1.PartialObjectMetadata
+	// OwnerListError is set if the owner resource could not be listed
+	OwnerListError error
+
+	// Objects holds all objects listed in the scan
+	Objects *ObjectIndex
+}
+
+// ObjectIndex holds the objects listed in a scan
+type ObjectIndex struct {
+	byGVR map[schema.GroupVersionResource][]*metav1.PartialObjectMetadata
+	byUID map[types.UID][]*metav1.PartialObjectMetadata
+	// byName indexes objects by resource and name, for finding objects that may be the intended owner
+	byName map[groupResourceName][]*metav1.PartialObjectMetadata
+}
+
+// groupResourceName identifies the objects of a resource with a name, in any namespace
+type groupResourceName struct {
+	resource schema.GroupResource
+	name     string
+}
+
+func newObjectIndex() *ObjectIndex {
+	return &ObjectIndex{
+		byGVR:  map[schema.GroupVersionResource][]*metav1.PartialObjectMetadata{},
+		byUID:  map[types.UID][]*metav1.PartialObjectMetadata{},
+		byName: map[groupResourceName][]*metav1.PartialObjectMetadata{},
+	}
+}
+
+func (i *ObjectIndex) add(gvr schema.GroupVersionResource, object *metav1.PartialObjectMetadata) {
+	i.byGVR[gvr] = append(i.byGVR[gvr], object)
+	key := groupResourceName{resource: gvr.GroupResource(), name: object.Name}
+	i.byName[key] = append(i.byName[key], object)
+	// objects read from manifests may not have a uid, and cannot be referenced as owners
+	if object.UID != "" {
+		i.byUID[object.UID] = append(i.byUID[object.UID], object)
+	}
+}
+
+// remove removes object, a gvr object in the index
+func (i *ObjectIndex) remove(gvr schema.GroupVersionResource, object *metav1.PartialObjectMetadata) {
+	i.byGVR[gvr] = removeObject(i.byGVR[gvr], object)
+	key := groupResourceName{resource: gvr.GroupResource(), name: object.Name}
+	if i.byName[key] = removeObject(i.byName[key], object); len(i.byName[key]) == 0 {
+		delete(i.byName, key)
+	}
+	if i.byUID[object.UID] = removeObject(i.byUID[object.UID], object); len(i.byUID[object.UID]) == 0 {
+		delete(i.byUID, object.UID)
+	}
+}
+
+// removeObject removes object from objects in place
+func removeObject(objects []*metav1.PartialObjectMetadata, object *metav1.PartialObjectMetadata) []*metav1.PartialObjectMetadata {
+	for j := range objects {
+		if objects[j] == object {
+			copy(objects[j:], objects[j+1:])
+			objects[len(objects)-1] = nil
+			return objects[:len(objects)-1]
+		}
+	}
+	return objects
+}
+
+// ByUID returns the objects with the given uid
+func (i *ObjectIndex) ByUID(uid types.UID) []*metav1.PartialObjectMetadata {
+	return i.byUID[uid]
+}
+
+// ByResource returns the objects of the given resource
+func (i *ObjectIndex) ByResource(gvr schema.GroupVersionResource) []*metav1.PartialObjectMetadata {
+	return i.byGVR[gvr]
+}
+
+// ByName returns the objects of the given resource, listed at any version, with the given name in any namespace
+func (i *ObjectIndex) ByName(resource schema.GroupResource, name string) []*metav1.PartialObjectMetadata {
+	return i.byName[groupResourceName{resource: resource, name: name}]
+}
+
+// DefaultRules returns the built-in rules, in the order they are evaluated
+func DefaultRules() []Rule {
+	return []Rule{
+		RuleFunc(checkAPIVersion),
+		RuleFunc(checkOwnerResolvable),
+		RuleFunc(checkOwnerScope),
+		RuleFunc(checkOwnerExists),
+		RuleFunc(checkOwnerNamespace),
+		RuleFunc(checkOwnerName),
+		RuleFunc(checkOwnerGroupKind),
+		RuleFunc(checkOwnerAPIVersionDeprecated),
+	}
+}
+
+// listingRules returns the default rules that do not depend on other objects having been listed, in the same order,
+// so they can be run on objects as they are listed: InvalidAPIVersion, UnresolvableOwner, and NamespacedOwnerOfClusterScopedChild
+func listingRules() []Rule {
+	return []Rule{
+		RuleFunc(checkAPIVersion),
+		RuleFunc(checkOwnerResolvable),
+		RuleFunc(checkOwnerScope),
+	}
+}
+
+func problem(level, code, message string) []Problem {
+	return []Problem{{Level: level, Code: code, Message: message}}
+}
+
+func checkAPIVersion(ctx *RuleContext) []Problem {
+	if ctx.OwnerGroupVersionError != nil {
+		return problem(LevelError, CodeInvalidAPIVersion, fmt.Sprintf("invalid owner apiVersion %s: %v", ctx.OwnerReference.APIVersion, ctx.OwnerGroupVersionError.Error()))
+	}
+	return nil
+}
+
+func checkOwnerResolvable(ctx *RuleContext) []Problem {
+	if ctx.OwnerMappingError == nil {
+		return nil
+	}
+	if ctx.OwnerDiscoveryError != nil {
+		// warn on discovery failure for the referenced apiVersion
+		return problem(LevelWarning, CodeOwnerDiscoveryFailed, fmt.Sprintf("failed resolving resources for %s: %v", ctx.OwnerReference.APIVersion, ctx.OwnerDiscoveryError.Error()))
+	}
+	return problem(LevelError, CodeUnresolvableOwner, describeUnresolvableOwner(ctx))
+}
+
+func checkOwnerScope(ctx *RuleContext) []Problem {
+	// ownerRef apiVersion/kind is namespaced, child is cluster-scoped
+	if ctx.OwnerMapping != nil && ctx.OwnerMapping.Scope.Name() == meta.RESTScopeNameNamespace && ctx.Child.Namespace == "" {
+		return problem(LevelError, CodeNamespacedOwnerOfClusterScopedChild, fmt.Sprintf("cannot reference namespaced type as owner (apiVersion=%s,kind=%s)", ctx.OwnerGroupVersion.String(), ctx.OwnerReference.Kind))
+	}
+	return nil
+}
+
+func checkOwnerExists(ctx *RuleContext) []Problem {
+	if ctx.OwnerMapping == nil || len(ctx.Owners) > 0 {
+		return nil
+	}
+	if ctx.OwnerListError != nil {
+		// warn on missing owners if failed to list owner resource
+		return problem(LevelWarning, CodeOwnerListFailed, fmt.Sprintf("could not list parent resource %v", ctx.OwnerMapping.Resource.GroupResource()))
+	}
+	return problem(LevelError, CodeOwnerNotFound, "no object found for uid")
+}
+
+func checkOwnerNamespace(ctx *RuleContext) []Problem {
+	actualNamespace := ""
+	for _, actualOwner := range ctx.Owners {
+		if actualOwner.Namespace == "" || actualOwner.Namespace == ctx.Child.Namespace {
+			return nil
+		}
+		actualNamespace = actualOwner.Namespace
+	}
+	if len(ctx.Owners) == 0 {
+		return nil
+	}
+	return problem(LevelError, CodeNamespaceMismatch, fmt.Sprintf("child namespace does not match owner namespace (%s)", actualNamespace))
+}
+
+func checkOwnerName(ctx *RuleContext) []Problem {
+	actualName := ""
+	for _, actualOwner := range ctx.Owners {
+		if actualOwner.Name == ctx.OwnerReference.Name {
+			return nil
+		}
+		actualName = actualOwner.Name
+	}
+	if len(ctx.Owners) == 0 {
+		return nil
+	}
+	return problem(LevelError, CodeNameMismatch, fmt.Sprintf("ownerReference name (%s) does not match owner name (%s)", ctx.OwnerReference.Name, actualName))
+}
+
+func checkOwnerGroupKind(ctx *RuleContext) []Problem {
+	actualGVK := schema.GroupVersionKind{}
+	for _, actualOwner := range ctx.Owners {
+		if actualOwner.APIVersion == "" || actualOwner.Kind == "" {
+			return nil
+		}
+		actualOwnerGV, _ := schema.ParseGroupVersion(actualOwner.APIVersion)
+		if OwnerGroupKindMatches(ctx.OwnerReference, actualOwnerGV.WithKind(actualOwner.Kind).GroupKind()) {
+			return nil
+		}
+		actualGVK = actualOwnerGV.WithKind(actualOwner.Kind)
+	}
+	if len(ctx.Owners) == 0 {
+		return nil
+	}
+	return problem(LevelError, CodeGroupKindMismatch, fmt.Sprintf("ownerReference group/kind (%s/%s) does not match owner group/kind (%s/%s)", ctx.OwnerGroupVersion.Group, ctx.OwnerReference.Kind, actualGVK.Group, actualGVK.Kind))
+}
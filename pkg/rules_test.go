@@ -0,0 +1,101 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestCustomRules(t *testing.T) {
+	discoveryClient := &fake.FakeDiscovery{Fake: &coretesting.Fake{}}
+	discoveryClient.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: []string{"get", "list", "delete"}},
+			{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: []string{"get", "list", "delete"}},
+		},
+	}}
+	metadataClient := metadatafake.NewSimpleMetadataClient(runtime.NewScheme())
+	addObject := func(resource, kind, name string, uid types.UID, owners ...metav1.OwnerReference) {
+		t.Helper()
+		client := metadataClient.Resource(schema.GroupVersionResource{Version: "v1", Resource: resource}).Namespace("ns1")
+		if _, err := client.(metadatafake.MetadataClient).CreateFake(&metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: kind},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns1", UID: uid, OwnerReferences: owners},
+		}, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addObject("configmaps", "ConfigMap", "cm1", "cmuid1")
+	addObject("pods", "Pod", "pod1", "poduid1", metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "cm1", UID: "cmuid1"})
+	addObject("pods", "Pod", "pod2", "poduid2", metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "missing", UID: "missinguid"})
+
+	// pods must not be owned by configmaps, checked only for ownerReferences that pass the built-in rules
+	var checked []string
+	noConfigMapOwners := RuleFunc(func(ctx *RuleContext) []Problem {
+		checked = append(checked, ctx.Child.Name)
+		if ctx.Resource.Resource != "pods" {
+			return nil
+		}
+		for _, owner := range ctx.Owners {
+			if owner.Kind == "ConfigMap" && len(ctx.Objects.ByResource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"})) > 0 {
+				return []Problem{{Level: LevelWarning, Code: "ConfigMapOwner", Message: "pods should not be owned by configmaps"}}
+			}
+		}
+		return nil
+	})
+
+	opts := &VerifyGCOptions{
+		DiscoveryClient: discoveryClient,
+		MetadataClient:  metadataClient,
+		Rules:           append(DefaultRules(), noConfigMapOwners),
+	}
+	report, err := opts.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct{ Name, Level, Code string }
+	got := []result{}
+	for _, finding := range report.Findings {
+		got = append(got, result{finding.Name, finding.Level, finding.Code})
+	}
+	expect := []result{
+		{"pod1", LevelWarning, "ConfigMapOwner"},
+		{"pod2", LevelError, CodeOwnerNotFound},
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"pod1"}, checked); diff != "" {
+		t.Errorf("unexpected objects checked by custom rule (-want +got):\n%s", diff)
+	}
+	if report.Errors != 1 || report.Warnings != 1 {
+		t.Errorf("expected 1 error and 1 warning, got %d errors and %d warnings", report.Errors, report.Warnings)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/klog/v2"
+)
+
+// ScanIdentity identifies a scan and the cluster it scanned, so outputs of many clusters can be aggregated
+type ScanIdentity struct {
+	// ID is unique to each scan
+	ID string `json:"id"`
+	// Cluster identifies the scanned cluster, if known
+	Cluster *ClusterInfo `json:"cluster,omitempty"`
+}
+
+// identity returns the identity of the scan of r, or nil if r is not the result of a single scan
+func (r *Report) identity() *ScanIdentity {
+	if r.Scan.ID == "" {
+		return nil
+	}
+	scan := r.Scan
+	return &scan
+}
+
+// scanIdentity returns a new scan ID, and v.Cluster with its UID set to the UID of the kube-system namespace.
+// The namespace is looked up in objects if namespaces were listed, and read from the cluster otherwise.
+// The cluster is nil if it could not be identified.
+func (v *VerifyGCOptions) scanIdentity(ctx context.Context, objects *ObjectIndex) ScanIdentity {
+	scan := ScanIdentity{ID: string(uuid.NewUUID())}
+	cluster := ClusterInfo{}
+	if v.Cluster != nil {
+		cluster = *v.Cluster
+	}
+	if cluster.UID == "" {
+		cluster.UID = v.kubeSystemUID(ctx, objects)
+	}
+	if cluster != (ClusterInfo{}) {
+		scan.Cluster = &cluster
+	}
+	return scan
+}
+
+// kubeSystemUID returns the UID of the kube-system namespace, which is stable for the lifetime of a cluster,
+// or an empty string if it could not be found
+func (v *VerifyGCOptions) kubeSystemUID(ctx context.Context, objects *ObjectIndex) string {
+	if objects != nil {
+		for _, namespace := range objects.ByName(namespacesResource.GroupResource(), metav1.NamespaceSystem) {
+			return string(namespace.UID)
+		}
+	}
+	if v.MetadataClient == nil {
+		return ""
+	}
+	namespace, err := v.MetadataClient.Resource(namespacesResource).Get(ctx, metav1.NamespaceSystem, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			klog.V(2).Infof("could not get the %s namespace to identify the cluster: %v", metav1.NamespaceSystem, err)
+		}
+		return ""
+	}
+	return string(namespace.UID)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+func TestScanIdentity(t *testing.T) {
+	cluster := newFakeCluster(t, "prod", "pod1")
+	metadataClient := cluster.Verify.MetadataClient.(*metadatafake.FakeMetadataClient)
+	if _, err := metadataClient.Resource(namespacesResource).(metadatafake.MetadataClient).CreateFake(&metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "kube-system-uid"},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	cluster.Verify.Cluster = &ClusterInfo{Server: "https://prod.example.com"}
+
+	// namespaces are not listed, so the kube-system namespace is read from the cluster
+	first, err := cluster.Verify.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := &ClusterInfo{Server: "https://prod.example.com", UID: "kube-system-uid"}
+	if diff := cmp.Diff(expect, first.Scan.Cluster); diff != "" {
+		t.Errorf("unexpected cluster (-want +got):\n%s", diff)
+	}
+	if cluster.Verify.Cluster.UID != "" {
+		t.Errorf("expected the configured cluster not to be modified")
+	}
+
+	// namespaces are listed, so the kube-system namespace is found without reading it again
+	discoveryClient := cluster.Verify.DiscoveryClient.(*fake.FakeDiscovery)
+	discoveryClient.Resources[0].APIResources = append(discoveryClient.Resources[0].APIResources, metav1.APIResource{Name: "namespaces", Kind: "Namespace", Verbs: []string{"get", "list", "delete"}})
+	metadataClient.ClearActions()
+	second, err := cluster.Verify.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expect, second.Scan.Cluster); diff != "" {
+		t.Errorf("unexpected cluster (-want +got):\n%s", diff)
+	}
+	for _, action := range metadataClient.Actions() {
+		if action.GetVerb() == "get" {
+			t.Errorf("unexpected get of %s", action.GetResource())
+		}
+	}
+	if first.Scan.ID == "" || first.Scan.ID == second.Scan.ID {
+		t.Errorf("expected unique scan IDs, got %q and %q", first.Scan.ID, second.Scan.ID)
+	}
+
+	// reports identify the scan and cluster
+	doc := newReportDocument(second, &ClusterInfo{Server: "https://prod.example.com"})
+	if doc.ScanID != second.Scan.ID {
+		t.Errorf("expected report scan ID %q, got %q", second.Scan.ID, doc.ScanID)
+	}
+	if diff := cmp.Diff(expect, doc.Cluster); diff != "" {
+		t.Errorf("unexpected report cluster (-want +got):\n%s", diff)
+	}
+
+	// clusters without a kube-system namespace are identified by what is configured
+	unknown := newFakeCluster(t, "staging")
+	report, err := unknown.Verify.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scan.ID == "" || report.Scan.Cluster != nil {
+		t.Errorf("expected a scan ID and no cluster, got %#v", report.Scan)
+	}
+
+	// the cluster is redacted
+	redact := &RedactOptions{Salt: []byte("0123456789abcdef")}
+	if err := redact.redact(second); err != nil {
+		t.Fatal(err)
+	}
+	if second.Scan.Cluster.Server == expect.Server || second.Scan.Cluster.UID == expect.UID || second.Scan.Cluster.Context != "" {
+		t.Errorf("expected cluster to be redacted, got %#v", second.Scan.Cluster)
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/metadata"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// ScanSnapshot holds the resources discovered and the objects listed by a scan, for re-analysis without the cluster
+type ScanSnapshot struct {
+	// Resources are the discovered resources, with the preferred version of each group listed first
+	Resources []*metav1.APIResourceList `json:"resources"`
+	// Objects are the listed objects, without managed fields
+	Objects []*metav1.PartialObjectMetadata `json:"objects"`
+	// Failures are the group versions that could not be discovered and the resources that could not be listed
+	Failures []ScanFailure `json:"failures,omitempty"`
+}
+
+// LoadScanSnapshot reads a gzip-compressed snapshot written by --snapshot-out from path
+func LoadScanSnapshot(path string) (*ScanSnapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot %s: %v", path, err)
+	}
+	defer gz.Close()
+	snapshot := &ScanSnapshot{}
+	if err := json.NewDecoder(gz).Decode(snapshot); err != nil {
+		return nil, fmt.Errorf("error reading snapshot %s: %v", path, err)
+	}
+	return snapshot, nil
+}
+
+// write writes the snapshot to path, gzip-compressed. The file is replaced atomically.
+func (s *ScanSnapshot) write(path string) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	gz := gzip.NewWriter(f)
+	if err := json.NewEncoder(gz).Encode(s); err != nil {
+		f.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Clients returns discovery and metadata clients serving the snapshot.
+// Listing resources that could not be listed when the snapshot was taken fails with the original error,
+// and group versions that could not be discovered are reported to stderr.
+func (s *ScanSnapshot) Clients(stderr io.Writer) (discovery.DiscoveryInterface, metadata.Interface, error) {
+	objects := []*unstructured.Unstructured{}
+	for _, object := range s.Objects {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+		if err != nil {
+			return nil, nil, err
+		}
+		objects = append(objects, &unstructured.Unstructured{Object: content})
+	}
+	discoveryClient, metadataClient, err := OfflineClients(s.Resources, objects, stderr)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, failure := range s.Failures {
+		failure := failure
+		gvr := schema.GroupVersionResource{Group: failure.GroupVersionResource.Group, Version: failure.GroupVersionResource.Version, Resource: failure.GroupVersionResource.Resource}
+		if gvr.Resource == "" {
+			fmt.Fprintf(stderr, "warning: %s could not be discovered when the snapshot was taken, so its resources cannot be resolved: %s\n", gvr.GroupVersion(), failure.Message)
+			continue
+		}
+		metadataClient.(*metadatafake.FakeMetadataClient).PrependReactor("list", gvr.Resource, func(action clienttesting.Action) (bool, runtime.Object, error) {
+			if action.GetResource() != gvr {
+				return false, nil, nil
+			}
+			return true, nil, fmt.Errorf("%s", failure.Message)
+		})
+	}
+	return discoveryClient, metadataClient, nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestScanSnapshot(t *testing.T) {
+	opts := newFakeCluster(t, "c", "pod1", "pod2").Verify
+	discoveryClient := opts.DiscoveryClient.(*fake.FakeDiscovery)
+	discoveryClient.Resources = append(discoveryClient.Resources, &metav1.APIResourceList{
+		GroupVersion: "forbidden/v1",
+		APIResources: []metav1.APIResource{{Name: "forbiddenresources", Namespaced: true, Kind: "ForbiddenKind", Verbs: []string{"get", "list", "delete"}}},
+	})
+	opts.MetadataClient.(*metadatafake.FakeMetadataClient).PrependReactor("list", "forbiddenresources", func(action coretesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("not authorized")
+	})
+	path := filepath.Join(t.TempDir(), "snapshot.json.gz")
+	opts.SnapshotOut = path
+	opts.Stderr = ioutil.Discard
+	opts.Stdout = ioutil.Discard
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	// the forbidden resource makes the scan incomplete
+	if err := opts.Run(context.Background()); err == nil || err.(*ExitError).Code != ExitCodeIncomplete {
+		t.Fatalf("expected an incomplete scan, got %v", err)
+	}
+	original, err := opts.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := LoadScanSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Objects) != 2 {
+		t.Errorf("expected 2 objects in the snapshot, got %d", len(snapshot.Objects))
+	}
+	stderr := &bytes.Buffer{}
+	replayDiscovery, replayMetadata, err := snapshot.Clients(stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stderr.Len() > 0 {
+		t.Errorf("unexpected warnings: %s", stderr.String())
+	}
+	replay, err := (&VerifyGCOptions{DiscoveryClient: replayDiscovery, MetadataClient: replayMetadata}).Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// re-analysis reports the same findings, and the same resources failing to list
+	if diff := cmp.Diff(original.Findings, replay.Findings); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(original.Failures, replay.Failures); diff != "" {
+		t.Errorf("unexpected failures (-want +got):\n%s", diff)
+	}
+	if original.Errors != 2 || replay.Errors != 2 || replay.Warnings != 1 {
+		t.Errorf("expected 2 errors and 1 warning, got %d errors and %d warnings", replay.Errors, replay.Warnings)
+	}
+}
+
+func TestScanSnapshotValidate(t *testing.T) {
+	opts := newFakeCluster(t, "c").Verify
+	opts.Stderr = ioutil.Discard
+	opts.Stdout = ioutil.Discard
+	opts.SnapshotOut = "snapshot.json.gz"
+	opts.Benchmark = true
+	if err := opts.Validate(); err == nil {
+		t.Error("expected error writing a snapshot when benchmarking")
+	}
+}
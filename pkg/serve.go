@@ -0,0 +1,365 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	klog "k8s.io/klog/v2"
+)
+
+// ServeOptions contains options controlling the long-running serve mode
+type ServeOptions struct {
+	// Verify holds the options used for each scan. Findings written to Verify.Stdout are discarded.
+	Verify *VerifyGCOptions
+	// Interval is the time between the end of one scan and the start of the next
+	Interval time.Duration
+	// Address is the address to serve metrics and results on
+	Address string
+	// GRPCAddress is optionally the address to serve the Findings gRPC service on, which streams the events of scheduled
+	// scans and runs scoped scans on demand
+	GRPCAddress string
+	// AuthToken, if set, is required as a bearer token for all endpoints other than /healthz and /readyz
+	AuthToken string
+	// Alertmanager optionally sends alerts for Error-level findings to Alertmanager after each scan
+	Alertmanager *AlertmanagerOptions
+	// History optionally retains the results of the last completed scans, served at /results/history
+	History *HistoryOptions
+	// Incremental keeps findings up to date from metadata informers after the first completed scan instead of rescanning,
+	// and publishes the current findings on the configured interval
+	Incremental bool
+	// LeaderElection optionally limits scanning to the replica holding a Lease. Other replicas serve the results of
+	// their last completed scan, if any, and report ready while they stand by.
+	LeaderElection *LeaderElectionOptions
+
+	lock    sync.Mutex
+	metrics metricsState
+	// incremental keeps findings up to date after the first completed scan, if Incremental is set
+	incremental *incrementalScan
+	// history holds the last completed scans, if History is set
+	history *scanHistory
+	// findings streams scan events to gRPC clients, if GRPCAddress is set
+	findings *findingsServer
+}
+
+// Validate ensures the specified options are valid
+func (s *ServeOptions) Validate() error {
+	if s.Verify == nil {
+		return fmt.Errorf("verify options are required")
+	}
+	if err := s.Verify.Validate(); err != nil {
+		return err
+	}
+	if s.Verify.Benchmark {
+		return fmt.Errorf("benchmark mode is not supported when serving")
+	}
+	if s.Interval <= 0 {
+		return fmt.Errorf("invalid interval, must be > 0")
+	}
+	if s.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+	if s.Alertmanager != nil {
+		if err := s.Alertmanager.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.History != nil {
+		if err := s.History.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.LeaderElection != nil {
+		if err := s.LeaderElection.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run serves metrics and results, and scans on the configured interval until ctx is done
+func (s *ServeOptions) Run(ctx context.Context) error {
+	if s.History != nil {
+		history, err := loadHistory(s.History)
+		if err != nil {
+			return fmt.Errorf("error loading scan history: %v", err)
+		}
+		s.history = history
+	}
+	listener, err := net.Listen("tcp", s.Address)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: s.handler()}
+	serveErr := make(chan error, 2)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	klog.Infof("serving metrics and results on http://%s", listener.Addr().String())
+	if s.GRPCAddress != "" {
+		grpcListener, err := net.Listen("tcp", s.GRPCAddress)
+		if err != nil {
+			server.Close()
+			return err
+		}
+		s.findings = newFindingsServer(s)
+		grpcServer := s.findings.server()
+		defer grpcServer.Stop()
+		go func() {
+			serveErr <- grpcServer.Serve(grpcListener)
+		}()
+		klog.Infof("serving the findings gRPC service on %s", grpcListener.Addr().String())
+	}
+
+	scanCtx, cancelScans := context.WithCancel(ctx)
+	defer cancelScans()
+	scanErr := make(chan error, 1)
+	if s.LeaderElection != nil {
+		s.setLeader(false)
+	}
+	go func() {
+		if s.LeaderElection == nil {
+			s.scanLoop(scanCtx)
+			scanErr <- nil
+			return
+		}
+		scanErr <- s.LeaderElection.run(scanCtx, func(ctx context.Context) {
+			s.scanLoop(ctx)
+			// informers stopped with ctx, rescan if leadership is regained
+			s.incremental = nil
+		}, s.setLeader)
+	}()
+
+	select {
+	case err := <-serveErr:
+		cancelScans()
+		<-scanErr
+		return err
+	case err := <-scanErr:
+		// scans stop when ctx is done, after releasing the lease
+		if err != nil {
+			server.Close()
+			return err
+		}
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// scanLoop scans on the configured interval until ctx is done
+func (s *ServeOptions) scanLoop(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			s.scan(ctx)
+			timer.Reset(s.Interval)
+		}
+	}
+}
+
+// setLeader records whether this replica holds the leader election Lease
+func (s *ServeOptions) setLeader(leader bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.metrics.leaderElection = true
+	s.metrics.leader = leader
+}
+
+func (s *ServeOptions) scan(ctx context.Context) {
+	if s.findings != nil {
+		s.findings.broadcast(startedEvent(time.Now()))
+	}
+	var result *Report
+	var err error
+	var publishErr *PublishError
+	if s.incremental != nil {
+		result = s.incremental.report()
+		err = s.Verify.publish(ctx, result)
+	} else {
+		opts := *s.Verify
+		opts.Stdout = io.Discard
+		opts.incremental = s.Incremental
+		if s.findings != nil {
+			opts.onListed = func(gvr schema.GroupVersionResource, objects int, err error) {
+				s.findings.broadcast(listedEvent(gvr, objects, err))
+			}
+		}
+		result, err = opts.run(ctx)
+		if (err == nil || errors.As(err, &publishErr)) && s.Incremental {
+			s.incremental = newIncrementalScan(ctx, &opts, result)
+		}
+	}
+	if errors.As(err, &publishErr) && result != nil {
+		// the scan completed, so its results are kept even though some destinations were not updated
+		klog.Errorf("%v", err)
+		err = nil
+	}
+	if s.findings != nil {
+		for _, event := range resultEvents(result, err) {
+			s.findings.broadcast(event)
+		}
+	}
+
+	if err != nil && ctx.Err() != nil {
+		// shutting down, keep the results of the last completed scan
+		klog.Infof("%v", err)
+		return
+	}
+
+	s.lock.Lock()
+	if err != nil {
+		klog.Errorf("scan failed: %v", err)
+		s.metrics.failures++
+	} else {
+		s.metrics.lastResult = result
+		s.metrics.lastSuccess = result.CompletionTime
+	}
+	s.lock.Unlock()
+
+	if err == nil && s.history != nil {
+		if err := s.history.add(result); err != nil {
+			klog.Errorf("error retaining scan history: %v", err)
+		}
+	}
+	if err == nil && s.Alertmanager != nil {
+		if err := s.Alertmanager.publish(ctx, result, s.Interval); err != nil {
+			klog.Errorf("%v", err)
+		}
+	}
+}
+
+func (s *ServeOptions) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		state := s.state()
+		if state.leaderElection && !state.leader {
+			// standing by, so rollouts are not blocked while another replica holds the lease
+			fmt.Fprint(w, "ok")
+			return
+		}
+		if state.lastResult == nil {
+			http.Error(w, "no scan has completed yet", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	})
+	mux.Handle("/metrics", s.authorize(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := writeMetrics(w, s.state()); err != nil {
+			klog.Warningf("error writing metrics: %v", err)
+		}
+	}))
+	mux.Handle("/results", s.authorize(func(w http.ResponseWriter, r *http.Request) {
+		state := s.state()
+		if state.lastResult == nil {
+			http.Error(w, "no scan has completed yet", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(newReportDocument(state.lastResult, nil)); err != nil {
+			klog.Warningf("error writing results: %v", err)
+		}
+	}))
+	mux.Handle("/results.html", s.authorize(func(w http.ResponseWriter, r *http.Request) {
+		state := s.state()
+		if state.lastResult == nil {
+			http.Error(w, "no scan has completed yet", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := resultsTemplate.Execute(w, newReportDocument(state.lastResult, nil)); err != nil {
+			klog.Warningf("error writing results: %v", err)
+		}
+	}))
+	if s.history != nil {
+		mux.Handle("/results/history", s.authorize(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(s.history.summaries()); err != nil {
+				klog.Warningf("error writing scan history: %v", err)
+			}
+		}))
+		mux.Handle("/results/history/", s.authorize(func(w http.ResponseWriter, r *http.Request) {
+			doc, ok := s.history.get(strings.TrimPrefix(r.URL.Path, "/results/history/"))
+			if !ok {
+				http.Error(w, "scan not found in history", http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(doc); err != nil {
+				klog.Warningf("error writing results: %v", err)
+			}
+		}))
+	}
+	return mux
+}
+
+// state returns a copy of the current scan state
+func (s *ServeOptions) state() metricsState {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.metrics
+}
+
+// authorize wraps handler, requiring the configured bearer token if one is set
+func (s *ServeOptions) authorize(handler http.HandlerFunc) http.Handler {
+	if s.AuthToken == "" {
+		return handler
+	}
+	expected := []byte("Bearer " + s.AuthToken)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	})
+}
+
+var resultsTemplate = template.Must(template.New("results").Parse(`<!DOCTYPE html>
+<html>
+<head><title>ownerReference check results</title></head>
+<body>
+<h1>ownerReference check results</h1>
+<p>Completed {{.CompletionTime.UTC}} in {{printf "%.1f" .DurationSeconds}}s: {{.Errors}} errors, {{.Warnings}} warnings</p>
+{{if .Findings}}<table border="1">
+<tr><th>Group</th><th>Resource</th><th>Namespace</th><th>Name</th><th>Owner UID</th><th>Level</th><th>Code</th><th>Message</th></tr>
+{{range .Findings}}<tr><td>{{.Resource.Group}}</td><td>{{.Resource.Resource}}</td><td>{{.Namespace}}</td><td>{{.Name}}</td><td>{{.OwnerReference.UID}}</td><td>{{.Level}}</td><td>{{.Code}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>{{else}}<p>No invalid ownerReferences found</p>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,130 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestServeHandler(t *testing.T) {
+	s := &ServeOptions{AuthToken: "secret"}
+	server := httptest.NewServer(s.handler())
+	defer server.Close()
+
+	get := func(path, token string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest("GET", server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	expectStatus := func(path, token string, status int) *http.Response {
+		t.Helper()
+		resp := get(path, token)
+		if resp.StatusCode != status {
+			t.Errorf("%s: expected %d, got %d", path, status, resp.StatusCode)
+		}
+		return resp
+	}
+
+	// before the first scan
+	expectStatus("/healthz", "", http.StatusOK)
+	expectStatus("/readyz", "", http.StatusServiceUnavailable)
+	expectStatus("/results", "", http.StatusUnauthorized)
+	expectStatus("/results", "wrong", http.StatusUnauthorized)
+	expectStatus("/results", "secret", http.StatusServiceUnavailable)
+	expectStatus("/metrics", "", http.StatusUnauthorized)
+	expectStatus("/metrics", "secret", http.StatusOK)
+
+	// after the first scan
+	s.metrics.lastResult = &Report{
+		Findings: []Finding{
+			{Resource: metav1.GroupVersionResource{Version: "v1", Resource: "pods"}, Namespace: "ns1", Name: "pod1", Level: LevelError, Code: CodeOwnerNotFound},
+		},
+		Errors:   1,
+		Duration: time.Second,
+	}
+	s.metrics.lastSuccess = time.Now()
+	expectStatus("/readyz", "", http.StatusOK)
+	expectStatus("/results.html", "secret", http.StatusOK)
+	resp := expectStatus("/results", "secret", http.StatusOK)
+	results := reportDocument{}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		t.Fatal(err)
+	}
+	if results.Errors != 1 || len(results.Findings) != 1 || results.Findings[0].Name != "pod1" {
+		t.Errorf("unexpected results: %#v", results)
+	}
+}
+
+func TestServePublishFailure(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "configmaps", func(action coretesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "ownerreferences", fmt.Errorf("denied"))
+	})
+	verify := newFakeCluster(t, "prod", "pod1").Verify
+	verify.Stdout, verify.Stderr = io.Discard, io.Discard
+	verify.ConfigMap = &ConfigMapOptions{Client: client.CoreV1(), Namespace: "monitoring", Name: "ownerreferences"}
+	verify.Events = &EventOptions{Client: client.CoreV1()}
+	if err := verify.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the scan is kept, and destinations after the one that failed are still published to
+	s := &ServeOptions{Verify: verify, Interval: time.Hour}
+	s.scan(context.Background())
+	state := s.state()
+	if state.lastResult == nil || state.lastResult.Errors != 1 || state.failures != 0 {
+		t.Errorf("expected the scan to be recorded despite the publish failure, got %#v", state)
+	}
+	events, err := client.CoreV1().Events("ns1").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events.Items) != 1 {
+		t.Errorf("expected an event to be published after the ConfigMap failed, got %d", len(events.Items))
+	}
+
+	var publishErr *PublishError
+	if _, err := verify.run(context.Background()); !errors.As(err, &publishErr) || !strings.Contains(err.Error(), "ConfigMap: ") {
+		t.Errorf("expected a publish error naming the ConfigMap, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+)
+
+// Report signature algorithms
+const (
+	SignatureEd25519    = "ed25519"
+	SignatureHMACSHA256 = "hmac-sha256"
+)
+
+// ReportSignature is a detached signature of an exported report, written next to it with a .sig suffix
+type ReportSignature struct {
+	// Algorithm is SignatureEd25519 or SignatureHMACSHA256
+	Algorithm string `json:"algorithm"`
+	// Digest is the sha256 digest of the report, as sha256:<hex>
+	Digest string `json:"digest"`
+	// Signature is the base64-encoded signature of the report
+	Signature string `json:"signature"`
+}
+
+// ReportKey signs or verifies reports. It holds an ed25519 key, or an HMAC secret.
+type ReportKey struct {
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+	secret  []byte
+}
+
+// LoadReportKey reads a key to sign or verify reports with from path:
+// a PEM-encoded PKCS #8 ed25519 private key, a PEM-encoded PKIX ed25519 public key (which can only verify),
+// or any other non-empty content, which is used as an HMAC-SHA256 secret.
+func LoadReportKey(path string) (*ReportKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	switch {
+	case block == nil:
+		if len(data) == 0 {
+			return nil, fmt.Errorf("report key %s is empty", path)
+		}
+		return &ReportKey{secret: data}, nil
+	case block.Type == "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing report key %s: %v", path, err)
+		}
+		private, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("report key %s is a %T, only ed25519 keys are supported", path, key)
+		}
+		return &ReportKey{private: private, public: private.Public().(ed25519.PublicKey)}, nil
+	case block.Type == "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing report key %s: %v", path, err)
+		}
+		public, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("report key %s is a %T, only ed25519 keys are supported", path, key)
+		}
+		return &ReportKey{public: public}, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in report key %s, expected PRIVATE KEY or PUBLIC KEY", block.Type, path)
+	}
+}
+
+// Sign returns the detached signature of report, encoded as JSON
+func (k *ReportKey) Sign(report []byte) ([]byte, error) {
+	digest := sha256.Sum256(report)
+	signature := ReportSignature{Digest: "sha256:" + hex.EncodeToString(digest[:])}
+	switch {
+	case k.secret != nil:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(report)
+		signature.Algorithm = SignatureHMACSHA256
+		signature.Signature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	case k.private != nil:
+		signature.Algorithm = SignatureEd25519
+		signature.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(k.private, report))
+	default:
+		return nil, fmt.Errorf("reports cannot be signed with a public key")
+	}
+	data, err := json.MarshalIndent(signature, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Verify returns an error if signature, a detached signature encoded as JSON, is not a valid signature of report with k
+func (k *ReportKey) Verify(report, signature []byte) error {
+	decoded := ReportSignature{}
+	if err := json.Unmarshal(signature, &decoded); err != nil {
+		return fmt.Errorf("error reading signature: %v", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(decoded.Signature)
+	if err != nil {
+		return fmt.Errorf("error decoding signature: %v", err)
+	}
+	digest := sha256.Sum256(report)
+	if decoded.Digest != "sha256:"+hex.EncodeToString(digest[:]) {
+		return fmt.Errorf("report digest sha256:%x does not match signed digest %s, the report was modified", digest, decoded.Digest)
+	}
+	switch decoded.Algorithm {
+	case SignatureHMACSHA256:
+		if k.secret == nil {
+			return fmt.Errorf("report is signed with an HMAC secret, but the key is an ed25519 key")
+		}
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(report)
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return fmt.Errorf("invalid %s signature", decoded.Algorithm)
+		}
+	case SignatureEd25519:
+		if k.public == nil {
+			return fmt.Errorf("report is signed with an ed25519 key, but the key is an HMAC secret")
+		}
+		if !ed25519.Verify(k.public, report, sig) {
+			return fmt.Errorf("invalid %s signature", decoded.Algorithm)
+		}
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q", decoded.Algorithm)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignedExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	privatePath := write("key.pem", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}))
+	publicPath := write("key.pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+	secretPath := write("secret", []byte("s3cr3t"))
+
+	result := &Report{
+		Findings:       []Finding{{Namespace: "ns1", Name: "pod1", Level: LevelError, Code: CodeOwnerNotFound}},
+		Errors:         1,
+		CompletionTime: time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC),
+	}
+	for _, tc := range []struct {
+		name      string
+		signKey   string
+		verifyKey string
+		wrongKey  string
+	}{
+		{name: "ed25519", signKey: privatePath, verifyKey: publicPath, wrongKey: secretPath},
+		{name: "hmac", signKey: secretPath, verifyKey: secretPath, wrongKey: publicPath},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			signKey, err := LoadReportKey(tc.signKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := &ExportOptions{URL: "file://" + dir + "/" + tc.name, SignKey: signKey}
+			if err := opts.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			key, err := opts.publish(context.Background(), result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			report, err := ioutil.ReadFile(filepath.Join(dir, tc.name, key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			signature, err := ioutil.ReadFile(filepath.Join(dir, tc.name, key+".sig"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			verifyKey, err := LoadReportKey(tc.verifyKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := verifyKey.Verify(report, signature); err != nil {
+				t.Errorf("expected signature to verify, got %v", err)
+			}
+			tampered := bytes.Replace(report, []byte(`"errors": 1`), []byte(`"errors": 0`), 1)
+			if err := verifyKey.Verify(tampered, signature); err == nil || !strings.Contains(err.Error(), "the report was modified") {
+				t.Errorf("expected modified report to fail verification, got %v", err)
+			}
+			wrongKey, err := LoadReportKey(tc.wrongKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := wrongKey.Verify(report, signature); err == nil {
+				t.Error("expected verification with the wrong kind of key to fail")
+			}
+		})
+	}
+
+	publicKey, err := LoadReportKey(publicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (&ExportOptions{URL: "file://" + dir, SignKey: publicKey}).Validate(); err == nil {
+		t.Error("expected signing with a public key to be invalid")
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"os"
+)
+
+// Deltas of findings relative to a previous run
+const (
+	// DeltaNew marks a finding that was not reported by the previous run
+	DeltaNew = "New"
+	// DeltaPersisting marks a finding that was also reported by the previous run
+	DeltaPersisting = "Persisting"
+	// DeltaResolved marks a finding of the previous run that is no longer reported
+	DeltaResolved = "Resolved"
+)
+
+// LoadPreviousFindings reads the findings of a previous run, written by -o json, or a report document, from path.
+// Findings marked as resolved by a previous comparison are skipped.
+func LoadPreviousFindings(path string) ([]Finding, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	findings, err := readFindings(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading previous findings %s: %v", path, err)
+	}
+	previous := []Finding{}
+	for _, finding := range findings {
+		if finding.Delta != DeltaResolved {
+			previous = append(previous, finding)
+		}
+	}
+	return previous, nil
+}
+
+// applySince marks findings as new or persisting relative to previous,
+// returning the marked findings and the previous findings that are no longer reported, marked as resolved.
+// Findings are matched by object, owner uid, and code, as in a baseline, and keep their order.
+func applySince(previous, findings []Finding) (marked, resolved []Finding) {
+	resolved, _, persisting := matchFindings(previous, findings, keyOf)
+	remaining := map[findingKey]int{}
+	for _, finding := range persisting {
+		remaining[keyOf(finding)]++
+	}
+	marked = make([]Finding, 0, len(findings))
+	for _, finding := range findings {
+		k := keyOf(finding)
+		if remaining[k] > 0 {
+			remaining[k]--
+			finding.Delta = DeltaPersisting
+		} else {
+			finding.Delta = DeltaNew
+		}
+		marked = append(marked, finding)
+	}
+	for i := range resolved {
+		resolved[i].Delta = DeltaResolved
+	}
+	return marked, resolved
+}
+
+// persisting returns the number of findings of r that were also reported by the previous run
+func (r *Report) persisting() int {
+	persisting := 0
+	for _, finding := range r.Findings {
+		if finding.Delta == DeltaPersisting {
+			persisting++
+		}
+	}
+	return persisting
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSince(t *testing.T) {
+	previous := newFakeCluster(t, "c", "pod1", "gone").Verify
+	previous.Stderr = io.Discard
+	report, err := previous.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousFindings := &bytes.Buffer{}
+	if err := report.Print(previousFindings, "json"); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "previous.json")
+	if err := ioutil.WriteFile(path, previousFindings.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	since, err := LoadPreviousFindings(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := &bytes.Buffer{}
+	opts := newFakeCluster(t, "c", "pod1", "pod2").Verify
+	opts.Since = since
+	opts.Output = "json"
+	opts.Stdout = stdout
+	opts.Stderr = io.Discard
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	err = opts.Run(context.Background())
+	if err == nil || err.Error() != "1 new error found" {
+		t.Errorf("expected error for the new finding, got %v", err)
+	}
+
+	output := stdout.Bytes()
+	findings, err := readFindings(bytes.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, finding := range findings {
+		got = append(got, finding.Delta+"/"+finding.Name)
+	}
+	if diff := cmp.Diff([]string{"Persisting/pod1", "New/pod2", "Resolved/gone"}, got); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+
+	// the output can be compared against by the next run, ignoring resolved findings
+	if err := ioutil.WriteFile(path, output, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if since, err = LoadPreviousFindings(path); err != nil {
+		t.Fatal(err)
+	}
+	opts.Since = since
+	opts.Stdout = io.Discard
+	if err := opts.Run(context.Background()); err != nil {
+		t.Errorf("expected no error without new findings, got %v", err)
+	}
+}
+
+func TestApplySinceDuplicates(t *testing.T) {
+	finding := Finding{Resource: metav1.GroupVersionResource{Version: "v1", Resource: "pods"}, Namespace: "ns1", Name: "pod1", Code: CodeOwnerNotFound}
+	marked, resolved := applySince([]Finding{finding}, []Finding{finding, finding})
+	if marked[0].Delta != DeltaPersisting || marked[1].Delta != DeltaNew || len(resolved) != 0 {
+		t.Errorf("expected one persisting and one new finding, got %v and %v resolved", marked, resolved)
+	}
+}
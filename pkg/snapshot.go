@@ -0,0 +1,112 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+// defaultDiscoverySnapshot describes the built-in resources of Kubernetes 1.22 that can be listed and deleted,
+// and is used to scan objects offline when no discovery snapshot is provided.
+// Within a group, the first version listed is preferred.
+const defaultDiscoverySnapshot = `
+- groupVersion: v1
+  resources:
+  - {name: configmaps, namespaced: true, kind: ConfigMap, verbs: [delete, get, list]}
+  - {name: endpoints, namespaced: true, kind: Endpoints, verbs: [delete, get, list]}
+  - {name: events, namespaced: true, kind: Event, verbs: [delete, get, list]}
+  - {name: limitranges, namespaced: true, kind: LimitRange, verbs: [delete, get, list]}
+  - {name: namespaces, namespaced: false, kind: Namespace, verbs: [delete, get, list]}
+  - {name: nodes, namespaced: false, kind: Node, verbs: [delete, get, list]}
+  - {name: persistentvolumeclaims, namespaced: true, kind: PersistentVolumeClaim, verbs: [delete, get, list]}
+  - {name: persistentvolumes, namespaced: false, kind: PersistentVolume, verbs: [delete, get, list]}
+  - {name: pods, namespaced: true, kind: Pod, verbs: [delete, get, list]}
+  - {name: podtemplates, namespaced: true, kind: PodTemplate, verbs: [delete, get, list]}
+  - {name: replicationcontrollers, namespaced: true, kind: ReplicationController, verbs: [delete, get, list]}
+  - {name: resourcequotas, namespaced: true, kind: ResourceQuota, verbs: [delete, get, list]}
+  - {name: secrets, namespaced: true, kind: Secret, verbs: [delete, get, list]}
+  - {name: serviceaccounts, namespaced: true, kind: ServiceAccount, verbs: [delete, get, list]}
+  - {name: services, namespaced: true, kind: Service, verbs: [delete, get, list]}
+- groupVersion: admissionregistration.k8s.io/v1
+  resources:
+  - {name: mutatingwebhookconfigurations, namespaced: false, kind: MutatingWebhookConfiguration, verbs: [delete, get, list]}
+  - {name: validatingwebhookconfigurations, namespaced: false, kind: ValidatingWebhookConfiguration, verbs: [delete, get, list]}
+- groupVersion: apiextensions.k8s.io/v1
+  resources:
+  - {name: customresourcedefinitions, namespaced: false, kind: CustomResourceDefinition, verbs: [delete, get, list]}
+- groupVersion: apiregistration.k8s.io/v1
+  resources:
+  - {name: apiservices, namespaced: false, kind: APIService, verbs: [delete, get, list]}
+- groupVersion: apps/v1
+  resources:
+  - {name: controllerrevisions, namespaced: true, kind: ControllerRevision, verbs: [delete, get, list]}
+  - {name: daemonsets, namespaced: true, kind: DaemonSet, verbs: [delete, get, list]}
+  - {name: deployments, namespaced: true, kind: Deployment, verbs: [delete, get, list]}
+  - {name: replicasets, namespaced: true, kind: ReplicaSet, verbs: [delete, get, list]}
+  - {name: statefulsets, namespaced: true, kind: StatefulSet, verbs: [delete, get, list]}
+- groupVersion: autoscaling/v1
+  resources:
+  - {name: horizontalpodautoscalers, namespaced: true, kind: HorizontalPodAutoscaler, verbs: [delete, get, list]}
+- groupVersion: batch/v1
+  resources:
+  - {name: cronjobs, namespaced: true, kind: CronJob, verbs: [delete, get, list]}
+  - {name: jobs, namespaced: true, kind: Job, verbs: [delete, get, list]}
+- groupVersion: certificates.k8s.io/v1
+  resources:
+  - {name: certificatesigningrequests, namespaced: false, kind: CertificateSigningRequest, verbs: [delete, get, list]}
+- groupVersion: coordination.k8s.io/v1
+  resources:
+  - {name: leases, namespaced: true, kind: Lease, verbs: [delete, get, list]}
+- groupVersion: discovery.k8s.io/v1
+  resources:
+  - {name: endpointslices, namespaced: true, kind: EndpointSlice, verbs: [delete, get, list]}
+- groupVersion: events.k8s.io/v1
+  resources:
+  - {name: events, namespaced: true, kind: Event, verbs: [delete, get, list]}
+- groupVersion: flowcontrol.apiserver.k8s.io/v1beta1
+  resources:
+  - {name: flowschemas, namespaced: false, kind: FlowSchema, verbs: [delete, get, list]}
+  - {name: prioritylevelconfigurations, namespaced: false, kind: PriorityLevelConfiguration, verbs: [delete, get, list]}
+- groupVersion: networking.k8s.io/v1
+  resources:
+  - {name: ingressclasses, namespaced: false, kind: IngressClass, verbs: [delete, get, list]}
+  - {name: ingresses, namespaced: true, kind: Ingress, verbs: [delete, get, list]}
+  - {name: networkpolicies, namespaced: true, kind: NetworkPolicy, verbs: [delete, get, list]}
+- groupVersion: node.k8s.io/v1
+  resources:
+  - {name: runtimeclasses, namespaced: false, kind: RuntimeClass, verbs: [delete, get, list]}
+- groupVersion: policy/v1
+  resources:
+  - {name: poddisruptionbudgets, namespaced: true, kind: PodDisruptionBudget, verbs: [delete, get, list]}
+- groupVersion: policy/v1beta1
+  resources:
+  - {name: podsecuritypolicies, namespaced: false, kind: PodSecurityPolicy, verbs: [delete, get, list]}
+- groupVersion: rbac.authorization.k8s.io/v1
+  resources:
+  - {name: clusterrolebindings, namespaced: false, kind: ClusterRoleBinding, verbs: [delete, get, list]}
+  - {name: clusterroles, namespaced: false, kind: ClusterRole, verbs: [delete, get, list]}
+  - {name: rolebindings, namespaced: true, kind: RoleBinding, verbs: [delete, get, list]}
+  - {name: roles, namespaced: true, kind: Role, verbs: [delete, get, list]}
+- groupVersion: scheduling.k8s.io/v1
+  resources:
+  - {name: priorityclasses, namespaced: false, kind: PriorityClass, verbs: [delete, get, list]}
+- groupVersion: storage.k8s.io/v1
+  resources:
+  - {name: csidrivers, namespaced: false, kind: CSIDriver, verbs: [delete, get, list]}
+  - {name: csinodes, namespaced: false, kind: CSINode, verbs: [delete, get, list]}
+  - {name: storageclasses, namespaced: false, kind: StorageClass, verbs: [delete, get, list]}
+  - {name: volumeattachments, namespaced: false, kind: VolumeAttachment, verbs: [delete, get, list]}
+- groupVersion: storage.k8s.io/v1beta1
+  resources:
+  - {name: csistoragecapacities, namespaced: true, kind: CSIStorageCapacity, verbs: [delete, get, list]}
+`
@@ -0,0 +1,122 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// What findings can be split into separate files by
+const (
+	SplitByNamespace = "namespace"
+	SplitByResource  = "resource"
+)
+
+// clusterScopedSplitName is the name of the file holding the findings of cluster-scoped objects when splitting by namespace.
+// Namespace names cannot contain underscores, so it cannot collide with a namespace.
+const clusterScopedSplitName = "_cluster"
+
+// SplitOptions controls writing findings to a separate file for each namespace or resource,
+// so each file can be routed to the team that owns it
+type SplitOptions struct {
+	// Dir is the directory to write files to. It is created if it does not exist.
+	// Files from previous runs are not removed, so a namespace or resource with no findings has no file only if Dir starts empty.
+	Dir string
+	// By is what findings are split by, SplitByNamespace or SplitByResource
+	By string
+}
+
+// Validate ensures the specified options are valid
+func (o *SplitOptions) Validate() error {
+	if o.Dir == "" {
+		return fmt.Errorf("output directory is required")
+	}
+	if o.By != SplitByNamespace && o.By != SplitByResource {
+		return fmt.Errorf("invalid split, only %q and %q are supported: %v", SplitByNamespace, SplitByResource, o.By)
+	}
+	return nil
+}
+
+// write writes the findings and resolved findings of report to a file in Dir for each namespace or resource with findings,
+// in output format, returning the paths written. When splitting by namespace, findings of cluster-scoped objects are written to _cluster.
+// When splitting by resource, files are named resource.version.group, or resource.version for the core group.
+// Each file is replaced atomically.
+func (o *SplitOptions) write(report *Report, output string) ([]string, error) {
+	if err := os.MkdirAll(o.Dir, 0755); err != nil {
+		return nil, err
+	}
+	reports := map[string]*Report{}
+	split := func(findings []Finding, add func(r *Report, finding Finding)) {
+		for _, finding := range findings {
+			name := o.name(finding)
+			if reports[name] == nil {
+				reports[name] = &Report{}
+			}
+			add(reports[name], finding)
+		}
+	}
+	split(report.Findings, func(r *Report, finding Finding) { r.Findings = append(r.Findings, finding) })
+	split(report.Resolved, func(r *Report, finding Finding) { r.Resolved = append(r.Resolved, finding) })
+
+	names := make([]string, 0, len(reports))
+	for name := range reports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	paths := make([]string, 0, len(names))
+	for _, name := range names {
+		data := &bytes.Buffer{}
+		if err := reports[name].Print(data, output); err != nil {
+			return nil, err
+		}
+		path := filepath.Join(o.Dir, name+splitExtension(output))
+		if err := writeFileAtomically(path, data.Bytes()); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// name returns the name of the file finding is written to, without an extension
+func (o *SplitOptions) name(finding Finding) string {
+	if o.By == SplitByResource {
+		name := finding.Resource.Resource + "." + finding.Resource.Version
+		if finding.Resource.Group != "" {
+			name += "." + finding.Resource.Group
+		}
+		return name
+	}
+	if finding.Namespace == "" {
+		return clusterScopedSplitName
+	}
+	return finding.Namespace
+}
+
+// splitExtension returns the file extension of files written in output format
+func splitExtension(output string) string {
+	switch output {
+	case "json", "objects":
+		return ".json"
+	default:
+		return ".txt"
+	}
+}
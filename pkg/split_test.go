@@ -0,0 +1,91 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSplitWrite(t *testing.T) {
+	pods := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	nodes := metav1.GroupVersionResource{Version: "v1", Resource: "nodes"}
+	report := &Report{
+		Findings: []Finding{
+			{Resource: pods, Namespace: "ns1", Name: "pod1", Level: LevelError, Code: CodeOwnerNotFound},
+			{Resource: nodes, Name: "node1", Level: LevelWarning, Code: CodeOwnerListFailed},
+			{Resource: pods, Namespace: "ns2", Name: "pod2", Level: LevelError, Code: CodeOwnerNotFound},
+			{Resource: pods, Namespace: "ns1", Name: "pod3", Level: LevelError, Code: CodeNameMismatch},
+		},
+		Resolved: []Finding{{Resource: pods, Namespace: "ns3", Name: "pod4", Level: LevelError, Code: CodeOwnerNotFound, Delta: DeltaResolved}},
+	}
+	dir := filepath.Join(t.TempDir(), "findings")
+	opts := &SplitOptions{Dir: dir, By: SplitByNamespace}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	paths, err := opts.write(report, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedPaths := []string{filepath.Join(dir, "_cluster.json"), filepath.Join(dir, "ns1.json"), filepath.Join(dir, "ns2.json"), filepath.Join(dir, "ns3.json")}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Fatalf("expected %v, got %v", expectedPaths, paths)
+	}
+	for path, expectedNames := range map[string][]string{
+		expectedPaths[0]: {"node1"},
+		expectedPaths[1]: {"pod1", "pod3"},
+		expectedPaths[2]: {"pod2"},
+		expectedPaths[3]: {"pod4"},
+	} {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		findings, err := readFindings(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, finding := range findings {
+			names = append(names, finding.Name)
+		}
+		if !reflect.DeepEqual(names, expectedNames) {
+			t.Errorf("%s: expected findings for %v, got %v", path, expectedNames, names)
+		}
+	}
+
+	opts = &SplitOptions{Dir: filepath.Join(t.TempDir(), "findings"), By: SplitByResource}
+	report.Findings = append(report.Findings, Finding{Resource: metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}, Namespace: "ns1", Name: "rs1"})
+	paths, err = opts.write(report, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedPaths = []string{filepath.Join(opts.Dir, "nodes.v1.txt"), filepath.Join(opts.Dir, "pods.v1.txt"), filepath.Join(opts.Dir, "replicasets.v1.apps.txt")}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("expected %v, got %v", expectedPaths, paths)
+	}
+
+	if err := (&SplitOptions{Dir: dir, By: "owner"}).Validate(); err == nil {
+		t.Errorf("expected error for unsupported split")
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// stallWatchdog cancels the listing of a resource that receives no page for a while,
+// such as one served by a hung aggregated API, so it does not take up the whole scan
+type stallWatchdog struct {
+	timeout time.Duration
+	timer   *time.Timer
+	// fired is set once the listing is cancelled for making no progress
+	fired int32
+}
+
+// newStallWatchdog returns a context that is cancelled when the returned watchdog is not told of progress for timeout,
+// and a function to stop the watchdog and cancel the context once listing is done
+func newStallWatchdog(ctx context.Context, timeout time.Duration) (context.Context, *stallWatchdog, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	w := &stallWatchdog{timeout: timeout}
+	w.timer = time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&w.fired, 1)
+		cancel()
+	})
+	return ctx, w, func() {
+		w.timer.Stop()
+		cancel()
+	}
+}
+
+// progress restarts the timeout, unless the listing was already cancelled. A nil watchdog does nothing.
+func (w *stallWatchdog) progress() {
+	if w != nil && !w.stalled() {
+		w.timer.Reset(w.timeout)
+	}
+}
+
+// stalled returns true if the listing was cancelled for making no progress
+func (w *stallWatchdog) stalled() bool {
+	return w != nil && atomic.LoadInt32(&w.fired) == 1
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestStallWatchdog(t *testing.T) {
+	// progress restarts the timeout
+	ctx, watchdog, stop := newStallWatchdog(context.Background(), 200*time.Millisecond)
+	for i := 0; i < 5; i++ {
+		time.Sleep(20 * time.Millisecond)
+		watchdog.progress()
+	}
+	if ctx.Err() != nil || watchdog.stalled() {
+		t.Fatalf("expected listing making progress not to be cancelled")
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected listing making no progress to be cancelled")
+	}
+	if !watchdog.stalled() {
+		t.Errorf("expected watchdog to have stalled")
+	}
+	stop()
+
+	// stopping cancels the context without stalling
+	ctx, watchdog, stop = newStallWatchdog(context.Background(), time.Hour)
+	stop()
+	if ctx.Err() == nil || watchdog.stalled() {
+		t.Errorf("expected stopped watchdog to cancel without stalling")
+	}
+
+	// a nil watchdog never stalls
+	var none *stallWatchdog
+	none.progress()
+	if none.stalled() {
+		t.Errorf("expected nil watchdog not to stall")
+	}
+}
+
+func TestScanStallTimeout(t *testing.T) {
+	cluster := newFakeCluster(t, "prod", "pod1")
+	discoveryClient := cluster.Verify.DiscoveryClient.(*fake.FakeDiscovery)
+	discoveryClient.Resources[0].APIResources = append(discoveryClient.Resources[0].APIResources, metav1.APIResource{Name: "nodes", Kind: "Node", Verbs: []string{"get", "list", "delete"}})
+	// listing nodes hangs, without observing cancellation
+	cluster.Verify.MetadataClient.(*metadatafake.FakeMetadataClient).PrependReactor("list", "nodes", func(action coretesting.Action) (bool, runtime.Object, error) {
+		time.Sleep(300 * time.Millisecond)
+		return true, nil, fmt.Errorf("hung")
+	})
+	stderr := &bytes.Buffer{}
+	cluster.Verify.Stdout, cluster.Verify.Stderr = &bytes.Buffer{}, stderr
+	cluster.Verify.StallTimeout = 50 * time.Millisecond
+	if err := cluster.Verify.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	report, err := cluster.Verify.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Interrupted {
+		t.Errorf("expected a stalled resource not to interrupt the scan")
+	}
+	if len(report.Failures) != 1 || report.Failures[0].GroupVersionResource.Resource != "nodes" || !strings.Contains(report.Failures[0].Message, "no page received for 50ms") {
+		t.Errorf("expected nodes to fail, got %#v", report.Failures)
+	}
+	if report.Errors != 1 {
+		t.Errorf("expected pods to be checked, got %d errors", report.Errors)
+	}
+	if !strings.Contains(stderr.String(), "warning: could not list /v1, Resource=nodes: no page received for 50ms, stopped listing after 0 objects") {
+		t.Errorf("expected stall warning, got %q", stderr.String())
+	}
+
+	cluster.Verify.StallTimeout = -time.Second
+	if err := cluster.Verify.Validate(); err == nil {
+		t.Errorf("expected error for negative stall timeout")
+	}
+}
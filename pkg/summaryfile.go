@@ -0,0 +1,105 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// levelCounts are the numbers of error- and warning-level findings in a group of findings
+type levelCounts struct {
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+}
+
+func (c *levelCounts) add(finding Finding) {
+	if finding.Level == LevelError {
+		c.Errors++
+	} else {
+		c.Warnings++
+	}
+}
+
+// scanSummary is the summary of a scan written to SummaryFile, without the findings,
+// for dashboards and alerts that only need counts
+type scanSummary struct {
+	ScanID          string       `json:"scanID,omitempty"`
+	Cluster         *ClusterInfo `json:"cluster,omitempty"`
+	CompletionTime  metav1.Time  `json:"completionTime"`
+	DurationSeconds float64      `json:"durationSeconds"`
+	// Complete is false if any resources could not be discovered or listed
+	Complete bool `json:"complete"`
+	Errors   int  `json:"errors"`
+	Warnings int  `json:"warnings"`
+	Findings int  `json:"findings"`
+	// Failures is the number of API group versions that could not be discovered and resources that could not be listed
+	Failures  int `json:"failures"`
+	Objects   int `json:"objects"`
+	Ignored   int `json:"ignored,omitempty"`
+	Newer     int `json:"newer,omitempty"`
+	Baselined int `json:"baselined,omitempty"`
+	// ByCode, ByResource, and ByNamespace count findings by code, by resource[.group] of the child,
+	// and by namespace of the child, with findings of cluster-scoped children counted under the empty namespace
+	ByCode      map[string]levelCounts `json:"byCode"`
+	ByResource  map[string]levelCounts `json:"byResource"`
+	ByNamespace map[string]levelCounts `json:"byNamespace"`
+}
+
+// summarize returns the summary of report. Resolved findings are not counted.
+func summarize(report *Report) scanSummary {
+	summary := scanSummary{
+		ScanID:          report.Scan.ID,
+		Cluster:         report.Scan.Cluster,
+		CompletionTime:  metav1.NewTime(report.CompletionTime),
+		DurationSeconds: report.Duration.Seconds(),
+		Complete:        report.Complete(),
+		Errors:          report.Errors,
+		Warnings:        report.Warnings,
+		Findings:        len(report.Findings),
+		Failures:        len(report.Failures),
+		Objects:         report.Stats.Objects,
+		Ignored:         report.Ignored,
+		Newer:           report.Newer,
+		Baselined:       report.Baselined,
+		ByCode:          map[string]levelCounts{},
+		ByResource:      map[string]levelCounts{},
+		ByNamespace:     map[string]levelCounts{},
+	}
+	count := func(counts map[string]levelCounts, key string, finding Finding) {
+		c := counts[key]
+		c.add(finding)
+		counts[key] = c
+	}
+	for _, finding := range report.Findings {
+		count(summary.ByCode, finding.Code, finding)
+		count(summary.ByResource, schema.GroupResource{Group: finding.Resource.Group, Resource: finding.Resource.Resource}.String(), finding)
+		count(summary.ByNamespace, finding.Namespace, finding)
+	}
+	return summary
+}
+
+// writeSummary writes the summary of report to path as JSON
+func writeSummary(path string, report *Report) error {
+	data, err := json.MarshalIndent(summarize(report), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(path, append(data, '\n'))
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSummarize(t *testing.T) {
+	pods := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	deployments := metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	nodes := metav1.GroupVersionResource{Version: "v1", Resource: "nodes"}
+	report := &Report{
+		Findings: []Finding{
+			{Resource: pods, Namespace: "ns1", Level: LevelError, Code: CodeOwnerNotFound},
+			{Resource: pods, Namespace: "ns2", Level: LevelError, Code: CodeOwnerNotFound},
+			{Resource: deployments, Namespace: "ns1", Level: LevelWarning, Code: CodeNameMismatch},
+			{Resource: nodes, Level: LevelError, Code: CodeInvalidAPIVersion},
+		},
+		// resolved findings are not counted
+		Resolved: []Finding{{Resource: pods, Namespace: "ns3", Level: LevelError, Code: CodeOwnerNotFound}},
+		Errors:   3,
+		Warnings: 2,
+		Failures: []ScanFailure{{GroupVersionResource: metav1.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1"}}},
+		Scan:     ScanIdentity{ID: "scan1", Cluster: &ClusterInfo{UID: "kube-system-uid"}},
+	}
+	summary := summarize(report)
+	if summary.ScanID != "scan1" || summary.Cluster.UID != "kube-system-uid" || summary.Complete || summary.Findings != 4 || summary.Failures != 1 {
+		t.Errorf("unexpected summary %#v", summary)
+	}
+	if diff := cmp.Diff(map[string]levelCounts{CodeOwnerNotFound: {Errors: 2}, CodeNameMismatch: {Warnings: 1}, CodeInvalidAPIVersion: {Errors: 1}}, summary.ByCode); diff != "" {
+		t.Errorf("unexpected counts by code (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]levelCounts{"pods": {Errors: 2}, "deployments.apps": {Warnings: 1}, "nodes": {Errors: 1}}, summary.ByResource); diff != "" {
+		t.Errorf("unexpected counts by resource (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]levelCounts{"ns1": {Errors: 1, Warnings: 1}, "ns2": {Errors: 1}, "": {Errors: 1}}, summary.ByNamespace); diff != "" {
+		t.Errorf("unexpected counts by namespace (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunSummaryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.json")
+	opts := newFakeCluster(t, "prod", "pod1", "pod2").Verify
+	opts.SummaryFile = path
+	opts.Output = "json"
+	opts.Stdout = &bytes.Buffer{}
+	opts.Stderr = &bytes.Buffer{}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := opts.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := scanSummary{}
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.ScanID == "" || !summary.Complete || summary.Errors != 2 || summary.Objects != 2 {
+		t.Errorf("unexpected summary %s", data)
+	}
+	if diff := cmp.Diff(map[string]levelCounts{"ns1": {Errors: 2}}, summary.ByNamespace); diff != "" {
+		t.Errorf("unexpected counts by namespace (-want +got):\n%s", diff)
+	}
+
+	opts.Benchmark = true
+	if err := opts.Validate(); err == nil {
+		t.Errorf("expected error writing a summary when benchmarking")
+	}
+}
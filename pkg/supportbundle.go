@@ -0,0 +1,218 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// SupportBundleOptions controls writing a support bundle, an archive describing a scan for filing issues
+type SupportBundleOptions struct {
+	// Path is the path to write the gzip-compressed tar archive to
+	Path string
+	// Flags are the command-line flags the scan was run with, with sensitive values already redacted
+	Flags map[string]string
+	// Redact replaces the values of labels and annotations in the bundled object metadata
+	Redact bool
+}
+
+// Validate ensures the specified options are valid
+func (o *SupportBundleOptions) Validate() error {
+	if o.Path == "" {
+		return fmt.Errorf("support bundle path is required")
+	}
+	return nil
+}
+
+// supportBundle holds the scan data included in a support bundle
+type supportBundle struct {
+	// resources are the discovered resources
+	resources []*metav1.APIResourceList
+	// objects are the children and owners of findings, without managed fields
+	objects []*metav1.PartialObjectMetadata
+}
+
+// supportBundleSummary is the summary.json of a support bundle
+type supportBundleSummary struct {
+	ScanID         string        `json:"scanID,omitempty"`
+	Cluster        *ClusterInfo  `json:"cluster,omitempty"`
+	Errors         int           `json:"errors"`
+	Warnings       int           `json:"warnings"`
+	Baselined      int           `json:"baselined"`
+	Interrupted    bool          `json:"interrupted"`
+	Complete       bool          `json:"complete"`
+	Failures       []ScanFailure `json:"failures,omitempty"`
+	Resources      int           `json:"resources"`
+	Objects        int           `json:"objects"`
+	Pages          int           `json:"pages"`
+	Retries        int           `json:"retries,omitempty"`
+	Discovery      string        `json:"discoveryDuration"`
+	List           string        `json:"listDuration"`
+	Duration       string        `json:"duration"`
+	CompletionTime time.Time     `json:"completionTime"`
+}
+
+// write writes the version, flags, summary, findings, discovered resources,
+// and metadata of the objects involved in findings of report to a gzip-compressed tar archive.
+// The file is replaced atomically.
+func (o *SupportBundleOptions) write(report *Report) error {
+	flags, err := json.MarshalIndent(o.Flags, "", "  ")
+	if err != nil {
+		return err
+	}
+	version, err := json.MarshalIndent(GetVersionInfo(), "", "  ")
+	if err != nil {
+		return err
+	}
+	summary, err := json.MarshalIndent(supportBundleSummary{
+		ScanID:         report.Scan.ID,
+		Cluster:        report.Scan.Cluster,
+		Errors:         report.Errors,
+		Warnings:       report.Warnings,
+		Baselined:      report.Baselined,
+		Interrupted:    report.Interrupted,
+		Complete:       report.Complete(),
+		Failures:       report.Failures,
+		Resources:      report.Stats.Resources,
+		Objects:        report.Stats.Objects,
+		Pages:          report.Stats.Pages,
+		Retries:        report.Stats.Retries,
+		Discovery:      report.Stats.DiscoveryDuration.String(),
+		List:           report.Stats.ListDuration.String(),
+		Duration:       report.Duration.String(),
+		CompletionTime: report.CompletionTime,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	findings := &bytes.Buffer{}
+	if err := report.Print(findings, "json"); err != nil {
+		return err
+	}
+	discovery, err := yaml.Marshal(report.bundle.resources)
+	if err != nil {
+		return err
+	}
+	objects := report.bundle.objects
+	if o.Redact {
+		objects = redactObjects(objects)
+	}
+	objectsData, err := json.MarshalIndent(objects, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(o.Path), filepath.Base(o.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{"version.json", version},
+		{"flags.json", flags},
+		{"summary.json", summary},
+		{"findings.json", findings.Bytes()},
+		{"discovery.yaml", discovery},
+		{"objects.json", objectsData},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.data)), ModTime: now, Typeflag: tar.TypeReg}); err != nil {
+			f.Close()
+			return err
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), o.Path)
+}
+
+// findingObjects returns the metadata of the children and owners of findings, without managed fields
+func findingObjects(findings []Finding, children, objects *ObjectIndex) []*metav1.PartialObjectMetadata {
+	seen := map[*metav1.PartialObjectMetadata]bool{}
+	found := []*metav1.PartialObjectMetadata{}
+	add := func(object *metav1.PartialObjectMetadata) {
+		if seen[object] {
+			return
+		}
+		seen[object] = true
+		object = object.DeepCopy()
+		object.ManagedFields = nil
+		found = append(found, object)
+	}
+	for _, finding := range findings {
+		gvr := schema.GroupVersionResource{Group: finding.Resource.Group, Version: finding.Resource.Version, Resource: finding.Resource.Resource}
+		for _, child := range children.ByResource(gvr) {
+			if child.Namespace == finding.Namespace && child.Name == finding.Name {
+				add(child)
+			}
+		}
+		for _, owner := range objects.ByUID(finding.OwnerReference.UID) {
+			add(owner)
+		}
+	}
+	return found
+}
+
+// redactObjects returns copies of objects with the values of their labels and annotations replaced
+func redactObjects(objects []*metav1.PartialObjectMetadata) []*metav1.PartialObjectMetadata {
+	redactedObjects := make([]*metav1.PartialObjectMetadata, 0, len(objects))
+	for _, object := range objects {
+		object = object.DeepCopy()
+		for key := range object.Labels {
+			object.Labels[key] = redacted
+		}
+		for key := range object.Annotations {
+			object.Annotations[key] = redacted
+		}
+		redactedObjects = append(redactedObjects, object)
+	}
+	return redactedObjects
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+func TestSupportBundle(t *testing.T) {
+	opts := newFakeCluster(t, "c", "pod1").Verify
+	// a child in another namespace than its owner, with metadata to redact
+	podClient := opts.MetadataClient.(*metadatafake.FakeMetadataClient).Resource(schema.GroupVersionResource{Version: "v1", Resource: "pods"}).Namespace("ns2")
+	if _, err := podClient.(metadatafake.MetadataClient).CreateFake(&metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "other", Namespace: "ns2", UID: "uid-other",
+			Labels:          map[string]string{"team": "payments"},
+			Annotations:     map[string]string{"token": "secret-value"},
+			ManagedFields:   []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: "pod1", UID: "uid-c-pod1"}},
+		},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	opts.SupportBundle = &SupportBundleOptions{Path: path, Flags: map[string]string{"qps": "10", "token": "<redacted>"}, Redact: true}
+	opts.Stderr = ioutil.Discard
+	opts.Stdout = ioutil.Discard
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := opts.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	files := readTarGz(t, path)
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if diff := cmp.Diff([]string{"discovery.yaml", "findings.json", "flags.json", "objects.json", "summary.json", "version.json"}, names); diff != "" {
+		t.Errorf("unexpected files (-want +got):\n%s", diff)
+	}
+
+	flags := map[string]string{}
+	if err := json.Unmarshal(files["flags.json"], &flags); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(opts.SupportBundle.Flags, flags); diff != "" {
+		t.Errorf("unexpected flags (-want +got):\n%s", diff)
+	}
+
+	summary := supportBundleSummary{}
+	if err := json.Unmarshal(files["summary.json"], &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.Errors != 2 || summary.Objects != 2 {
+		t.Errorf("expected 2 errors and 2 objects, got %d errors and %d objects", summary.Errors, summary.Objects)
+	}
+
+	// the children of both findings and the owner of one are included, redacted and without managed fields
+	objects := []*metav1.PartialObjectMetadata{}
+	if err := json.Unmarshal(files["objects.json"], &objects); err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, object := range objects {
+		got = append(got, object.Namespace+"/"+object.Name)
+		if object.Name == "other" {
+			if object.Labels["team"] != redacted || object.Annotations["token"] != redacted || object.ManagedFields != nil {
+				t.Errorf("expected redacted metadata without managed fields, got %#v", object.ObjectMeta)
+			}
+		}
+	}
+	sort.Strings(got)
+	if diff := cmp.Diff([]string{"ns1/pod1", "ns2/other"}, got); diff != "" {
+		t.Errorf("unexpected objects (-want +got):\n%s", diff)
+	}
+}
+
+func TestSupportBundleValidate(t *testing.T) {
+	opts := newFakeCluster(t, "c").Verify
+	opts.Stderr = ioutil.Discard
+	opts.Stdout = ioutil.Discard
+	opts.SupportBundle = &SupportBundleOptions{}
+	if err := opts.Validate(); err == nil {
+		t.Error("expected error for a support bundle without a path")
+	}
+	opts.SupportBundle.Path = "bundle.tar.gz"
+	opts.Benchmark = true
+	if err := opts.Validate(); err == nil {
+		t.Error("expected error writing a support bundle when benchmarking")
+	}
+}
+
+// readTarGz returns the contents of the files in a gzip-compressed tar archive
+func readTarGz(t *testing.T, path string) map[string][]byte {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = data
+	}
+}
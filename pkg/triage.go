@@ -0,0 +1,83 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// printTriage writes kubectl commands to inspect the child and claimed owner of each finding to w:
+// 'kubectl get -o yaml' for the child and owner, and 'kubectl get --raw' listing the owner's resource
+// in all namespaces to search for an object with the referenced uid.
+// Owners whose apiVersion and kind cannot be resolved with restMapper are not looked up.
+func printTriage(w io.Writer, findings []Finding, restMapper meta.RESTMapper) error {
+	b := &strings.Builder{}
+	for _, finding := range findings {
+		ownerRef := finding.OwnerReference
+		fmt.Fprintf(b, "\n# %s %s: %s %s: %s\n", finding.Level, finding.Code, kubectlResource(finding.Resource), namespacedName(finding.Namespace, finding.Name), finding.Message)
+		fmt.Fprintf(b, "kubectl get %s %s%s -o yaml\n", kubectlResource(finding.Resource), finding.Name, kubectlNamespace(finding.Namespace))
+
+		if restMapper == nil {
+			continue
+		}
+		mapping, err := ResolveOwnerReference(restMapper, ownerRef, finding.Namespace)
+		if err != nil {
+			fmt.Fprintf(b, "# owner %s %s cannot be looked up: %v\n", ownerRef.APIVersion, ownerRef.Kind, err)
+			continue
+		}
+		ownerNamespace := ""
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			ownerNamespace = finding.Namespace
+		}
+		owner := metav1.GroupVersionResource(mapping.Resource)
+		fmt.Fprintf(b, "kubectl get %s %s%s -o yaml\n", kubectlResource(owner), ownerRef.Name, kubectlNamespace(ownerNamespace))
+		if ownerRef.UID != "" {
+			fmt.Fprintf(b, "kubectl get --raw %s | jq '.items[].metadata | select(.uid == \"%s\")'\n", rawListPath(mapping.Resource), ownerRef.UID)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// kubectlResource returns the fully-qualified resource argument for kubectl, such as 'deployments.v1.apps'
+func kubectlResource(gvr metav1.GroupVersionResource) string {
+	if gvr.Group == "" {
+		return gvr.Resource
+	}
+	return gvr.Resource + "." + gvr.Version + "." + gvr.Group
+}
+
+func kubectlNamespace(namespace string) string {
+	if namespace == "" {
+		return ""
+	}
+	return " -n " + namespace
+}
+
+// rawListPath returns the API path listing gvr in all namespaces
+func rawListPath(gvr schema.GroupVersionResource) string {
+	if gvr.Group == "" {
+		return "/api/" + gvr.Version + "/" + gvr.Resource
+	}
+	return "/apis/" + gvr.Group + "/" + gvr.Version + "/" + gvr.Resource
+}
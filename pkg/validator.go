@@ -0,0 +1,220 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/metadata"
+)
+
+// OwnerGetter gets the metadata of an object that may be an owner, such as from a metadata client or an informer cache.
+// A NotFound error means the object does not exist.
+type OwnerGetter interface {
+	Get(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) (*metav1.PartialObjectMetadata, error)
+}
+
+// OwnerGetterFunc is a function that implements OwnerGetter
+type OwnerGetterFunc func(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) (*metav1.PartialObjectMetadata, error)
+
+// Get implements OwnerGetter
+func (f OwnerGetterFunc) Get(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) (*metav1.PartialObjectMetadata, error) {
+	return f(ctx, gvr, namespace, name)
+}
+
+// MetadataOwnerGetter returns an OwnerGetter that gets owners from the API server with client
+func MetadataOwnerGetter(client metadata.Interface) OwnerGetter {
+	return OwnerGetterFunc(func(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) (*metav1.PartialObjectMetadata, error) {
+		return client.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	})
+}
+
+// ownerCacheKey identifies an owner looked up by name
+type ownerCacheKey struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	name      string
+}
+
+// ownerCacheEntry is the result of a lookup, an owner or a NotFound error
+type ownerCacheEntry struct {
+	owner   *metav1.PartialObjectMetadata
+	err     error
+	expires time.Time
+}
+
+// cachedOwnerGetter caches the results of the lookups of another OwnerGetter
+type cachedOwnerGetter struct {
+	getter OwnerGetter
+	ttl    time.Duration
+	// now returns the current time, time.Now if nil
+	now func() time.Time
+
+	lock      sync.Mutex
+	entries   map[ownerCacheKey]ownerCacheEntry
+	nextSweep time.Time
+}
+
+// CachedOwnerGetter returns an OwnerGetter that caches the owners returned by getter, and the owners it did not find,
+// for ttl, so popular owners such as a Deployment with thousands of Pods are not fetched for each child.
+// Other errors are not cached. Owners that are deleted or recreated are seen after at most ttl.
+// The returned owners are shared and must not be modified.
+func CachedOwnerGetter(getter OwnerGetter, ttl time.Duration) OwnerGetter {
+	return &cachedOwnerGetter{getter: getter, ttl: ttl, entries: map[ownerCacheKey]ownerCacheEntry{}}
+}
+
+// Get implements OwnerGetter
+func (c *cachedOwnerGetter) Get(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) (*metav1.PartialObjectMetadata, error) {
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+	key := ownerCacheKey{gvr: gvr, namespace: namespace, name: name}
+	c.lock.Lock()
+	entry, ok := c.entries[key]
+	c.lock.Unlock()
+	if ok && now().Before(entry.expires) {
+		return entry.owner, entry.err
+	}
+
+	owner, err := c.getter.Get(ctx, gvr, namespace, name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return owner, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// drop expired entries at most once per ttl, so owners looked up once do not accumulate
+	if t := now(); !t.Before(c.nextSweep) {
+		for key, entry := range c.entries {
+			if !t.Before(entry.expires) {
+				delete(c.entries, key)
+			}
+		}
+		c.nextSweep = t.Add(c.ttl)
+	}
+	c.entries[key] = ownerCacheEntry{owner: owner, err: err, expires: now().Add(c.ttl)}
+	return owner, err
+}
+
+// Validator checks the ownerReferences of individual objects on demand, for use in controllers and admission webhooks.
+// Owners are resolved as the garbage collector resolves them: the ownerReference apiVersion and kind are mapped to a resource,
+// the owner is looked up by name in the child's namespace (or cluster-wide for cluster-scoped owners),
+// and an object with a different uid does not count as the owner.
+type Validator struct {
+	// RESTMapper maps ownerReference apiVersions and kinds to resources
+	RESTMapper meta.RESTMapper
+	// Owners gets owners by name
+	Owners OwnerGetter
+	// Rules are evaluated for each ownerReference. If nil, DefaultRules() are used.
+	// RuleContext.Objects holds only the owners that were found.
+	Rules []Rule
+	// RuleConfig optionally disables, filters, and overrides the level of findings reported by rules.
+	// Thresholds are not applied to the findings of individual objects.
+	RuleConfig *RuleConfig
+}
+
+// NewValidator returns a Validator that resolves ownerReferences with cached discovery from discoveryClient
+// and gets owners with metadataClient. Discovery is refreshed when an apiVersion or kind cannot be resolved.
+func NewValidator(discoveryClient discovery.DiscoveryInterface, metadataClient metadata.Interface) *Validator {
+	return &Validator{
+		RESTMapper: newGCRESTMapper(memory.NewMemCacheClient(discoveryClient)),
+		Owners:     MetadataOwnerGetter(metadataClient),
+	}
+}
+
+// Validate returns the findings for the ownerReferences of object, which must have its apiVersion and kind set.
+// Owners that cannot be retrieved are reported as OwnerListFailed warnings.
+// An error is returned if the kind of object cannot be resolved, or ctx is done.
+func (v *Validator) Validate(ctx context.Context, object runtime.Object) ([]Finding, error) {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return nil, err
+	}
+	gvk := object.GetObjectKind().GroupVersionKind()
+	if gvk.Empty() {
+		return nil, fmt.Errorf("apiVersion and kind are required to validate %s", namespacedName(accessor.GetNamespace(), accessor.GetName()))
+	}
+	mapping, err := v.RESTMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	child := &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: gvk.GroupVersion().String(), Kind: gvk.Kind},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       accessor.GetNamespace(),
+			Name:            accessor.GetName(),
+			UID:             accessor.GetUID(),
+			OwnerReferences: accessor.GetOwnerReferences(),
+		},
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		child.Namespace = ""
+	}
+
+	// get the owners, so they are found by uid as in a scan
+	owners := newObjectIndex()
+	getErrors := map[schema.GroupResource]error{}
+	for _, ownerRef := range child.OwnerReferences {
+		ownerMapping, err := ResolveOwnerReference(v.RESTMapper, ownerRef, child.Namespace)
+		if err != nil {
+			// reported by rules
+			continue
+		}
+		namespace := ""
+		if ownerMapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			namespace = child.Namespace
+		}
+		owner, err := v.Owners.Get(ctx, ownerMapping.Resource, namespace, ownerRef.Name)
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			getErrors[ownerMapping.Resource.GroupResource()] = err
+			continue
+		}
+		// owners may come from a shared cache, and are looked up by the referenced kind
+		owner = owner.DeepCopy()
+		owner.APIVersion, owner.Kind = ownerMapping.GroupVersionKind.GroupVersion().String(), ownerMapping.GroupVersionKind.Kind
+		owners.add(ownerMapping.Resource, owner)
+	}
+
+	rules := v.Rules
+	if rules == nil {
+		rules = DefaultRules()
+	}
+	checker := &checker{
+		restMapper:          v.RESTMapper,
+		rules:               rules,
+		ruleConfig:          v.RuleConfig,
+		objects:             owners,
+		gvDiscoveryFailures: map[schema.GroupVersion]error{},
+		grListErrors:        getErrors,
+	}
+	return checker.check(mapping.Resource, child), nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/fake"
+)
+
+func TestValidator(t *testing.T) {
+	cluster := newFakeCluster(t, "c", "owner")
+	v := NewValidator(cluster.Verify.DiscoveryClient, cluster.Verify.MetadataClient)
+	core := cluster.Verify.DiscoveryClient.(*fake.FakeDiscovery).Resources[0]
+	core.APIResources = append(core.APIResources, metav1.APIResource{Name: "namespaces", Namespaced: false, Kind: "Namespace", Verbs: []string{"get", "list", "delete"}})
+
+	child := func(namespace string, ownerRefs ...metav1.OwnerReference) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "child", OwnerReferences: ownerRefs},
+		}
+	}
+	codes := func(object *metav1.PartialObjectMetadata) []string {
+		t.Helper()
+		findings, err := v.Validate(context.Background(), object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		codes := []string{}
+		for _, finding := range findings {
+			codes = append(codes, finding.Code)
+		}
+		return codes
+	}
+	owner := metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: "owner", UID: "uid-c-owner"}
+	recreated := metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: "owner", UID: "olduid"}
+	unknown := metav1.OwnerReference{APIVersion: "example.com/v1", Kind: "Widget", Name: "w", UID: "wuid"}
+
+	if diff := cmp.Diff([]string{}, codes(child("ns1", owner))); diff != "" {
+		t.Errorf("expected a valid ownerReference (-want +got):\n%s", diff)
+	}
+	// an object with the referenced name but a different uid is not the owner, as in the garbage collector
+	if diff := cmp.Diff([]string{CodeOwnerNotFound}, codes(child("ns1", recreated))); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+	// owners are looked up in the child's namespace
+	if diff := cmp.Diff([]string{CodeOwnerNotFound, CodeUnresolvableOwner}, codes(child("ns2", owner, unknown))); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+	namespace := child("", owner)
+	namespace.Kind = "Namespace"
+	if diff := cmp.Diff([]string{CodeNamespacedOwnerOfClusterScopedChild}, codes(namespace)); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+
+	// owners that cannot be retrieved are warnings
+	v.Owners = OwnerGetterFunc(func(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) (*metav1.PartialObjectMetadata, error) {
+		return nil, fmt.Errorf("unavailable")
+	})
+	if diff := cmp.Diff([]string{CodeOwnerListFailed}, codes(child("ns1", owner))); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+
+	// the object's kind must be resolvable
+	if _, err := v.Validate(context.Background(), &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "untyped"}}); err == nil {
+		t.Errorf("expected error validating an object without a kind")
+	}
+	v.Owners = MetadataOwnerGetter(cluster.Verify.MetadataClient)
+	widget := child("ns1", owner)
+	widget.APIVersion, widget.Kind = "example.com/v1", "Widget"
+	if _, err := v.Validate(context.Background(), widget); err == nil {
+		t.Errorf("expected error validating an object of an unknown kind")
+	}
+}
+
+func TestCachedOwnerGetter(t *testing.T) {
+	gets := map[string]int{}
+	getter := OwnerGetterFunc(func(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) (*metav1.PartialObjectMetadata, error) {
+		gets[name]++
+		switch name {
+		case "owner":
+			return &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, UID: "uid"}}, nil
+		case "missing":
+			return nil, apierrors.NewNotFound(gvr.GroupResource(), name)
+		default:
+			return nil, fmt.Errorf("unavailable")
+		}
+	})
+	now := time.Now()
+	cache := CachedOwnerGetter(getter, time.Minute).(*cachedOwnerGetter)
+	cache.now = func() time.Time { return now }
+
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	for i := 0; i < 3; i++ {
+		if owner, err := cache.Get(context.Background(), pods, "ns1", "owner"); err != nil || owner.UID != "uid" {
+			t.Fatalf("unexpected owner %v, %v", owner, err)
+		}
+		if _, err := cache.Get(context.Background(), pods, "ns1", "missing"); !apierrors.IsNotFound(err) {
+			t.Fatalf("expected NotFound, got %v", err)
+		}
+		if _, err := cache.Get(context.Background(), pods, "ns1", "broken"); err == nil {
+			t.Fatalf("expected error")
+		}
+	}
+	// owners are cached by namespace
+	if _, err := cache.Get(context.Background(), pods, "ns2", "owner"); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]int{"owner": 2, "missing": 1, "broken": 3}, gets); diff != "" {
+		t.Errorf("unexpected gets (-want +got):\n%s", diff)
+	}
+
+	// expired entries are fetched again, and dropped when not
+	now = now.Add(time.Minute)
+	if _, err := cache.Get(context.Background(), pods, "ns1", "owner"); err != nil {
+		t.Fatal(err)
+	}
+	if gets["owner"] != 3 {
+		t.Errorf("expected an expired owner to be fetched again, got %d gets", gets["owner"])
+	}
+	if len(cache.entries) != 1 {
+		t.Errorf("expected expired entries to be dropped, got %d entries", len(cache.entries))
+	}
+}
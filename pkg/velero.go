@@ -0,0 +1,113 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// CodeOwnerRecreatedOnRestore indicates the owner is in the backup being scanned,
+// and is restored with a new uid that the ownerReference does not match
+const CodeOwnerRecreatedOnRestore = "OwnerRecreatedOnRestore"
+
+// ReadVeleroBackup reads the objects in a Velero backup tarball, as written by "velero backup download".
+// resources is returned with resources added for kinds it does not contain, such as custom resources, named from the backup's directories.
+func ReadVeleroBackup(path string, resources []*metav1.APIResourceList) ([]*metav1.APIResourceList, []*unstructured.Unstructured, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var archive io.Reader = r
+	if magic, _ := r.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading velero backup %s: %v", path, err)
+		}
+		defer gz.Close()
+		archive = gz
+	}
+
+	inferred := newInferredResources(resources)
+	objects := []*unstructured.Unstructured{}
+	seen := map[string]bool{}
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("error reading velero backup %s: %v", path, err)
+		}
+		if header.Typeflag != tar.TypeReg || !strings.HasSuffix(header.Name, ".json") {
+			continue
+		}
+		// objects are stored in resources/<resource>.<group>/namespaces/<namespace>/<name>.json or resources/<resource>.<group>/cluster/<name>.json,
+		// and with API group versions enabled, also in a directory for each version between the resource and scope
+		segments := strings.Split(strings.TrimPrefix(header.Name, "./"), "/")
+		if len(segments) < 4 || segments[0] != "resources" {
+			continue
+		}
+		if segments[2] != "namespaces" && segments[2] != "cluster" {
+			if !strings.HasSuffix(segments[2], "-preferredversion") {
+				continue
+			}
+			segments = append(segments[:2], segments[3:]...)
+		}
+		key := strings.Join(segments, "/")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		fileObjects, err := ReadObjects(tr, header.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		resource := strings.SplitN(segments[1], ".", 2)[0]
+		for _, object := range fileObjects {
+			inferred.add(object, resource)
+		}
+		objects = append(objects, fileObjects...)
+	}
+	return inferred.extend(resources), objects, nil
+}
+
+// VeleroRestoreRule reports ownerReferences to owners in the backup being scanned, which dangle after a restore
+// because restored objects are created with new uids. Evaluated after DefaultRules, it only reports ownerReferences without other problems.
+func VeleroRestoreRule() Rule {
+	return RuleFunc(checkOwnerRecreatedOnRestore)
+}
+
+func checkOwnerRecreatedOnRestore(ctx *RuleContext) []Problem {
+	if len(ctx.Owners) == 0 {
+		return nil
+	}
+	owner := ctx.Owners[0]
+	return problem(LevelWarning, CodeOwnerRecreatedOnRestore, fmt.Sprintf("owner %s %s is restored with a new uid, so this ownerReference will dangle unless it is removed or rewritten on restore", owner.Kind, namespacedName(owner.Namespace, owner.Name)))
+}
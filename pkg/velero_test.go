@@ -0,0 +1,97 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestVeleroBackupScan(t *testing.T) {
+	deployment := `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"d1","namespace":"app","uid":"d1uid"}}`
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"metadata/version", "1"},
+		{"resources/deployments.apps/namespaces/app/d1.json", deployment},
+		// copies written with API group versions enabled are skipped
+		{"resources/deployments.apps/v1-preferredversion/namespaces/app/d1.json", deployment},
+		{"resources/deployments.apps/v1beta1/namespaces/app/d1.json", `{"apiVersion":"apps/v1beta1","kind":"Deployment","metadata":{"name":"d1","namespace":"app","uid":"d1uid"}}`},
+		{"resources/replicasets.apps/namespaces/app/rs1.json", `{"apiVersion":"apps/v1","kind":"ReplicaSet","metadata":{"name":"rs1","namespace":"app","uid":"rs1uid","ownerReferences":[{"apiVersion":"apps/v1","kind":"Deployment","name":"d1","uid":"d1uid"}]}}`},
+		// owners that were not backed up are not found
+		{"resources/pods/namespaces/app/p1.json", `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"p1","namespace":"app","uid":"p1uid","ownerReferences":[{"apiVersion":"apps/v1","kind":"ReplicaSet","name":"rs2","uid":"rs2uid"}]}}`},
+		// custom resources are named from their directories
+		{"resources/widgets.example.com/cluster/w1.json", `{"apiVersion":"example.com/v1","kind":"Widget","metadata":{"name":"w1","uid":"w1uid"}}`},
+		{"resources/gadgets.example.com/namespaces/app/g1.json", `{"apiVersion":"example.com/v1","kind":"Gadget","metadata":{"name":"g1","namespace":"app","uid":"g1uid","ownerReferences":[{"apiVersion":"example.com/v1","kind":"Widget","name":"w1","uid":"w1uid"}]}}`},
+	}
+	compressed := &bytes.Buffer{}
+	gz := gzip.NewWriter(compressed)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(file.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+	path := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if err := ioutil.WriteFile(path, compressed.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resources, objects, err := ReadVeleroBackup(path, DefaultDiscoverySnapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := &bytes.Buffer{}
+	discoveryClient, metadataClient, err := OfflineClients(resources, objects, stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stderr.Len() > 0 {
+		t.Errorf("unexpected warnings: %s", stderr.String())
+	}
+	opts := &VerifyGCOptions{DiscoveryClient: discoveryClient, MetadataClient: metadataClient, Rules: append(DefaultRules(), VeleroRestoreRule())}
+	report, err := opts.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := report.Print(out, ""); err != nil {
+		t.Fatal(err)
+	}
+	expect := `
+GROUP RESOURCE NAMESPACE NAME OWNER_UID LEVEL MESSAGE
+pods app p1 rs2uid Error no object found for uid
+apps replicasets app rs1 d1uid Warning owner Deployment app/d1 is restored with a new uid, so this ownerReference will dangle unless it is removed or rewritten on restore
+example.com gadgets app g1 w1uid Warning owner Widget w1 is restored with a new uid, so this ownerReference will dangle unless it is removed or rewritten on restore
+`
+	if diff := cmp.Diff(normalize(expect), normalize(out.String())); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+}
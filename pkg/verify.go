@@ -24,16 +24,22 @@ import (
 	"io"
 	"sort"
 	"strings"
+	"time"
 
 	klog "k8s.io/klog/v2"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/pager"
@@ -46,6 +52,177 @@ type VerifyGCOptions struct {
 	Output          string
 	Stderr          io.Writer
 	Stdout          io.Writer
+
+	// Objects optionally limits the scan to checking the ownerReferences of these objects, such as objects read from a file.
+	// Only the resources referenced by their ownerReferences are listed to find owners,
+	// so an owner of a different kind than its ownerReference is reported as not found.
+	Objects []*unstructured.Unstructured
+	// ObjectsNamespace is the namespace of namespaced Objects without one, such as objects in rendered manifests
+	ObjectsNamespace string
+	// AllVersions lists every served version of each resource, rather than only the preferred version,
+	// indexing each object only at the first version it is listed at. Discovery can report a preferred version
+	// that does not serve all objects, such as during custom resource version migrations.
+	AllVersions bool
+	// Retry optionally configures retrying failed list requests per API group. Requests are not retried if unset.
+	Retry *RetryConfig
+	// ListRequestTimeout bounds each page request of a list, unless the Retry policy of its group sets a timeout,
+	// so a page that hangs fails and can be retried instead of taking up the whole scan. Unbounded if 0.
+	ListRequestTimeout time.Duration
+	// StallTimeout stops listing a resource that receives no page for this long, including retries,
+	// recording it as failed and continuing with the next resource, so a hung aggregated API does not wedge the scan.
+	// Unbounded if 0.
+	StallTimeout time.Duration
+	// Cluster optionally identifies the scanned cluster in outputs. Its UID is set to the UID of the kube-system namespace if empty.
+	Cluster *ClusterInfo
+	// SkipRediscovery skips running discovery again after listing. By default, resources discovered then,
+	// such as custom resources installed during a long scan, are listed too, so objects owned by them are not reported
+	// as having owners of unknown kinds.
+	SkipRediscovery bool
+	// RequiredVerbs are the verbs a resource must support to be listed, DefaultRequiredVerbs if empty.
+	// Resources without delete are not garbage collected, but listing them indexes their objects as potential owners.
+	RequiredVerbs []string
+	// StartFrom optionally resumes a previous partial scan from a resource, in the order resources are listed.
+	// Only objects of this and later resources are checked. Earlier resources are only listed if they are referenced as owners.
+	StartFrom *schema.GroupResource
+	// Scope optionally limits the scan to checking the ownerReferences of objects in some namespaces or resources.
+	// All resources are still listed to find owners.
+	Scope *ScanScope
+
+	// Rules are evaluated for each ownerReference. If nil, DefaultRules() are used.
+	Rules []Rule
+	// RuleConfig optionally disables, filters, and overrides the level of findings reported by rules
+	RuleConfig *RuleConfig
+	// Baseline optionally excludes or demotes previously acknowledged findings
+	Baseline *Baseline
+	// Ignore optionally drops known-noise findings and failures by code or message
+	Ignore *IgnoreFilter
+	// OlderThan optionally drops the findings of objects created less than this long before the scan started,
+	// to separate long-standing debris from new breakage. Findings of objects with unknown creation timestamps are kept.
+	OlderThan time.Duration
+	// Audit optionally correlates findings with apiserver audit logs, to identify who wrote each invalid ownerReference
+	Audit *AuditOptions
+	// FailOnErrors returns an error from Run if any error-level findings are reported
+	FailOnErrors bool
+	// FailFast stops checking objects at the first error-level finding not dropped by Ignore or Baseline,
+	// and returns an error from Run describing it. Rule thresholds are not applied to the finding.
+	// The default checks that do not depend on other objects, such as UnresolvableOwner, are run as objects are listed,
+	// so listing stops at the first error they find.
+	FailFast bool
+	// FailOnWarnings returns an ExitError with ExitCodeWarnings from Run if any warning-level findings are reported
+	// by a complete scan, and the scan did not otherwise fail
+	FailOnWarnings bool
+	// Since optionally holds the findings of a previous run. Findings are marked as new or persisting,
+	// previous findings that are no longer reported are included as resolved,
+	// and Run returns an error if any new error-level findings are reported.
+	Since []Finding
+	// SnapshotOut is optionally the path to write the resources discovered and objects listed by a complete scan to,
+	// for re-analysis with LoadScanSnapshot
+	SnapshotOut string
+	// Inventory is optionally the path to write every ownerReference of the objects checked by a scan that was not interrupted to,
+	// valid or not, as newline-delimited InventoryRecords, for dependency analysis
+	Inventory string
+	// EstimateDeletions writes the number of objects the garbage collector is expected to delete once it processes
+	// the ownerReferences of a complete scan to Stderr after the summary, per resource and namespace
+	EstimateDeletions bool
+	// SupportBundle optionally writes an archive describing the scan, for filing issues
+	SupportBundle *SupportBundleOptions
+	// SummaryFile is optionally the path to write a JSON summary of a scan that was not interrupted to, after each scan:
+	// the number of findings by code, resource, and namespace, completeness, duration, and scan ID, without the findings
+	SummaryFile string
+	// WriteBaseline is optionally the path to write all findings of a complete scan to, for use as a baseline
+	WriteBaseline string
+	// Preflight optionally reviews access to list each resource before listing, skipping resources that cannot be listed
+	Preflight *PreflightOptions
+	// Owners optionally supplies owners from an inventory built outside the cluster, found in addition to or instead of listed owners
+	Owners *ExternalOwners
+	// GCHealth optionally checks the health of the garbage collector before listing, warning if it is not running or stuck,
+	// since findings are then stale
+	GCHealth *GCHealthOptions
+	// WarningsOut optionally receives the warning-level findings in the configured output format instead of Stdout,
+	// so warnings and errors can be routed separately
+	WarningsOut io.Writer
+	// Split optionally also writes the findings of a complete scan to a separate file for each namespace or resource.
+	// MaxFindings does not apply to the files.
+	Split *SplitOptions
+	// MaxFindings optionally limits the number of findings written to Stdout. Further findings are still counted,
+	// and the number not written is included in the summary written to Stderr.
+	MaxFindings int
+	// SummarizeOwnerKinds writes the number of findings for each owner group kind to Stderr after the summary, most errors first,
+	// to identify the controllers responsible for most findings
+	SummarizeOwnerKinds bool
+	// SummarizeManagers writes the number of findings for the children managed by each tool instance to Stderr after the summary,
+	// most errors first, to group findings that share their remediation
+	SummarizeManagers bool
+	// EmitTriage writes kubectl commands to inspect the child and claimed owner of each finding to Stdout after the findings.
+	// Only supported with table output.
+	EmitTriage bool
+	// Redact optionally replaces namespaces, names, and UIDs in findings with salted hashes, so reports can be shared
+	Redact *RedactOptions
+	// NamespaceLabels optionally adds the values of labels and annotations of the namespace of each finding to findings,
+	// as fields with 'json' output and as columns in tables. If namespaces cannot be listed, a warning is written instead.
+	NamespaceLabels *NamespaceLabelOptions
+
+	// Benchmark runs only the discovery and listing phases and reports throughput instead of findings
+	Benchmark bool
+	// APICallCounter optionally counts the API requests made by the clients, and is included in benchmark results
+	APICallCounter *APICallCounter
+
+	// CRDReport optionally writes results to OwnerReferenceReport custom resources. Required for 'crd' output.
+	CRDReport *CRDReportOptions
+	// ConfigMap optionally writes a summary of results to a ConfigMap
+	ConfigMap *ConfigMapOptions
+	// Events optionally records Events on objects with invalid ownerReferences
+	Events *EventOptions
+	// Notify optionally posts a summary of results to a webhook
+	Notify *NotifyOptions
+	// Export optionally uploads reports to object storage
+	Export *ExportOptions
+	// FindingsDB optionally records the findings of each scan in a SQLite database, for trend reporting
+	FindingsDB *FindingsDBOptions
+	// GitHub optionally opens or updates GitHub issues for new Error-level findings
+	GitHub *GitHubIssueOptions
+	// Pushgateway optionally pushes the metrics of each scan to a Prometheus Pushgateway
+	Pushgateway *PushgatewayOptions
+
+	// incremental retains the checker and listed resources in the report, for incremental revalidation
+	incremental bool
+	// Progress optionally receives ProgressEvents as NDJSON as the scan runs
+	Progress io.Writer
+	// ShowProgress draws the progress of the scan as a line on Stderr, redrawn in place, for terminals.
+	// Warnings written during the scan are written above the line, and the line is cleared when the scan ends.
+	ShowProgress bool
+	// Color colors the level of findings in table output, for terminals
+	Color bool
+
+	// onListed is optionally called as each resource is listed, with the number of objects listed and the error if it could not be
+	onListed func(gvr schema.GroupVersionResource, objects int, err error)
+}
+
+// ScanScope limits the objects whose ownerReferences are checked
+type ScanScope struct {
+	// Namespaces optionally limits checks to objects in these namespaces
+	Namespaces []string
+	// Resources optionally limits checks to objects of these resources
+	Resources []schema.GroupResource
+}
+
+// includes returns true if objects of gvr in namespace are in scope
+func (s *ScanScope) includes(gvr schema.GroupVersionResource, namespace string) bool {
+	if s == nil {
+		return true
+	}
+	if len(s.Namespaces) > 0 && !sets.NewString(s.Namespaces...).Has(namespace) {
+		return false
+	}
+	if len(s.Resources) == 0 {
+		return true
+	}
+	for _, resource := range s.Resources {
+		if resource == gvr.GroupResource() {
+			return true
+		}
+	}
+	return false
 }
 
 // Validate ensures the specified options are valid
@@ -62,92 +239,987 @@ func (v *VerifyGCOptions) Validate() error {
 	if v.Stdout == nil {
 		return fmt.Errorf("stdout is required")
 	}
-	if v.Output != "" && v.Output != "json" {
-		return fmt.Errorf("invalid output format, only '' and 'json' are supported: %v", v.Output)
+	if v.Output != "" && v.Output != "json" && v.Output != "name" && v.Output != "objects" && v.Output != "crd" {
+		return fmt.Errorf("invalid output format, only '', 'json', 'name', 'objects', and 'crd' are supported: %v", v.Output)
+	}
+	if v.RuleConfig != nil {
+		if err := v.RuleConfig.Validate(); err != nil {
+			return err
+		}
+	}
+	if v.Retry != nil {
+		if err := v.Retry.Validate(); err != nil {
+			return err
+		}
+	}
+	if v.ListRequestTimeout < 0 {
+		return fmt.Errorf("invalid list request timeout, must be >= 0")
+	}
+	if v.StallTimeout < 0 {
+		return fmt.Errorf("invalid stall timeout, must be >= 0")
+	}
+	if v.Audit != nil {
+		if err := v.Audit.Validate(); err != nil {
+			return err
+		}
+	}
+	if v.SnapshotOut != "" && (v.Benchmark || v.Objects != nil) {
+		return fmt.Errorf("snapshots cannot be written when benchmarking or checking specific objects")
+	}
+	if v.Inventory != "" && v.Benchmark {
+		return fmt.Errorf("inventories cannot be written when benchmarking")
+	}
+	if v.SummaryFile != "" && v.Benchmark {
+		return fmt.Errorf("summaries cannot be written when benchmarking")
+	}
+	if v.EstimateDeletions && v.Benchmark {
+		return fmt.Errorf("deletions cannot be estimated when benchmarking")
+	}
+	if v.SupportBundle != nil {
+		if err := v.SupportBundle.Validate(); err != nil {
+			return err
+		}
+		if v.Benchmark {
+			return fmt.Errorf("support bundles cannot be written when benchmarking")
+		}
+	}
+	if v.Owners != nil && v.Owners.Replace && v.Objects == nil {
+		return fmt.Errorf("replacing listed owners with an inventory is only supported when checking specific objects")
+	}
+	if v.Scope != nil && v.Objects != nil {
+		return fmt.Errorf("scans of specific objects cannot be scoped")
+	}
+	if v.Preflight != nil {
+		if err := v.Preflight.Validate(); err != nil {
+			return err
+		}
+	}
+	if v.GCHealth != nil {
+		if err := v.GCHealth.Validate(); err != nil {
+			return err
+		}
+	}
+	if v.StartFrom != nil && v.AllVersions {
+		return fmt.Errorf("starting from a resource is not supported when listing all versions")
+	}
+	if v.StartFrom != nil && (v.Objects != nil || v.SnapshotOut != "" || v.WriteBaseline != "" || v.incremental) {
+		return fmt.Errorf("starting from a resource is not supported when checking specific objects, writing snapshots or baselines, or updating incrementally")
+	}
+	if v.FailFast && v.Since != nil {
+		return fmt.Errorf("failing fast is not supported when comparing to a previous run")
+	}
+	if v.MaxFindings < 0 {
+		return fmt.Errorf("invalid max findings, must be >= 0")
+	}
+	if v.OlderThan < 0 {
+		return fmt.Errorf("invalid minimum age, must be >= 0")
+	}
+	if err := validateRequiredVerbs(v.RequiredVerbs); err != nil {
+		return err
+	}
+	if v.WarningsOut != nil && v.Output == "crd" {
+		return fmt.Errorf("writing warnings separately is not supported with 'crd' output")
+	}
+	if v.Split != nil {
+		if err := v.Split.Validate(); err != nil {
+			return err
+		}
+		if v.Output == "crd" {
+			return fmt.Errorf("splitting findings into files is not supported with 'crd' output")
+		}
+	}
+	if v.EmitTriage && v.Output != "" {
+		return fmt.Errorf("triage commands are only supported with table output")
+	}
+	if v.Redact != nil {
+		if err := v.Redact.Validate(); err != nil {
+			return err
+		}
+		if v.EmitTriage || v.Events != nil || v.Output == "crd" || v.SupportBundle != nil {
+			return fmt.Errorf("redaction is not supported with triage commands, events, support bundles, or 'crd' output")
+		}
+		if v.WriteBaseline != "" {
+			// redacted findings would not match the findings of later scans, and unredacted ones would leak names
+			return fmt.Errorf("writing a baseline is not supported with redaction")
+		}
+		if v.Inventory != "" {
+			// the inventory names every object listed, not only those with findings
+			return fmt.Errorf("writing an inventory is not supported with redaction")
+		}
+	}
+	if v.NamespaceLabels != nil {
+		if err := v.NamespaceLabels.Validate(); err != nil {
+			return err
+		}
+		if v.Redact != nil {
+			return fmt.Errorf("namespace labels are not supported with redaction")
+		}
+	}
+	if v.Output == "crd" && v.CRDReport == nil {
+		return fmt.Errorf("report options are required for 'crd' output")
+	}
+	if v.CRDReport != nil {
+		if err := v.CRDReport.Validate(); err != nil {
+			return err
+		}
+	}
+	if v.ConfigMap != nil {
+		if err := v.ConfigMap.Validate(); err != nil {
+			return err
+		}
+	}
+	if v.Events != nil {
+		if err := v.Events.Validate(); err != nil {
+			return err
+		}
+	}
+	if v.Notify != nil {
+		if err := v.Notify.Validate(); err != nil {
+			return err
+		}
+	}
+	if v.Export != nil {
+		if err := v.Export.Validate(); err != nil {
+			return err
+		}
+	}
+	if v.FindingsDB != nil {
+		if err := v.FindingsDB.Validate(); err != nil {
+			return err
+		}
+	}
+	if v.GitHub != nil {
+		if err := v.GitHub.Validate(); err != nil {
+			return err
+		}
+	}
+	if v.Pushgateway != nil {
+		if err := v.Pushgateway.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run executes the verify operation.
+// If ctx is cancelled, findings for the objects checked so far are written before returning an error.
+func (v *VerifyGCOptions) Run(ctx context.Context) error {
+	report, err := v.run(ctx)
+	if err != nil {
+		return err
+	}
+	if v.Since != nil && report != nil && report.NewErrors > 0 {
+		return fmt.Errorf("%s found", pluralize(report.NewErrors, "new error", "new errors"))
+	}
+	if v.Since == nil && v.FailOnErrors && report != nil && report.Errors > 0 {
+		return fmt.Errorf("%s found", pluralize(report.Errors, "error", "errors"))
+	}
+	if report != nil && !report.Complete() {
+		return &ExitError{Code: ExitCodeIncomplete, Err: fmt.Errorf("scan incomplete: %s could not be discovered or listed", pluralize(len(report.Failures), "resource", "resources"))}
+	}
+	if v.FailOnWarnings && report != nil && report.Warnings > 0 {
+		return &ExitError{Code: ExitCodeWarnings, Err: fmt.Errorf("%s found", pluralize(report.Warnings, "warning", "warnings"))}
 	}
 	return nil
 }
 
-// Run executes the verify operation
-func (v *VerifyGCOptions) Run() error {
-	errorCount := 0
-	warningCount := 0
+// Report is the result of a completed scan
+type Report struct {
+	// Findings are the invalid ownerReferences found, ordered by resource
+	Findings []Finding
+	// Errors is the number of Error-level findings
+	Errors int
+	// Warnings is the number of Warning-level findings, plus the number of resources that could not be discovered or listed
+	Warnings int
+	// Failures are the API group versions that could not be discovered and the resources that could not be listed
+	Failures []ScanFailure
+	// Stats describes the work done to discover and list resources
+	Stats ScanStats
+	// Duration is how long the scan took
+	Duration time.Duration
+	// CompletionTime is when the scan completed
+	CompletionTime time.Time
+	// Scan identifies the scan and the scanned cluster in outputs
+	Scan ScanIdentity
+	// Interrupted is true if the scan was cancelled before all resources were listed. Objects listed before cancellation are checked.
+	// Owners in resources that were not listed are reported as OwnerListFailed warnings.
+	Interrupted bool
+	// FailedFast is true if listing or checking stopped at the first error-level finding, with FailFast.
+	// Findings holds only the findings of the object with the error.
+	FailedFast bool
+	// Ignored is the number of findings and failures that matched Ignore, and were dropped
+	Ignored int
+	// Newer is the number of findings of objects newer than OlderThan, which were dropped
+	Newer int
+	// Consistency estimates how much the cluster changed while listing, nil when benchmarking
+	Consistency *Consistency
+	// Baselined is the number of findings that matched the baseline, and were excluded or demoted
+	Baselined int
+	// Resolved are the findings of the previous run that are no longer reported, if Since is set
+	Resolved []Finding
+	// NewErrors is the number of Error-level findings that were not reported by the previous run, if Since is set
+	NewErrors int
+
+	// unbaselinedFindings are the findings before the baseline was applied, if any
+	unbaselinedFindings []Finding
+	// snapshot holds the resources and objects of the scan, if SnapshotOut is set
+	snapshot *ScanSnapshot
+	// inventory holds the ownerReferences of the checked objects, if Inventory is set
+	inventory []InventoryRecord
+	// deletions estimates the objects garbage collection is expected to delete, if EstimateDeletions is set
+	deletions []DeletionEstimate
+	// bundle holds the data included in the support bundle, if SupportBundle is set
+	bundle *supportBundle
+	// color colors the level of findings in table output
+	color bool
+	// namespaceLabelKeys are the keys of the NamespaceLabels of findings, printed as table columns
+	namespaceLabelKeys []string
+	// restMapper resolves ownerReferences as the scan did, for triage commands
+	restMapper meta.RESTMapper
+	// checker and resources are the checker and the listed resources of the scan, if incremental is set
+	checker   *checker
+	resources []schema.GroupVersionResource
+}
+
+// Finding describes an invalid ownerReference
+type Finding struct {
+	// Cluster identifies the cluster the finding is from when scanning multiple clusters
+	Cluster        string                      `json:"cluster,omitempty"`
+	Resource       metav1.GroupVersionResource `json:"resource"`
+	Kind           metav1.GroupVersionKind     `json:"kind"`
+	Namespace      string                      `json:"namespace"`
+	Name           string                      `json:"name"`
+	UID            types.UID                   `json:"uid,omitempty"`
+	OwnerReference metav1.OwnerReference       `json:"ownerReference"`
+	Level          string                      `json:"level"`
+	Code           string                      `json:"code"`
+	Message        string                      `json:"message"`
+	// Audit describes the request that last wrote the child object, if audit logs were correlated and a request was found
+	Audit *AuditEntry `json:"audit,omitempty"`
+	// CreationTimestamp is when the object was created, if known
+	CreationTimestamp *metav1.Time `json:"creationTimestamp,omitempty"`
+	// NamespaceLabels are the values of the selected labels and annotations of the namespace of the object, if requested
+	NamespaceLabels map[string]string `json:"namespaceLabels,omitempty"`
+	// ManagedBy is the tool managing the object, if detected from its labels and annotations
+	ManagedBy *ManagedBy `json:"managedBy,omitempty"`
+	// Candidates are the objects of the referenced kind and name that may be the intended owner, if the owner was not found
+	Candidates []OwnerCandidate `json:"candidates,omitempty"`
+	// Delta is DeltaNew, DeltaPersisting, or DeltaResolved if findings were compared to a previous run
+	Delta string `json:"delta,omitempty"`
+	// Scan identifies the scan and cluster the finding is from. It is only set in -o json output.
+	Scan *ScanIdentity `json:"scan,omitempty"`
+}
+
+// ScanFailure describes an API group version that could not be discovered, or a resource that could not be listed
+type ScanFailure struct {
+	// GroupVersionResource identifies what failed. Resource is empty for discovery failures.
+	GroupVersionResource metav1.GroupVersionResource `json:"groupVersionResource"`
+	// Message describes the failure
+	Message string `json:"message"`
+	// Forbidden is true if the failure was caused by missing permissions
+	Forbidden bool `json:"forbidden,omitempty"`
+}
+
+// ScanStats describes the work done to discover and list resources
+type ScanStats struct {
+	Resources int
+	Objects   int
+	Pages     int
+	Retries   int
+
+	DiscoveryDuration time.Duration
+	ListDuration      time.Duration
+}
+
+// Levels of findings
+const (
+	LevelError   = "Error"
+	LevelWarning = "Warning"
+)
+
+// Print writes the findings in r to w, either as a table if output is ”, or as a stream of JSON objects if output is 'json'.
+// If output is 'name' or 'objects', the child objects with findings are written once each, for use with kubectl:
+// as resource[.group]/namespace/name lines, or as a v1 List of PartialObjectMetadata. Resolved findings are not included.
+func (r *Report) Print(w io.Writer, output string) error {
+	switch output {
+	case "":
+		findings := append(r.Findings[:len(r.Findings):len(r.Findings)], r.Resolved...)
+		if len(findings) == 0 {
+			return nil
+		}
+		// optional columns are included when any finding has the value: the cluster when printing findings from
+		// multiple clusters, the writer when correlated with audit logs, the status when compared to a previous run,
+		// the age when creation timestamps are known, and the managing tool when detected
+		withCluster, withAudit, withDelta, withAge, withManagedBy := false, false, false, false, false
+		for _, finding := range findings {
+			withCluster = withCluster || finding.Cluster != ""
+			withAudit = withAudit || finding.Audit != nil
+			withDelta = withDelta || finding.Delta != ""
+			withAge = withAge || finding.CreationTimestamp != nil
+			withManagedBy = withManagedBy || finding.ManagedBy != nil
+		}
+		now := time.Now()
+		tabwriter := printers.GetNewTabWriter(w)
+		if withDelta {
+			tabwriter.Write([]byte("STATUS\t"))
+		}
+		if withCluster {
+			tabwriter.Write([]byte("CLUSTER\t"))
+		}
+		tabwriter.Write([]byte("GROUP\tRESOURCE\tNAMESPACE\t"))
+		for _, key := range r.namespaceLabelKeys {
+			tabwriter.Write([]byte(strings.ToUpper(key) + "\t"))
+		}
+		tabwriter.Write([]byte("NAME\tOWNER_UID\t" + r.colorLevel("LEVEL") + "\t"))
+		if withAge {
+			tabwriter.Write([]byte("AGE\t"))
+		}
+		if withAudit {
+			tabwriter.Write([]byte("WRITTEN_BY\t"))
+		}
+		if withManagedBy {
+			tabwriter.Write([]byte("MANAGED_BY\t"))
+		}
+		tabwriter.Write([]byte("MESSAGE\n"))
+		for _, finding := range findings {
+			if withDelta {
+				tabwriter.Write([]byte(finding.Delta + "\t"))
+			}
+			if withCluster {
+				tabwriter.Write([]byte(finding.Cluster + "\t"))
+			}
+			columns := []string{finding.Resource.Group, finding.Resource.Resource, finding.Namespace}
+			for _, key := range r.namespaceLabelKeys {
+				columns = append(columns, finding.NamespaceLabels[key])
+			}
+			columns = append(columns, finding.Name, string(finding.OwnerReference.UID), r.colorLevel(finding.Level))
+			if withAge {
+				columns = append(columns, findingAge(finding, now))
+			}
+			tabwriter.Write([]byte(strings.Join(columns, "\t") + "\t"))
+			if withAudit {
+				writtenBy := "<unknown>"
+				if finding.Audit != nil {
+					writtenBy = finding.Audit.User
+				}
+				tabwriter.Write([]byte(writtenBy + "\t"))
+			}
+			if withManagedBy {
+				tabwriter.Write([]byte(finding.ManagedBy.String() + "\t"))
+			}
+			tabwriter.Write([]byte(finding.Message + "\n"))
+		}
+		return tabwriter.Flush()
+	case "json":
+		encoder := json.NewEncoder(w)
+		scan := r.identity()
+		for _, finding := range append(r.Findings[:len(r.Findings):len(r.Findings)], r.Resolved...) {
+			if finding.Scan == nil {
+				finding.Scan = scan
+			}
+			if err := encoder.Encode(finding); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "name":
+		for _, object := range childObjects(r.Findings) {
+			resource := schema.GroupResource{Group: object.resource.Group, Resource: object.resource.Resource}
+			if _, err := fmt.Fprintf(w, "%s/%s\n", resource, namespacedName(object.Namespace, object.Name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "objects":
+		list := &metav1.List{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "List"}, Items: []runtime.RawExtension{}}
+		for _, object := range childObjects(r.Findings) {
+			list.Items = append(list.Items, runtime.RawExtension{Object: object.PartialObjectMetadata})
+		}
+		data, err := json.MarshalIndent(list, "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	default:
+		return fmt.Errorf("invalid output format, only '', 'json', 'name', and 'objects' are supported: %v", output)
+	}
+}
+
+// Terminal color escape sequences. Each is the same length, so colored table cells stay aligned.
+const (
+	colorRed     = "\x1b[31m"
+	colorYellow  = "\x1b[33m"
+	colorDefault = "\x1b[39m"
+)
+
+// colorLevel returns level colored by severity if r.color is set.
+// Other values, such as the column header, are wrapped in escape sequences of the same length that do not change the color.
+func (r *Report) colorLevel(level string) string {
+	if !r.color {
+		return level
+	}
+	switch level {
+	case LevelError:
+		return colorRed + level + colorDefault
+	case LevelWarning:
+		return colorYellow + level + colorDefault
+	default:
+		return colorDefault + level + colorDefault
+	}
+}
+
+// childObject is an object with findings, and the resource it was listed from
+type childObject struct {
+	*metav1.PartialObjectMetadata
+	resource metav1.GroupVersionResource
+}
+
+// Complete returns true if all resources were discovered and listed, except failures dropped by Ignore
+func (r *Report) Complete() bool {
+	return !r.Interrupted && len(r.Failures) == 0
+}
+
+// limited returns a copy of the report with at most max findings and resolved findings, and the number omitted.
+// If max is 0, the report is returned unchanged.
+func (r *Report) limited(max int) (*Report, int) {
+	if max <= 0 || len(r.Findings)+len(r.Resolved) <= max {
+		return r, 0
+	}
+	limited := *r
+	if len(limited.Findings) > max {
+		limited.Findings = limited.Findings[:max]
+	}
+	limited.Resolved = limited.Resolved[:max-len(limited.Findings)]
+	return &limited, len(r.Findings) + len(r.Resolved) - max
+}
+
+// byLevel returns copies of the report with only its error-level and only its warning-level findings and resolved findings
+func (r *Report) byLevel() (*Report, *Report) {
+	errorReport, warningReport := *r, *r
+	errorReport.Findings, warningReport.Findings = splitLevels(r.Findings)
+	errorReport.Resolved, warningReport.Resolved = splitLevels(r.Resolved)
+	return &errorReport, &warningReport
+}
+
+// splitLevels returns the error-level and the warning-level findings of findings
+func splitLevels(findings []Finding) ([]Finding, []Finding) {
+	var errorFindings, warningFindings []Finding
+	for _, finding := range findings {
+		if finding.Level == LevelError {
+			errorFindings = append(errorFindings, finding)
+		} else {
+			warningFindings = append(warningFindings, finding)
+		}
+	}
+	return errorFindings, warningFindings
+}
+
+// omittedSummary describes the number of findings omitted from output, for appending to a summary
+func omittedSummary(omitted int) string {
+	if omitted == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (and %s not shown)", pluralize(omitted, "more finding", "more findings"))
+}
+
+// childObjects returns the objects with findings, in the order of their first finding
+func childObjects(findings []Finding) []childObject {
+	objects := []childObject{}
+	seen := map[string]bool{}
+	for _, finding := range findings {
+		key := strings.Join([]string{finding.Cluster, finding.Resource.Group, finding.Resource.Resource, finding.Namespace, finding.Name}, "/")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		objects = append(objects, childObject{
+			PartialObjectMetadata: &metav1.PartialObjectMetadata{
+				TypeMeta:   metav1.TypeMeta{APIVersion: schema.GroupVersion{Group: finding.Kind.Group, Version: finding.Kind.Version}.String(), Kind: finding.Kind.Kind},
+				ObjectMeta: metav1.ObjectMeta{Namespace: finding.Namespace, Name: finding.Name, UID: finding.UID},
+			},
+			resource: finding.Resource,
+		})
+	}
+	return objects
+}
+
+// run scans the cluster, writes findings to Stdout in the configured output format, and publishes the report.
+// If only publishing fails, the report is returned with a PublishError.
+func (v *VerifyGCOptions) run(ctx context.Context) (*Report, error) {
+	report, err := v.Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if v.Benchmark {
+		return nil, v.printBenchmark(report.Stats)
+	}
+	if v.NamespaceLabels != nil {
+		if err := v.NamespaceLabels.enrich(ctx, v.MetadataClient, report); err != nil {
+			fmt.Fprintf(v.Stderr, "warning: could not list namespaces to add namespace labels to findings: %v\n", err)
+		}
+	}
+	if v.Redact != nil {
+		if err := v.Redact.redact(report); err != nil {
+			return nil, err
+		}
+	}
+
+	// findings are written to reports by publish for 'crd' output
+	report.color = v.Color
+	printed, omitted := report.limited(v.MaxFindings)
+	if v.Output != "crd" && v.WarningsOut != nil {
+		errorReport, warningReport := printed.byLevel()
+		if err := errorReport.Print(v.Stdout, v.Output); err != nil {
+			return nil, err
+		}
+		if err := warningReport.Print(v.WarningsOut, v.Output); err != nil {
+			return nil, fmt.Errorf("error writing warnings: %v", err)
+		}
+	} else if v.Output != "crd" {
+		if err := printed.Print(v.Stdout, v.Output); err != nil {
+			return nil, err
+		}
+	}
+	if v.EmitTriage {
+		if err := printTriage(v.Stdout, printed.Findings, report.restMapper); err != nil {
+			return nil, err
+		}
+	}
+	// support bundles are written for interrupted scans too, since they may be what the issue is about
+	if report.bundle != nil {
+		if err := v.SupportBundle.write(report); err != nil {
+			return nil, fmt.Errorf("error writing support bundle: %v", err)
+		}
+	}
+	if report.FailedFast {
+		// partial results are not published, to avoid replacing complete results
+		for _, finding := range report.Findings {
+			if finding.Level == LevelError {
+				return nil, fmt.Errorf("stopped at the first error: %s %s: %s", schema.GroupResource{Group: finding.Resource.Group, Resource: finding.Resource.Resource}, namespacedName(finding.Namespace, finding.Name), finding.Message)
+			}
+		}
+	}
+	if report.Interrupted {
+		fmt.Fprintf(v.Stderr, "scan interrupted, results are partial: %s, %s%s\n", pluralize(report.Errors, "error", "errors"), pluralize(report.Warnings, "warning", "warnings"), omittedSummary(omitted))
+		// partial results are not published, to avoid replacing complete results
+		return nil, fmt.Errorf("scan interrupted: %v", ctx.Err())
+	}
+	if report.Errors > 0 || report.Warnings > 0 {
+		fmt.Fprintf(v.Stderr, "%s, %s%s\n", pluralize(report.Errors, "error", "errors"), pluralize(report.Warnings, "warning", "warnings"), omittedSummary(omitted))
+	} else {
+		fmt.Fprintf(v.Stderr, "No invalid ownerReferences found\n")
+	}
+	if v.SummarizeOwnerKinds {
+		if err := printOwnerKindSummary(v.Stderr, report.Findings); err != nil {
+			return nil, err
+		}
+	}
+	if v.SummarizeManagers {
+		if err := printManagerSummary(v.Stderr, report.Findings); err != nil {
+			return nil, err
+		}
+	}
+	if v.EstimateDeletions {
+		if err := printDeletionEstimate(v.Stderr, report.deletions); err != nil {
+			return nil, err
+		}
+	}
+	if err := printForbiddenHint(v.Stderr, report.Failures); err != nil {
+		return nil, err
+	}
+	if report.Ignored > 0 {
+		fmt.Fprintf(v.Stderr, "%s ignored\n", pluralize(report.Ignored, "finding or failure", "findings and failures"))
+	}
+	if report.Newer > 0 {
+		fmt.Fprintf(v.Stderr, "%s of objects newer than %v not reported\n", pluralize(report.Newer, "finding", "findings"), v.OlderThan)
+	}
+	if c := report.Consistency; c != nil && c.Confidence != ConfidenceHigh {
+		fmt.Fprintf(v.Stderr, "warning: consistency confidence %s: the cluster changed while listing %s (resourceVersion spread %d, %d created, %d being deleted), findings may be artifacts of these changes, rescan to confirm them\n",
+			c.Confidence, pluralize(report.Stats.Objects, "object", "objects"), c.ResourceVersionSpread, c.Created, c.Deleting)
+	}
+	if v.Baseline != nil {
+		action := "excluded"
+		if v.Baseline.Demote {
+			action = "demoted to warnings"
+		}
+		fmt.Fprintf(v.Stderr, "%s %s by the baseline\n", pluralize(report.Baselined, "finding", "findings"), action)
+	}
+	if v.Since != nil {
+		fmt.Fprintf(v.Stderr, "since the previous run: %d new (%s), %d persisting, %d resolved\n", len(report.Findings)-report.persisting(), pluralize(report.NewErrors, "error", "errors"), report.persisting(), len(report.Resolved))
+	}
+	if v.Split != nil {
+		paths, err := v.Split.write(report, v.Output)
+		if err != nil {
+			return nil, fmt.Errorf("error writing findings to %s: %v", v.Split.Dir, err)
+		}
+		fmt.Fprintf(v.Stderr, "findings written to %s in %s\n", pluralize(len(paths), "file", "files"), v.Split.Dir)
+	}
+	if v.WriteBaseline != "" {
+		if err := writeBaseline(v.WriteBaseline, report); err != nil {
+			return nil, fmt.Errorf("error writing baseline: %v", err)
+		}
+	}
+	if report.snapshot != nil {
+		if err := report.snapshot.write(v.SnapshotOut); err != nil {
+			return nil, fmt.Errorf("error writing snapshot: %v", err)
+		}
+	}
+	if v.Inventory != "" {
+		if err := writeInventory(v.Inventory, report.inventory); err != nil {
+			return nil, fmt.Errorf("error writing inventory: %v", err)
+		}
+	}
+	if v.SummaryFile != "" {
+		if err := writeSummary(v.SummaryFile, report); err != nil {
+			return nil, fmt.Errorf("error writing summary: %v", err)
+		}
+	}
+
+	if err := v.publish(ctx, report); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// Scan discovers and lists all resources and checks their ownerReferences, returning the findings without writing them.
+// Only the DiscoveryClient, MetadataClient, Objects, ObjectsNamespace, Scope, Rules, RuleConfig, Ignore, Baseline, Audit, Since, SnapshotOut, Inventory, SupportBundle, AllVersions, RequiredVerbs, SkipRediscovery, Retry, ListRequestTimeout, StallTimeout, Cluster, Stderr, and Benchmark options are used.
+// If SnapshotOut, Inventory, or SupportBundle is set, the snapshot, inventory, or bundle data is collected but not written.
+// Warnings about resources that cannot be discovered or listed are written to Stderr, if set.
+// If Benchmark is set, objects are not checked and only Stats are returned.
+func (v *VerifyGCOptions) Scan(ctx context.Context) (*Report, error) {
+	stderr := v.Stderr
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	start := time.Now()
+	report := &Report{}
+	var display *progressLine
+	if v.ShowProgress {
+		display = &progressLine{w: stderr}
+		defer display.clear()
+		stderr = display
+	}
+	progress := newProgressWriter(v.Progress, display, stderr)
+	if v.GCHealth != nil {
+		v.GCHealth.check(ctx, stderr)
+	}
+	// addFailure records failure as a warning and returns true, or counts it as ignored and returns false
+	addFailure := func(failure ScanFailure) bool {
+		if v.Ignore != nil && v.Ignore.failure(failure) {
+			report.Ignored++
+			return false
+		}
+		report.Warnings++
+		report.Failures = append(report.Failures, failure)
+		return true
+	}
 
 	// set up REST mapper
 	gvDiscoveryFailures := map[schema.GroupVersion]error{}
-	groupDiscoveryError := &discovery.ErrGroupDiscoveryFailed{}
-	allGroupResources, err := restmapper.GetAPIGroupResources(v.DiscoveryClient)
-	if errors.As(err, &groupDiscoveryError) {
-		// tolerate partial discovery
-		for failedGV, err := range groupDiscoveryError.Groups {
+	recordDiscoveryFailures := func(groupDiscoveryError *discovery.ErrGroupDiscoveryFailed) {
+		failedGVs := []schema.GroupVersion{}
+		for failedGV := range groupDiscoveryError.Groups {
+			failedGVs = append(failedGVs, failedGV)
+		}
+		sort.Slice(failedGVs, func(i, j int) bool { return failedGVs[i].String() < failedGVs[j].String() })
+		for _, failedGV := range failedGVs {
+			err := groupDiscoveryError.Groups[failedGV]
 			if _, alreadyFailed := gvDiscoveryFailures[failedGV]; !alreadyFailed {
 				gvDiscoveryFailures[failedGV] = err
-				warningCount++
-				fmt.Fprintf(v.Stderr, "warning: could not discover resources in %s: %v", failedGV, err.Error())
+				if addFailure(ScanFailure{
+					GroupVersionResource: metav1.GroupVersionResource{Group: failedGV.Group, Version: failedGV.Version},
+					Message:              err.Error(),
+				}) {
+					fmt.Fprintf(stderr, "warning: could not discover resources in %s: %v", failedGV, err.Error())
+				}
 			}
 		}
+	}
+	groupDiscoveryError := &discovery.ErrGroupDiscoveryFailed{}
+	// discovery is cached and shared with the REST mapper, which is built as the garbage collector's is,
+	// so ownerReferences resolve as they do for the garbage collector
+	cachedDiscovery := memory.NewMemCacheClient(v.DiscoveryClient)
+	allGroupResources, err := restmapper.GetAPIGroupResources(cachedDiscovery)
+	if errors.As(err, &groupDiscoveryError) {
+		// tolerate partial discovery
+		recordDiscoveryFailures(groupDiscoveryError)
 	} else if err != nil {
-		return err
+		return nil, err
 	}
-	restMapper := restmapper.NewDiscoveryRESTMapper(allGroupResources)
+	restMapper := newGCRESTMapper(cachedDiscovery)
+	report.restMapper = restMapper
 
-	// get preferred versions of GC-able resources
-	preferredResources, err := discovery.ServerPreferredResources(v.DiscoveryClient)
+	// get preferred versions of resources supporting the required verbs, falling back to served versions of resources without one
+	preferredResources, err := discovery.ServerPreferredResources(cachedDiscovery)
 	if errors.As(err, &groupDiscoveryError) {
 		// tolerate partial discovery
-		for failedGV, err := range groupDiscoveryError.Groups {
-			if _, alreadyFailed := gvDiscoveryFailures[failedGV]; !alreadyFailed {
-				gvDiscoveryFailures[failedGV] = err
-				warningCount++
-				fmt.Fprintf(v.Stderr, "warning: could not discover resources in %s: %v", failedGV, err.Error())
-			}
-		}
+		recordDiscoveryFailures(groupDiscoveryError)
 	} else if err != nil {
-		return err
+		return nil, err
 	}
-	gcResources := discovery.FilteredBy(discovery.SupportsAllVerbs{Verbs: []string{"list", "get", "delete"}}, preferredResources)
-	gvrMap, err := discovery.GroupVersionResources(gcResources)
+	gvrs, fallbacks, err := gcResourceVersions(preferredResources, allGroupResources, v.RequiredVerbs)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	gvrs := []schema.GroupVersionResource{}
-	for gvr := range gvrMap {
-		gvrs = append(gvrs, gvr)
+	for _, gvr := range fallbacks {
+		fmt.Fprintf(stderr, "warning: no preferred version of %s was discovered, listing %s\n", gvr.GroupResource(), gvr.GroupVersion())
 	}
-	sort.Slice(gvrs, func(i, j int) bool {
-		if gvrs[i].Group != gvrs[j].Group {
-			return gvrs[i].Group < gvrs[j].Group
+	// resources serving the same objects as other resources are not listed, to avoid listing and checking objects twice
+	gvrs, aliases, virtual := dedupeAliases(gvrs, allGroupResources)
+	if len(aliases) > 0 {
+		fmt.Fprintf(stderr, "not listing %s serving the objects of other resources: %s\n", pluralize(len(aliases), "resource", "resources"), aliasList(aliases))
+	}
+	if len(virtual) > 0 {
+		fmt.Fprintf(stderr, "not listing %s computed from other resources: %s\n", pluralize(len(virtual), "virtual resource", "virtual resources"), gvrList(virtual))
+	}
+	// other served versions are listed after the preferred versions, so objects are indexed at their preferred version
+	extraVersions := map[schema.GroupVersionResource]bool{}
+	if v.AllVersions {
+		extra := otherServedVersions(allGroupResources, gvrs, v.RequiredVerbs)
+		for _, gvr := range extra {
+			extraVersions[gvr] = true
 		}
-		if gvrs[i].Version != gvrs[j].Version {
-			return gvrs[i].Version < gvrs[j].Version
+		gvrs = append(gvrs, extra...)
+	}
+
+	// when checking specific objects, only their owners' resources are listed
+	children, childGVRs := (*ObjectIndex)(nil), gvrs
+	ownerResources := map[schema.GroupResource]bool{}
+	if v.Objects != nil {
+		children, childGVRs = newObjectIndex(), nil
+		for _, object := range v.Objects {
+			child := &metav1.PartialObjectMetadata{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object.Object, child); err != nil {
+				return nil, fmt.Errorf("error reading metadata of %s %s: %v", object.GetKind(), object.GetName(), err)
+			}
+			gvk := object.GroupVersionKind()
+			mapping, err := restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+			if err != nil {
+				report.Warnings++
+				fmt.Fprintf(stderr, "warning: could not check %s %s: %v\n", gvk.Kind, namespacedName(child.Namespace, child.Name), err)
+				continue
+			}
+			if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+				child.Namespace = ""
+			} else if child.Namespace == "" {
+				child.Namespace = v.ObjectsNamespace
+			}
+			if len(children.ByResource(mapping.Resource)) == 0 {
+				childGVRs = append(childGVRs, mapping.Resource)
+			}
+			children.add(mapping.Resource, child)
+			for _, ownerRef := range child.OwnerReferences {
+				if resource, ok := ownerResource(restMapper, ownerRef); ok {
+					ownerResources[resource] = true
+				}
+			}
 		}
-		return gvrs[i].Resource < gvrs[j].Resource
-	})
+		sortGVRs(childGVRs)
+		if v.Owners != nil && v.Owners.Replace {
+			// owners are only found in the inventory
+			ownerResources = map[schema.GroupResource]bool{}
+		}
+	}
+
+	// when starting from a resource, it and later resources are listed first,
+	// then earlier resources their objects refer to as owners
+	listGVRs := gvrs
+	beforeStart := map[schema.GroupVersionResource]bool{}
+	if v.StartFrom != nil {
+		start := -1
+		for i, gvr := range gvrs {
+			if gvr.GroupResource() == *v.StartFrom {
+				start = i
+				break
+			}
+		}
+		if start == -1 {
+			return nil, fmt.Errorf("resource to start from %s was not discovered", v.StartFrom)
+		}
+		childGVRs = gvrs[start:]
+		listGVRs = append(append([]schema.GroupVersionResource{}, gvrs[start:]...), gvrs[:start]...)
+		for _, gvr := range gvrs[:start] {
+			beforeStart[gvr] = true
+		}
+	}
 
 	grListErrors := map[schema.GroupResource]error{}
+	for _, gvr := range virtual {
+		grListErrors[gvr.GroupResource()] = errVirtualResource
+	}
+
+	// review access before listing, so resources that would be forbidden are reported up front and skipped
+	denied := map[schema.GroupVersionResource]string{}
+	if v.Preflight != nil {
+		candidates := []schema.GroupVersionResource{}
+		for _, gvr := range gvrs {
+			if v.Objects == nil || ownerResources[gvr.GroupResource()] {
+				candidates = append(candidates, gvr)
+			}
+		}
+		if denied, err = v.Preflight.review(ctx, candidates); err != nil {
+			return nil, err
+		}
+		for _, gvr := range candidates {
+			reason, ok := denied[gvr]
+			if !ok {
+				continue
+			}
+			if addFailure(ScanFailure{
+				GroupVersionResource: metav1.GroupVersionResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource},
+				Message:              "skipped by pre-flight access review: " + reason,
+				Forbidden:            true,
+			}) {
+				fmt.Fprintf(stderr, "warning: skipping %v: %s\n", gvr, reason)
+			}
+			grListErrors[gvr.GroupResource()] = errors.New(reason)
+		}
+		covered := coverage(len(candidates), len(denied))
+		fmt.Fprintf(stderr, "pre-flight access review: %d of %d resources can be listed (%.1f%%)\n", len(candidates)-len(denied), len(candidates), covered)
+		if covered < v.Preflight.MinCoverage {
+			return nil, fmt.Errorf("only %.1f%% of resources can be listed, below the minimum coverage of %g%%", covered, v.Preflight.MinCoverage)
+		}
+	}
+
+	report.Stats.DiscoveryDuration = time.Since(start)
+	listStart := time.Now()
 
 	// fetch all resources
 	// TODO: scope to just fetching some resources, or some namespaces
-	byGVR := map[schema.GroupVersionResource][]*metav1.PartialObjectMetadata{}
-	byUID := map[types.UID][]*metav1.PartialObjectMetadata{}
+	toList := 0
 	for _, gvr := range gvrs {
+		if (v.Objects == nil || ownerResources[gvr.GroupResource()]) && !beforeStart[gvr] {
+			toList++
+		}
+	}
+	progress.emit(ProgressEvent{Phase: ProgressPhaseDiscovery, Resources: toList})
+	objects := newObjectIndex()
+	retrier := newListRetrier(v.Retry, v.ListRequestTimeout, stderr)
+	churn := &churnTracker{start: start}
+	resourceIndex := 0
+	var startOwnerResources map[schema.GroupResource]bool
+	rediscovered := v.SkipRediscovery || v.Objects != nil || v.StartFrom != nil || v.Benchmark
+	// with FailFast, the checks that do not depend on other objects are run on children as they are listed
+	var listingChecker *checker
+	if v.FailFast && v.Objects == nil && !v.Benchmark {
+		listingChecker = &checker{
+			restMapper:          restMapper,
+			rules:               listingRules(),
+			ruleConfig:          v.RuleConfig,
+			objects:             newObjectIndex(),
+			gvDiscoveryFailures: gvDiscoveryFailures,
+			grListErrors:        map[schema.GroupResource]error{},
+		}
+	}
+	for i := 0; ; i++ {
+		if report.FailedFast {
+			break
+		}
+		if i == len(listGVRs) {
+			// resources created while listing, such as by operators being installed, are listed too,
+			// so the owners of objects created with them are found
+			if rediscovered || ctx.Err() != nil {
+				break
+			}
+			rediscovered = true
+			discovered := append([]schema.GroupVersionResource{}, gvrs...)
+			for _, alias := range aliases {
+				discovered = append(discovered, alias.Alias)
+			}
+			discovered = append(discovered, virtual...)
+			added, err := rediscoverResources(cachedDiscovery, restMapper, discovered, v.RequiredVerbs)
+			if err != nil {
+				fmt.Fprintf(stderr, "warning: could not rediscover resources after listing: %v\n", err)
+				break
+			}
+			if len(added) == 0 {
+				break
+			}
+			fmt.Fprintf(stderr, "%s discovered while listing, listing %s\n", pluralize(len(added), "resource was", "resources were"), gvrList(added))
+			gvrs = append(append([]schema.GroupVersionResource{}, gvrs...), added...)
+			sortGVRs(gvrs)
+			childGVRs = gvrs
+			listGVRs = append(listGVRs, added...)
+			toList += len(added)
+		}
+		gvr := listGVRs[i]
+		if v.Objects != nil && !ownerResources[gvr.GroupResource()] {
+			continue
+		}
+		if beforeStart[gvr] {
+			if startOwnerResources == nil {
+				startOwnerResources = ownerResourcesOf(restMapper, objects, childGVRs)
+			}
+			if !startOwnerResources[gvr.GroupResource()] {
+				continue
+			}
+			toList++
+		}
+		resourceIndex++
+		// other versions of resources listed with AllVersions do not affect whether owners of the resource can be found
+		listErrors := grListErrors
+		if extraVersions[gvr] {
+			listErrors = map[schema.GroupResource]error{}
+		}
+		if _, ok := denied[gvr]; ok {
+			progress.list(gvr, ProgressEvent{Resource: resourceIndex, Resources: toList, Done: true, Error: fmt.Sprint(listErrors[gvr.GroupResource()])})
+			if v.onListed != nil {
+				v.onListed(gvr, 0, listErrors[gvr.GroupResource()])
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			// owners in resources that were not listed cannot be checked
+			report.Interrupted = true
+			listErrors[gvr.GroupResource()] = ctx.Err()
+			continue
+		}
+
 		// reverse-lookup the kind for this resource to fill in individual items
 		gvk, _ := restMapper.KindFor(gvr)
 
 		if klog.V(2).Enabled() {
-			fmt.Fprintf(v.Stderr, "fetching %v, %v\n", gvr.GroupVersion().String(), gvr.Resource)
+			fmt.Fprintf(stderr, "fetching %v, %v\n", gvr.GroupVersion().String(), gvr.Resource)
+		}
+		listed, page := 0, 0
+		listCtx, stall, stopWatchdog := ctx, (*stallWatchdog)(nil), func() {}
+		if v.StallTimeout > 0 {
+			listCtx, stall, stopWatchdog = newStallWatchdog(ctx, v.StallTimeout)
 		}
 		pager.New(func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
-			list, err := v.MetadataClient.Resource(gvr).List(ctx, opts)
-			if err != nil {
-				warningCount++
-				fmt.Fprintf(v.Stderr, "warning: could not list %v: %v\n", gvr, err.Error())
-				grListErrors[gvr.GroupResource()] = err
+			list, err := retrier.list(ctx, gvr, func(ctx context.Context) (*metav1.PartialObjectMetadataList, error) {
+				return v.MetadataClient.Resource(gvr).List(ctx, opts)
+			})
+			report.Stats.Pages++
+			page++
+			if err == nil {
+				stall.progress()
+				churn.observeList(list)
+				progress.list(gvr, ProgressEvent{Resource: resourceIndex, Resources: toList, Page: page, Items: len(list.Items)})
+			}
+			if err != nil && stall.stalled() {
+				// recorded once listing stops
+			} else if err != nil && report.FailedFast {
+				// pages buffered ahead are cancelled once listing stops at the first error
+			} else if err != nil && ctx.Err() != nil {
+				// interrupted, not a failure of this resource
+				report.Interrupted = true
+				listErrors[gvr.GroupResource()] = ctx.Err()
+			} else if err != nil {
+				if addFailure(ScanFailure{
+					GroupVersionResource: metav1.GroupVersionResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource},
+					Message:              err.Error(),
+					Forbidden:            apierrors.IsForbidden(err),
+				}) {
+					fmt.Fprintf(stderr, "warning: could not list %v: %v\n", gvr, err.Error())
+				}
+				listErrors[gvr.GroupResource()] = err
 			} else if klog.V(3).Enabled() {
-				fmt.Fprintf(v.Stderr, "got %s\n", pluralize(len(list.Items), "item", "items"))
+				fmt.Fprintf(stderr, "got %s\n", pluralize(len(list.Items), "item", "items"))
 			}
 			return list, err
-		}).EachListItem(context.Background(), metav1.ListOptions{}, func(object runtime.Object) error {
+		}).EachListItem(listCtx, metav1.ListOptions{}, func(object runtime.Object) error {
 			item, ok := object.(*metav1.PartialObjectMetadata)
 			if !ok {
 				return fmt.Errorf("expected type *metav1.PartialObjectMetadata, got type %T", item)
@@ -156,168 +1228,397 @@ func (v *VerifyGCOptions) Run() error {
 				item.APIVersion = gvk.GroupVersion().String()
 				item.Kind = gvk.Kind
 			}
-			byUID[item.UID] = append(byUID[item.UID], item)
-			byGVR[gvr] = append(byGVR[gvr], item)
+			report.Stats.Objects++
+			listed++
+			if v.Benchmark {
+				// objects are not retained when benchmarking
+				return nil
+			}
+			if extraVersions[gvr] && item.UID != "" && len(objects.ByUID(item.UID)) > 0 {
+				// already listed at another version
+				return nil
+			}
+			churn.observe(item)
+			objects.add(gvr, item)
+			if listingChecker != nil && !beforeStart[gvr] && v.Scope.includes(gvr, item.Namespace) {
+				if findings := listingChecker.check(gvr, item); v.failsFast(findings, start) {
+					// stops listing this resource, and no other resource is listed
+					report.Findings, report.FailedFast = findings, true
+					return errFailedFast
+				}
+			}
 			return nil
 		})
+		stopWatchdog()
+		if stall.stalled() && ctx.Err() != nil {
+			report.Interrupted = true
+			listErrors[gvr.GroupResource()] = ctx.Err()
+		} else if stall.stalled() {
+			// the resource is recorded as failed, and listing continues with the next resource
+			err := fmt.Errorf("no page received for %v, stopped listing after %s", v.StallTimeout, pluralize(listed, "object", "objects"))
+			if addFailure(ScanFailure{
+				GroupVersionResource: metav1.GroupVersionResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource},
+				Message:              err.Error(),
+			}) {
+				fmt.Fprintf(stderr, "warning: could not list %v: %v\n", gvr, err)
+			}
+			listErrors[gvr.GroupResource()] = err
+		}
+		report.Stats.Resources++
+		listedEvent := ProgressEvent{Resource: resourceIndex, Resources: toList, Items: listed, Done: true}
+		if err := listErrors[gvr.GroupResource()]; err != nil {
+			listedEvent.Error = err.Error()
+		}
+		progress.list(gvr, listedEvent)
+		if v.onListed != nil {
+			v.onListed(gvr, listed, listErrors[gvr.GroupResource()])
+		}
+	}
+	report.Stats.ListDuration = time.Since(listStart)
+	indexAliases(objects, aliases, grListErrors)
+	if v.Owners != nil {
+		added := v.Owners.index(objects)
+		fmt.Fprintf(stderr, "%s added from the owner inventory\n", pluralize(added, "owner", "owners"))
 	}
+	report.Stats.Retries = retrier.total()
 
-	tabwriter := printers.GetNewTabWriter(v.Stdout)
-	initialized := false
-	var outputRefMessage func(gvr schema.GroupVersionResource, item *metav1.PartialObjectMetadata, ownerRef metav1.OwnerReference, level string, msg string)
-	if v.Output == "" {
-		outputRefMessage = func(gvr schema.GroupVersionResource, item *metav1.PartialObjectMetadata, ownerRef metav1.OwnerReference, level string, msg string) {
-			if level == levelError {
-				errorCount++
-			} else {
-				warningCount++
-			}
-			if !initialized {
-				initialized = true
-				tabwriter.Write([]byte("GROUP\tRESOURCE\tNAMESPACE\tNAME\tOWNER_UID\tLEVEL\tMESSAGE\n"))
-			}
-			tabwriter.Write([]byte(
-				strings.Join([]string{
-					gvr.Group, gvr.Resource, item.Namespace, item.Name, string(ownerRef.UID), level, msg,
-				}, "\t") + "\n",
-			))
-		}
-	} else if v.Output == "json" {
-		outputRefMessage = func(gvr schema.GroupVersionResource, item *metav1.PartialObjectMetadata, ownerRef metav1.OwnerReference, level string, msg string) {
-			json.NewEncoder(v.Stdout).Encode(invalidReference{
-				Resource:       metav1.GroupVersionResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource},
-				Kind:           metav1.GroupVersionKind{Group: gvr.Group, Version: gvr.Version, Kind: item.Kind},
-				Namespace:      item.Namespace,
-				Name:           item.Name,
-				OwnerReference: ownerRef,
-				Level:          level,
-				Message:        msg,
-			})
-		}
+	if v.Benchmark {
+		return report, nil
+	}
+	report.Consistency = churn.consistency(report.Stats.Objects)
+
+	rules := v.Rules
+	if rules == nil {
+		rules = DefaultRules()
+	}
+	checker := &checker{
+		restMapper:          restMapper,
+		rules:               rules,
+		ruleConfig:          v.RuleConfig,
+		objects:             objects,
+		gvDiscoveryFailures: gvDiscoveryFailures,
+		grListErrors:        grListErrors,
 	}
 
+	// check everything that was listed, even if interrupted, since checking is done in memory
 	// iterate over all resource types
-	for _, gvr := range gvrs {
+	if children == nil {
+		children = objects
+	}
+	toCheck := 0
+	for _, gvr := range childGVRs {
+		toCheck += len(children.ByResource(gvr))
+	}
+	progress.emit(ProgressEvent{Phase: ProgressPhaseCheck, Items: toCheck})
+checkChildren:
+	for _, gvr := range childGVRs {
+		if report.FailedFast {
+			// stopped while listing
+			break
+		}
 		// iterate over all items
-		for _, child := range byGVR[gvr] {
-			// iterate over all owners
-			for _, ownerRef := range child.OwnerReferences {
-				// resolve REST info
-				ownerGV, err := schema.ParseGroupVersion(ownerRef.APIVersion)
-				if err != nil {
-					outputRefMessage(gvr, child, ownerRef, levelError, fmt.Sprintf("invalid owner apiVersion %s: %v", ownerRef.APIVersion, err.Error()))
-					continue
-				}
-				ownerGVK := ownerGV.WithKind(ownerRef.Kind)
-				mapping, err := restMapper.RESTMapping(ownerGVK.GroupKind(), ownerGVK.Version)
-				if err != nil {
-					if discoveryErr, discoveryFailed := gvDiscoveryFailures[ownerGV]; discoveryFailed {
-						// warn on discovery failure for the referenced apiVersion
-						outputRefMessage(gvr, child, ownerRef, levelWarning, fmt.Sprintf("failed resolving resources for %s: %v", ownerRef.APIVersion, discoveryErr.Error()))
-						continue
-					}
-					outputRefMessage(gvr, child, ownerRef, levelError, fmt.Sprintf("cannot resolve owner apiVersion/kind: %v", err))
-					continue
-				}
-				ownerGR := mapping.Resource.GroupResource()
-				// ownerRef apiVersion/kind is namespaced, child is cluster-scoped
-				if mapping.Scope.Name() == meta.RESTScopeNameNamespace && child.Namespace == "" {
-					outputRefMessage(gvr, child, ownerRef, levelError, fmt.Sprintf("cannot reference namespaced type as owner (apiVersion=%s,kind=%s)", ownerGVK.GroupVersion().String(), ownerGVK.Kind))
-					continue
-				}
+		for _, child := range children.ByResource(gvr) {
+			if !v.Scope.includes(gvr, child.Namespace) {
+				continue
+			}
+			findings := checker.check(gvr, child)
+			if v.FailFast && v.failsFast(findings, start) {
+				report.Findings, report.FailedFast = findings, true
+				break checkChildren
+			}
+			report.Findings = append(report.Findings, findings...)
+		}
+	}
+	if v.incremental {
+		report.checker, report.resources = checker, gvrs
+	}
+	// estimated before findings are ignored or filtered, since the garbage collector acts on all of them
+	if v.EstimateDeletions && !report.FailedFast {
+		report.deletions = estimateDeletions(childGVRs, children, report.Findings)
+	}
 
-				// compare with actual objects we found with that uid
-				actualOwners := byUID[ownerRef.UID]
-				if len(actualOwners) == 0 {
-					if _, listFailed := grListErrors[ownerGR]; listFailed {
-						// warn on missing owners if failed to list owner resource
-						outputRefMessage(gvr, child, ownerRef, levelWarning, fmt.Sprintf("could not list parent resource %v", ownerGR))
-						continue
-					}
-					outputRefMessage(gvr, child, ownerRef, levelError, "no object found for uid")
-					continue
-				}
+	if v.SnapshotOut != "" {
+		report.snapshot = &ScanSnapshot{Resources: discoverySnapshotOf(allGroupResources), Objects: []*metav1.PartialObjectMetadata{}, Failures: report.Failures}
+		for _, gvr := range gvrs {
+			for _, object := range objects.ByResource(gvr) {
+				object = object.DeepCopy()
+				object.ManagedFields = nil
+				report.snapshot.Objects = append(report.snapshot.Objects, object)
+			}
+		}
+	}
 
-				var (
-					namespaceOk     = false
-					actualNamespace = ""
-
-					nameOk     = false
-					actualName = ""
-
-					groupKindOk = false
-					actualGVK   = schema.GroupVersionKind{}
-				)
-				for _, actualOwner := range actualOwners {
-					if actualOwner.Name == ownerRef.Name {
-						nameOk = true
-					} else {
-						actualName = actualOwner.Name
-					}
+	if v.RuleConfig != nil && !report.FailedFast {
+		report.Findings = v.RuleConfig.applyThresholds(report.Findings)
+	}
+	if v.Ignore != nil {
+		var ignored int
+		report.Findings, ignored = v.Ignore.apply(report.Findings)
+		report.Ignored += ignored
+	}
+	if v.OlderThan > 0 {
+		report.Findings, report.Newer = olderThan(report.Findings, v.OlderThan, start)
+	}
+	if v.Baseline != nil {
+		report.unbaselinedFindings = report.Findings
+		report.Findings, report.Baselined = v.Baseline.apply(report.Findings)
+	}
+	if v.Audit != nil {
+		if err := v.Audit.correlate(report.Findings); err != nil {
+			fmt.Fprintf(stderr, "warning: could not correlate findings with audit logs: %v\n", err)
+		}
+	}
+	if v.Since != nil {
+		report.Findings, report.Resolved = applySince(v.Since, report.Findings)
+	}
+	for _, finding := range report.Findings {
+		if finding.Level == LevelError {
+			report.Errors++
+			if finding.Delta == DeltaNew {
+				report.NewErrors++
+			}
+		} else {
+			report.Warnings++
+		}
+	}
 
-					if actualOwner.Namespace == "" || actualOwner.Namespace == child.Namespace {
-						namespaceOk = true
-					} else {
-						actualNamespace = actualOwner.Namespace
-					}
+	if v.Inventory != "" {
+		report.inventory = inventoryOf(childGVRs, children, v.Scope, report.Findings)
+	}
+	if v.SupportBundle != nil {
+		report.bundle = &supportBundle{resources: discoverySnapshotOf(allGroupResources), objects: findingObjects(report.Findings, children, objects)}
+	}
 
-					if actualOwner.APIVersion == "" || actualOwner.Kind == "" {
-						groupKindOk = true
-					} else {
-						actualOwnerGV, _ := schema.ParseGroupVersion(actualOwner.APIVersion)
-						if actualOwner.Kind == ownerRef.Kind && actualOwnerGV.Group == ownerGV.Group {
-							groupKindOk = true
-						} else if strings.ToLower(actualOwner.Kind) == ownerRef.Kind && actualOwnerGV.Group == ownerGV.Group {
-							// RESTMapper tolerates an all-lowercase kind as input to the lookup
-							// https://github.com/kubernetes/kubernetes/blob/release-1.20/staging/src/k8s.io/client-go/restmapper/discovery.go#L114
-							groupKindOk = true
-						} else {
-							actualGVK = actualOwnerGV.WithKind(actualOwner.Kind)
-						}
-					}
-				}
+	report.Scan = v.scanIdentity(ctx, objects)
+	report.CompletionTime = time.Now()
+	report.Duration = report.CompletionTime.Sub(start)
+	progress.emit(ProgressEvent{Phase: ProgressPhaseComplete, Summary: &ProgressSummary{
+		Findings:        len(report.Findings),
+		Errors:          report.Errors,
+		Warnings:        report.Warnings,
+		Objects:         report.Stats.Objects,
+		Interrupted:     report.Interrupted,
+		Complete:        report.Complete(),
+		DurationSeconds: report.Duration.Seconds(),
+	}})
+	return report, nil
+}
+
+// ownerResource returns the resource ownerRef refers to, or false if it cannot be resolved
+func ownerResource(restMapper meta.RESTMapper, ownerRef metav1.OwnerReference) (schema.GroupResource, bool) {
+	ownerGV, err := schema.ParseGroupVersion(ownerRef.APIVersion)
+	if err != nil {
+		return schema.GroupResource{}, false
+	}
+	mapping, err := restMapper.RESTMapping(schema.GroupKind{Group: ownerGV.Group, Kind: ownerRef.Kind}, ownerGV.Version)
+	if err != nil {
+		return schema.GroupResource{}, false
+	}
+	return mapping.Resource.GroupResource(), true
+}
 
-				if !namespaceOk {
-					outputRefMessage(gvr, child, ownerRef, levelError, fmt.Sprintf("child namespace does not match owner namespace (%s)", actualNamespace))
-					continue
+// ownerResourcesOf returns the resources referred to as owners by the objects of gvrs in objects
+func ownerResourcesOf(restMapper meta.RESTMapper, objects *ObjectIndex, gvrs []schema.GroupVersionResource) map[schema.GroupResource]bool {
+	resources := map[schema.GroupResource]bool{}
+	for _, gvr := range gvrs {
+		for _, object := range objects.ByResource(gvr) {
+			for _, ownerRef := range object.OwnerReferences {
+				if resource, ok := ownerResource(restMapper, ownerRef); ok {
+					resources[resource] = true
 				}
-				if !nameOk {
-					outputRefMessage(gvr, child, ownerRef, levelError, fmt.Sprintf("ownerReference name (%s) does not match owner name (%s)", ownerRef.Name, actualName))
-					continue
+			}
+		}
+	}
+	return resources
+}
+
+// failsFast returns true if any of findings is at the error level once Ignore, OlderThan, and Baseline are applied,
+// with ages relative to the start of the scan as when the report is filtered
+func (v *VerifyGCOptions) failsFast(findings []Finding, start time.Time) bool {
+	if v.Ignore != nil {
+		findings, _ = v.Ignore.apply(findings)
+	}
+	if v.OlderThan > 0 {
+		findings, _ = olderThan(findings, v.OlderThan, start)
+	}
+	if v.Baseline != nil {
+		findings, _ = v.Baseline.apply(findings)
+	}
+	for _, finding := range findings {
+		if finding.Level == LevelError {
+			return true
+		}
+	}
+	return false
+}
+
+// errFailedFast stops listing at the first error-level finding, with FailFast
+var errFailedFast = errors.New("failed fast")
+
+// checker evaluates rules for the ownerReferences of child objects against the objects listed in a scan
+type checker struct {
+	restMapper meta.RESTMapper
+	rules      []Rule
+	ruleConfig *RuleConfig
+	objects    *ObjectIndex
+
+	gvDiscoveryFailures map[schema.GroupVersion]error
+	grListErrors        map[schema.GroupResource]error
+}
+
+// check returns the findings for the ownerReferences of child, a gvr object
+func (c *checker) check(gvr schema.GroupVersionResource, child *metav1.PartialObjectMetadata) []Finding {
+	var findings []Finding
+	// iterate over all owners
+	for _, ownerRef := range child.OwnerReferences {
+		ruleCtx := &RuleContext{
+			Resource:       gvr,
+			Child:          child,
+			OwnerReference: ownerRef,
+			Owners:         c.objects.ByUID(ownerRef.UID),
+			Objects:        c.objects,
+		}
+		// resolve REST info
+		resolveOwner(c.restMapper, ruleCtx)
+		if ruleCtx.OwnerMappingError != nil {
+			ruleCtx.OwnerDiscoveryError = c.gvDiscoveryFailures[ruleCtx.OwnerGroupVersion]
+		} else if ruleCtx.OwnerMapping != nil {
+			ruleCtx.OwnerListError = c.grListErrors[ruleCtx.OwnerMapping.Resource.GroupResource()]
+		}
+
+		for _, rule := range c.rules {
+			reported := false
+			for _, problem := range rule.Check(ruleCtx) {
+				if c.ruleConfig != nil {
+					var ok bool
+					if problem, ok = c.ruleConfig.apply(ruleCtx, problem); !ok {
+						continue
+					}
 				}
-				if !groupKindOk {
-					outputRefMessage(gvr, child, ownerRef, levelError, fmt.Sprintf("ownerReference group/kind (%s/%s) does not match owner group/kind (%s/%s)", ownerGV.Group, ownerRef.Kind, actualGVK.Group, actualGVK.Kind))
-					continue
+				var candidates []OwnerCandidate
+				if problem.Code == CodeOwnerNotFound {
+					// turn the dead end into a lead, such as an owner recreated with a new uid
+					if candidates = ownerCandidates(ruleCtx); len(candidates) > 0 {
+						problem.Message += describeCandidates(candidates)
+					}
 				}
+				findings = append(findings, Finding{
+					Resource:          metav1.GroupVersionResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource},
+					Kind:              metav1.GroupVersionKind{Group: gvr.Group, Version: gvr.Version, Kind: child.Kind},
+					Namespace:         child.Namespace,
+					Name:              child.Name,
+					UID:               child.UID,
+					OwnerReference:    ownerRef,
+					Level:             problem.Level,
+					Code:              problem.Code,
+					Message:           problem.Message,
+					CreationTimestamp: creationTimestampOf(child),
+					ManagedBy:         managedByOf(child),
+					Candidates:        candidates,
+				})
+				reported = true
+			}
+			if reported {
+				break
 			}
 		}
-		// flush after each type
-		tabwriter.Flush()
 	}
+	return findings
+}
 
-	if errorCount > 0 || warningCount > 0 {
-		fmt.Fprintf(v.Stderr, "%s, %s\n", pluralize(errorCount, "error", "errors"), pluralize(warningCount, "warning", "warnings"))
-	} else {
-		fmt.Fprintf(v.Stderr, "No invalid ownerReferences found\n")
+// sortGVRs sorts gvrs by group, version, and resource
+func sortGVRs(gvrs []schema.GroupVersionResource) {
+	sort.Slice(gvrs, func(i, j int) bool {
+		if gvrs[i].Group != gvrs[j].Group {
+			return gvrs[i].Group < gvrs[j].Group
+		}
+		if gvrs[i].Version != gvrs[j].Version {
+			return gvrs[i].Version < gvrs[j].Version
+		}
+		return gvrs[i].Resource < gvrs[j].Resource
+	})
+}
+
+// publish writes result to the configured destinations other than stdout, returning a PublishError for those that failed
+func (v *VerifyGCOptions) publish(ctx context.Context, result *Report) error {
+	// every destination is published to, so one that fails does not hold back the others
+	var errs []error
+	if v.CRDReport != nil {
+		names, err := v.CRDReport.publish(ctx, result)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("report CRD: %v", err))
+		} else if v.Output == "crd" {
+			for _, name := range names {
+				fmt.Fprintf(v.Stdout, "%s/%s\n", schema.GroupResource{Group: ReportGroupVersionResource.Group, Resource: "ownerreferencereport"}, name)
+			}
+		}
 	}
-	return nil
+	if v.ConfigMap != nil {
+		if err := v.ConfigMap.publish(ctx, result); err != nil {
+			errs = append(errs, fmt.Errorf("ConfigMap: %v", err))
+		}
+	}
+	if v.Events != nil {
+		if err := v.Events.publish(ctx, result); err != nil {
+			errs = append(errs, fmt.Errorf("Events: %v", err))
+		}
+	}
+	if v.Notify != nil {
+		if err := v.Notify.publish(ctx, result); err != nil {
+			errs = append(errs, fmt.Errorf("notification: %v", err))
+		}
+	}
+	if v.Export != nil {
+		key, err := v.Export.publish(ctx, result)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("export: %v", err))
+		} else {
+			klog.V(1).Infof("exported report to %s as %s", v.Export.URL, key)
+		}
+	}
+	if v.FindingsDB != nil {
+		if err := v.FindingsDB.publish(ctx, result); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if v.GitHub != nil {
+		if err := v.GitHub.publish(ctx, result); err != nil {
+			errs = append(errs, fmt.Errorf("GitHub issue: %v", err))
+		}
+	}
+	if v.Pushgateway != nil {
+		if err := v.Pushgateway.publish(ctx, result); err != nil {
+			errs = append(errs, fmt.Errorf("Pushgateway: %v", err))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &PublishError{Err: utilerrors.NewAggregate(errs)}
 }
 
-var (
-	levelError   = "Error"
-	levelWarning = "Warning"
+// Codes identifying the problem reported by a finding
+const (
+	// CodeInvalidAPIVersion indicates the ownerReference apiVersion could not be parsed
+	CodeInvalidAPIVersion = "InvalidAPIVersion"
+	// CodeUnresolvableOwner indicates the ownerReference apiVersion/kind is not served by the cluster
+	CodeUnresolvableOwner = "UnresolvableOwner"
+	// CodeOwnerDiscoveryFailed indicates resources could not be discovered for the ownerReference apiVersion
+	CodeOwnerDiscoveryFailed = "OwnerDiscoveryFailed"
+	// CodeNamespacedOwnerOfClusterScopedChild indicates a cluster-scoped object references a namespaced owner
+	CodeNamespacedOwnerOfClusterScopedChild = "NamespacedOwnerOfClusterScopedChild"
+	// CodeOwnerNotFound indicates no object exists with the ownerReference uid
+	CodeOwnerNotFound = "OwnerNotFound"
+	// CodeOwnerListFailed indicates the owner resource could not be listed
+	CodeOwnerListFailed = "OwnerListFailed"
+	// CodeNamespaceMismatch indicates the owner is in a different namespace than the child
+	CodeNamespaceMismatch = "NamespaceMismatch"
+	// CodeNameMismatch indicates the ownerReference name does not match the owner name
+	CodeNameMismatch = "NameMismatch"
+	// CodeGroupKindMismatch indicates the ownerReference group/kind does not match the owner group/kind
+	CodeGroupKindMismatch = "GroupKindMismatch"
 )
 
-type invalidReference struct {
-	Resource       metav1.GroupVersionResource `json:"resource"`
-	Kind           metav1.GroupVersionKind     `json:"kind"`
-	Namespace      string                      `json:"namespace"`
-	Name           string                      `json:"name"`
-	OwnerReference metav1.OwnerReference       `json:"ownerReference"`
-	Level          string                      `json:"level"`
-	Message        string                      `json:"message"`
-}
-
 func pluralize(count int, singular, plural string) string {
 	if count == 1 {
 		return fmt.Sprintf("%d %s", count, singular)
@@ -18,12 +18,16 @@ package pkg
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"reflect"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -131,6 +135,23 @@ func TestVerify(t *testing.T) {
             fetching forbidden/v1, forbiddenresources
             warning: could not list forbidden/v1, Resource=forbiddenresources: forbiddenresources is forbidden: not authorized
             0 errors, 2 warnings
+            1 resource could not be listed for lack of permission:
+              list forbiddenresources.forbidden
+            Grant the missing permissions by binding this ClusterRole to the scanning user, or regenerate all permissions with the rbac command:
+            apiVersion: rbac.authorization.k8s.io/v1
+            kind: ClusterRole
+            metadata:
+              creationTimestamp: null
+              name: kubectl-check-ownerreferences-missing
+            rules:
+            - apiGroups:
+              - forbidden
+              resources:
+              - forbiddenresources
+              verbs:
+              - get
+              - list
+              - watch
 			`,
 		},
 		{
@@ -176,7 +197,7 @@ func TestVerify(t *testing.T) {
 			},
 			expectOut: `
 			GROUP   RESOURCE   NAMESPACE   NAME   OWNER_UID   LEVEL   MESSAGE
-			        pods       ns1         pod1   node1uid    Error   cannot resolve owner apiVersion/kind: no matches for kind "Node" in version "v2"
+			        pods       ns1         pod1   node1uid    Error   cannot resolve owner apiVersion/kind: no matches for kind "Node" in version "v2" (v2 is not served, Node is served at v1, update the ownerReference apiVersion)
 			`,
 			expectErr: `
 			fetching v1, nodes
@@ -419,7 +440,11 @@ func TestVerify(t *testing.T) {
 			if err := opts.Validate(); err != nil {
 				t.Fatal(err)
 			}
-			if err := opts.Run(); err != nil {
+			// scans warning about resources they could not discover or list are incomplete
+			expectIncomplete := strings.Contains(tc.expectErr, "warning: could not")
+			if err := opts.Run(context.Background()); expectIncomplete && (err == nil || err.(*ExitError).Code != ExitCodeIncomplete) {
+				t.Fatalf("expected an incomplete scan, got %v", err)
+			} else if !expectIncomplete && err != nil {
 				t.Fatal(err)
 			}
 			if e, a := normalize(tc.expectOut), normalize(out.String()); !reflect.DeepEqual(e, a) {
@@ -434,6 +459,310 @@ func TestVerify(t *testing.T) {
 	}
 }
 
+func TestScan(t *testing.T) {
+	discoveryClient := &fake.FakeDiscovery{Fake: &coretesting.Fake{}}
+	discoveryClient.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: []string{"get", "list", "delete"}}},
+		},
+		{
+			GroupVersion: "forbidden/v1",
+			APIResources: []metav1.APIResource{{Name: "forbiddenresources", Namespaced: true, Kind: "ForbiddenKind", Verbs: []string{"get", "list", "delete"}}},
+		},
+	}
+	metadataClient := metadatafake.NewSimpleMetadataClient(runtime.NewScheme())
+	ownerRef := metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: "missing", UID: "missinguid"}
+	pod := &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns1", UID: "poduid1", OwnerReferences: []metav1.OwnerReference{ownerRef}},
+	}
+	podClient := metadataClient.Resource(schema.GroupVersionResource{Version: "v1", Resource: "pods"}).Namespace("ns1")
+	if _, err := podClient.(metadatafake.MetadataClient).CreateFake(pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	metadataClient.PrependReactor("list", "forbiddenresources", func(action coretesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, fmt.Errorf("not authorized")
+	})
+
+	// no writers are required to scan
+	opts := &VerifyGCOptions{DiscoveryClient: discoveryClient, MetadataClient: metadataClient}
+	report, err := opts.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectFindings := []Finding{{
+		Resource:       metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+		Kind:           metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Namespace:      "ns1",
+		Name:           "pod1",
+		UID:            "poduid1",
+		OwnerReference: ownerRef,
+		Level:          LevelError,
+		Code:           CodeOwnerNotFound,
+		Message:        "no object found for uid",
+	}}
+	if diff := cmp.Diff(expectFindings, report.Findings); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+	expectFailures := []ScanFailure{{
+		GroupVersionResource: metav1.GroupVersionResource{Group: "forbidden", Version: "v1", Resource: "forbiddenresources"},
+		Message:              "not authorized",
+	}}
+	if diff := cmp.Diff(expectFailures, report.Failures); diff != "" {
+		t.Errorf("unexpected failures (-want +got):\n%s", diff)
+	}
+	if report.Errors != 1 || report.Warnings != 1 {
+		t.Errorf("expected 1 error and 1 warning, got %d errors and %d warnings", report.Errors, report.Warnings)
+	}
+	if report.Stats.Resources != 2 || report.Stats.Objects != 1 {
+		t.Errorf("expected 2 resources and 1 object, got %#v", report.Stats)
+	}
+	if report.CompletionTime.IsZero() {
+		t.Error("expected completion time to be set")
+	}
+
+	out := &bytes.Buffer{}
+	if err := report.Print(out, "json"); err != nil {
+		t.Fatal(err)
+	}
+	printed, err := readFindings(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// printed findings identify the scan
+	if report.Scan.ID == "" {
+		t.Error("expected scan ID to be set")
+	}
+	for i := range expectFindings {
+		expectFindings[i].Scan = &ScanIdentity{ID: report.Scan.ID}
+	}
+	if diff := cmp.Diff(expectFindings, printed); diff != "" {
+		t.Errorf("unexpected printed findings (-want +got):\n%s", diff)
+	}
+}
+
+func TestPrintObjects(t *testing.T) {
+	pods := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	clusterRoles := metav1.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}
+	report := &Report{
+		Findings: []Finding{
+			{Resource: pods, Kind: metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}, Namespace: "ns1", Name: "pod1", UID: "pod1uid", Code: CodeOwnerNotFound},
+			{Resource: pods, Kind: metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}, Namespace: "ns1", Name: "pod1", UID: "pod1uid", Code: CodeUnresolvableOwner},
+			{Resource: clusterRoles, Kind: metav1.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}, Name: "role1", UID: "role1uid", Code: CodeOwnerNotFound},
+		},
+		Resolved: []Finding{
+			{Resource: pods, Kind: metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}, Namespace: "ns1", Name: "fixed", Code: CodeOwnerNotFound, Delta: DeltaResolved},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	if err := report.Print(out, "name"); err != nil {
+		t.Fatal(err)
+	}
+	if expect := "pods/ns1/pod1\nclusterroles.rbac.authorization.k8s.io/role1\n"; out.String() != expect {
+		t.Errorf("expected names %q, got %q", expect, out.String())
+	}
+
+	out.Reset()
+	if err := report.Print(out, "objects"); err != nil {
+		t.Fatal(err)
+	}
+	objects, err := ReadObjects(out, "objects")
+	if err != nil {
+		t.Fatal(err)
+	}
+	printed := []string{}
+	for _, object := range objects {
+		printed = append(printed, strings.Join([]string{object.GetAPIVersion(), object.GetKind(), object.GetNamespace(), object.GetName(), string(object.GetUID())}, " "))
+	}
+	expect := []string{
+		"v1 Pod ns1 pod1 pod1uid",
+		"rbac.authorization.k8s.io/v1 ClusterRole  role1 role1uid",
+	}
+	if diff := cmp.Diff(expect, printed); diff != "" {
+		t.Errorf("unexpected objects (-want +got):\n%s", diff)
+	}
+}
+
+func TestScanObjects(t *testing.T) {
+	cluster, err := ReadObjects(bytes.NewBufferString(`
+{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"d1","namespace":"app","uid":"d1uid"}}
+{"apiVersion":"v1","kind":"Pod","metadata":{"name":"p1","namespace":"app","uid":"p1uid","ownerReferences":[{"apiVersion":"v1","kind":"Node","name":"n1","uid":"missinguid"}]}}
+`), "cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := &bytes.Buffer{}
+	discoveryClient, metadataClient, err := OfflineClients(DefaultDiscoverySnapshot(), cluster, stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a list and a stream of objects, which do not need to exist in the cluster
+	objects, err := ReadObjects(bytes.NewBufferString(`
+{"apiVersion":"v1","kind":"List","items":[
+{"apiVersion":"apps/v1","kind":"ReplicaSet","metadata":{"name":"rs1","namespace":"app","uid":"rs1uid","ownerReferences":[{"apiVersion":"apps/v1","kind":"Deployment","name":"d1","uid":"d1uid"}]}},
+{"apiVersion":"apps/v1","kind":"ReplicaSet","metadata":{"name":"rs2","namespace":"app","uid":"rs2uid","ownerReferences":[{"apiVersion":"apps/v1","kind":"Deployment","name":"d1","uid":"wronguid"}]}}
+]}
+{"apiVersion":"other.io/v1","kind":"Gadget","metadata":{"name":"g1","namespace":"app"}}
+`), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &VerifyGCOptions{DiscoveryClient: discoveryClient, MetadataClient: metadataClient, Objects: objects, Stderr: stderr}
+	report, err := opts.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := report.Print(out, ""); err != nil {
+		t.Fatal(err)
+	}
+	// the pod in the cluster is not checked
+	expect := `
+GROUP RESOURCE NAMESPACE NAME OWNER_UID LEVEL MESSAGE
+apps replicasets app rs2 wronguid Error no object found for uid; did you mean app/d1 (uid d1uid)?
+`
+	if diff := cmp.Diff(normalize(expect), normalize(out.String())); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+	expectWarning := "warning: could not check Gadget app/g1: no matches for kind \"Gadget\" in version \"other.io/v1\"\n"
+	if !strings.Contains(stderr.String(), expectWarning) {
+		t.Errorf("expected warning %q, got %q", expectWarning, stderr.String())
+	}
+	if report.Errors != 1 || report.Warnings != 1 {
+		t.Errorf("expected 1 error and 1 warning, got %d errors and %d warnings", report.Errors, report.Warnings)
+	}
+	// only the resources of owners are listed
+	if report.Stats.Resources != 1 || report.Stats.Objects != 1 {
+		t.Errorf("expected 1 resource and 1 object, got %#v", report.Stats)
+	}
+}
+
+func TestRunRenderedManifests(t *testing.T) {
+	cluster, err := ReadObjects(bytes.NewBufferString(`
+{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"d1","namespace":"app","uid":"d1uid"}}
+{"apiVersion":"v1","kind":"Node","metadata":{"name":"n1","uid":"n1uid"}}
+`), "cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	discoveryClient, metadataClient, err := OfflineClients(DefaultDiscoverySnapshot(), cluster, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rendered manifests omit the namespace, and may set one on cluster-scoped objects
+	objects, err := ReadObjects(bytes.NewBufferString(`
+apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  name: rs1
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: d1
+    uid: d1uid
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: cr1
+  namespace: app
+  ownerReferences:
+  - apiVersion: v1
+    kind: Node
+    name: n1
+    uid: n1uid
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm1
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: d1
+    uid: wronguid
+`), "rendered.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := &bytes.Buffer{}
+	opts := &VerifyGCOptions{
+		DiscoveryClient:  discoveryClient,
+		MetadataClient:   metadataClient,
+		Objects:          objects,
+		ObjectsNamespace: "app",
+		FailOnErrors:     true,
+		Stderr:           ioutil.Discard,
+		Stdout:           stdout,
+	}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	err = opts.Run(context.Background())
+	if err == nil || err.Error() != "1 error found" {
+		t.Errorf("expected 1 error found, got %v", err)
+	}
+	expect := `
+GROUP RESOURCE NAMESPACE NAME OWNER_UID LEVEL MESSAGE
+configmaps app cm1 wronguid Error no object found for uid; did you mean app/d1 (uid d1uid)?
+`
+	if diff := cmp.Diff(normalize(expect), normalize(stdout.String())); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunInterrupted(t *testing.T) {
+	gcVerbs := []string{"get", "list", "delete"}
+	discoveryClient := &fake.FakeDiscovery{Fake: &coretesting.Fake{}}
+	discoveryClient.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: gcVerbs},
+			{Name: "nodes", Namespaced: false, Kind: "Node", Verbs: gcVerbs},
+			{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: gcVerbs},
+		},
+	}}
+	metadataClient := metadatafake.NewSimpleMetadataClient(runtime.NewScheme())
+	configMapClient := metadataClient.Resource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}).Namespace("ns1")
+	if _, err := configMapClient.(metadatafake.MetadataClient).CreateFake(&metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "cm1", Namespace: "ns1", UID: "cmuid1", OwnerReferences: []metav1.OwnerReference{{APIVersion: "v1", Kind: "Node", Name: "node1", UID: "nodeuid1"}}},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// cancel while listing nodes
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	metadataClient.PrependReactor("list", "nodes", func(action coretesting.Action) (handled bool, ret runtime.Object, err error) {
+		cancel()
+		return true, nil, context.Canceled
+	})
+
+	out := bytes.NewBuffer(nil)
+	errOut := bytes.NewBuffer(nil)
+	opts := &VerifyGCOptions{DiscoveryClient: discoveryClient, MetadataClient: metadataClient, Output: "json", Stdout: out, Stderr: errOut}
+	if err := opts.Run(ctx); err == nil || !strings.Contains(err.Error(), "scan interrupted") {
+		t.Fatalf("expected scan interrupted error, got %v", err)
+	}
+	findings, err := readFindings(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 || findings[0].Name != "cm1" || findings[0].Code != CodeOwnerListFailed {
+		t.Errorf("expected OwnerListFailed finding for cm1, got %#v", findings)
+	}
+	if !strings.Contains(errOut.String(), "scan interrupted, results are partial: 0 errors, 1 warning\n") {
+		t.Errorf("expected interrupted summary, got %q", errOut.String())
+	}
+}
+
 func normalize(in string) []string {
 	normalized := regexp.MustCompile("[ \t]+").ReplaceAllString(in, " ")
 	trimmed := strings.TrimSpace(normalized)
@@ -443,3 +772,265 @@ func normalize(in string) []string {
 	}
 	return split
 }
+
+func TestPrintTriage(t *testing.T) {
+	cluster := newFakeCluster(t, "prod", "pod1")
+	stdout := &bytes.Buffer{}
+	opts := cluster.Verify
+	opts.Stdout, opts.Stderr, opts.EmitTriage = stdout, ioutil.Discard, true
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opts.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expectTriage := `
+# Error OwnerNotFound: pods ns1/pod1: no object found for uid
+kubectl get pods pod1 -n ns1 -o yaml
+kubectl get pods missing -n ns1 -o yaml
+kubectl get --raw /api/v1/pods | jq '.items[].metadata | select(.uid == "missinguid-prod")'
+`
+	if !strings.HasSuffix(stdout.String(), expectTriage) {
+		t.Errorf("expected triage commands after findings:\n%s\ngot:\n%s", expectTriage, stdout.String())
+	}
+
+	opts.Output = "json"
+	if err := opts.Validate(); err == nil {
+		t.Error("expected triage commands to require table output")
+	}
+}
+
+func TestRunFailOnWarnings(t *testing.T) {
+	cluster := newFakeCluster(t, "prod", "pod1", "pod2")
+	opts := cluster.Verify
+	opts.Stdout, opts.Stderr, opts.FailOnWarnings = ioutil.Discard, ioutil.Discard, true
+	// demote the finding for pod1 to a warning
+	opts.Baseline = &Baseline{Demote: true, Findings: []Finding{{
+		Resource:       metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+		Namespace:      "ns1",
+		Name:           "pod1",
+		OwnerReference: metav1.OwnerReference{UID: "missinguid-prod"},
+		Code:           CodeOwnerNotFound,
+	}}}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	expectExitCode := func(code int, message string) {
+		t.Helper()
+		err := opts.Run(context.Background())
+		exitErr := &ExitError{}
+		if !errors.As(err, &exitErr) || exitErr.Code != code || err.Error() != message {
+			t.Errorf("expected %q with exit code %d, got %#v", message, code, err)
+		}
+	}
+
+	// warnings fail the scan with a distinct exit code, unless error-level findings fail it
+	expectExitCode(ExitCodeWarnings, "1 warning found")
+	opts.FailOnErrors = true
+	if err := opts.Run(context.Background()); err == nil || err.Error() != "1 error found" {
+		t.Errorf("expected 1 error found, got %#v", err)
+	}
+	opts.FailOnErrors = false
+
+	// incomplete scans fail with their own exit code, with or without FailOnWarnings
+	discoveryClient := opts.DiscoveryClient.(*fake.FakeDiscovery)
+	discoveryClient.Resources[0].APIResources = append(discoveryClient.Resources[0].APIResources,
+		metav1.APIResource{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: []string{"get", "list", "delete"}})
+	opts.MetadataClient.(*metadatafake.FakeMetadataClient).PrependReactor("list", "configmaps", func(action coretesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("unavailable")
+	})
+	expectExitCode(ExitCodeIncomplete, "scan incomplete: 1 resource could not be discovered or listed")
+	opts.FailOnWarnings = false
+	expectExitCode(ExitCodeIncomplete, "scan incomplete: 1 resource could not be discovered or listed")
+}
+
+func TestRunMaxFindings(t *testing.T) {
+	cluster := newFakeCluster(t, "prod", "pod1", "pod2", "pod3")
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	opts := cluster.Verify
+	opts.Stdout, opts.Stderr, opts.Output, opts.MaxFindings = stdout, stderr, "json", 2
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	report, err := opts.run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := readFindings(stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 2 || len(report.Findings) != 3 || report.Errors != 3 {
+		t.Errorf("expected 2 of 3 findings written and all counted, got %d written, %d reported, %d errors", len(findings), len(report.Findings), report.Errors)
+	}
+	if expect := "3 errors, 0 warnings (and 1 more finding not shown)\n"; !strings.HasSuffix(stderr.String(), expect) {
+		t.Errorf("expected summary %q, got %q", expect, stderr.String())
+	}
+}
+
+func TestRunFailFast(t *testing.T) {
+	cluster := newFakeCluster(t, "prod", "pod1", "pod2")
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	opts := cluster.Verify
+	opts.Stdout, opts.Stderr, opts.Output, opts.FailFast = stdout, stderr, "json", true
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	err := opts.Run(context.Background())
+	if err == nil || err.Error() != "stopped at the first error: pods ns1/pod1: no object found for uid" {
+		t.Errorf("expected to stop at the first error, got %v", err)
+	}
+	findings, err := readFindings(stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 || findings[0].Name != "pod1" {
+		t.Errorf("expected only the first finding, got %#v", findings)
+	}
+
+	// errors dropped by ignore patterns do not stop the scan
+	opts.Stdout = ioutil.Discard
+	if opts.Ignore, err = NewIgnoreFilter([]string{"OwnerNotFound"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := opts.Run(context.Background()); err != nil {
+		t.Errorf("expected ignored errors not to fail fast, got %v", err)
+	}
+
+	// owners that cannot be resolved stop listing, without listing the remaining resources
+	listing := newFakeCluster(t, "prod", "pod1").Verify
+	discoveryClient := listing.DiscoveryClient.(*fake.FakeDiscovery)
+	discoveryClient.Resources[0].APIResources = append(discoveryClient.Resources[0].APIResources, metav1.APIResource{Name: "secrets", Namespaced: true, Kind: "Secret", Verbs: []string{"get", "list", "delete"}})
+	metadataClient := listing.MetadataClient.(*metadatafake.FakeMetadataClient)
+	if _, err := metadataClient.Resource(schema.GroupVersionResource{Version: "v1", Resource: "pods"}).Namespace("ns1").(metadatafake.MetadataClient).CreateFake(&metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "pod0", Namespace: "ns1", UID: "uid-pod0", OwnerReferences: []metav1.OwnerReference{{APIVersion: "example.com/v1", Kind: "Widget", Name: "widget1", UID: "widgetuid1"}}},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	listing.Stdout, listing.Stderr, listing.FailFast = ioutil.Discard, ioutil.Discard, true
+	if err := listing.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	report, err := listing.run(context.Background())
+	if err == nil || !strings.HasPrefix(err.Error(), "stopped at the first error: pods ns1/pod0: ") {
+		t.Errorf("expected to stop at the unresolvable owner, got %v", err)
+	}
+	if report != nil {
+		t.Errorf("expected no report when failing fast, got %#v", report)
+	}
+	for _, action := range metadataClient.Actions() {
+		if action.GetResource().Resource == "secrets" {
+			t.Errorf("expected listing to stop at the first error, got %v", action)
+		}
+	}
+
+	// ages are relative to the start of the scan, as when the report is filtered with OlderThan
+	start := time.Now().Add(-time.Hour)
+	opts.Ignore, opts.OlderThan = nil, 24*time.Hour
+	created := &metav1.Time{Time: start.Add(-23*time.Hour - 30*time.Minute)}
+	if opts.failsFast([]Finding{{Name: "pod1", Level: LevelError, CreationTimestamp: created}}, start) {
+		t.Errorf("expected an error about an object newer than OlderThan at the start of the scan not to fail fast")
+	}
+}
+
+func TestRunWarningsOut(t *testing.T) {
+	cluster := newFakeCluster(t, "prod", "pod1", "pod2")
+	stdout, warnings := &bytes.Buffer{}, &bytes.Buffer{}
+	opts := cluster.Verify
+	opts.Stdout, opts.Stderr, opts.WarningsOut, opts.Output = stdout, ioutil.Discard, warnings, "json"
+	// demote the finding for pod2 to a warning
+	opts.Baseline = &Baseline{Demote: true, Findings: []Finding{{
+		Resource:       metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+		Namespace:      "ns1",
+		Name:           "pod2",
+		OwnerReference: metav1.OwnerReference{UID: "missinguid-prod"},
+		Code:           CodeOwnerNotFound,
+	}}}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opts.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		out   *bytes.Buffer
+		name  string
+		level string
+	}{{stdout, "pod1", LevelError}, {warnings, "pod2", LevelWarning}} {
+		findings, err := readFindings(tc.out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(findings) != 1 || findings[0].Name != tc.name || findings[0].Level != tc.level {
+			t.Errorf("expected only the %s finding for %s, got %#v", tc.level, tc.name, findings)
+		}
+	}
+}
+
+func TestRunStartFrom(t *testing.T) {
+	gcVerbs := []string{"get", "list", "delete"}
+	discoveryClient := &fake.FakeDiscovery{Fake: &coretesting.Fake{}}
+	discoveryClient.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: gcVerbs},
+			{Name: "endpoints", Namespaced: true, Kind: "Endpoints", Verbs: gcVerbs},
+			{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: gcVerbs},
+			{Name: "secrets", Namespaced: true, Kind: "Secret", Verbs: gcVerbs},
+		},
+	}}
+	metadataClient := metadatafake.NewSimpleMetadataClient(runtime.NewScheme())
+	create := func(resource, kind, name string, ownerRefs ...metav1.OwnerReference) {
+		t.Helper()
+		client := metadataClient.Resource(schema.GroupVersionResource{Version: "v1", Resource: resource}).Namespace("ns1")
+		if _, err := client.(metadatafake.MetadataClient).CreateFake(&metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: kind},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns1", UID: types.UID(name), OwnerReferences: ownerRefs},
+		}, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the config map is before the resource to start from, and is only listed as an owner
+	create("configmaps", "ConfigMap", "cm1", metav1.OwnerReference{APIVersion: "v1", Kind: "Secret", Name: "missing", UID: "missing"})
+	create("endpoints", "Endpoints", "ep1", metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: "missing", UID: "missing"})
+	create("pods", "Pod", "pod1", metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "cm1", UID: "cm1"})
+	create("secrets", "Secret", "secret1", metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: "missing", UID: "missing"})
+
+	stdout := &bytes.Buffer{}
+	opts := &VerifyGCOptions{
+		DiscoveryClient: discoveryClient,
+		MetadataClient:  metadataClient,
+		StartFrom:       &schema.GroupResource{Resource: "pods"},
+		Output:          "json",
+		Stderr:          ioutil.Discard,
+		Stdout:          stdout,
+	}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opts.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	findings, err := readFindings(stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 || findings[0].Name != "secret1" {
+		t.Errorf("expected only the finding for secret1, got %#v", findings)
+	}
+	listed := []string{}
+	for _, action := range metadataClient.Actions() {
+		if action.GetVerb() == "list" {
+			listed = append(listed, action.GetResource().Resource)
+		}
+	}
+	if diff := cmp.Diff([]string{"pods", "secrets", "configmaps"}, listed); diff != "" {
+		t.Errorf("unexpected resources listed (-want +got):\n%s", diff)
+	}
+
+	opts.StartFrom = &schema.GroupResource{Group: "apps", Resource: "deployments"}
+	if _, err := opts.run(context.Background()); err == nil {
+		t.Error("expected an error starting from an undiscovered resource")
+	}
+}
@@ -16,8 +16,51 @@ limitations under the License.
 
 package pkg
 
+import (
+	"runtime/debug"
+)
+
 // Version indicates the build version, and is intended to be overridden via build flags.
 var Version = "devel"
 
 // GoVersion indicates the version of go this was built with, and is intended to be overridden via build flags.
 var GoVersion = "unknown go version"
+
+// GitCommit indicates the git commit this was built from, and is intended to be overridden via build flags.
+var GitCommit = ""
+
+// BuildDate indicates when this was built in RFC 3339 format, and is intended to be overridden via build flags.
+var BuildDate = ""
+
+// VersionInfo describes the build, for programmatic inventory of installed versions
+type VersionInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit,omitempty"`
+	BuildDate string `json:"buildDate,omitempty"`
+	GoVersion string `json:"goVersion"`
+	// ClientGoVersion and APIMachineryVersion are the versions of k8s.io/client-go and k8s.io/apimachinery compiled in
+	ClientGoVersion     string `json:"clientGoVersion,omitempty"`
+	APIMachineryVersion string `json:"apimachineryVersion,omitempty"`
+}
+
+// GetVersionInfo returns the version information of the running binary.
+// Dependency versions are read from the build info embedded by go, and are empty if it is unavailable.
+func GetVersionInfo() VersionInfo {
+	info := VersionInfo{Version: Version, GitCommit: GitCommit, BuildDate: BuildDate, GoVersion: GoVersion}
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range buildInfo.Deps {
+			version := dep.Version
+			// replaced modules are compiled in at the replacement version
+			if dep.Replace != nil {
+				version = dep.Replace.Version
+			}
+			switch dep.Path {
+			case "k8s.io/client-go":
+				info.ClientGoVersion = version
+			case "k8s.io/apimachinery":
+				info.APIMachineryVersion = version
+			}
+		}
+	}
+	return info
+}
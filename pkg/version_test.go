@@ -0,0 +1,36 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetVersionInfo(t *testing.T) {
+	defer func(commit, date string) { GitCommit, BuildDate = commit, date }(GitCommit, BuildDate)
+	GitCommit, BuildDate = "abc123", "2021-09-01T00:00:00Z"
+
+	info := GetVersionInfo()
+	if info.Version != Version || info.GoVersion != GoVersion || info.GitCommit != "abc123" || info.BuildDate != "2021-09-01T00:00:00Z" {
+		t.Errorf("unexpected version info: %#v", info)
+	}
+	// test binaries embed the versions of their dependencies
+	if !strings.HasPrefix(info.ClientGoVersion, "v0.") || !strings.HasPrefix(info.APIMachineryVersion, "v0.") {
+		t.Errorf("expected client-go and apimachinery versions, got %#v", info)
+	}
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WatchOptions contains options controlling watch mode, which keeps rescanning after an initial scan
+// and reports the findings introduced and resolved by each rescan
+type WatchOptions struct {
+	// Verify holds the options used for each scan. Findings of the initial scan are written as usual,
+	// and changes in findings are written to Verify.Stdout in Verify.Output format as they are detected.
+	Verify *VerifyGCOptions
+	// Interval is the time between the end of one scan and the start of the next,
+	// or between writing changes in findings if Incremental is set
+	Interval time.Duration
+	// Incremental keeps findings up to date from metadata informers after the initial scan instead of rescanning,
+	// rechecking only objects whose metadata changed and the objects that reference them as owners
+	Incremental bool
+}
+
+// Validate ensures the specified options are valid
+func (w *WatchOptions) Validate() error {
+	if w.Verify == nil {
+		return fmt.Errorf("verify options are required")
+	}
+	if err := w.Verify.Validate(); err != nil {
+		return err
+	}
+	if w.Verify.Benchmark || w.Verify.Objects != nil {
+		return fmt.Errorf("benchmarking and checking specific objects are not supported when watching")
+	}
+	if w.Verify.Output != "" && w.Verify.Output != "json" {
+		return fmt.Errorf("only '' and 'json' output are supported when watching: %v", w.Verify.Output)
+	}
+	if w.Verify.EmitTriage {
+		return fmt.Errorf("triage commands are not supported when watching")
+	}
+	if w.Verify.Redact != nil {
+		return fmt.Errorf("redaction is not supported when watching")
+	}
+	if w.Verify.StartFrom != nil {
+		return fmt.Errorf("starting from a resource is not supported when watching")
+	}
+	if w.Verify.AllVersions {
+		return fmt.Errorf("listing all versions is not supported when watching")
+	}
+	if w.Verify.WarningsOut != nil {
+		return fmt.Errorf("writing warnings separately is not supported when watching")
+	}
+	if w.Verify.OlderThan > 0 {
+		return fmt.Errorf("filtering findings by age is not supported when watching")
+	}
+	if w.Verify.SummarizeOwnerKinds {
+		return fmt.Errorf("summarizing owner kinds is not supported when watching")
+	}
+	if w.Verify.SummarizeManagers {
+		return fmt.Errorf("summarizing managing tools is not supported when watching")
+	}
+	if w.Verify.Inventory != "" {
+		return fmt.Errorf("writing inventories is not supported when watching")
+	}
+	if w.Verify.SummaryFile != "" {
+		return fmt.Errorf("writing summary files is not supported when watching")
+	}
+	if w.Verify.Owners != nil {
+		return fmt.Errorf("owner inventories are not supported when watching")
+	}
+	if w.Verify.EstimateDeletions {
+		return fmt.Errorf("estimating deletions is not supported when watching")
+	}
+	if w.Verify.NamespaceLabels != nil {
+		return fmt.Errorf("namespace labels are not supported when watching")
+	}
+	if w.Verify.Split != nil {
+		return fmt.Errorf("splitting findings into files is not supported when watching")
+	}
+	if w.Verify.MaxFindings > 0 {
+		return fmt.Errorf("limiting findings is not supported when watching")
+	}
+	if w.Verify.FailOnErrors || w.Verify.FailOnWarnings || w.Verify.FailFast {
+		return fmt.Errorf("failing on errors or warnings is not supported when watching")
+	}
+	if w.Interval <= 0 {
+		return fmt.Errorf("invalid interval, must be > 0")
+	}
+	return nil
+}
+
+// Watch event types
+const (
+	WatchEventIntroduced = "Introduced"
+	WatchEventResolved   = "Resolved"
+)
+
+// WatchEvent is written with 'json' output for each finding introduced or resolved by a rescan
+type WatchEvent struct {
+	Type    string      `json:"type"`
+	Time    metav1.Time `json:"time"`
+	Finding Finding     `json:"finding"`
+}
+
+// Run scans and writes findings like VerifyGCOptions.Run, then rescans on the configured interval until ctx is done,
+// writing the findings introduced and resolved by each rescan
+func (w *WatchOptions) Run(ctx context.Context) error {
+	opts := *w.Verify
+	opts.incremental = w.Incremental
+	report, err := opts.run(ctx)
+	if err != nil {
+		return err
+	}
+	findings := report.Findings
+	var incremental *incrementalScan
+	if w.Incremental {
+		incremental = newIncrementalScan(ctx, &opts, report)
+	}
+
+	timer := time.NewTimer(w.Interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			if incremental != nil {
+				current := incremental.report()
+				w.writeChanges(findings, current.Findings, current.CompletionTime)
+				findings = current.Findings
+			} else {
+				findings = w.rescan(ctx, findings)
+			}
+			timer.Reset(w.Interval)
+		}
+	}
+}
+
+// rescan scans again and writes the findings introduced and resolved since previous, returning the current findings.
+// Findings of resources that could not be listed are carried over from previous, rather than reported as resolved.
+// If the rescan fails or is interrupted, previous is returned.
+func (w *WatchOptions) rescan(ctx context.Context, previous []Finding) []Finding {
+	report, err := w.Verify.Scan(ctx)
+	if ctx.Err() != nil {
+		return previous
+	}
+	if err != nil {
+		fmt.Fprintf(w.Verify.Stderr, "warning: rescan failed: %v\n", err)
+		return previous
+	}
+
+	findings := report.Findings
+	listFailed := map[metav1.GroupVersionResource]bool{}
+	for _, failure := range report.Failures {
+		if failure.GroupVersionResource.Resource != "" {
+			listFailed[failure.GroupVersionResource] = true
+		}
+	}
+	for _, finding := range previous {
+		if listFailed[finding.Resource] {
+			findings = append(findings, finding)
+		}
+	}
+
+	w.writeChanges(previous, findings, report.CompletionTime)
+	return findings
+}
+
+// writeChanges writes the findings introduced and resolved between previous and findings, detected at the given time
+func (w *WatchOptions) writeChanges(previous, findings []Finding, at time.Time) {
+	diff := DiffFindings(previous, findings)
+	if len(diff.Introduced) == 0 && len(diff.Resolved) == 0 {
+		return
+	}
+	if err := printWatchEvents(w.Verify.Stdout, w.Verify.Output, diff, at); err != nil {
+		fmt.Fprintf(w.Verify.Stderr, "warning: could not write changes: %v\n", err)
+	}
+	fmt.Fprintf(w.Verify.Stderr, "%s: %d introduced, %d resolved\n", at.Format(time.RFC3339), len(diff.Introduced), len(diff.Resolved))
+}
+
+// printWatchEvents writes the findings introduced and resolved in diff to out,
+// either as a table with a status column if output is ”, or as a stream of WatchEvent objects if output is 'json'
+func printWatchEvents(out io.Writer, output string, diff *FindingsDiff, at time.Time) error {
+	switch output {
+	case "":
+		return printDiffRows(out, []diffRows{{"introduced", diff.Introduced}, {"resolved", diff.Resolved}})
+	case "json":
+		encoder := json.NewEncoder(out)
+		for _, events := range []struct {
+			eventType string
+			findings  []Finding
+		}{{WatchEventIntroduced, diff.Introduced}, {WatchEventResolved, diff.Resolved}} {
+			for _, finding := range events.findings {
+				if err := encoder.Encode(WatchEvent{Type: events.eventType, Time: metav1.NewTime(at), Finding: finding}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid output format, only '' and 'json' are supported: %v", output)
+	}
+}
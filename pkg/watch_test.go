@@ -0,0 +1,96 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+func TestWatchRescan(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	opts := &WatchOptions{Verify: newFakeCluster(t, "c", "pod1", "pod2").Verify, Interval: time.Minute}
+	opts.Verify.Output = "json"
+	opts.Verify.Stdout = stdout
+	opts.Verify.Stderr = io.Discard
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	initial, err := opts.Verify.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pod1 is deleted and pod3 is created with an invalid ownerReference
+	podClient := opts.Verify.MetadataClient.(*metadatafake.FakeMetadataClient).Resource(schema.GroupVersionResource{Version: "v1", Resource: "pods"}).Namespace("ns1")
+	if err := podClient.Delete(context.Background(), "pod1", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := podClient.(metadatafake.MetadataClient).CreateFake(&metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "pod3", Namespace: "ns1", UID: "uid-c-pod3", OwnerReferences: []metav1.OwnerReference{initial.Findings[0].OwnerReference}},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	findings := opts.rescan(context.Background(), initial.Findings)
+	if len(findings) != 2 {
+		t.Errorf("expected 2 current findings, got %d", len(findings))
+	}
+	got := []string{}
+	decoder := json.NewDecoder(stdout)
+	for decoder.More() {
+		event := WatchEvent{}
+		if err := decoder.Decode(&event); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, event.Type+"/"+event.Finding.Name)
+	}
+	if diff := cmp.Diff([]string{"Introduced/pod3", "Resolved/pod1"}, got); diff != "" {
+		t.Errorf("unexpected events (-want +got):\n%s", diff)
+	}
+
+	// nothing is written when findings are unchanged
+	stdout.Reset()
+	opts.rescan(context.Background(), findings)
+	if stdout.Len() != 0 {
+		t.Errorf("expected no events, got %q", stdout.String())
+	}
+}
+
+func TestWatchValidate(t *testing.T) {
+	opts := &WatchOptions{Verify: newFakeCluster(t, "c").Verify}
+	opts.Verify.Stderr = io.Discard
+	opts.Verify.Stdout = io.Discard
+	if err := opts.Validate(); err == nil {
+		t.Error("expected error for a watch without an interval")
+	}
+	opts.Interval = time.Minute
+	opts.Verify.FailOnErrors = true
+	if err := opts.Validate(); err == nil {
+		t.Error("expected error failing on errors when watching")
+	}
+}
@@ -0,0 +1,290 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/pager"
+)
+
+// WhyTerminatingOptions contains options controlling how the objects keeping a namespace from being deleted are found
+type WhyTerminatingOptions struct {
+	DiscoveryClient discovery.DiscoveryInterface
+	MetadataClient  metadata.Interface
+	Output          string
+	Stderr          io.Writer
+	Stdout          io.Writer
+
+	// Namespace is the name of the namespace to analyze
+	Namespace string
+	// RequiredVerbs are the verbs a resource must support to be listed, DefaultRequiredVerbs if empty
+	RequiredVerbs []string
+	// Now returns the current time, time.Now if nil
+	Now func() time.Time
+}
+
+// Validate ensures the specified options are valid
+func (o *WhyTerminatingOptions) Validate() error {
+	if o.DiscoveryClient == nil {
+		return fmt.Errorf("discovery client is required")
+	}
+	if o.MetadataClient == nil {
+		return fmt.Errorf("metadata client is required")
+	}
+	if o.Stderr == nil {
+		return fmt.Errorf("stderr is required")
+	}
+	if o.Stdout == nil {
+		return fmt.Errorf("stdout is required")
+	}
+	if o.Output != "" && o.Output != "json" {
+		return fmt.Errorf("invalid output format, only '' and 'json' are supported: %v", o.Output)
+	}
+	if o.Namespace == "" {
+		return fmt.Errorf("namespace is required")
+	}
+	return validateRequiredVerbs(o.RequiredVerbs)
+}
+
+// TerminatingNamespace describes what keeps a namespace from being deleted
+type TerminatingNamespace struct {
+	Namespace string `json:"namespace"`
+	// DeletionTimestamp is when the namespace was deleted, unset if it is not terminating
+	DeletionTimestamp *metav1.Time `json:"deletionTimestamp,omitempty"`
+	// Finalizers are the finalizers in the metadata of the namespace
+	Finalizers []string `json:"finalizers,omitempty"`
+	// UnavailableGroupVersions are the API group versions that could not be discovered.
+	// The namespace controller does not finish deleting a namespace while it cannot discover all of them.
+	UnavailableGroupVersions []string `json:"unavailableGroupVersions,omitempty"`
+	// Objects are the objects still present in the namespace
+	Objects []RemainingObject `json:"objects"`
+	// NextSteps are suggested actions to unblock the deletion, in the order they should be taken
+	NextSteps []string `json:"nextSteps"`
+}
+
+// RemainingObject is an object still present in a terminating namespace
+type RemainingObject struct {
+	Resource metav1.GroupVersionResource `json:"resource"`
+	Name     string                      `json:"name"`
+	UID      types.UID                   `json:"uid"`
+	// Deleting is true if the object is being deleted, waiting for its finalizers to be removed
+	Deleting   bool     `json:"deleting"`
+	Finalizers []string `json:"finalizers,omitempty"`
+	// Dependents are the objects in the namespace that block the deletion of the object with blockOwnerDeletion,
+	// as resource/name, which the garbage collector waits for when it is deleted in the foreground
+	Dependents []string `json:"dependents,omitempty"`
+	// Blockers describe why the object is still present
+	Blockers []string `json:"blockers"`
+}
+
+// Run lists the objects remaining in the namespace, and writes them with what blocks their deletion and suggested
+// next steps to Stdout, either as a table if Output is ”, or as a JSON object if Output is 'json'
+func (o *WhyTerminatingOptions) Run(ctx context.Context) error {
+	now := time.Now
+	if o.Now != nil {
+		now = o.Now
+	}
+	result := &TerminatingNamespace{Namespace: o.Namespace, Objects: []RemainingObject{}, NextSteps: []string{}}
+	namespace, err := o.MetadataClient.Resource(namespacesResource).Get(ctx, o.Namespace, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	result.DeletionTimestamp, result.Finalizers = namespace.DeletionTimestamp, namespace.Finalizers
+	if namespace.DeletionTimestamp == nil {
+		fmt.Fprintf(o.Stderr, "warning: namespace %s is not terminating\n", o.Namespace)
+	}
+
+	// the namespace controller deletes the contents of every namespaced resource it discovers
+	preferredResources, err := discovery.ServerPreferredNamespacedResources(o.DiscoveryClient)
+	groupDiscoveryError := &discovery.ErrGroupDiscoveryFailed{}
+	if errors.As(err, &groupDiscoveryError) {
+		for failedGV := range groupDiscoveryError.Groups {
+			result.UnavailableGroupVersions = append(result.UnavailableGroupVersions, failedGV.String())
+		}
+		sort.Strings(result.UnavailableGroupVersions)
+	} else if err != nil {
+		return err
+	}
+	gvrSet, err := discovery.GroupVersionResources(discovery.FilteredBy(requiredVerbs(o.RequiredVerbs), preferredResources))
+	if err != nil {
+		return err
+	}
+	gvrs := []schema.GroupVersionResource{}
+	for gvr := range gvrSet {
+		gvrs = append(gvrs, gvr)
+	}
+	sortGVRs(gvrs)
+	groupList, err := o.DiscoveryClient.ServerGroups()
+	if err != nil {
+		return err
+	}
+	groups := []string{}
+	for _, group := range groupList.Groups {
+		groups = append(groups, group.Name)
+	}
+
+	objects := []*metav1.PartialObjectMetadata{}
+	resources := map[types.UID]schema.GroupVersionResource{}
+	for _, gvr := range gvrs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err := pager.New(func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return o.MetadataClient.Resource(gvr).Namespace(o.Namespace).List(ctx, opts)
+		}).EachListItem(ctx, metav1.ListOptions{}, func(object runtime.Object) error {
+			item, ok := object.(*metav1.PartialObjectMetadata)
+			if !ok {
+				return fmt.Errorf("expected type *metav1.PartialObjectMetadata, got type %T", item)
+			}
+			if _, seen := resources[item.UID]; !seen {
+				objects = append(objects, item)
+				resources[item.UID] = gvr
+			}
+			return nil
+		})
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		} else if err != nil {
+			fmt.Fprintf(o.Stderr, "warning: could not list %v: %v\n", gvr, err)
+		}
+	}
+
+	// dependents that block the deletion of their owners, by owner uid
+	blocking := map[types.UID][]string{}
+	for _, object := range objects {
+		for _, ownerRef := range object.OwnerReferences {
+			if ownerRef.BlockOwnerDeletion != nil && *ownerRef.BlockOwnerDeletion {
+				blocking[ownerRef.UID] = append(blocking[ownerRef.UID], resources[object.UID].Resource+"/"+object.Name)
+			}
+		}
+	}
+
+	nextSteps := []string{}
+	for _, gv := range result.UnavailableGroupVersions {
+		nextSteps = append(nextSteps, fmt.Sprintf("restore the API server of %s, or delete its APIService if it was uninstalled, so the namespace controller can delete its objects: kubectl get apiservice %s", gv, apiServiceName(gv)))
+	}
+	for _, object := range objects {
+		gvr := resources[object.UID]
+		name := gvr.GroupResource().String() + "/" + object.Name
+		remaining := RemainingObject{
+			Resource:   metav1.GroupVersionResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource},
+			Name:       object.Name,
+			UID:        object.UID,
+			Deleting:   object.DeletionTimestamp != nil,
+			Finalizers: object.Finalizers,
+			Dependents: blocking[object.UID],
+			Blockers:   []string{},
+		}
+		if !remaining.Deleting {
+			remaining.Blockers = append(remaining.Blockers, "not deleted yet")
+		}
+		if !remaining.Deleting && namespace.DeletionTimestamp != nil {
+			nextSteps = append(nextSteps, fmt.Sprintf("check the namespace conditions for errors deleting its contents: kubectl get namespace %s -o jsonpath='{.status.conditions}'", o.Namespace))
+		}
+		for i, finalizer := range object.Finalizers {
+			switch {
+			case finalizer == metav1.FinalizerDeleteDependents:
+				remaining.Blockers = append(remaining.Blockers, fmt.Sprintf("finalizer %s, waiting for %s to be deleted", finalizer, pluralize(len(remaining.Dependents), "dependent", "dependents")))
+				if len(remaining.Dependents) > 0 {
+					nextSteps = append(nextSteps, fmt.Sprintf("unblock the dependents of %s first: %s", name, strings.Join(remaining.Dependents, ", ")))
+				} else {
+					nextSteps = append(nextSteps, fmt.Sprintf("check the garbage collector is running, it removes %s from %s: kubectl-check-ownerreferences scan --check-gc-health", finalizer, name))
+				}
+			case finalizer == metav1.FinalizerOrphanDependents:
+				remaining.Blockers = append(remaining.Blockers, fmt.Sprintf("finalizer %s, waiting for the garbage collector to orphan its dependents", finalizer))
+				nextSteps = append(nextSteps, fmt.Sprintf("check the garbage collector is running, it removes %s from %s: kubectl-check-ownerreferences scan --check-gc-health", finalizer, name))
+			case !finalizerServed(finalizer, groups):
+				remaining.Blockers = append(remaining.Blockers, fmt.Sprintf("finalizer %s, whose controller is missing", finalizer))
+				nextSteps = append(nextSteps, fmt.Sprintf("if the controller of %s was uninstalled, remove the finalizer from %s: kubectl patch %s %s -n %s --type=json -p '[{\"op\":\"remove\",\"path\":\"/metadata/finalizers/%d\"}]'", finalizer, name, gvr.GroupResource(), object.Name, o.Namespace, i))
+			default:
+				remaining.Blockers = append(remaining.Blockers, fmt.Sprintf("finalizer %s, waiting for its controller", finalizer))
+				nextSteps = append(nextSteps, fmt.Sprintf("check the logs of the controller of %s, which removes it from %s once it has cleaned up", finalizer, name))
+			}
+		}
+		if remaining.Deleting && len(object.Finalizers) == 0 {
+			remaining.Blockers = append(remaining.Blockers, "being deleted")
+		}
+		result.Objects = append(result.Objects, remaining)
+	}
+	if len(result.Objects) == 0 && len(result.UnavailableGroupVersions) == 0 && namespace.DeletionTimestamp != nil {
+		nextSteps = append(nextSteps, fmt.Sprintf("no objects remain, check the namespace finalizers and conditions: kubectl get namespace %s -o yaml", o.Namespace))
+	}
+	seen := map[string]bool{}
+	for _, step := range nextSteps {
+		if !seen[step] {
+			seen[step] = true
+			result.NextSteps = append(result.NextSteps, step)
+		}
+	}
+
+	if o.Output == "json" {
+		encoder := json.NewEncoder(o.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	if namespace.DeletionTimestamp != nil {
+		fmt.Fprintf(o.Stdout, "Namespace %s has been terminating for %s\n", o.Namespace, duration.HumanDuration(now().Sub(namespace.DeletionTimestamp.Time)))
+	}
+	for _, gv := range result.UnavailableGroupVersions {
+		fmt.Fprintf(o.Stdout, "API %s is unavailable, its objects cannot be deleted\n", gv)
+	}
+	if len(result.Objects) == 0 {
+		fmt.Fprintf(o.Stdout, "No objects remain in namespace %s\n", o.Namespace)
+	} else {
+		tabwriter := printers.GetNewTabWriter(o.Stdout)
+		fmt.Fprintln(tabwriter, "RESOURCE\tNAME\tDELETING\tBLOCKED_BY")
+		for _, object := range result.Objects {
+			resource := schema.GroupResource{Group: object.Resource.Group, Resource: object.Resource.Resource}
+			fmt.Fprintf(tabwriter, "%s\t%s\t%t\t%s\n", resource, object.Name, object.Deleting, strings.Join(object.Blockers, "; "))
+		}
+		if err := tabwriter.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(result.NextSteps) > 0 {
+		fmt.Fprintln(o.Stdout, "\nNext steps:")
+		for i, step := range result.NextSteps {
+			fmt.Fprintf(o.Stdout, "%d. %s\n", i+1, step)
+		}
+	}
+	return nil
+}
+
+// apiServiceName returns the name of the APIService serving a group version, e.g. v1beta1.metrics.k8s.io
+func apiServiceName(groupVersion string) string {
+	gv, err := schema.ParseGroupVersion(groupVersion)
+	if err != nil || gv.Group == "" {
+		return groupVersion
+	}
+	return gv.Version + "." + gv.Group
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestWhyTerminating(t *testing.T) {
+	gcVerbs := []string{"get", "list", "delete"}
+	discoveryClient := &fake.FakeDiscovery{Fake: &coretesting.Fake{}}
+	discoveryClient.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: gcVerbs},
+			{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: gcVerbs},
+			{Name: "namespaces", Kind: "Namespace", Verbs: gcVerbs},
+		}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{{Name: "deployments", Namespaced: true, Kind: "Deployment", Verbs: gcVerbs}}},
+	}
+	metadataClient := metadatafake.NewSimpleMetadataClient(runtime.NewScheme())
+	now := time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+	deleted := metav1.NewTime(now.Add(-time.Hour))
+	create := func(gvr schema.GroupVersionResource, object metav1.ObjectMeta) {
+		t.Helper()
+		client := metadataClient.Resource(gvr).Namespace(object.Namespace)
+		if _, err := client.(metadatafake.MetadataClient).CreateFake(&metav1.PartialObjectMetadata{ObjectMeta: object}, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	block := true
+	configmaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	create(schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}, metav1.ObjectMeta{Name: "ns1", DeletionTimestamp: &deleted})
+	create(configmaps, metav1.ObjectMeta{Namespace: "ns1", Name: "cm1", UID: "cm1", DeletionTimestamp: &deleted, Finalizers: []string{"gone.io/cleanup"}})
+	create(configmaps, metav1.ObjectMeta{Namespace: "ns2", Name: "cm2", UID: "cm2", Finalizers: []string{"gone.io/cleanup"}})
+	create(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, metav1.ObjectMeta{Namespace: "ns1", Name: "web", UID: "web", DeletionTimestamp: &deleted, Finalizers: []string{metav1.FinalizerDeleteDependents}})
+	create(schema.GroupVersionResource{Version: "v1", Resource: "pods"}, metav1.ObjectMeta{Namespace: "ns1", Name: "web-1", UID: "web-1", OwnerReferences: []metav1.OwnerReference{
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", UID: "web", BlockOwnerDeletion: &block},
+	}})
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	opts := &WhyTerminatingOptions{
+		DiscoveryClient: discoveryClient,
+		MetadataClient:  metadataClient,
+		Output:          "json",
+		Stderr:          stderr,
+		Stdout:          stdout,
+		Namespace:       "ns1",
+		Now:             func() time.Time { return now },
+	}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := opts.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	result := &TerminatingNamespace{}
+	if err := json.Unmarshal(stdout.Bytes(), result); err != nil {
+		t.Fatal(err)
+	}
+	type blockers struct {
+		Name       string
+		UID        types.UID
+		Blockers   []string
+		Dependents []string
+	}
+	got := []blockers{}
+	for _, object := range result.Objects {
+		got = append(got, blockers{Name: object.Name, UID: object.UID, Blockers: object.Blockers, Dependents: object.Dependents})
+	}
+	expected := []blockers{
+		{Name: "cm1", UID: "cm1", Blockers: []string{"finalizer gone.io/cleanup, whose controller is missing"}},
+		{Name: "web-1", UID: "web-1", Blockers: []string{"not deleted yet"}},
+		{Name: "web", UID: "web", Blockers: []string{"finalizer foregroundDeletion, waiting for 1 dependent to be deleted"}, Dependents: []string{"pods/web-1"}},
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("unexpected objects (-want +got):\n%s", diff)
+	}
+	expectedSteps := []string{
+		`if the controller of gone.io/cleanup was uninstalled, remove the finalizer from configmaps/cm1: kubectl patch configmaps cm1 -n ns1 --type=json -p '[{"op":"remove","path":"/metadata/finalizers/0"}]'`,
+		"check the namespace conditions for errors deleting its contents: kubectl get namespace ns1 -o jsonpath='{.status.conditions}'",
+		"unblock the dependents of deployments.apps/web first: pods/web-1",
+	}
+	if diff := cmp.Diff(expectedSteps, result.NextSteps); diff != "" {
+		t.Errorf("unexpected next steps (-want +got):\n%s", diff)
+	}
+
+	stdout.Reset()
+	opts.Output = ""
+	if err := opts.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"Namespace ns1 has been terminating for 60m\n", "deployments.apps   web     true       finalizer foregroundDeletion", "\nNext steps:\n1. "} {
+		if !strings.Contains(stdout.String(), expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, stdout.String())
+		}
+	}
+	if stderr.Len() > 0 {
+		t.Errorf("unexpected warnings: %s", stderr.String())
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+func newPolicyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "policy",
+		Short: "Work with admission policies preventing invalid ownerReferences",
+		Args:  cobra.NoArgs,
+	}
+	cmd.AddCommand(newPolicyGenerateCommand())
+	return cmd
+}
+
+func newPolicyGenerateCommand() *cobra.Command {
+	filename := ""
+	opts := &pkg.PolicyOptions{Format: "vap", Name: "check-ownerreferences"}
+	cmd := &cobra.Command{
+		Use:   "generate -f FILENAME",
+		Short: "Generate admission policies from findings",
+		Long: `Reads findings written by "scan -o json", or a report from /results or --export,
+and prints admission policies rejecting objects with the classes of invalid ownerReferences found:
+references to apiVersions or kinds the cluster does not serve, and namespaced owners of cluster-scoped objects.
+
+Problems that depend on the owner objects in the cluster, like missing owners or owners in
+another namespace, cannot be checked by a stateless admission policy and are listed in a comment.
+
+  kubectl-check-ownerreferences scan -o json > findings.json
+  kubectl-check-ownerreferences policy generate -f findings.json --format kyverno | kubectl apply -f -`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			input, err := openInput(filename)
+			if err != nil {
+				return err
+			}
+			defer input.Close()
+			opts.Input = input
+			opts.Stdout = cmd.OutOrStdout()
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			return opts.Run()
+		},
+	}
+	cmd.Flags().StringVarP(&filename, "filename", "f", filename, "File containing findings to generate policies from, or '-' to read from stdin.")
+	cmd.Flags().StringVar(&opts.Format, "format", opts.Format, "Policy format. May be 'vap' (ValidatingAdmissionPolicy), 'kyverno', or 'gatekeeper'.")
+	cmd.Flags().StringVar(&opts.Name, "name", opts.Name, "Name of the generated policy objects.")
+	cmd.MarkFlagRequired("filename")
+	return cmd
+}
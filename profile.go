@@ -0,0 +1,94 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	rpprof "runtime/pprof"
+
+	"k8s.io/klog/v2"
+)
+
+// startProfiling begins CPU profiling into profileDir (if set) and serves pprof handlers on pprofAddress (if set).
+// The returned function stops CPU profiling and writes a heap profile, and must be called before exiting.
+func startProfiling(profileDir, pprofAddress string) (func(), error) {
+	stop := func() {}
+
+	if pprofAddress != "" {
+		host, _, err := net.SplitHostPort(pprofAddress)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pprof address %q: %v", pprofAddress, err)
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return nil, fmt.Errorf("invalid pprof address %q, must be a localhost address", pprofAddress)
+		}
+		listener, err := net.Listen("tcp", pprofAddress)
+		if err != nil {
+			return nil, err
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		klog.Infof("serving pprof on http://%s/debug/pprof/", listener.Addr().String())
+		go func() {
+			if err := http.Serve(listener, mux); err != nil {
+				klog.Warningf("pprof server stopped: %v", err)
+			}
+		}()
+	}
+
+	if profileDir != "" {
+		if err := os.MkdirAll(profileDir, 0755); err != nil {
+			return nil, err
+		}
+		cpuFile, err := os.Create(filepath.Join(profileDir, "cpu.pprof"))
+		if err != nil {
+			return nil, err
+		}
+		if err := rpprof.StartCPUProfile(cpuFile); err != nil {
+			cpuFile.Close()
+			return nil, err
+		}
+		stop = func() {
+			rpprof.StopCPUProfile()
+			cpuFile.Close()
+
+			heapFile, err := os.Create(filepath.Join(profileDir, "heap.pprof"))
+			if err != nil {
+				klog.Warningf("could not write heap profile: %v", err)
+				return
+			}
+			defer heapFile.Close()
+			// get up-to-date statistics
+			runtime.GC()
+			if err := rpprof.WriteHeapProfile(heapFile); err != nil {
+				klog.Warningf("could not write heap profile: %v", err)
+			}
+		}
+	}
+
+	return stop, nil
+}
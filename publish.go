@@ -0,0 +1,237 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"k8s.io/client-go/dynamic"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+// publishOptions holds the flags controlling where scan results are published in addition to stdout
+type publishOptions struct {
+	reportName         string
+	reportPerNamespace bool
+
+	configMap string
+	events    bool
+
+	notifyURL       string
+	notifyFormat    string
+	notifyThreshold int
+
+	export  string
+	signKey string
+
+	findingsDB        string
+	findingsDBCluster string
+
+	githubRepo          string
+	githubTokenFile     string
+	githubGroupBy       string
+	githubLabels        []string
+	githubIssueTemplate string
+	githubAPIURL        string
+
+	pushgatewayURL      string
+	pushgatewayJob      string
+	pushgatewayGrouping map[string]string
+}
+
+func newPublishOptions() *publishOptions {
+	return &publishOptions{
+		reportName:      "cluster",
+		notifyFormat:    "json",
+		notifyThreshold: 1,
+		githubGroupBy:   "namespace",
+		githubLabels:    []string{"invalid-ownerreferences"},
+		pushgatewayJob:  pkg.DefaultPushgatewayJob,
+	}
+}
+
+func (o *publishOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.reportName, "report-name", o.reportName, "Name of the OwnerReferenceReport to write results to. Per-namespace reports are named <report-name>.<namespace>.")
+	flags.BoolVar(&o.reportPerNamespace, "report-per-namespace", o.reportPerNamespace, "Write an OwnerReferenceReport per namespace instead of a single report for the whole cluster.")
+	flags.StringVar(&o.configMap, "publish-configmap", o.configMap, "ConfigMap to write a summary and findings to after each scan, as namespace/name.")
+	flags.BoolVar(&o.events, "emit-events", o.events, "Record a Warning Event on each object with an invalid ownerReference.")
+	flags.StringVar(&o.notifyURL, "notify-url", o.notifyURL, "Webhook URL to POST a summary and the top findings to after each scan.")
+	flags.StringVar(&o.notifyFormat, "notify-format", o.notifyFormat, "Format of webhook notifications. May be 'json' or 'slack'.")
+	flags.IntVar(&o.notifyThreshold, "notify-threshold", o.notifyThreshold, "Minimum number of errors required to send a webhook notification.")
+	flags.StringVar(&o.export, "export", o.export, "Location to upload a JSON report to after each scan, as s3://bucket/prefix, gcs://bucket/prefix, or file:///path/to/dir.")
+	flags.StringVar(&o.signKey, "sign-key", o.signKey, "Key file to sign reports uploaded with --export with, writing a detached signature next to each report as <report>.sig: a PEM ed25519 private key, or an HMAC secret.")
+	flags.StringVar(&o.findingsDB, "findings-db", o.findingsDB, "SQLite database file to record the findings of each scan in, for reporting trends with the trends command.")
+	flags.StringVar(&o.githubRepo, "github-repo", o.githubRepo, "GitHub repository to open issues in for new errors after each scan, as owner/name.")
+	flags.StringVar(&o.githubTokenFile, "github-token-file", o.githubTokenFile, "File containing a GitHub token allowed to read and write issues in --github-repo. Defaults to the GITHUB_TOKEN environment variable.")
+	flags.StringVar(&o.githubGroupBy, "github-group-by", o.githubGroupBy, "How errors are grouped into GitHub issues. May be 'namespace' or 'owner'.")
+	flags.StringSliceVar(&o.githubLabels, "github-labels", o.githubLabels, "Labels to add to GitHub issues. Open issues are found by the first label.")
+	flags.StringVar(&o.githubIssueTemplate, "github-issue-template", o.githubIssueTemplate, "File containing a Go template for GitHub issue bodies, given .Description, .Namespace, .Owner, and .Findings.")
+	flags.StringVar(&o.githubAPIURL, "github-api-url", o.githubAPIURL, "GitHub API URL, for GitHub Enterprise. Defaults to https://api.github.com.")
+	flags.StringVar(&o.pushgatewayURL, "pushgateway-url", o.pushgatewayURL, "Prometheus Pushgateway URL to push the metrics of each scan to, replacing the metrics of the previous scan.")
+	flags.StringVar(&o.pushgatewayJob, "pushgateway-job", o.pushgatewayJob, "Job label to push metrics to --pushgateway-url with.")
+	flags.StringToStringVar(&o.pushgatewayGrouping, "pushgateway-grouping", o.pushgatewayGrouping, "Additional labels to group metrics pushed to --pushgateway-url by, such as cluster=prod.")
+	flags.StringVar(&o.findingsDBCluster, "findings-db-cluster", o.findingsDBCluster, "Name of the cluster scans are recorded for in --findings-db. Defaults to the kubeconfig context, or the server URL.")
+}
+
+// enabled returns true if results are published anywhere other than stdout and OwnerReferenceReports
+func (o *publishOptions) enabled() bool {
+	return o.configMap != "" || o.events || o.notifyURL != "" || o.export != "" || o.findingsDB != "" || o.githubRepo != "" || o.pushgatewayURL != ""
+}
+
+// clusterWriteFlags returns the set flags that publish results by writing to the cluster
+func (o *publishOptions) clusterWriteFlags() []string {
+	flags := []string{}
+	if o.configMap != "" {
+		flags = append(flags, "--publish-configmap")
+	}
+	if o.events {
+		flags = append(flags, "--emit-events")
+	}
+	return flags
+}
+
+// configure sets up the publishing options in opts, using config to build clients.
+// If writeReports is true, results are written to OwnerReferenceReport objects.
+func (o *publishOptions) configure(opts *pkg.VerifyGCOptions, config *rest.Config, cluster *pkg.ClusterInfo, writeReports bool) error {
+	if writeReports {
+		client, err := dynamic.NewForConfig(config)
+		if err != nil {
+			return err
+		}
+		opts.CRDReport = &pkg.CRDReportOptions{
+			Client:       client,
+			Name:         o.reportName,
+			PerNamespace: o.reportPerNamespace,
+		}
+	}
+
+	if o.configMap != "" || o.events {
+		coreClient, err := corev1client.NewForConfig(config)
+		if err != nil {
+			return err
+		}
+		if o.configMap != "" {
+			parts := strings.Split(o.configMap, "/")
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return fmt.Errorf("invalid --publish-configmap %q, expected namespace/name", o.configMap)
+			}
+			opts.ConfigMap = &pkg.ConfigMapOptions{
+				Client:    coreClient,
+				Namespace: parts[0],
+				Name:      parts[1],
+			}
+		}
+		if o.events {
+			opts.Events = &pkg.EventOptions{Client: coreClient}
+		}
+	}
+
+	if o.notifyURL != "" {
+		opts.Notify = &pkg.NotifyOptions{
+			URL:       o.notifyURL,
+			Format:    o.notifyFormat,
+			Threshold: o.notifyThreshold,
+		}
+	}
+
+	if o.export != "" {
+		opts.Export = &pkg.ExportOptions{URL: o.export, Cluster: cluster}
+		if o.signKey != "" {
+			key, err := pkg.LoadReportKey(o.signKey)
+			if err != nil {
+				return err
+			}
+			opts.Export.SignKey = key
+		}
+	} else if o.signKey != "" {
+		return fmt.Errorf("--sign-key requires --export")
+	}
+
+	if o.findingsDB != "" {
+		name := o.findingsDBCluster
+		if name == "" {
+			name = cluster.Context
+		}
+		if name == "" {
+			name = cluster.Server
+		}
+		opts.FindingsDB = &pkg.FindingsDBOptions{Path: o.findingsDB, Cluster: name}
+	}
+
+	if o.githubRepo != "" {
+		token := os.Getenv("GITHUB_TOKEN")
+		if o.githubTokenFile != "" {
+			data, err := ioutil.ReadFile(o.githubTokenFile)
+			if err != nil {
+				return err
+			}
+			token = strings.TrimSpace(string(data))
+		}
+		if token == "" {
+			return fmt.Errorf("--github-repo requires --github-token-file or GITHUB_TOKEN")
+		}
+		issueTemplate := ""
+		if o.githubIssueTemplate != "" {
+			data, err := ioutil.ReadFile(o.githubIssueTemplate)
+			if err != nil {
+				return err
+			}
+			issueTemplate = string(data)
+		}
+		opts.GitHub = &pkg.GitHubIssueOptions{
+			Repository: o.githubRepo,
+			Token:      token,
+			GroupBy:    o.githubGroupBy,
+			Labels:     o.githubLabels,
+			Template:   issueTemplate,
+			APIURL:     o.githubAPIURL,
+		}
+	}
+
+	if o.pushgatewayURL != "" {
+		opts.Pushgateway = &pkg.PushgatewayOptions{
+			URL:      o.pushgatewayURL,
+			Job:      o.pushgatewayJob,
+			Grouping: o.pushgatewayGrouping,
+		}
+	}
+	return nil
+}
+
+func newCRDCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "crd",
+		Short: "Print the OwnerReferenceReport CustomResourceDefinition manifest",
+		Long: `Prints the CustomResourceDefinition manifest for OwnerReferenceReport objects,
+written by "scan -o crd" and "serve --publish-reports". To install it:
+
+  kubectl-check-ownerreferences crd | kubectl apply -f -`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Fprint(cmd.OutOrStdout(), pkg.ReportCRD)
+		},
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+)
+
+// rateLimit holds a qps/burst pair
+type rateLimit struct {
+	qps   int
+	burst int
+}
+
+func (r rateLimit) validate() error {
+	if r.burst <= 0 {
+		return fmt.Errorf("invalid burst rate, must be > 0")
+	}
+	if r.qps < -1 {
+		return fmt.Errorf("invalid qps, must be >= 0")
+	}
+	return nil
+}
+
+func (r rateLimit) apply(config *rest.Config) *rest.Config {
+	config = rest.CopyConfig(config)
+	config.QPS = float32(r.qps)
+	config.Burst = r.burst
+	return config
+}
+
+// parseGroupRateLimits parses group=qps[:burst] values into per-group rate limits.
+// The core group may be specified as "core". If burst is omitted, defaultBurst is used.
+func parseGroupRateLimits(values []string, defaultBurst int) (map[string]rateLimit, error) {
+	limits := map[string]rateLimit{}
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return nil, fmt.Errorf("invalid group rate limit %q, expected group=qps[:burst]", value)
+		}
+		group := parts[0]
+		if group == "core" {
+			group = ""
+		}
+		limit := rateLimit{burst: defaultBurst}
+		qpsBurst := strings.SplitN(parts[1], ":", 2)
+		qps, err := strconv.Atoi(qpsBurst[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid group rate limit %q: %v", value, err)
+		}
+		limit.qps = qps
+		if len(qpsBurst) == 2 {
+			burst, err := strconv.Atoi(qpsBurst[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid group rate limit %q: %v", value, err)
+			}
+			limit.burst = burst
+		}
+		if err := limit.validate(); err != nil {
+			return nil, fmt.Errorf("invalid group rate limit %q: %v", value, err)
+		}
+		limits[group] = limit
+	}
+	return limits, nil
+}
+
+// groupMetadataClient dispatches requests to a per-group metadata client if one is configured
+type groupMetadataClient struct {
+	defaultClient metadata.Interface
+	groupClients  map[string]metadata.Interface
+}
+
+func (g *groupMetadataClient) Resource(resource schema.GroupVersionResource) metadata.Getter {
+	if client, ok := g.groupClients[resource.Group]; ok {
+		return client.Resource(resource)
+	}
+	return g.defaultClient.Resource(resource)
+}
+
+// newMetadataClient returns a metadata client using config, with separate rate limiters for the groups in groupLimits
+func newMetadataClient(config *rest.Config, groupLimits map[string]rateLimit) (metadata.Interface, error) {
+	defaultClient, err := metadata.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if len(groupLimits) == 0 {
+		return defaultClient, nil
+	}
+	client := &groupMetadataClient{defaultClient: defaultClient, groupClients: map[string]metadata.Interface{}}
+	for group, limit := range groupLimits {
+		groupClient, err := metadata.NewForConfig(limit.apply(config))
+		if err != nil {
+			return nil, err
+		}
+		client.groupClients[group] = groupClient
+	}
+	return client, nil
+}
+
+// newDynamicClients returns a dynamic client using config, and dynamic clients with separate rate limiters for the groups
+// in groupLimits, for getting full objects from API servers that reject metadata-only requests
+func newDynamicClients(config *rest.Config, groupLimits map[string]rateLimit) (dynamic.Interface, map[string]dynamic.Interface, error) {
+	defaultClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	groupClients := map[string]dynamic.Interface{}
+	for group, limit := range groupLimits {
+		groupClient, err := dynamic.NewForConfig(limit.apply(config))
+		if err != nil {
+			return nil, nil, err
+		}
+		groupClients[group] = groupClient
+	}
+	return defaultClient, groupClients, nil
+}
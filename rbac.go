@@ -0,0 +1,77 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+func newRBACCommand(clientOpts *clientOptions) *cobra.Command {
+	opts := &pkg.RBACOptions{Name: "kubectl-check-ownerreferences", ServiceAccount: "kubectl-check-ownerreferences"}
+	cmd := &cobra.Command{
+		Use:   "rbac",
+		Short: "Print least-privilege RBAC manifests for running scans in-cluster",
+		Long: `Discovers the resources scans list, and prints a ClusterRole granting read-only access
+to them and a ClusterRoleBinding to the ServiceAccount the tool runs as.
+Add permissions for the publishing options you use with --emit-events, --publish-reports,
+and --leader-elect, for --check-gc-health, and patch access to the scanned resources with --fix.
+
+Resources served by the cluster later, such as new custom resources, are not included,
+and are skipped with a warning until the manifests are regenerated.
+
+  kubectl-check-ownerreferences rbac --service-account-namespace=monitoring --leader-elect | kubectl apply -f -`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := clientOpts.complete(cmd.Flags()); err != nil {
+				return err
+			}
+			config, err := clientOpts.restConfig()
+			if err != nil {
+				return err
+			}
+			if opts.DiscoveryClient, _, err = clientOpts.clients(config); err != nil {
+				return err
+			}
+			if opts.Namespace == "" {
+				if opts.Namespace, _, err = clientOpts.configFlags.ToRawKubeConfigLoader().Namespace(); err != nil {
+					return err
+				}
+			}
+			opts.Stderr = os.Stderr
+			opts.Stdout = cmd.OutOrStdout()
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			return opts.Run()
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&opts.Name, "name", opts.Name, "Name of the generated roles and bindings.")
+	flags.StringVar(&opts.ServiceAccount, "service-account", opts.ServiceAccount, "Name of the ServiceAccount the tool runs as.")
+	flags.StringVar(&opts.Namespace, "service-account-namespace", opts.Namespace, "Namespace of the ServiceAccount, and of the leader election Lease. Defaults to the kubeconfig namespace.")
+	flags.StringSliceVar(&opts.RequiredVerbs, "require-verbs", pkg.DefaultRequiredVerbs, "Verbs a resource must support to be scanned, matching the --require-verbs of scans.")
+	flags.BoolVar(&opts.Fix, "fix", opts.Fix, "Also grant patch on the scanned resources, for removing invalid ownerReferences.")
+	flags.BoolVar(&opts.Events, "emit-events", opts.Events, "Grant writing Events, for scanning with --emit-events.")
+	flags.BoolVar(&opts.Reports, "publish-reports", opts.Reports, "Grant writing OwnerReferenceReport objects, for scanning with -o crd or serving with --publish-reports.")
+	flags.BoolVar(&opts.LeaderElection, "leader-elect", opts.LeaderElection, "Grant writing Leases in the ServiceAccount namespace, for serving with --leader-elect.")
+	flags.BoolVar(&opts.GCHealth, "check-gc-health", opts.GCHealth, "Grant reading the kube-controller-manager Lease in kube-system, and its metrics through pods/proxy on every pod in kube-system, for scanning with --check-gc-health.")
+	return cmd
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/spf13/pflag"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+// ruleOptions holds the flags controlling which checks are run
+type ruleOptions struct {
+	configFile string
+	ignore     []string
+}
+
+func (o *ruleOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.configFile, "rule-config", o.configFile, "YAML file enabling, disabling, and overriding the level of individual checks, and defining custom checks as CEL expressions and Rego policies.")
+	flags.StringArrayVar(&o.ignore, "ignore", o.ignore, "Drop findings with this code, or findings and list failures whose message (or failed resource, as resource.group or group/version) matches this regular expression, from output and counts. May be repeated.")
+}
+
+// configure sets up the rule options in opts
+func (o *ruleOptions) configure(opts *pkg.VerifyGCOptions) error {
+	if len(o.ignore) > 0 {
+		ignore, err := pkg.NewIgnoreFilter(o.ignore)
+		if err != nil {
+			return err
+		}
+		opts.Ignore = ignore
+	}
+	if o.configFile == "" {
+		return nil
+	}
+	config, err := pkg.LoadRuleConfig(o.configFile)
+	if err != nil {
+		return err
+	}
+	customRules, err := config.CustomRules()
+	if err != nil {
+		return err
+	}
+	opts.RuleConfig = config
+	opts.Rules = append(pkg.DefaultRules(), customRules...)
+	return nil
+}
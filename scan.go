@@ -0,0 +1,737 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/metadata"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+// scanOptions holds the flags for the scan command
+type scanOptions struct {
+	output    string
+	benchmark bool
+
+	contexts         []string
+	allContexts      bool
+	parallelClusters int
+
+	capi          bool
+	capiNamespace string
+	capiSelector  string
+
+	baseline      string
+	baselineMode  string
+	writeBaseline string
+
+	auditLogs []string
+
+	since string
+
+	filenames      []string
+	failOnErrors   bool
+	failOnWarnings bool
+	failFast       bool
+	maxFindings    int
+	olderThan      time.Duration
+	allVersions    bool
+	rediscover     bool
+	retryConfig    string
+	listTimeout    time.Duration
+	stallTimeout   time.Duration
+	requiredVerbs  []string
+	progressEvents string
+	progress       bool
+	color          string
+	warningsFile   string
+	outputDir      string
+	namespaceKeys  []string
+	splitBy        string
+	startFrom      string
+	emitTriage     bool
+	ownerSummary   bool
+	managerSummary bool
+
+	redact        bool
+	redactSalt    string
+	redactMapping string
+
+	preflight        bool
+	gcHealth         bool
+	gcHealthInterval time.Duration
+	minCoverage      float64
+
+	watch         bool
+	watchInterval time.Duration
+	incremental   bool
+
+	fromDir           string
+	fromEtcdSnapshot  string
+	fromVeleroBackup  string
+	fromSnapshot      string
+	snapshotOut       string
+	inventory         string
+	summaryFile       string
+	estimateDeletions bool
+	ownersFrom        string
+	ownersFromOnly    bool
+	supportBundle     string
+	redactBundle      bool
+	etcdPrefix        string
+	discoverySnapshot string
+
+	rules   *ruleOptions
+	publish *publishOptions
+}
+
+func newScanOptions() *scanOptions {
+	return &scanOptions{contexts: []string{}, auditLogs: []string{}, filenames: []string{}, namespaceKeys: []string{}, requiredVerbs: append([]string{}, pkg.DefaultRequiredVerbs...), rediscover: true, parallelClusters: 4, watchInterval: time.Minute, baselineMode: "exclude", color: "auto", splitBy: pkg.SplitByNamespace, etcdPrefix: "/registry", rules: &ruleOptions{}, publish: newPublishOptions()}
+}
+
+func (o *scanOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&o.output, "output", "o", o.output, "Output format. May be '', 'json', 'name' (resource/namespace/name of each object with findings), 'objects' (a v1 List of the objects with findings, for 'kubectl delete -f -'), or 'crd' (write results to OwnerReferenceReport objects).")
+	flags.BoolVar(&o.benchmark, "benchmark", o.benchmark, "Only discover and list resources, and report throughput and API call counts instead of checking ownerReferences.")
+	flags.StringSliceVar(&o.contexts, "contexts", o.contexts, "Kubeconfig contexts to scan, combining the results with a cluster column.")
+	flags.BoolVar(&o.allContexts, "all-contexts", o.allContexts, "Scan all kubeconfig contexts, combining the results with a cluster column.")
+	flags.BoolVar(&o.capi, "capi", o.capi, "Scan the workload clusters of the Cluster API Cluster objects in the cluster, using their <cluster>-kubeconfig Secrets.")
+	flags.StringVar(&o.capiNamespace, "capi-namespace", o.capiNamespace, "Namespace of the Cluster API Cluster objects to scan with --capi. Defaults to all namespaces.")
+	flags.StringVar(&o.capiSelector, "capi-selector", o.capiSelector, "Label selector of the Cluster API Cluster objects to scan with --capi.")
+	flags.IntVar(&o.parallelClusters, "parallel-clusters", o.parallelClusters, "Number of clusters to scan at once with --contexts, --all-contexts, or --capi.")
+	flags.StringVar(&o.baseline, "baseline", o.baseline, "File of previously acknowledged findings, written by --write-baseline or -o json, to exclude or demote.")
+	flags.StringVar(&o.baselineMode, "baseline-mode", o.baselineMode, "How findings matching --baseline are reported. May be 'exclude' or 'demote' (report as warnings).")
+	flags.StringVar(&o.writeBaseline, "write-baseline", o.writeBaseline, "File to write all findings of a complete scan to, including those matching --baseline, for use with --baseline.")
+	flags.StringVar(&o.since, "since", o.since, "Findings of a previous run, written by -o json, to mark each finding as new, persisting, or resolved against. Only new errors fail the scan.")
+	flags.StringSliceVarP(&o.filenames, "filename", "f", o.filenames, "Only check the objects in these files, or '-' to read from stdin, such as the output of 'kubectl get -o json'. Owners are looked up in the cluster.")
+	flags.BoolVar(&o.preflight, "preflight", o.preflight, "Review access to list each resource with SelfSubjectAccessReviews before listing, and skip the resources that cannot be listed.")
+	flags.BoolVar(&o.gcHealth, "check-gc-health", o.gcHealth, "Before listing, warn if the garbage collector appears not to be running or stuck, from the kube-controller-manager Lease and, where accessible through the API server, its sync error metrics.")
+	flags.DurationVar(&o.gcHealthInterval, "gc-health-sample-interval", o.gcHealthInterval, "With --check-gc-health, sample the garbage collector sync error metrics twice this far apart, e.g. 1m, and only warn if they increased in between. The metrics count every failure since the controller manager started, so if 0, past failures are reported too.")
+	flags.Float64Var(&o.minCoverage, "min-coverage", o.minCoverage, "With --preflight, abort before listing if less than this percentage of resources can be listed.")
+	flags.BoolVar(&o.ownerSummary, "summarize-owner-kinds", o.ownerSummary, "After the summary, write the number of errors and warnings for each owner kind to stderr, most errors first, to pinpoint the controllers responsible for most findings.")
+	flags.BoolVar(&o.managerSummary, "summarize-managers", o.managerSummary, "After the summary, write the number of errors and warnings for the objects managed by each tool instance (Argo CD application, Helm release, Velero restore, ...) to stderr, most errors first.")
+	flags.BoolVar(&o.emitTriage, "emit-triage", o.emitTriage, "After the findings, print kubectl commands to inspect the object and claimed owner of each finding, and to search for the owner uid.")
+	flags.BoolVar(&o.redact, "redact", o.redact, "Replace the namespaces, names, and UIDs of objects and owners in findings with hashes keyed by --redact-salt-file, so results can be shared without revealing internal naming. The same value always has the same hash.")
+	flags.StringVar(&o.redactSalt, "redact-salt-file", o.redactSalt, "File containing the salt to hash values with --redact, created with a random salt if it does not exist. Keep it private, and reuse it so hashes match across reports.")
+	flags.StringVar(&o.redactMapping, "redact-mapping", o.redactMapping, "File to write a JSON object mapping each hash in the findings to the original value to with --redact, for de-anonymizing shared results internally.")
+	flags.BoolVar(&o.failOnErrors, "fail-on-errors", o.failOnErrors, "Exit with an error if any error-level findings are reported, for use as a CI or pre-apply gate.")
+	flags.StringVar(&o.startFrom, "start-from", o.startFrom, "Resume a partial scan from a resource, as group/resource (or resource for the core group), skipping earlier resources in the sorted order resources are listed in. Earlier resources are only listed to find the owners of later objects.")
+	flags.StringVar(&o.warningsFile, "warnings-file", o.warningsFile, "File to write warning-level findings to in the --output format, instead of stdout, so errors and warnings can be routed separately.")
+	flags.StringSliceVar(&o.namespaceKeys, "enrich-namespace-labels", o.namespaceKeys, "Label or annotation keys of the namespace of each finding to add to findings, e.g. team,owner, as columns or a namespaceLabels field, so findings can be routed to their owners. Labels take precedence over annotations with the same key.")
+	flags.StringVar(&o.outputDir, "output-dir", o.outputDir, "Directory to also write the findings of a complete scan to in the --output format, in a separate file for each value of --split-by, so each file can be routed to its owners. Findings of cluster-scoped objects are written to _cluster.")
+	flags.StringVar(&o.splitBy, "split-by", o.splitBy, "What findings are split into files by with --output-dir. May be 'namespace', or 'resource' (one file per resource, named resource.version.group).")
+	flags.BoolVar(&o.progress, "progress", o.progress, "Show the progress of the scan as a line on stderr, redrawn in place. Defaults to true when stderr is a terminal, unless --log-file is set.")
+	flags.StringVar(&o.color, "color", o.color, "Color the level of findings in table output. May be 'auto' (when stdout is a terminal and NO_COLOR is not set), 'always', or 'never'.")
+	flags.StringVar(&o.progressEvents, "progress-events", o.progressEvents, "File to write progress events to as newline-delimited JSON as the scan runs, such as a named pipe, or '-' for stderr, so wrapping automation can display progress and detect stalls.")
+	flags.BoolVar(&o.failFast, "fail-fast", o.failFast, "Stop at the first error-level finding, and exit with an error describing it, for smoke tests that only need a yes or no answer. Invalid apiVersions, unresolvable owner kinds, and namespaced owners of cluster-scoped objects stop listing as soon as they are listed. Other checks need every resource listed first, since owners may be in any resource.")
+	flags.StringSliceVar(&o.requiredVerbs, "require-verbs", o.requiredVerbs, "Verbs a resource must support to be listed. Must include list. Drop delete to also index the objects of list-only resources as potential owners, or add watch to only scan watchable resources.")
+	flags.StringVar(&o.retryConfig, "retry-config", o.retryConfig, "YAML file configuring retries of failed list requests, with a default policy and policies for specific API groups, each with retries per request, a retry budget per scan, backoff, and a timeout per request.")
+	flags.DurationVar(&o.listTimeout, "list-request-timeout", o.listTimeout, "Timeout of each page request when listing resources, e.g. 30s, so a page that hangs fails instead of taking up the whole scan. Timed out pages are retried as configured by --retry-config, whose per-group timeouts take precedence. Unbounded if 0.")
+	flags.DurationVar(&o.stallTimeout, "stall-timeout", o.stallTimeout, "Stop listing a resource that receives no page for this long, including retries, e.g. 5m, recording it as failed and continuing with the next resource, so a hung aggregated API does not wedge the scan. Unbounded if 0.")
+	flags.BoolVar(&o.rediscover, "rediscover", o.rediscover, "Run discovery again after listing, and also list resources discovered then, such as custom resources installed during the scan, so objects owned by them are not reported as having owners of unknown kinds.")
+	flags.BoolVar(&o.allVersions, "all-versions", o.allVersions, "List every served version of each resource, rather than only the preferred version, counting each object once. Finds objects the preferred version does not serve, e.g. during custom resource version migrations, at the cost of more list requests.")
+	flags.DurationVar(&o.olderThan, "older-than", o.olderThan, "Only report findings of objects created at least this long ago, e.g. 720h, to separate long-standing debris from newly introduced breakage. Findings of objects without a creation timestamp are always reported.")
+	flags.IntVar(&o.maxFindings, "max-findings", o.maxFindings, "Write at most this many findings, still counting the rest in the summary, to keep logs bounded. Unlimited if 0.")
+	flags.BoolVar(&o.failOnWarnings, "fail-on-warning", o.failOnWarnings, fmt.Sprintf("Exit with code %d if any warning-level findings are reported. Error-level findings with --fail-on-errors exit with 1, and scans that could not discover or list some resources always exit with %d.", pkg.ExitCodeWarnings, pkg.ExitCodeIncomplete))
+	flags.BoolVar(&o.watch, "watch", o.watch, "After the initial scan, keep rescanning and write the findings introduced and resolved by each rescan as they are detected.")
+	flags.DurationVar(&o.watchInterval, "watch-interval", o.watchInterval, "Time to wait between the end of one scan and the start of the next with --watch.")
+	flags.BoolVar(&o.incremental, "incremental", o.incremental, "With --watch, keep findings up to date from metadata watches after the initial scan instead of rescanning, rechecking only objects whose metadata changed and their dependents.")
+	flags.StringVar(&o.fromDir, "from-dir", o.fromDir, "Scan the objects in the YAML and JSON files in a directory instead of a cluster, such as rendered manifests or an exported cluster dump.")
+	flags.StringVar(&o.fromEtcdSnapshot, "from-etcd-snapshot", o.fromEtcdSnapshot, "Scan the objects stored in an etcd snapshot, written by 'etcdctl snapshot save', instead of a cluster.")
+	flags.StringVar(&o.fromVeleroBackup, "from-velero-backup", o.fromVeleroBackup, "Scan the objects in a Velero backup tarball, written by 'velero backup download', instead of a cluster, and report ownerReferences that will dangle after restore.")
+	flags.StringVar(&o.fromSnapshot, "from-snapshot", o.fromSnapshot, "Scan the resources and objects in a snapshot written by --snapshot-out instead of a cluster, to re-run checks or change output formats without listing the cluster again.")
+	flags.StringVar(&o.snapshotOut, "snapshot-out", o.snapshotOut, "Write the resources discovered and objects listed by a complete scan to this file, gzip-compressed, for use with --from-snapshot.")
+	flags.StringVar(&o.ownersFrom, "owners-from", o.ownersFrom, "Owner inventory to find owners in, in addition to the owners listed from the cluster: a snapshot written by --snapshot-out, or JSON records of uid, apiVersion, kind, namespace, and name, e.g. to validate an export against the cluster it came from.")
+	flags.BoolVar(&o.ownersFromOnly, "owners-from-only", o.ownersFromOnly, "With --owners-from and --filename, only find owners in the inventory instead of listing them from the cluster.")
+	flags.BoolVar(&o.estimateDeletions, "estimate-deletions", o.estimateDeletions, "After the summary, write the number of objects the garbage collector is expected to delete once it processes the ownerReferences found, per resource and namespace, including dependents deleted in cascade, to plan for the load of remediating findings.")
+	flags.StringVar(&o.inventory, "inventory", o.inventory, "Write every ownerReference of the checked objects to this file, valid or not, as newline-delimited JSON records of the child resource, namespace, name, and uid, the owner apiVersion, kind, name, uid, and controller flag, and the codes of any findings, for dependency analysis.")
+	flags.StringVar(&o.summaryFile, "summary-file", o.summaryFile, "Write a JSON summary of each scan to this file, without the findings: the number of errors and warnings by code, resource, and namespace, completeness, duration, and scan ID, for dashboards.")
+	flags.StringVar(&o.supportBundle, "support-bundle", o.supportBundle, "Write an archive of the report, discovered resources, scan stats, flags, version, and the metadata of the objects involved in findings to this file, gzip-compressed, for filing issues.")
+	flags.BoolVar(&o.redactBundle, "support-bundle-redact", o.redactBundle, "Replace the values of labels and annotations in the objects written to --support-bundle.")
+	flags.StringVar(&o.etcdPrefix, "etcd-prefix", o.etcdPrefix, "The --etcd-prefix of the apiserver that stored the objects read with --from-etcd-snapshot.")
+	flags.StringVar(&o.discoverySnapshot, "discovery-snapshot", o.discoverySnapshot, "File describing the API resources of the objects scanned with --from-dir, --from-etcd-snapshot, or --from-velero-backup, written by the discovery-snapshot command. Defaults to the built-in resources of Kubernetes 1.22.")
+	flags.StringSliceVar(&o.auditLogs, "audit-log", o.auditLogs, "Apiserver audit log files to find the user and user agent that last wrote each object with an invalid ownerReference in. Files ending in .gz are decompressed.")
+	o.rules.addFlags(flags)
+	o.publish.addFlags(flags)
+}
+
+// offline returns true if objects are read from files instead of a cluster
+func (o *scanOptions) offline() bool {
+	return o.fromDir != "" || o.fromEtcdSnapshot != "" || o.fromVeleroBackup != "" || o.fromSnapshot != ""
+}
+
+// configure sets up the rule, baseline, since, audit, and support bundle options in opts
+func (o *scanOptions) configure(opts *pkg.VerifyGCOptions, flags *pflag.FlagSet) error {
+	if err := o.rules.configure(opts); err != nil {
+		return err
+	}
+	if o.baselineMode != "exclude" && o.baselineMode != "demote" {
+		return fmt.Errorf("invalid --baseline-mode, only 'exclude' and 'demote' are supported: %v", o.baselineMode)
+	}
+	if o.baseline != "" {
+		findings, err := pkg.LoadBaseline(o.baseline)
+		if err != nil {
+			return err
+		}
+		opts.Baseline = &pkg.Baseline{Findings: findings, Demote: o.baselineMode == "demote"}
+	}
+	if o.ownersFromOnly && o.ownersFrom == "" {
+		return fmt.Errorf("--owners-from-only requires --owners-from")
+	}
+	if o.ownersFrom != "" {
+		owners, err := pkg.LoadOwners(o.ownersFrom)
+		if err != nil {
+			return err
+		}
+		opts.Owners = &pkg.ExternalOwners{Owners: owners, Replace: o.ownersFromOnly}
+	}
+	if o.since != "" {
+		findings, err := pkg.LoadPreviousFindings(o.since)
+		if err != nil {
+			return err
+		}
+		opts.Since = findings
+	}
+	opts.WriteBaseline = o.writeBaseline
+	opts.FailOnErrors = o.failOnErrors
+	opts.FailOnWarnings = o.failOnWarnings
+	opts.FailFast = o.failFast
+	opts.MaxFindings = o.maxFindings
+	opts.OlderThan = o.olderThan
+	opts.AllVersions = o.allVersions
+	opts.SkipRediscovery = !o.rediscover
+	if o.retryConfig != "" {
+		config, err := pkg.LoadRetryConfig(o.retryConfig)
+		if err != nil {
+			return err
+		}
+		opts.Retry = config
+	}
+	opts.ListRequestTimeout = o.listTimeout
+	opts.StallTimeout = o.stallTimeout
+	opts.RequiredVerbs = o.requiredVerbs
+	if o.startFrom != "" {
+		resource, err := parseStartFrom(o.startFrom)
+		if err != nil {
+			return err
+		}
+		opts.StartFrom = &resource
+	}
+	if o.warningsFile != "" {
+		file, err := os.Create(o.warningsFile)
+		if err != nil {
+			return err
+		}
+		atExit = append(atExit, func() { file.Close() })
+		opts.WarningsOut = file
+	}
+	if len(o.namespaceKeys) > 0 {
+		opts.NamespaceLabels = &pkg.NamespaceLabelOptions{Keys: o.namespaceKeys}
+	}
+	if o.outputDir != "" {
+		opts.Split = &pkg.SplitOptions{Dir: o.outputDir, By: o.splitBy}
+	} else if flags.Changed("split-by") {
+		return fmt.Errorf("--split-by requires --output-dir")
+	}
+	color, err := useColor(o.color)
+	if err != nil {
+		return err
+	}
+	opts.Color = color
+	opts.ShowProgress = stderrTerminal && o.progressEvents != "-"
+	if flags.Changed("progress") {
+		if o.progress && o.progressEvents == "-" {
+			return fmt.Errorf("--progress cannot be used with --progress-events=-")
+		}
+		opts.ShowProgress = o.progress
+	}
+	if o.progressEvents == "-" {
+		opts.Progress = os.Stderr
+	} else if o.progressEvents != "" {
+		file, err := os.Create(o.progressEvents)
+		if err != nil {
+			return err
+		}
+		atExit = append(atExit, func() { file.Close() })
+		opts.Progress = file
+	}
+	opts.EmitTriage = o.emitTriage
+	opts.SummarizeOwnerKinds = o.ownerSummary
+	opts.SummarizeManagers = o.managerSummary
+	opts.SnapshotOut = o.snapshotOut
+	opts.Inventory = o.inventory
+	opts.SummaryFile = o.summaryFile
+	opts.EstimateDeletions = o.estimateDeletions
+	if o.redact {
+		if o.redactSalt == "" {
+			return fmt.Errorf("--redact requires --redact-salt-file")
+		}
+		salt, err := pkg.LoadRedactSalt(o.redactSalt)
+		if err != nil {
+			return err
+		}
+		opts.Redact = &pkg.RedactOptions{Salt: salt, MappingPath: o.redactMapping}
+	} else if o.redactSalt != "" || o.redactMapping != "" {
+		return fmt.Errorf("--redact-salt-file and --redact-mapping require --redact")
+	}
+	if len(o.auditLogs) > 0 {
+		opts.Audit = &pkg.AuditOptions{Paths: o.auditLogs}
+	}
+	if o.supportBundle != "" {
+		opts.SupportBundle = &pkg.SupportBundleOptions{Path: o.supportBundle, Flags: redactedFlags(flags), Redact: o.redactBundle}
+	}
+	return nil
+}
+
+func newScanCommand(clientOpts *clientOptions, scanOpts *scanOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scan",
+		Short: "Check all objects in the cluster for invalid ownerReferences",
+		Long: `Discovers the resources in the cluster, lists the metadata of all objects,
+and checks the ownerReferences of each object refer to existing owners
+with matching names, kinds, and namespaces.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runScan(cmd, clientOpts, scanOpts)
+		},
+	}
+	scanOpts.addFlags(cmd.Flags())
+	return cmd
+}
+
+func runScan(cmd *cobra.Command, clientOpts *clientOptions, scanOpts *scanOptions) error {
+	if err := clientOpts.complete(cmd.Flags()); err != nil {
+		return err
+	}
+	if len(scanOpts.filenames) > 0 {
+		if scanOpts.offline() || len(scanOpts.contexts) > 0 || scanOpts.allContexts || scanOpts.capi {
+			return fmt.Errorf("--filename is not supported when scanning offline, or with --contexts, --all-contexts, or --capi")
+		}
+		if scanOpts.output == "crd" || scanOpts.benchmark || scanOpts.writeBaseline != "" || scanOpts.publish.enabled() {
+			return fmt.Errorf("-o crd, --benchmark, --write-baseline, and publishing results are not supported with --filename")
+		}
+	}
+	writeFlags := scanOpts.publish.clusterWriteFlags()
+	if scanOpts.output == "crd" {
+		writeFlags = append(writeFlags, "-o crd")
+	}
+	if err := clientOpts.checkReadOnly(writeFlags); err != nil {
+		return err
+	}
+	if scanOpts.incremental && !scanOpts.watch {
+		return fmt.Errorf("--incremental requires --watch")
+	}
+	if scanOpts.watch {
+		if scanOpts.offline() || len(scanOpts.filenames) > 0 || len(scanOpts.contexts) > 0 || scanOpts.allContexts || scanOpts.capi {
+			return fmt.Errorf("--watch is not supported when scanning offline, or with --filename, --contexts, --all-contexts, or --capi")
+		}
+	}
+	if scanOpts.minCoverage != 0 && !scanOpts.preflight {
+		return fmt.Errorf("--min-coverage requires --preflight")
+	}
+	if scanOpts.preflight && (scanOpts.offline() || len(scanOpts.contexts) > 0 || scanOpts.allContexts || scanOpts.capi) {
+		return fmt.Errorf("--preflight is not supported when scanning offline, or with --contexts, --all-contexts, or --capi")
+	}
+	if scanOpts.gcHealth && (scanOpts.offline() || len(scanOpts.contexts) > 0 || scanOpts.allContexts || scanOpts.capi) {
+		return fmt.Errorf("--check-gc-health is not supported when scanning offline, or with --contexts, --all-contexts, or --capi")
+	}
+	if scanOpts.gcHealthInterval != 0 && !scanOpts.gcHealth {
+		return fmt.Errorf("--gc-health-sample-interval requires --check-gc-health")
+	}
+	if scanOpts.offline() {
+		return runOfflineScan(cmd, scanOpts)
+	}
+	if len(scanOpts.contexts) > 0 || scanOpts.allContexts || scanOpts.capi {
+		return runMultiClusterScan(cmd, clientOpts, scanOpts)
+	}
+	config, err := clientOpts.restConfig()
+	if err != nil {
+		return err
+	}
+
+	var apiCallCounter *pkg.APICallCounter
+	if scanOpts.benchmark {
+		apiCallCounter = &pkg.APICallCounter{}
+		config.Wrap(apiCallCounter.Wrap)
+	}
+
+	discoveryClient, metadataClient, err := clientOpts.clients(config)
+	if err != nil {
+		return err
+	}
+
+	opts := &pkg.VerifyGCOptions{
+		DiscoveryClient: discoveryClient,
+		MetadataClient:  metadataClient,
+		Output:          scanOpts.output,
+		Stderr:          os.Stderr,
+		Stdout:          os.Stdout,
+		Benchmark:       scanOpts.benchmark,
+		APICallCounter:  apiCallCounter,
+	}
+	if len(scanOpts.filenames) > 0 {
+		if opts.Objects, err = readObjectFiles(scanOpts.filenames); err != nil {
+			return err
+		}
+		// rendered manifests are applied to the current namespace
+		if opts.ObjectsNamespace, _, err = clientOpts.configFlags.ToRawKubeConfigLoader().Namespace(); err != nil {
+			return err
+		}
+	}
+	if scanOpts.preflight {
+		authorizationClient, err := authorizationv1client.NewForConfig(config)
+		if err != nil {
+			return err
+		}
+		opts.Preflight = &pkg.PreflightOptions{Client: authorizationClient, MinCoverage: scanOpts.minCoverage}
+	}
+	if scanOpts.gcHealth {
+		healthConfig := clientOpts.listLimit.apply(config)
+		leaseClient, err := coordinationv1client.NewForConfig(healthConfig)
+		if err != nil {
+			return err
+		}
+		podClient, err := corev1client.NewForConfig(healthConfig)
+		if err != nil {
+			return err
+		}
+		opts.GCHealth = &pkg.GCHealthOptions{Leases: leaseClient, Pods: podClient, SampleInterval: scanOpts.gcHealthInterval}
+	}
+	if err := scanOpts.configure(opts, cmd.Flags()); err != nil {
+		return err
+	}
+	opts.Cluster = clientOpts.clusterInfo(config)
+	if err := scanOpts.publish.configure(opts, clientOpts.listLimit.apply(config), opts.Cluster, scanOpts.output == "crd"); err != nil {
+		return err
+	}
+	if scanOpts.watch {
+		watchOpts := &pkg.WatchOptions{Verify: opts, Interval: scanOpts.watchInterval, Incremental: scanOpts.incremental}
+		if err := watchOpts.Validate(); err != nil {
+			return err
+		}
+		return watchOpts.Run(cmd.Context())
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	return opts.Run(cmd.Context())
+}
+
+// sensitiveFlags are the flags whose values are redacted from support bundles.
+// URLs of other flags are redacted too if they carry credentials.
+var sensitiveFlags = map[string]bool{
+	"token": true, "username": true, "password": true,
+	"notify-url": true, "pushgateway-url": true, "alertmanager-url": true, "export": true, "github-api-url": true,
+}
+
+// redactedFlags returns the flags set on the command line, with the values of sensitive flags redacted,
+// and the userinfo and query string of values of other flags that are URLs
+func redactedFlags(flags *pflag.FlagSet) map[string]string {
+	set := map[string]string{}
+	flags.Visit(func(flag *pflag.Flag) {
+		value := flag.Value.String()
+		if sensitiveFlags[flag.Name] {
+			value = "<redacted>"
+		} else {
+			value = redactURL(value)
+		}
+		set[flag.Name] = value
+	})
+	return set
+}
+
+// redactURL returns value with the userinfo and query string redacted if it is a URL with either,
+// since they may hold basic auth credentials or signatures
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" || (u.User == nil && u.RawQuery == "") {
+		return value
+	}
+	if u.User != nil {
+		u.User = url.User("REDACTED")
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = "REDACTED"
+	}
+	return u.String()
+}
+
+// readObjectFiles reads the objects in filenames, where '-' is stdin
+func readObjectFiles(filenames []string) ([]*unstructured.Unstructured, error) {
+	objects := []*unstructured.Unstructured{}
+	for _, filename := range filenames {
+		f, err := openInput(filename)
+		if err != nil {
+			return nil, err
+		}
+		source := filename
+		if filename == "-" {
+			source = "stdin"
+		}
+		fileObjects, err := pkg.ReadObjects(f, source)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, fileObjects...)
+	}
+	return objects, nil
+}
+
+func runOfflineScan(cmd *cobra.Command, scanOpts *scanOptions) error {
+	sources := 0
+	for _, source := range []string{scanOpts.fromDir, scanOpts.fromEtcdSnapshot, scanOpts.fromVeleroBackup, scanOpts.fromSnapshot} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("only one of --from-dir, --from-etcd-snapshot, --from-velero-backup, and --from-snapshot may be used")
+	}
+	if len(scanOpts.contexts) > 0 || scanOpts.allContexts || scanOpts.capi {
+		return fmt.Errorf("--contexts, --all-contexts, and --capi are not supported when scanning offline")
+	}
+	if scanOpts.output == "crd" || scanOpts.benchmark || scanOpts.publish.enabled() {
+		return fmt.Errorf("-o crd, --benchmark, and publishing results are not supported when scanning offline")
+	}
+	if scanOpts.fromSnapshot != "" && scanOpts.discoverySnapshot != "" {
+		return fmt.Errorf("--discovery-snapshot cannot be used with --from-snapshot, which includes the discovered resources")
+	}
+	discoveryClient, metadataClient, err := offlineClients(scanOpts)
+	if err != nil {
+		return err
+	}
+
+	opts := &pkg.VerifyGCOptions{
+		DiscoveryClient: discoveryClient,
+		MetadataClient:  metadataClient,
+		Output:          scanOpts.output,
+		Stderr:          os.Stderr,
+		Stdout:          os.Stdout,
+	}
+	if err := scanOpts.configure(opts, cmd.Flags()); err != nil {
+		return err
+	}
+	if scanOpts.fromVeleroBackup != "" {
+		if opts.Rules == nil {
+			opts.Rules = pkg.DefaultRules()
+		}
+		opts.Rules = append(opts.Rules, pkg.VeleroRestoreRule())
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	return opts.Run(cmd.Context())
+}
+
+// offlineClients returns clients serving the objects read from the offline source selected by scanOpts
+func offlineClients(scanOpts *scanOptions) (discovery.DiscoveryInterface, metadata.Interface, error) {
+	if scanOpts.fromSnapshot != "" {
+		snapshot, err := pkg.LoadScanSnapshot(scanOpts.fromSnapshot)
+		if err != nil {
+			return nil, nil, err
+		}
+		return snapshot.Clients(os.Stderr)
+	}
+	var err error
+	resources := pkg.DefaultDiscoverySnapshot()
+	if scanOpts.discoverySnapshot != "" {
+		if resources, err = pkg.LoadDiscoverySnapshot(scanOpts.discoverySnapshot); err != nil {
+			return nil, nil, err
+		}
+	}
+	var objects []*unstructured.Unstructured
+	switch {
+	case scanOpts.fromDir != "":
+		objects, err = pkg.ReadObjectsFromDir(scanOpts.fromDir)
+	case scanOpts.fromEtcdSnapshot != "":
+		resources, objects, err = pkg.ReadEtcdSnapshot(scanOpts.fromEtcdSnapshot, scanOpts.etcdPrefix, resources, os.Stderr)
+	default:
+		resources, objects, err = pkg.ReadVeleroBackup(scanOpts.fromVeleroBackup, resources)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return pkg.OfflineClients(resources, objects, os.Stderr)
+}
+
+func newDiscoverySnapshotCommand(clientOpts *clientOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "discovery-snapshot",
+		Short: "Print the API resources served by the cluster, for use with scanning objects offline",
+		Long: `Prints the API resources served by the cluster as YAML, for use with "scan --from-dir --discovery-snapshot"
+to scan objects offline with the same resources, scopes, and preferred versions as the cluster.
+
+  kubectl-check-ownerreferences discovery-snapshot > snapshot.yaml
+  kubectl-check-ownerreferences scan --from-dir ./rendered --discovery-snapshot snapshot.yaml`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := clientOpts.complete(cmd.Flags()); err != nil {
+				return err
+			}
+			config, err := clientOpts.restConfig()
+			if err != nil {
+				return err
+			}
+			discoveryClient, _, err := clientOpts.clients(config)
+			if err != nil {
+				return err
+			}
+			return pkg.WriteDiscoverySnapshot(discoveryClient, cmd.OutOrStdout())
+		},
+	}
+}
+
+func runMultiClusterScan(cmd *cobra.Command, clientOpts *clientOptions, scanOpts *scanOptions) error {
+	modes := 0
+	for _, set := range []bool{len(scanOpts.contexts) > 0, scanOpts.allContexts, scanOpts.capi} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return fmt.Errorf("only one of --contexts, --all-contexts, and --capi may be used")
+	}
+	if scanOpts.benchmark || scanOpts.publish.enabled() {
+		return fmt.Errorf("--benchmark and publishing results are not supported when scanning multiple clusters")
+	}
+	if scanOpts.baseline != "" || scanOpts.writeBaseline != "" || scanOpts.since != "" || len(scanOpts.auditLogs) > 0 || scanOpts.snapshotOut != "" || scanOpts.inventory != "" || scanOpts.summaryFile != "" || scanOpts.supportBundle != "" || scanOpts.emitTriage || scanOpts.redact || scanOpts.failFast || scanOpts.progress || scanOpts.progressEvents != "" || scanOpts.warningsFile != "" || scanOpts.outputDir != "" || len(scanOpts.namespaceKeys) > 0 || scanOpts.startFrom != "" {
+		return fmt.Errorf("--baseline, --write-baseline, --since, --audit-log, --snapshot-out, --inventory, --summary-file, --support-bundle, --emit-triage, --redact, --fail-fast, --progress, --progress-events, --warnings-file, --output-dir, --enrich-namespace-labels, and --start-from are not supported when scanning multiple clusters")
+	}
+
+	var clusters []pkg.WorkloadCluster
+	if scanOpts.capi {
+		var err error
+		if clusters, err = capiClusters(cmd, clientOpts, scanOpts); err != nil {
+			return err
+		}
+		if len(clusters) == 0 {
+			return fmt.Errorf("no Cluster API clusters found")
+		}
+	} else {
+		contexts := scanOpts.contexts
+		if scanOpts.allContexts {
+			var err error
+			if contexts, err = clientOpts.contextNames(); err != nil {
+				return err
+			}
+		}
+		for _, context := range contexts {
+			config, err := clientOpts.restConfigForContext(context)
+			if err != nil {
+				return fmt.Errorf("context %s: %v", context, err)
+			}
+			clusters = append(clusters, pkg.WorkloadCluster{Name: context, Config: config})
+		}
+	}
+
+	color, err := useColor(scanOpts.color)
+	if err != nil {
+		return err
+	}
+	opts := &pkg.MultiClusterOptions{
+		Color:               color,
+		Parallelism:         scanOpts.parallelClusters,
+		Output:              scanOpts.output,
+		FailOnErrors:        scanOpts.failOnErrors,
+		FailOnWarnings:      scanOpts.failOnWarnings,
+		MaxFindings:         scanOpts.maxFindings,
+		SummarizeOwnerKinds: scanOpts.ownerSummary,
+		SummarizeManagers:   scanOpts.managerSummary,
+		Stderr:              os.Stderr,
+		Stdout:              os.Stdout,
+	}
+	var retry *pkg.RetryConfig
+	if scanOpts.retryConfig != "" {
+		if retry, err = pkg.LoadRetryConfig(scanOpts.retryConfig); err != nil {
+			return err
+		}
+	}
+	for _, cluster := range clusters {
+		if cluster.Err != nil {
+			opts.Clusters = append(opts.Clusters, pkg.ClusterScan{Name: cluster.Name, Err: cluster.Err})
+			continue
+		}
+		discoveryClient, metadataClient, err := clientOpts.clients(cluster.Config)
+		if err != nil {
+			return fmt.Errorf("cluster %s: %v", cluster.Name, err)
+		}
+		verifyOpts := &pkg.VerifyGCOptions{DiscoveryClient: discoveryClient, MetadataClient: metadataClient, OlderThan: scanOpts.olderThan, AllVersions: scanOpts.allVersions, SkipRediscovery: !scanOpts.rediscover, RequiredVerbs: scanOpts.requiredVerbs, Retry: retry, ListRequestTimeout: scanOpts.listTimeout, StallTimeout: scanOpts.stallTimeout, Cluster: &pkg.ClusterInfo{Server: cluster.Config.Host}}
+		if err := scanOpts.rules.configure(verifyOpts); err != nil {
+			return err
+		}
+		opts.Clusters = append(opts.Clusters, pkg.ClusterScan{Name: cluster.Name, Verify: verifyOpts})
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	return opts.Run(cmd.Context())
+}
+
+// capiClusters finds the Cluster API workload clusters managed by the selected cluster
+func capiClusters(cmd *cobra.Command, clientOpts *clientOptions, scanOpts *scanOptions) ([]pkg.WorkloadCluster, error) {
+	config, err := clientOpts.restConfig()
+	if err != nil {
+		return nil, err
+	}
+	discoveryClient, metadataClient, err := clientOpts.clients(config)
+	if err != nil {
+		return nil, err
+	}
+	coreClient, err := corev1client.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	opts := &pkg.CAPIOptions{
+		DiscoveryClient: discoveryClient,
+		MetadataClient:  metadataClient,
+		Secrets:         coreClient,
+		Namespace:       scanOpts.capiNamespace,
+		Selector:        scanOpts.capiSelector,
+		ConfigureClient: clientOpts.configureClient,
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return opts.WorkloadClusters(cmd.Context())
+}
+
+// parseStartFrom parses a --start-from resource, given as group/resource, or resource for the core group
+func parseStartFrom(value string) (schema.GroupResource, error) {
+	resource := schema.GroupResource{Resource: value}
+	if i := strings.LastIndex(value, "/"); i >= 0 {
+		resource = schema.GroupResource{Group: value[:i], Resource: value[i+1:]}
+	}
+	if resource.Resource == "" || strings.Contains(resource.Group, "/") {
+		return schema.GroupResource{}, fmt.Errorf("invalid --start-from %q, must be group/resource, or resource for the core group", value)
+	}
+	return resource, nil
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+func newServeCommand(clientOpts *clientOptions) *cobra.Command {
+	interval := time.Hour
+	address := ":8080"
+	grpcAddress := ""
+	authTokenFile := ""
+	publishReports := false
+	incremental := false
+	historySize := 0
+	historyDir := ""
+	alertmanagerURL := ""
+	leaderElect := false
+	leaderElection := &pkg.LeaderElectionOptions{
+		Name:          "kubectl-check-ownerreferences",
+		LeaseDuration: 15 * time.Second,
+		RenewDeadline: 10 * time.Second,
+		RetryPeriod:   2 * time.Second,
+	}
+	alertmanagerLabels := map[string]string{}
+	alertmanagerAnnotations := map[string]string{}
+	ruleOpts := &ruleOptions{}
+	publishOpts := newPublishOptions()
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Scan on a schedule and serve metrics and results over HTTP",
+		Long: `Runs continuously, rescanning the cluster on the given interval,
+and serves the results of the last completed scan over HTTP:
+
+  /healthz                liveness probe
+  /readyz                 readiness probe, succeeds once a scan has completed
+  /metrics                Prometheus metrics
+  /results                findings of the last completed scan as JSON
+  /results.html           findings of the last completed scan as HTML
+  /results/history        summaries of the last --history-size scans, newest first
+  /results/history/<id>   findings of a scan in history, by the id in its summary
+
+If --grpc-address is set, the Findings gRPC service defined in
+pkg/api/v1alpha1/findings.proto is also served, streaming the events of
+each scan as it runs, and running scans scoped to namespaces or resources
+on demand.
+
+If --auth-token-file is set, requests to /metrics and /results, and gRPC
+calls, must include the token as an "Authorization: Bearer <token>" header.
+
+When running multiple replicas, --leader-elect limits scanning to the
+replica holding a Lease. Other replicas stand by and report ready.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := clientOpts.complete(cmd.Flags()); err != nil {
+				return err
+			}
+			writeFlags := publishOpts.clusterWriteFlags()
+			if publishReports {
+				writeFlags = append(writeFlags, "--publish-reports")
+			}
+			if leaderElect {
+				writeFlags = append(writeFlags, "--leader-elect")
+			}
+			if err := clientOpts.checkReadOnly(writeFlags); err != nil {
+				return err
+			}
+			config, err := clientOpts.restConfig()
+			if err != nil {
+				return err
+			}
+			discoveryClient, metadataClient, err := clientOpts.clients(config)
+			if err != nil {
+				return err
+			}
+
+			authToken := ""
+			if authTokenFile != "" {
+				data, err := ioutil.ReadFile(authTokenFile)
+				if err != nil {
+					return err
+				}
+				authToken = strings.TrimSpace(string(data))
+				if authToken == "" {
+					return fmt.Errorf("auth token file %s is empty", authTokenFile)
+				}
+			}
+
+			opts := &pkg.ServeOptions{
+				Verify: &pkg.VerifyGCOptions{
+					DiscoveryClient: discoveryClient,
+					MetadataClient:  metadataClient,
+					Stderr:          os.Stderr,
+					Stdout:          os.Stdout,
+				},
+				Interval:    interval,
+				Address:     address,
+				GRPCAddress: grpcAddress,
+				AuthToken:   authToken,
+				Incremental: incremental,
+			}
+			if historySize > 0 {
+				opts.History = &pkg.HistoryOptions{Size: historySize, Dir: historyDir}
+			} else if historyDir != "" {
+				return fmt.Errorf("--history-dir requires --history-size")
+			}
+			if alertmanagerURL != "" {
+				opts.Alertmanager = &pkg.AlertmanagerOptions{
+					URL:         alertmanagerURL,
+					Labels:      alertmanagerLabels,
+					Annotations: alertmanagerAnnotations,
+				}
+			}
+			if leaderElect {
+				client, err := coordinationv1client.NewForConfig(clientOpts.listLimit.apply(config))
+				if err != nil {
+					return err
+				}
+				leaderElection.Client = client
+				if leaderElection.Namespace == "" {
+					if leaderElection.Namespace, _, err = clientOpts.configFlags.ToRawKubeConfigLoader().Namespace(); err != nil {
+						return err
+					}
+				}
+				if leaderElection.Identity == "" {
+					if leaderElection.Identity, err = os.Hostname(); err != nil {
+						return err
+					}
+				}
+				opts.LeaderElection = leaderElection
+			}
+			if err := ruleOpts.configure(opts.Verify); err != nil {
+				return err
+			}
+			opts.Verify.Cluster = clientOpts.clusterInfo(config)
+			if err := publishOpts.configure(opts.Verify, clientOpts.listLimit.apply(config), opts.Verify.Cluster, publishReports); err != nil {
+				return err
+			}
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+
+			return opts.Run(cmd.Context())
+		},
+	}
+	cmd.Flags().DurationVar(&interval, "interval", interval, "Time to wait between the end of one scan and the start of the next.")
+	cmd.Flags().StringVar(&address, "address", address, "Address to serve metrics and results on.")
+	cmd.Flags().StringVar(&grpcAddress, "grpc-address", grpcAddress, "Address to serve the Findings gRPC service on, e.g. :9090. Disabled if empty.")
+	cmd.Flags().StringVar(&authTokenFile, "auth-token-file", authTokenFile, "File containing a bearer token required to access metrics and results.")
+	cmd.Flags().BoolVar(&incremental, "incremental", incremental, "Keep findings up to date from metadata watches after the first completed scan instead of rescanning, and publish the current findings every --interval.")
+	cmd.Flags().IntVar(&historySize, "history-size", historySize, "Number of completed scans to retain and serve at /results/history. Disabled if 0.")
+	cmd.Flags().StringVar(&historyDir, "history-dir", historyDir, "Directory to persist the scans retained with --history-size to, so history survives restarts.")
+	cmd.Flags().BoolVar(&publishReports, "publish-reports", publishReports, "Write the results of each scan to OwnerReferenceReport objects.")
+	cmd.Flags().StringVar(&alertmanagerURL, "alertmanager-url", alertmanagerURL, "Alertmanager URL to send alerts for errors to after each scan, e.g. http://alertmanager:9093.")
+	cmd.Flags().StringToStringVar(&alertmanagerLabels, "alertmanager-labels", alertmanagerLabels, "Labels to add to alerts sent to Alertmanager, as key=value pairs.")
+	cmd.Flags().StringToStringVar(&alertmanagerAnnotations, "alertmanager-annotations", alertmanagerAnnotations, "Annotations to add to alerts sent to Alertmanager, as key=value pairs.")
+	cmd.Flags().BoolVar(&leaderElect, "leader-elect", leaderElect, "Only scan while holding a Lease, so one of several replicas scans at a time.")
+	cmd.Flags().StringVar(&leaderElection.Name, "leader-elect-lease-name", leaderElection.Name, "Name of the Lease used for leader election.")
+	cmd.Flags().StringVar(&leaderElection.Namespace, "leader-elect-lease-namespace", leaderElection.Namespace, "Namespace of the Lease used for leader election. Defaults to the namespace of the current context, or the pod's namespace in-cluster.")
+	cmd.Flags().StringVar(&leaderElection.Identity, "leader-elect-identity", leaderElection.Identity, "Identity of this replica recorded in the Lease. Defaults to the hostname, which is the pod name in-cluster.")
+	cmd.Flags().DurationVar(&leaderElection.LeaseDuration, "leader-elect-lease-duration", leaderElection.LeaseDuration, "Time standby replicas wait before taking over a Lease that was not renewed.")
+	cmd.Flags().DurationVar(&leaderElection.RenewDeadline, "leader-elect-renew-deadline", leaderElection.RenewDeadline, "Time the leader retries renewing the Lease before it stops scanning.")
+	cmd.Flags().DurationVar(&leaderElection.RetryPeriod, "leader-elect-retry-period", leaderElection.RetryPeriod, "Time between attempts to acquire or renew the Lease.")
+	ruleOpts.addFlags(cmd.Flags())
+	publishOpts.addFlags(cmd.Flags())
+	return cmd
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+func newTrendsCommand() *cobra.Command {
+	opts := &pkg.TrendOptions{By: "code", Period: "month"}
+	since := ""
+	cmd := &cobra.Command{
+		Use:   "trends DATABASE",
+		Short: "Report finding counts over time from a findings database",
+		Long: `Reads a SQLite database written by "scan --findings-db" or "serve --findings-db",
+and prints the number of errors and warnings per code or namespace in the last scan
+of each cluster in each day, week, month, or quarter.
+
+  kubectl-check-ownerreferences scan --findings-db findings.db
+  ...
+  kubectl-check-ownerreferences trends findings.db --period quarter --by namespace`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Path = args[0]
+			opts.Stdout = cmd.OutOrStdout()
+			if since != "" {
+				t, err := time.Parse("2006-01-02", since)
+				if err != nil {
+					return fmt.Errorf("invalid --since %q, expected YYYY-MM-DD", since)
+				}
+				opts.Since = t
+			}
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			return opts.Run(cmd.Context())
+		},
+	}
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", opts.Output, "Output format. May be '' or 'json'.")
+	cmd.Flags().StringVar(&opts.By, "by", opts.By, "Group counts by 'code' or 'namespace'.")
+	cmd.Flags().StringVar(&opts.Period, "period", opts.Period, "Report the last scan in each 'day', 'week', 'month', or 'quarter'.")
+	cmd.Flags().StringVar(&opts.Cluster, "cluster", opts.Cluster, "Only report scans of this cluster, as named by --findings-db-cluster.")
+	cmd.Flags().StringVar(&since, "since", since, "Only report scans completed on or after this date, as YYYY-MM-DD.")
+	return cmd
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// stdoutTerminal and stderrTerminal are true if stdout and stderr are terminals, to default to output for humans.
+// stderrTerminal is cleared when --log-file copies stderr to a file, so progress lines are not written to the file.
+var (
+	stdoutTerminal = term.IsTerminal(int(os.Stdout.Fd()))
+	stderrTerminal = term.IsTerminal(int(os.Stderr.Fd()))
+)
+
+// useColor returns true if output should be colored for the --color mode: always, never,
+// or auto to color output to a terminal unless the NO_COLOR environment variable is set
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		_, noColor := os.LookupEnv("NO_COLOR")
+		return stdoutTerminal && !noColor, nil
+	default:
+		return false, fmt.Errorf("invalid --color, only 'auto', 'always', and 'never' are supported: %v", mode)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+func newVerifyReportCommand() *cobra.Command {
+	keyFile := ""
+	signatureFile := ""
+	cmd := &cobra.Command{
+		Use:   "verify-report REPORT --key FILE",
+		Short: "Verify the signature of a report exported with --sign-key",
+		Long: `Verifies that a report exported with --export and --sign-key was not modified after it was signed,
+using the detached signature written next to it (REPORT.sig, or --signature).
+
+Reports signed with an ed25519 private key are verified with the PEM public key (or the private key),
+and reports signed with an HMAC secret with the same secret.
+
+  openssl genpkey -algorithm ed25519 -out report-key.pem
+  openssl pkey -in report-key.pem -pubout -out report-key.pub
+  kubectl-check-ownerreferences scan --export=file:///reports --sign-key=report-key.pem
+  kubectl-check-ownerreferences verify-report /reports/20210203T040506Z.json --key report-key.pub`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := pkg.LoadReportKey(keyFile)
+			if err != nil {
+				return err
+			}
+			report, err := ioutil.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			if signatureFile == "" {
+				signatureFile = args[0] + ".sig"
+			}
+			signature, err := ioutil.ReadFile(signatureFile)
+			if err != nil {
+				return err
+			}
+			if err := key.Verify(report, signature); err != nil {
+				return fmt.Errorf("%s: %v", args[0], err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s: signature verified\n", args[0])
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&keyFile, "key", keyFile, "Key file to verify the signature with: a PEM ed25519 public or private key, or an HMAC secret.")
+	cmd.Flags().StringVar(&signatureFile, "signature", signatureFile, "Signature file. Defaults to REPORT.sig.")
+	cmd.MarkFlagRequired("key")
+	return cmd
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+func versionString() string {
+	return fmt.Sprintf("kubectl-check-ownerreferences version %s (built with %v)\n", pkg.Version, pkg.GoVersion)
+}
+
+func newVersionCommand() *cobra.Command {
+	output := ""
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Display version information",
+		Long: `Displays the version of kubectl-check-ownerreferences. With -o json or -o yaml,
+also displays the git commit, build date, go version, and the versions of
+k8s.io/client-go and k8s.io/apimachinery compiled in.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var data []byte
+			var err error
+			switch output {
+			case "":
+				fmt.Fprint(cmd.OutOrStdout(), versionString())
+				return nil
+			case "json":
+				data, err = json.MarshalIndent(pkg.GetVersionInfo(), "", "  ")
+				data = append(data, '\n')
+			case "yaml":
+				data, err = yaml.Marshal(pkg.GetVersionInfo())
+			default:
+				return fmt.Errorf("invalid output format, only '', 'json', and 'yaml' are supported: %v", output)
+			}
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", output, "Output format. May be '', 'json', or 'yaml'.")
+	return cmd
+}
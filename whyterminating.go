@@ -0,0 +1,97 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kubectl-check-ownerreferences/pkg"
+)
+
+func newWhyTerminatingCommand(clientOpts *clientOptions) *cobra.Command {
+	opts := &pkg.WhyTerminatingOptions{}
+	cmd := &cobra.Command{
+		Use:   "why-terminating namespace/<name>",
+		Short: "Show what keeps a terminating namespace from being deleted",
+		Long: `Lists everything still present in a namespace, and prints what blocks the deletion of each
+object and suggested next steps: finalizers whose controllers are missing or have not removed
+them yet, owners deleted in the foreground waiting for dependents with blockOwnerDeletion,
+objects the namespace controller has not deleted, and APIs that cannot be discovered, whose
+objects the namespace controller cannot delete.
+
+  kubectl-check-ownerreferences why-terminating namespace/team-a`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			namespaces := []string{}
+			for _, namespace := range completeNamespaces(cmd, clientOpts) {
+				namespaces = append(namespaces, "namespace/"+namespace)
+			}
+			return filterCompletions(namespaces, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, err := parseNamespaceArg(args[0])
+			if err != nil {
+				return err
+			}
+			if err := clientOpts.complete(cmd.Flags()); err != nil {
+				return err
+			}
+			config, err := clientOpts.restConfig()
+			if err != nil {
+				return err
+			}
+			if opts.DiscoveryClient, opts.MetadataClient, err = clientOpts.clients(config); err != nil {
+				return err
+			}
+			opts.Namespace = namespace
+			opts.Stderr = os.Stderr
+			opts.Stdout = cmd.OutOrStdout()
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			return opts.Run(cmd.Context())
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.Output, "output", "o", opts.Output, "Output format. May be '' or 'json'.")
+	flags.StringSliceVar(&opts.RequiredVerbs, "require-verbs", pkg.DefaultRequiredVerbs, "Verbs a resource must support to be listed, matching the --require-verbs of scans.")
+	return cmd
+}
+
+// parseNamespaceArg parses a namespace given as namespace/<name>, as kubectl accepts, or as a name
+func parseNamespaceArg(arg string) (string, error) {
+	name := arg
+	if i := strings.Index(arg, "/"); i >= 0 {
+		switch arg[:i] {
+		case "namespace", "namespaces", "ns":
+			name = arg[i+1:]
+		default:
+			return "", fmt.Errorf("expected namespace/<name>, got %q", arg)
+		}
+	}
+	if name == "" {
+		return "", fmt.Errorf("expected namespace/<name>, got %q", arg)
+	}
+	return name, nil
+}